@@ -1,14 +1,395 @@
 package main
 
 import (
+	"crypto/tls"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	_ "github.com/joho/godotenv/autoload"
+	"github.com/kr/pretty"
 	"github.com/richo225/octgopus/internal/api"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/config"
+	"github.com/richo225/octgopus/internal/fix"
+	"github.com/richo225/octgopus/internal/grpcapi"
 	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/internal/ratelimit"
+	"github.com/richo225/octgopus/internal/storage"
+	"github.com/richo225/octgopus/internal/wal"
 )
 
 func main() {
 	p := orderbook.NewTradingPlatform()
-	p.SeedData()
 
-	api.Start(p)
+	if mode := os.Getenv("SELF_TRADE_PREVENTION"); mode != "" {
+		if err := p.SetSelfTradePrevention(orderbook.SelfTradePrevention(mode)); err != nil {
+			panic(err)
+		}
+	}
+
+	for _, check := range riskChecks() {
+		p.AddRiskCheck(check)
+	}
+	p.SetCircuitBreaker(circuitBreaker())
+	p.SetAuctionSchedule(auctionSchedule())
+
+	if err := p.SetMarkets(markets()); err != nil {
+		panic(err)
+	}
+
+	if dir := os.Getenv("WAL_DIR"); dir != "" {
+		log := openJournal(dir)
+		defer log.Close()
+
+		store := &orderbook.SnapshotStore{Dir: os.Getenv("SNAPSHOT_DIR")}
+		if store.Dir != "" {
+			restoreSnapshot(p, store)
+		}
+
+		if err := p.Recover(log); err != nil {
+			panic(err)
+		}
+		if err := p.Accounts.Reconcile(); err != nil {
+			panic(err)
+		}
+		p.UseJournal(log)
+
+		if store.Dir != "" {
+			snapshotter := orderbook.NewSnapshotter(p, store, log, snapshotInterval())
+			snapshotter.Start()
+			defer snapshotter.Stop()
+		}
+	}
+
+	if path := os.Getenv("SQLITE_PATH"); path != "" {
+		store, err := storage.OpenSQLite(path)
+		if err != nil {
+			panic(err)
+		}
+		defer store.Close()
+
+		p.Accounts.UseRepository(store)
+		recorder := orderbook.NewHistoryRecorder(p, store)
+		recorder.Start()
+		defer recorder.Stop()
+	}
+
+	if err := p.OpenMarkets(); err != nil {
+		panic(err)
+	}
+
+	keys := keyStore()
+	config := api.Config{
+		Verifier: verifier(keys),
+		DevMode:  os.Getenv("DEV_MODE") == "true",
+		RateLimits: api.RateLimits{
+			Orders:  ratePolicy("ORDERS"),
+			Cancels: ratePolicy("CANCELS"),
+			Reads:   ratePolicy("READS"),
+		},
+	}
+	if path := os.Getenv("AUDIT_LOG"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		config.AuditLog = f
+	}
+
+	go tick(p)
+
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		go serveGRPC(p, grpcapi.Config{Verifier: config.Verifier, RateLimits: config.RateLimits}, ":"+port)
+	}
+	if port := os.Getenv("FIX_PORT"); port != "" {
+		go serveFIX(p, fixConfig(keys), ":"+port)
+	}
+
+	api.Start(p, config)
+}
+
+// tick ends halts and auctions on quiet markets once their time is up.
+func tick(p *orderbook.TradingPlatform) {
+	for range time.Tick(time.Second) {
+		if err := p.Tick(); err != nil {
+			pretty.Log("Failed to end market phase", err.Error())
+		}
+	}
+}
+
+// serveGRPC serves the gRPC API alongside the HTTP API, with the same keys
+// and rate limit policies.
+func serveGRPC(p *orderbook.TradingPlatform, config grpcapi.Config, addr string) {
+	if err := grpcapi.Start(p, config, addr); err != nil {
+		pretty.Log("gRPC server stopped", err.Error())
+		os.Exit(1)
+	}
+}
+
+// serveFIX serves the FIX gateway alongside the HTTP API. Logons are
+// authenticated with the API keys.
+func serveFIX(p *orderbook.TradingPlatform, config fix.Config, addr string) {
+	if err := fix.Start(p, config, addr); err != nil {
+		pretty.Log("FIX acceptor stopped", err.Error())
+		os.Exit(1)
+	}
+}
+
+// fixConfig reads FIX_COMP_ID, FIX_SEQ_DIR and FIX_TLS_CERT and FIX_TLS_KEY.
+// Without FIX_SEQ_DIR sequence numbers start again at every restart. TLS is
+// required when logons are authenticated, as they carry the API secret.
+func fixConfig(keys *auth.KeyStore) fix.Config {
+	config := fix.Config{CompID: os.Getenv("FIX_COMP_ID"), Keys: keys}
+	if dir := os.Getenv("FIX_SEQ_DIR"); dir != "" {
+		config.Store = &fix.FileStore{Dir: dir}
+	}
+
+	cert, key := os.Getenv("FIX_TLS_CERT"), os.Getenv("FIX_TLS_KEY")
+	if cert != "" || key != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			panic(err)
+		}
+		config.TLS = &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}
+	}
+	if keys != nil && config.TLS == nil {
+		pretty.Log("FIX_TLS_CERT and FIX_TLS_KEY must be set to serve FIX with API_KEYS_FILE")
+		os.Exit(1)
+	}
+
+	return config
+}
+
+// markets reads the markets declared in MARKETS_CONFIG, or falls back to the
+// default markets seeded from ./data. SEED_DATA=false opens them empty.
+func markets() []orderbook.MarketSpec {
+	specs := orderbook.DefaultMarkets()
+	if path := os.Getenv("MARKETS_CONFIG"); path != "" {
+		c, err := config.Load(path)
+		if err != nil {
+			pretty.Log(err.Error())
+			os.Exit(1)
+		}
+		specs = c.Specs()
+	}
+
+	if os.Getenv("SEED_DATA") == "false" {
+		for i := range specs {
+			specs[i].Seed = ""
+		}
+	}
+
+	return specs
+}
+
+// keyStore loads the API keys named by API_KEYS_FILE. Without it requests are
+// not authenticated, so the server refuses to start unless DEV_MODE=true.
+func keyStore() *auth.KeyStore {
+	path := os.Getenv("API_KEYS_FILE")
+	if path == "" {
+		if os.Getenv("DEV_MODE") != "true" {
+			pretty.Log("API_KEYS_FILE must be set unless DEV_MODE=true")
+			os.Exit(1)
+		}
+		pretty.Log("WARNING: API_KEYS_FILE not set, authentication is DISABLED. Every account, order and admin endpoint is open. Never run like this outside local development.")
+		return nil
+	}
+
+	keys, err := auth.LoadKeyStore(path)
+	if err != nil {
+		panic(err)
+	}
+
+	return keys
+}
+
+// verifier authenticates requests with keys. MAX_BODY_SIZE caps the bytes
+// read from a request body to check its signature.
+func verifier(keys *auth.KeyStore) *auth.Verifier {
+	if keys == nil {
+		return nil
+	}
+
+	v := auth.NewVerifier(keys)
+	if s := os.Getenv("MAX_BODY_SIZE"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			pretty.Log("MAX_BODY_SIZE must be a positive number of bytes")
+			os.Exit(1)
+		}
+		v.SetMaxBodySize(n)
+	}
+
+	return v
+}
+
+func openJournal(dir string) *wal.Log {
+	policy, err := wal.ParseSyncPolicy(os.Getenv("WAL_SYNC"))
+	if err != nil {
+		panic(err)
+	}
+
+	var interval time.Duration
+	if s := os.Getenv("WAL_SYNC_INTERVAL"); s != "" {
+		interval, err = time.ParseDuration(s)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	log, err := wal.Open(wal.Options{Dir: dir, Sync: policy, SyncInterval: interval})
+	if err != nil {
+		panic(err)
+	}
+
+	if torn := log.TornTail(); torn > 0 {
+		pretty.Log("Discarded torn write at end of journal", torn, "bytes")
+	}
+	pretty.Log("Recovering from journal...", log.LastSequence(), "commands")
+
+	return log
+}
+
+func restoreSnapshot(p *orderbook.TradingPlatform, store *orderbook.SnapshotStore) {
+	snapshot, err := store.Latest()
+	if err != nil {
+		panic(err)
+	}
+	if snapshot == nil {
+		return
+	}
+
+	if err := p.Restore(snapshot); err != nil {
+		panic(err)
+	}
+	pretty.Log("Restored snapshot at command", snapshot.Sequence)
+}
+
+func snapshotInterval() time.Duration {
+	s := os.Getenv("SNAPSHOT_INTERVAL")
+	if s == "" {
+		return 5 * time.Minute
+	}
+
+	interval, err := time.ParseDuration(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return interval
+}
+
+// ratePolicy reads the RATE_LIMIT_<class>_SIGNER and RATE_LIMIT_<class>_IP
+// limits, e.g. "10/s:20". Unset limits are unlimited.
+func ratePolicy(class string) ratelimit.Policy {
+	perSigner, err := ratelimit.ParseLimit(os.Getenv("RATE_LIMIT_" + class + "_SIGNER"))
+	if err != nil {
+		panic(err)
+	}
+
+	perIP, err := ratelimit.ParseLimit(os.Getenv("RATE_LIMIT_" + class + "_IP"))
+	if err != nil {
+		panic(err)
+	}
+
+	return ratelimit.Policy{PerSigner: perSigner, PerIP: perIP}
+}
+
+// riskChecks reads the pre-trade risk limits. Unset limits are not checked.
+func riskChecks() []orderbook.RiskCheck {
+	checks := []orderbook.RiskCheck{}
+
+	if s := os.Getenv("RISK_MAX_ORDER_NOTIONAL"); s != "" {
+		checks = append(checks, orderbook.MaxOrderNotional(parseFloat("RISK_MAX_ORDER_NOTIONAL", s)))
+	}
+
+	if s := os.Getenv("RISK_MAX_OPEN_ORDERS"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			panic("RISK_MAX_OPEN_ORDERS: " + err.Error())
+		}
+		checks = append(checks, orderbook.MaxOpenOrders(limit))
+	}
+
+	// e.g. "BTC=10,ETH=100"
+	if s := os.Getenv("RISK_MAX_POSITION"); s != "" {
+		limits := make(map[string]float64)
+		for _, pair := range strings.Split(s, ",") {
+			asset, limit, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				panic("RISK_MAX_POSITION: expected ASSET=limit, got " + pair)
+			}
+			limits[asset] = parseFloat("RISK_MAX_POSITION", limit)
+		}
+		checks = append(checks, orderbook.MaxPosition(limits))
+	}
+
+	if s := os.Getenv("RISK_PRICE_BAND"); s != "" {
+		reference := orderbook.PriceReference(os.Getenv("RISK_PRICE_BAND_REFERENCE"))
+		switch reference {
+		case "":
+			reference = orderbook.ReferenceLastTrade
+		case orderbook.ReferenceLastTrade, orderbook.ReferenceTopOfBook:
+		default:
+			panic("RISK_PRICE_BAND_REFERENCE: unknown reference " + string(reference))
+		}
+		checks = append(checks, orderbook.PriceBand(parseFloat("RISK_PRICE_BAND", s), reference))
+	}
+
+	return checks
+}
+
+// circuitBreaker reads CIRCUIT_BREAKER_PERCENT, CIRCUIT_BREAKER_WINDOW and
+// CIRCUIT_BREAKER_HALT_FOR. Markets are never halted automatically without a
+// percentage.
+func circuitBreaker() *orderbook.CircuitBreaker {
+	s := os.Getenv("CIRCUIT_BREAKER_PERCENT")
+	if s == "" {
+		return nil
+	}
+
+	breaker := &orderbook.CircuitBreaker{Percent: parseFloat("CIRCUIT_BREAKER_PERCENT", s), Window: time.Minute}
+	if s := os.Getenv("CIRCUIT_BREAKER_WINDOW"); s != "" {
+		breaker.Window = parseDuration("CIRCUIT_BREAKER_WINDOW", s)
+	}
+	if s := os.Getenv("CIRCUIT_BREAKER_HALT_FOR"); s != "" {
+		breaker.HaltFor = parseDuration("CIRCUIT_BREAKER_HALT_FOR", s)
+	}
+
+	return breaker
+}
+
+// auctionSchedule reads AUCTION_OPENING and AUCTION_REOPENING. Unset
+// auctions are skipped.
+func auctionSchedule() orderbook.AuctionSchedule {
+	schedule := orderbook.AuctionSchedule{}
+	if s := os.Getenv("AUCTION_OPENING"); s != "" {
+		schedule.Opening = parseDuration("AUCTION_OPENING", s)
+	}
+	if s := os.Getenv("AUCTION_REOPENING"); s != "" {
+		schedule.Reopening = parseDuration("AUCTION_REOPENING", s)
+	}
+
+	return schedule
+}
+
+func parseDuration(name string, s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		panic(name + ": " + err.Error())
+	}
+
+	return d
+}
+
+func parseFloat(name string, s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		panic(name + ": " + err.Error())
+	}
+
+	return f
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/richo225/octgopus/pkg/client"
+)
+
+var accountCommands = map[string]command{
+	"create":       runAccountCreate,
+	"balance":      runAccountBalance,
+	"deposit":      runAccountAction("deposit", (*client.Client).Deposit),
+	"withdraw":     runAccountAction("withdraw", (*client.Client).Withdraw),
+	"send":         runAccountSend,
+	"transactions": runAccountTransactions,
+}
+
+func signerFlag(s *session) *string {
+	return s.flags.String("signer", "", "account name")
+}
+
+func runAccountCreate(args []string, out io.Writer) error {
+	s := newSession("account create", out)
+	signer := signerFlag(s)
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	if err := s.required(*signer); err != nil {
+		return err
+	}
+
+	if err := s.client.CreateAccount(s.ctx, *signer); err != nil {
+		return err
+	}
+
+	return s.printMessage("Account created")
+}
+
+func runAccountBalance(args []string, out io.Writer) error {
+	s := newSession("account balance", out)
+	signer := signerFlag(s)
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	if err := s.required(*signer); err != nil {
+		return err
+	}
+
+	balance, err := s.client.Balance(s.ctx, *signer)
+	if err != nil {
+		return err
+	}
+
+	result := map[string]interface{}{"signer": *signer, "balance": balance}
+	return s.print(result, func() [][]string {
+		return [][]string{{"SIGNER", "BALANCE"}, {*signer, formatFloat(balance)}}
+	})
+}
+
+// runAccountAction returns the command that deposits into or withdraws from
+// an account with call.
+func runAccountAction(action string, call func(*client.Client, context.Context, string, float64) (*client.Tx, error)) command {
+	return func(args []string, out io.Writer) error {
+		s := newSession("account "+action, out)
+		signer := signerFlag(s)
+		amount := s.flags.Float64("amount", 0, "amount to "+action)
+		if err := s.parse(args); err != nil {
+			return err
+		}
+		if err := s.required(*signer); err != nil {
+			return err
+		}
+
+		tx, err := call(s.client, s.ctx, *signer, *amount)
+		if err != nil {
+			return err
+		}
+
+		return s.print(tx, func() [][]string { return txRows(*tx) })
+	}
+}
+
+func runAccountSend(args []string, out io.Writer) error {
+	s := newSession("account send", out)
+	signer := signerFlag(s)
+	recipient := s.flags.String("to", "", "account to send to")
+	amount := s.flags.Float64("amount", 0, "amount to send")
+	create := s.flags.Bool("create", false, "open an account for a recipient that does not have one")
+	key := s.flags.String("idempotency-key", "", "key that makes a retried send return the original transfer")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	if err := s.required(*signer, *recipient); err != nil {
+		return err
+	}
+
+	// A send is a withdraw from the sender and a deposit to the recipient.
+	txs, err := s.client.Send(s.ctx, client.SendRequest{
+		Signer:          *signer,
+		Recipient:       *recipient,
+		Amount:          *amount,
+		CreateRecipient: *create,
+		IdempotencyKey:  *key,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.print(txs, func() [][]string { return txRows(txs...) })
+}
+
+func runAccountTransactions(args []string, out io.Writer) error {
+	s := newSession("account transactions", out)
+	signer := signerFlag(s)
+	before := s.flags.Uint64("before", 0, "show entries older than this entry id")
+	limit := s.flags.Int("limit", 0, "entries to show, the API default when 0")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	if err := s.required(*signer); err != nil {
+		return err
+	}
+
+	page, err := s.client.Transactions(s.ctx, *signer, *before, *limit)
+	if err != nil {
+		return err
+	}
+
+	return s.print(page, func() [][]string {
+		rows := [][]string{{"ID", "TX", "ACTION", "DEBIT", "CREDIT", "BALANCE", "TIME", "MEMO"}}
+		for _, e := range page.Entries {
+			rows = append(rows, []string{fmt.Sprint(e.ID), fmt.Sprint(e.TxID), string(e.Action), formatFloat(e.Debit), formatFloat(e.Credit), formatFloat(e.Balance), formatTime(e.Timestamp), e.Memo})
+		}
+		if page.Next > 0 {
+			rows = append(rows, []string{}, []string{fmt.Sprintf("More with -before %d", page.Next)})
+		}
+		return rows
+	})
+}
+
+func txRows(txs ...client.Tx) [][]string {
+	rows := [][]string{{"TX", "ACTION", "SIGNER", "AMOUNT", "TIME"}}
+	for _, tx := range txs {
+		rows = append(rows, []string{fmt.Sprint(tx.ID), string(tx.Action), tx.Signer, formatFloat(tx.Amount), formatTime(tx.Timestamp)})
+	}
+
+	return rows
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountDeposit(t *testing.T) {
+	platform, cmd := testAPI(t)
+	require.NoError(t, platform.CreateAccount("alice"))
+
+	var out bytes.Buffer
+	err := run(cmd(aliceKey, "account", "deposit", "-signer", "alice", "-amount", "5"), &out)
+	require.NoError(t, err, "signed request should be accepted")
+	assert.Contains(t, out.String(), "deposit", "transaction should be printed")
+	balance, _ := platform.Accounts.BalanceOf("alice")
+	assert.Equal(t, 5.0, balance, "deposit should be made")
+
+	wrongSecret := &auth.Key{ID: aliceKey.ID, Secret: "wrong"}
+	err = run(cmd(wrongSecret, "account", "deposit", "-signer", "alice", "-amount", "5"), &out)
+	assert.EqualError(t, err, "401: Unauthorized: invalid signature", "request signed with the wrong secret should be refused")
+}
+
+func TestAccountSend(t *testing.T) {
+	platform, cmd := testAPI(t)
+	require.NoError(t, platform.CreateAccount("alice"))
+	_, err := platform.Deposit("alice", 100)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	args := cmd(aliceKey, "account", "send", "-signer", "alice", "-to", "bob", "-amount", "30", "-create", "-idempotency-key", "k1")
+	require.NoError(t, run(args, &out), "send should not return an error")
+	assert.Contains(t, out.String(), "withdraw  alice", "sender leg should be printed")
+	assert.Contains(t, out.String(), "deposit   bob", "recipient leg should be printed")
+
+	out.Reset()
+	require.NoError(t, run(append(args, "-output", "json"), &out), "repeated send should not return an error")
+	txs := []client.Tx{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &txs), "json output should decode")
+	balance, _ := platform.Accounts.BalanceOf("alice")
+	assert.Equal(t, 70.0, balance, "a send repeated with its idempotency key should not be made twice")
+}
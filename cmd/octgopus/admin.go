@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/richo225/octgopus/pkg/client"
+)
+
+var adminCommands = map[string]command{
+	"reset":         runAdminReset,
+	"remove-market": runAdminRemoveMarket,
+	"halt":          runAdminMarket("halt", client.StateHalted),
+	"resume":        runAdminMarket("resume", client.StateOpen),
+	"state":         runAdminState,
+	"fees":          runAdminFees,
+	"accounts":      runAdminAccounts,
+	"adjust":        runAdminAdjust,
+}
+
+func runAdminReset(args []string, out io.Writer) error {
+	s := newSession("admin reset", out)
+	if err := s.parse(args); err != nil {
+		return err
+	}
+
+	if err := s.client.Reset(s.ctx); err != nil {
+		return err
+	}
+
+	return s.printMessage("Orderbooks reset successfully")
+}
+
+func runAdminRemoveMarket(args []string, out io.Writer) error {
+	s := newSession("admin remove-market", out)
+	market := marketFlag(s)
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := s.client.RemoveMarket(s.ctx, pair)
+	if err != nil {
+		return err
+	}
+
+	return s.print(cancelled, func() [][]string {
+		return append([][]string{{fmt.Sprintf("Removed %s, cancelling %d orders", pair, len(cancelled))}}, orderRows(cancelled...)...)
+	})
+}
+
+// runAdminMarket returns the command that halts or resumes a market.
+func runAdminMarket(action string, state client.TradingState) command {
+	return func(args []string, out io.Writer) error {
+		s := newSession("admin "+action, out)
+		market := marketFlag(s)
+		if err := s.parse(args); err != nil {
+			return err
+		}
+		pair, err := parseMarketFlag(s, *market)
+		if err != nil {
+			return err
+		}
+
+		if err := s.client.SetMarketState(s.ctx, pair, state, ""); err != nil {
+			return err
+		}
+
+		return s.printMessage("Market state set to " + string(state))
+	}
+}
+
+func runAdminState(args []string, out io.Writer) error {
+	s := newSession("admin state", out)
+	market := marketFlag(s)
+	state := s.flags.String("state", "", "open, halted, cancel_only or auction")
+	reason := s.flags.String("reason", "", "reason recorded with the change")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+	if err := s.required(*state); err != nil {
+		return err
+	}
+
+	if err := s.client.SetMarketState(s.ctx, pair, client.TradingState(*state), *reason); err != nil {
+		return err
+	}
+
+	return s.printMessage("Market state set to " + *state)
+}
+
+func runAdminFees(args []string, out io.Writer) error {
+	s := newSession("admin fees", out)
+	market := marketFlag(s)
+	maker := s.flags.Float64("maker", 0, "maker rate, negative for a rebate")
+	taker := s.flags.Float64("taker", 0, "taker rate")
+	tiers := s.flags.String("tiers", "", `volume tiers as JSON, e.g. [{"min_volume":100000,"maker_rate":0,"taker_rate":0.001}]`)
+	clearFees := s.flags.Bool("clear", false, "remove the fees of the market")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+
+	var schedule *client.FeeSchedule
+	if !*clearFees {
+		schedule = &client.FeeSchedule{MakerRate: *maker, TakerRate: *taker}
+		if *tiers != "" {
+			if err := json.Unmarshal([]byte(*tiers), &schedule.Tiers); err != nil {
+				return fmt.Errorf("tiers: %w", err)
+			}
+		}
+	}
+
+	schedule, err = s.client.SetFees(s.ctx, pair, schedule)
+	if err != nil {
+		return err
+	}
+
+	return s.print(schedule, func() [][]string {
+		if schedule == nil {
+			return [][]string{{"Fees cleared for " + pair.String()}}
+		}
+		rows := [][]string{{"MIN VOLUME", "MAKER", "TAKER"}, {"0", formatFloat(schedule.MakerRate), formatFloat(schedule.TakerRate)}}
+		for _, tier := range schedule.Tiers {
+			rows = append(rows, []string{formatFloat(tier.MinVolume), formatFloat(tier.MakerRate), formatFloat(tier.TakerRate)})
+		}
+		return rows
+	})
+}
+
+func runAdminAccounts(args []string, out io.Writer) error {
+	s := newSession("admin accounts", out)
+	if err := s.parse(args); err != nil {
+		return err
+	}
+
+	accounts, err := s.client.Accounts(s.ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.print(accounts, func() [][]string {
+		signers := []string{}
+		for signer := range accounts {
+			signers = append(signers, signer)
+		}
+		sort.Strings(signers)
+
+		rows := [][]string{{"SIGNER", "BALANCE"}}
+		for _, signer := range signers {
+			rows = append(rows, []string{signer, formatFloat(accounts[signer])})
+		}
+		return rows
+	})
+}
+
+func runAdminAdjust(args []string, out io.Writer) error {
+	s := newSession("admin adjust", out)
+	signer := signerFlag(s)
+	amount := s.flags.Float64("amount", 0, "amount to add, negative to reduce the balance")
+	reason := s.flags.String("reason", "", "reason recorded in the ledger")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	if err := s.required(*signer, *reason); err != nil {
+		return err
+	}
+
+	tx, err := s.client.AdjustBalance(s.ctx, *signer, *amount, *reason)
+	if err != nil {
+		return err
+	}
+
+	return s.print(tx, func() [][]string { return txRows(*tx) })
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/richo225/octgopus/pkg/client"
+)
+
+var bookCommands = map[string]command{
+	"list":   runBookList,
+	"get":    runBookGet,
+	"create": runBookCreate,
+	"import": runBookImport,
+	"watch":  runBookWatch,
+}
+
+func runBookList(args []string, out io.Writer) error {
+	s := newSession("book list", out)
+	if err := s.parse(args); err != nil {
+		return err
+	}
+
+	markets, err := s.client.Markets(s.ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.print(markets, func() [][]string {
+		rows := [][]string{{"MARKET", "STATE", "BID", "ASK", "LAST"}}
+		for _, m := range markets {
+			rows = append(rows, []string{m.Market.String(), string(m.State), formatPrice(m.BestBid), formatPrice(m.BestAsk), formatPrice(m.LastTradePrice)})
+		}
+		return rows
+	})
+}
+
+// marketFlag adds the -market flag that most commands need.
+func marketFlag(s *session) *string {
+	return s.flags.String("market", "", "market, e.g. BTC/USD")
+}
+
+func parseMarketFlag(s *session, market string) (client.Market, error) {
+	if err := s.required(market); err != nil {
+		return client.Market{}, err
+	}
+
+	return parseMarket(market)
+}
+
+func runBookGet(args []string, out io.Writer) error {
+	s := newSession("book get", out)
+	market := marketFlag(s)
+	depth := s.flags.Int("depth", 10, "price levels to show on each side")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+
+	book, err := s.client.Orderbook(s.ctx, pair)
+	if err != nil {
+		return err
+	}
+
+	return s.print(book, func() [][]string { return bookRows(book, *depth) })
+}
+
+// bookRows lays out the top of a book with the asks above the bids, best
+// prices nearest the spread.
+func bookRows(book *client.Orderbook, depth int) [][]string {
+	title := fmt.Sprintf("%s  %s", book.Market, book.State)
+	if book.LastTradePrice > 0 {
+		title += "  last " + formatFloat(book.LastTradePrice)
+	}
+	rows := [][]string{{title}, {"", "PRICE", "SIZE", "ORDERS"}}
+
+	asks := book.Asks
+	if len(asks) > depth {
+		asks = asks[:depth]
+	}
+	for i := len(asks) - 1; i >= 0; i-- {
+		rows = append(rows, levelRow("ask", asks[i]))
+	}
+
+	spread := ""
+	if len(book.Asks) > 0 && len(book.Bids) > 0 {
+		spread = formatFloat(book.Asks[0].Price - book.Bids[0].Price)
+	}
+	rows = append(rows, []string{"", "---", "spread " + spread, "---"})
+	if book.Indicative != nil {
+		rows = append(rows, []string{"", "indicative " + formatFloat(book.Indicative.Price), "volume " + formatFloat(book.Indicative.Volume)})
+	}
+
+	for i, bid := range book.Bids {
+		if i == depth {
+			break
+		}
+		rows = append(rows, levelRow("bid", bid))
+	}
+
+	return rows
+}
+
+func levelRow(side string, limit *client.Limit) []string {
+	return []string{side, formatFloat(limit.Price), formatFloat(limit.TotalVolume), fmt.Sprint(len(limit.Orders))}
+}
+
+// formatPrice leaves missing prices blank.
+func formatPrice(price float64) string {
+	if price == 0 {
+		return ""
+	}
+
+	return formatFloat(price)
+}
+
+func runBookCreate(args []string, out io.Writer) error {
+	s := newSession("book create", out)
+	market := marketFlag(s)
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+
+	book, err := s.client.CreateMarket(s.ctx, pair)
+	if err != nil {
+		return err
+	}
+
+	return s.print(book, func() [][]string {
+		return [][]string{{"Created " + book.Market.String()}}
+	})
+}
+
+func runBookImport(args []string, out io.Writer) error {
+	s := newSession("book import", out)
+	market := marketFlag(s)
+	file := s.flags.String("file", "", "CSV file of orders to place")
+	create := s.flags.Bool("create", false, "create the market if it does not exist")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+	if err := s.required(*file); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	result, err := s.client.ImportOrderbook(s.ctx, pair, f, *create)
+	if err != nil {
+		return err
+	}
+
+	return s.print(result, func() [][]string {
+		return [][]string{{fmt.Sprintf("Placed %d orders on %s (%s layout)", result.Orders, result.Market, result.Layout)}}
+	})
+}
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+func runBookWatch(args []string, out io.Writer) error {
+	s := newSession("book watch", out)
+	market := marketFlag(s)
+	depth := s.flags.Int("depth", 10, "price levels to show on each side")
+	interval := s.flags.Duration("interval", time.Second, "time between refreshes")
+	count := s.flags.Int("count", 0, "stop after this many refreshes, 0 to run until interrupted")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for i := 0; *count == 0 || i < *count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+
+		book, err := s.client.Orderbook(ctx, pair)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// JSON output is a stream of books, one per refresh.
+		if s.output == outputTable {
+			fmt.Fprint(out, clearScreen)
+		}
+		if err := s.print(book, func() [][]string { return bookRows(book, *depth) }); err != nil {
+			return err
+		}
+		if s.output == outputTable {
+			fmt.Fprintf(out, "\n%s, every %s, ctrl-c to stop\n", time.Now().Format("15:04:05"), *interval)
+		}
+	}
+
+	return nil
+}
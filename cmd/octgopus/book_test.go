@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBook() *client.Orderbook {
+	return &client.Orderbook{
+		Market: client.NewMarket("BTC", "USD"),
+		State:  client.StateOpen,
+		Asks: []*client.Limit{
+			{Price: 101, TotalVolume: 2, Orders: []*client.Order{{ID: 1, Side: client.Ask}, {ID: 2, Side: client.Ask}}},
+			{Price: 102, TotalVolume: 1, Orders: []*client.Order{{ID: 3, Side: client.Ask}}},
+		},
+		Bids: []*client.Limit{
+			{Price: 99, TotalVolume: 3, Orders: []*client.Order{{ID: 4, Side: client.Bid}}},
+			{Price: 98, TotalVolume: 4, Orders: []*client.Order{{ID: 5, Side: client.Bid}}},
+		},
+		LastTradePrice: 100,
+	}
+}
+
+func TestBookRows(t *testing.T) {
+	rows := bookRows(testBook(), 1)
+
+	assert.Equal(t, [][]string{
+		{"BTC/USD  open  last 100"},
+		{"", "PRICE", "SIZE", "ORDERS"},
+		{"ask", "101", "2", "2"},
+		{"", "---", "spread 2", "---"},
+		{"bid", "99", "3", "1"},
+	}, rows, "depth should keep the best level of each side around the spread")
+
+	rows = bookRows(testBook(), 10)
+	assert.Equal(t, []string{"ask", "102", "1", "1"}, rows[2], "asks should be shown highest first")
+	assert.Equal(t, []string{"bid", "98", "4", "1"}, rows[len(rows)-1], "bids should be shown highest first")
+}
+
+// placeBook rests an ask at 101 and a bid at 99 on BTC/USD.
+func placeBook(t *testing.T, platform *orderbook.TradingPlatform) {
+	require.NoError(t, platform.PlaceLimitOrder(btcUSD, 101, orderbook.NewOrder(orderbook.Ask, 2)))
+	require.NoError(t, platform.PlaceLimitOrder(btcUSD, 99, orderbook.NewOrder(orderbook.Bid, 3)))
+}
+
+func TestBookGet(t *testing.T) {
+	platform, cmd := testAPI(t)
+	placeBook(t, platform)
+
+	var out bytes.Buffer
+	err := run(cmd(nil, "book", "get", "-market", "BTC/USD"), &out)
+	require.NoError(t, err, "book get should not return an error")
+	assert.Contains(t, out.String(), "spread 2", "book should be printed as a table")
+
+	out.Reset()
+	err = run(cmd(nil, "book", "get", "-market", "BTC/USD", "-output", "json"), &out)
+	require.NoError(t, err, "book get should not return an error")
+	book := &client.Orderbook{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), book), "json output should decode")
+	assert.Equal(t, 101.0, book.Asks[0].Price, "json output should hold the book")
+}
+
+func TestBookWatch(t *testing.T) {
+	platform, cmd := testAPI(t)
+	placeBook(t, platform)
+
+	var out bytes.Buffer
+	err := run(cmd(nil, "book", "watch", "-market", "BTC/USD", "-interval", "10ms", "-count", "3"), &out)
+	require.NoError(t, err, "book watch should not return an error")
+	assert.Equal(t, 3, strings.Count(out.String(), clearScreen), "screen should be redrawn on each refresh")
+	assert.Equal(t, 3, strings.Count(out.String(), "spread 2"), "book should be drawn on each refresh")
+}
+
+func TestBookImport(t *testing.T) {
+	platform, cmd := testAPI(t)
+
+	path := filepath.Join(t.TempDir(), "eth.csv")
+	os.WriteFile(path, []byte("Side,Price,Size\nask,101,1\n"), 0o644)
+
+	var out bytes.Buffer
+	err := run(cmd(adminKey, "book", "import", "-market", "ETH/USD", "-file", path, "-create"), &out)
+	require.NoError(t, err, "book import should not return an error")
+	assert.Equal(t, "Placed 1 orders on ETH/USD (orders layout)\n", out.String())
+
+	book, err := platform.OrderBookCopy(orderbook.NewTradingPair("ETH", "USD"))
+	require.NoError(t, err, "import should create the market")
+	require.Len(t, book.Asks, 1, "imported ask should rest on the book")
+	assert.Equal(t, 101.0, book.Asks[0].Price)
+
+	err = run(cmd(aliceKey, "book", "import", "-market", "ETH/USD", "-file", path), &out)
+	assert.EqualError(t, err, "403: Forbidden: requires the admin role", "imports should need an admin key")
+}
+
+func TestUsage(t *testing.T) {
+	assert.ErrorIs(t, run([]string{}, io.Discard), errUsage, "no command should print the usage")
+	assert.ErrorIs(t, run([]string{"book", "nope"}, io.Discard), errUsage, "unknown command should print the usage")
+	assert.ErrorIs(t, run([]string{"book", "get"}, io.Discard), errUsage, "missing market should print the usage")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/richo225/octgopus/pkg/client"
+)
+
+// runExport downloads a book, its orders or its trades as CSV or JSON.
+func runExport(args []string, out io.Writer) error {
+	s := newSession("export", out)
+	market := marketFlag(s)
+	view := s.flags.String("view", "book", "book, orders or trades")
+	format := s.flags.String("format", "csv", "csv or json")
+	output := s.flags.String("o", "", "file to write instead of stdout")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := s.client.Export(s.ctx, out, pair, client.ExportView(*view), client.ExportFormat(*format)); err != nil {
+		// A failed request leaves nothing worth keeping in the file.
+		if *output != "" {
+			os.Remove(*output)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	platform, cmd := testAPI(t)
+	placeBook(t, platform)
+
+	var out bytes.Buffer
+	err := runExport(cmd(nil, "-market", "btc/usd", "-view", "book"), &out)
+	require.NoError(t, err, "export should not return an error")
+	assert.Contains(t, out.String(), "101", "export should be written to stdout")
+
+	path := filepath.Join(t.TempDir(), "book.csv")
+	err = runExport(cmd(nil, "-market", "BTC-USD", "-view", "book", "-o", path), &bytes.Buffer{})
+	require.NoError(t, err, "export to a file should not return an error")
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, out.String(), string(data), "export should be written to the file")
+}
+
+func TestExportError(t *testing.T) {
+	_, cmd := testAPI(t)
+
+	path := filepath.Join(t.TempDir(), "eth.csv")
+	err := runExport(cmd(nil, "-market", "ETH/USD", "-o", path), &bytes.Buffer{})
+	assert.EqualError(t, err, "404: MarketNotfound : ETH/USD", "API errors should be reported")
+	assert.NoFileExists(t, path, "a failed export should not leave a file behind")
+
+	err = runExport(cmd(nil, "-market", "ETHUSD"), &bytes.Buffer{})
+	assert.EqualError(t, err, `market "ETHUSD" must be written as BASE/QUOTE`, "malformed market should be refused")
+}
@@ -0,0 +1,109 @@
+// Command octgopus is a command line client for the trading API.
+//
+//	octgopus book list
+//	octgopus book watch -market BTC/USD
+//	octgopus order place -market BTC/USD -side bid -type limit -price 100 -size 1
+//	octgopus account balance -signer alice -output json
+//	octgopus export -market BTC/USD -view book -format csv > btc_usd.csv
+//
+// The API is found at -url, OCTGOPUS_URL or the url of the config file, and
+// requests are signed with the API key given the same way.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/richo225/octgopus/pkg/client"
+)
+
+// command runs a subcommand with its arguments, writing its output to out.
+type command func(args []string, out io.Writer) error
+
+var commands = map[string]command{
+	"book":    group("book", bookCommands),
+	"order":   group("order", orderCommands),
+	"account": group("account", accountCommands),
+	"admin":   group("admin", adminCommands),
+	"export":  runExport,
+	"health":  runHealth,
+}
+
+// errUsage is returned after a command has printed its usage.
+var errUsage = errors.New("usage")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "octgopus:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	return group("octgopus", commands)(args, out)
+}
+
+// runHealth checks that the API is up.
+func runHealth(args []string, out io.Writer) error {
+	s := newSession("health", out)
+	if err := s.parse(args); err != nil {
+		return err
+	}
+
+	if err := s.client.Health(s.ctx); err != nil {
+		return err
+	}
+
+	return s.printMessage("Up")
+}
+
+// group returns a command that runs the subcommand named by its first
+// argument.
+func group(name string, subcommands map[string]command) command {
+	return func(args []string, out io.Writer) error {
+		if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+			groupUsage(name, subcommands)
+			return errUsage
+		}
+
+		cmd, ok := subcommands[args[0]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: unknown command %q\n", name, args[0])
+			groupUsage(name, subcommands)
+			return errUsage
+		}
+
+		return cmd(args[1:], out)
+	}
+}
+
+func groupUsage(name string, subcommands map[string]command) {
+	names := []string{}
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\ncommands: %s\n", name, strings.Join(names, ", "))
+}
+
+// parseMarket reads a market written as BASE/QUOTE or BASE-QUOTE.
+func parseMarket(s string) (client.Market, error) {
+	base, quote, ok := strings.Cut(s, "/")
+	if !ok {
+		base, quote, ok = strings.Cut(s, "-")
+	}
+	if !ok || base == "" || quote == "" {
+		return client.Market{}, fmt.Errorf("market %q must be written as BASE/QUOTE", s)
+	}
+
+	return client.NewMarket(strings.ToUpper(base), strings.ToUpper(quote)), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/richo225/octgopus/pkg/client"
+)
+
+var orderCommands = map[string]command{
+	"place":  runOrderPlace,
+	"cancel": runOrderCancel,
+}
+
+func runOrderPlace(args []string, out io.Writer) error {
+	s := newSession("order place", out)
+	market := marketFlag(s)
+	side := s.flags.String("side", "", "bid or ask")
+	orderType := s.flags.String("type", string(client.LimitOrder), "limit or market")
+	price := s.flags.Float64("price", 0, "limit price")
+	size := s.flags.Float64("size", 0, "size in the base currency")
+	stp := s.flags.String("stp", "", "self trade prevention mode, the platform default when empty")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+	if err := s.required(*side); err != nil {
+		return err
+	}
+
+	order := client.OrderRequest{
+		Market:              pair,
+		Side:                client.Side(*side),
+		Price:               *price,
+		Size:                *size,
+		SelfTradePrevention: client.SelfTradePrevention(*stp),
+	}
+
+	// Market orders are shown by their matches, limit orders by the order.
+	switch client.OrderType(*orderType) {
+	case client.MarketOrder:
+		matches, err := s.client.PlaceMarketOrder(s.ctx, order)
+		if err != nil {
+			return err
+		}
+
+		return s.print(matches, func() [][]string { return matchRows(matches) })
+	case client.LimitOrder:
+		placed, err := s.client.PlaceLimitOrder(s.ctx, order)
+		if err != nil {
+			return err
+		}
+
+		return s.print(placed, func() [][]string { return orderRows(*placed) })
+	}
+
+	return fmt.Errorf("type %q must be limit or market", *orderType)
+}
+
+func runOrderCancel(args []string, out io.Writer) error {
+	s := newSession("order cancel", out)
+	market := marketFlag(s)
+	id := s.flags.Uint64("id", 0, "id of the order to cancel")
+	if err := s.parse(args); err != nil {
+		return err
+	}
+	pair, err := parseMarketFlag(s, *market)
+	if err != nil {
+		return err
+	}
+	if *id == 0 {
+		s.flags.Usage()
+		return errUsage
+	}
+
+	order, err := s.client.CancelOrder(s.ctx, pair, *id)
+	if err != nil {
+		return err
+	}
+
+	return s.print(order, func() [][]string { return orderRows(*order) })
+}
+
+func orderRows(orders ...client.Order) [][]string {
+	rows := [][]string{{"ID", "SIDE", "PRICE", "SIZE", "SIGNER", "PLACED"}}
+	for _, o := range orders {
+		rows = append(rows, []string{fmt.Sprint(o.ID), string(o.Side), formatPrice(o.Price), formatFloat(o.Size), o.Signer, formatTime(o.Timestamp)})
+	}
+
+	return rows
+}
+
+func matchRows(matches []client.Match) [][]string {
+	if len(matches) == 0 {
+		return [][]string{{"No matches"}}
+	}
+
+	rows := [][]string{{"PRICE", "SIZE", "ASK", "BID", "ASK FEE", "BID FEE"}}
+	for _, m := range matches {
+		rows = append(rows, []string{formatFloat(m.Price), formatFloat(m.SizeFilled), fmt.Sprint(m.Ask.ID), fmt.Sprint(m.Bid.ID), formatFloat(m.AskFee), formatFloat(m.BidFee)})
+	}
+
+	return rows
+}
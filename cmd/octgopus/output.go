@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// print writes v as indented JSON with -output json, and otherwise as the
+// table rows returns.
+func (s *session) print(v interface{}, rows func() [][]string) error {
+	if s.output == outputJSON {
+		encoder := json.NewEncoder(s.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	return writeTable(s, rows())
+}
+
+// printMessage writes a plain text response, as a {"message": ...} object
+// with -output json.
+func (s *session) printMessage(message string) error {
+	return s.print(map[string]string{"message": message}, func() [][]string {
+		return [][]string{{message}}
+	})
+}
+
+func writeTable(s *session, rows [][]string) error {
+	tw := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// formatTime formats unix nanoseconds, leaving zero times blank.
+func formatTime(nanos int64) string {
+	if nanos == 0 {
+		return ""
+	}
+
+	return time.Unix(0, nanos).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/richo225/octgopus/pkg/client"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// fileConfig is the config file, by default octgopus/config.json in the user
+// config directory:
+//
+//	{"url": "http://localhost:8080", "key_id": "dev-alice", "secret": "..."}
+type fileConfig struct {
+	URL    string `json:"url"`
+	KeyID  string `json:"key_id"`
+	Secret string `json:"secret"`
+}
+
+// session holds the flags every command shares and the client they set up.
+type session struct {
+	ctx    context.Context
+	flags  *flag.FlagSet
+	out    io.Writer
+	output string
+	client *client.Client
+
+	configPath string
+	url        string
+	keyID      string
+	secret     string
+}
+
+func newSession(name string, out io.Writer) *session {
+	s := &session{ctx: context.Background(), flags: flag.NewFlagSet(name, flag.ContinueOnError), out: out}
+	s.flags.StringVar(&s.configPath, "config", os.Getenv("OCTGOPUS_CONFIG"), "config file, or OCTGOPUS_CONFIG")
+	s.flags.StringVar(&s.url, "url", "", "base URL of the API, or OCTGOPUS_URL (default http://localhost:8080)")
+	s.flags.StringVar(&s.keyID, "key", "", "API key id, or OCTGOPUS_KEY")
+	s.flags.StringVar(&s.secret, "secret", "", "API key secret, or OCTGOPUS_SECRET")
+	s.flags.StringVar(&s.output, "output", outputTable, "table or json")
+
+	return s
+}
+
+// parse reads the flags and sets up the client. Flags take precedence over
+// the environment, which takes precedence over the config file.
+func (s *session) parse(args []string) error {
+	if err := s.flags.Parse(args); err != nil {
+		return err
+	}
+	if s.flags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", s.flags.Arg(0))
+	}
+	if s.output != outputTable && s.output != outputJSON {
+		return fmt.Errorf("output %q must be table or json", s.output)
+	}
+
+	config, err := s.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	s.url = first(s.url, os.Getenv("OCTGOPUS_URL"), config.URL, "http://localhost:8080")
+	s.keyID = first(s.keyID, os.Getenv("OCTGOPUS_KEY"), config.KeyID)
+	s.secret = first(s.secret, os.Getenv("OCTGOPUS_SECRET"), config.Secret)
+	s.client = client.New(s.url, client.Options{KeyID: s.keyID, Secret: s.secret})
+
+	return nil
+}
+
+// loadConfig reads the config file. A missing default config file is not an
+// error, but a missing file given with -config is.
+func (s *session) loadConfig() (fileConfig, error) {
+	config := fileConfig{}
+
+	path := s.configPath
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return config, nil
+		}
+		path = filepath.Join(dir, "octgopus", "config.json")
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && s.configPath == "" {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// required reports the usage of the command when a flag has been left empty.
+func (s *session) required(values ...string) error {
+	for _, value := range values {
+		if value == "" {
+			s.flags.Usage()
+			return errUsage
+		}
+	}
+
+	return nil
+}
+
+func first(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/richo225/octgopus/internal/api"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	aliceKey = &auth.Key{ID: "dev-alice", Secret: "alice-secret", Signer: "alice"}
+	adminKey = &auth.Key{ID: "dev-admin", Secret: "admin-secret", Signer: "admin", Roles: []string{auth.AdminRole}}
+	btcUSD   = orderbook.NewTradingPair("BTC", "USD")
+)
+
+// testAPI serves the API with BTC/USD open and keys for alice and an admin.
+// The function it returns gives the flags that send a command to it, signed
+// with key.
+func testAPI(t *testing.T) (*orderbook.TradingPlatform, func(key *auth.Key, args ...string) []string) {
+	platform := orderbook.NewTradingPlatform()
+	_, err := platform.AddNewMarket(btcUSD)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(api.NewServer(platform, api.Config{
+		Verifier: auth.NewVerifier(auth.NewKeyStore(aliceKey, adminKey)),
+		AuditLog: io.Discard,
+	}))
+	t.Cleanup(server.Close)
+
+	// An empty config file keeps the user's own config out of the tests.
+	config := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{}`), 0o600))
+
+	return platform, func(key *auth.Key, args ...string) []string {
+		flags := []string{"-url", server.URL, "-config", config}
+		if key != nil {
+			flags = append(flags, "-key", key.ID, "-secret", key.Secret)
+		}
+		return append(args, flags...)
+	}
+}
+
+func TestSessionPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"url": "http://config:8080", "key_id": "config-key", "secret": "config-secret"}`), 0o600)
+	t.Setenv("OCTGOPUS_URL", "http://env:8080")
+	t.Setenv("OCTGOPUS_KEY", "")
+	t.Setenv("OCTGOPUS_SECRET", "env-secret")
+
+	s := newSession("test", io.Discard)
+	require.NoError(t, s.parse([]string{"-config", path, "-key", "flag-key"}), "parse should not return an error")
+
+	assert.Equal(t, "http://env:8080", s.url, "environment should override the config file")
+	assert.Equal(t, "flag-key", s.keyID, "flag should override the config file")
+	assert.Equal(t, "env-secret", s.secret, "environment should override the config file")
+}
+
+func TestSessionConfigErrors(t *testing.T) {
+	s := newSession("test", io.Discard)
+	err := s.parse([]string{"-config", filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err, "a missing config file given with -config should be an error")
+
+	s = newSession("test", io.Discard)
+	err = s.parse([]string{"-output", "yaml"})
+	assert.EqualError(t, err, `output "yaml" must be table or json`, "unknown output should be refused")
+}
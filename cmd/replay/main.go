@@ -0,0 +1,95 @@
+// Command replay feeds a recorded command log through a fresh trading platform
+// with a fixed clock, printing the resulting trades and books or diffing them
+// against an earlier recorded run.
+//
+//	replay -wal data/wal -record baseline.json
+//	replay -wal data/wal -diff baseline.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/richo225/octgopus/internal/wal"
+)
+
+func main() {
+	dir := flag.String("wal", "", "directory of the command log to replay")
+	until := flag.Uint64("until", 0, "stop after this command sequence number")
+	clock := flag.String("clock", "2000-01-01T00:00:00Z", "fixed time used for event timestamps")
+	record := flag.String("record", "", "write the run as JSON to this file")
+	against := flag.String("diff", "", "compare the run against a recorded JSON run")
+	asJSON := flag.Bool("json", false, "print the run as JSON")
+	flag.Parse()
+
+	if *dir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	now, err := time.Parse(time.RFC3339, *clock)
+	if err != nil {
+		fail(err)
+	}
+
+	log, err := wal.Open(wal.Options{Dir: *dir, ReadOnly: true})
+	if err != nil {
+		fail(err)
+	}
+	defer log.Close()
+
+	if log.FirstSequence() > 1 {
+		fail(fmt.Errorf("log has been compacted and starts at command %d", log.FirstSequence()))
+	}
+
+	run, err := replay(log, *until, now)
+	if err != nil {
+		fail(err)
+	}
+
+	if *record != "" {
+		data, _ := json.MarshalIndent(run, "", "  ")
+		if err := os.WriteFile(*record, data, 0o644); err != nil {
+			fail(err)
+		}
+	}
+
+	if *against != "" {
+		data, err := os.ReadFile(*against)
+		if err != nil {
+			fail(err)
+		}
+
+		recorded := &Run{}
+		if err := json.Unmarshal(data, recorded); err != nil {
+			fail(err)
+		}
+
+		diffs := diff(recorded, run)
+		for _, d := range diffs {
+			fmt.Println(d)
+		}
+		if len(diffs) > 0 {
+			os.Exit(1)
+		}
+
+		fmt.Println("No differences")
+		return
+	}
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(run, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	printRun(os.Stdout, run)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "replay:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/richo225/octgopus/internal/orderbook"
+)
+
+// Run is the outcome of replaying a command log: every trade in the order it
+// executed and the final state of each book.
+type Run struct {
+	Commands uint64                     `json:"commands"`
+	Trades   []orderbook.TradeExecuted  `json:"trades"`
+	Books    []orderbook.MarketSnapshot `json:"books"`
+	Accounts map[string]float64         `json:"accounts"`
+}
+
+var errStop = errors.New("stop")
+
+// untilReader stops replay after the command numbered until, if set.
+type untilReader struct {
+	reader orderbook.JournalReader
+	until  uint64
+	last   uint64
+}
+
+func (r *untilReader) Replay(from uint64, fn func(seq uint64, payload []byte) error) error {
+	err := r.reader.Replay(from, func(seq uint64, payload []byte) error {
+		if r.until > 0 && seq > r.until {
+			return errStop
+		}
+		if err := fn(seq, payload); err != nil {
+			return err
+		}
+		r.last = seq
+		return nil
+	})
+	if err == errStop {
+		return nil
+	}
+
+	return err
+}
+
+func replay(reader orderbook.JournalReader, until uint64, clock time.Time) (*Run, error) {
+	platform := orderbook.NewTradingPlatform()
+	platform.SetClock(orderbook.FixedClock{Time: clock})
+
+	sub := platform.Subscribe()
+	bounded := &untilReader{reader: reader, until: until}
+	err := platform.Recover(bounded)
+	sub.Close()
+
+	run := &Run{
+		Commands: bounded.last,
+		Trades:   []orderbook.TradeExecuted{},
+	}
+	for event := range sub.C {
+		if trade, ok := event.(*orderbook.TradeExecuted); ok {
+			run.Trades = append(run.Trades, *trade)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := platform.Snapshot()
+	run.Books = snapshot.Markets
+	run.Accounts = snapshot.Accounts
+
+	return run, nil
+}
+
+// diff lists the differences between a recorded run and a replayed one,
+// reporting only the first diverging trade since every later one usually
+// differs as a consequence.
+func diff(expected *Run, actual *Run) []string {
+	diffs := []string{}
+
+	if expected.Commands != actual.Commands {
+		diffs = append(diffs, fmt.Sprintf("commands: recorded %d, replayed %d", expected.Commands, actual.Commands))
+	}
+
+	for i := 0; i < len(expected.Trades) || i < len(actual.Trades); i++ {
+		if i >= len(expected.Trades) {
+			diffs = append(diffs, fmt.Sprintf("trade %d: not recorded, replayed %s", i, formatTrade(actual.Trades[i])))
+			break
+		}
+		if i >= len(actual.Trades) {
+			diffs = append(diffs, fmt.Sprintf("trade %d: recorded %s, not replayed", i, formatTrade(expected.Trades[i])))
+			break
+		}
+		if expected.Trades[i] != actual.Trades[i] {
+			diffs = append(diffs, fmt.Sprintf("trade %d: recorded %s, replayed %s", i, formatTrade(expected.Trades[i]), formatTrade(actual.Trades[i])))
+			break
+		}
+	}
+
+	expectedBooks := booksByMarket(expected.Books)
+	actualBooks := booksByMarket(actual.Books)
+	for _, market := range marketNames(expectedBooks, actualBooks) {
+		if !reflect.DeepEqual(expectedBooks[market], actualBooks[market]) {
+			diffs = append(diffs, fmt.Sprintf("book %s: recorded %s, replayed %s", market, formatBook(expectedBooks[market]), formatBook(actualBooks[market])))
+		}
+	}
+
+	signers := []string{}
+	for signer := range expected.Accounts {
+		signers = append(signers, signer)
+	}
+	for signer := range actual.Accounts {
+		if _, ok := expected.Accounts[signer]; !ok {
+			signers = append(signers, signer)
+		}
+	}
+	sort.Strings(signers)
+	for _, signer := range signers {
+		e, eok := expected.Accounts[signer]
+		a, aok := actual.Accounts[signer]
+		if e != a || eok != aok {
+			diffs = append(diffs, fmt.Sprintf("account %s: recorded %v, replayed %v", signer, e, a))
+		}
+	}
+
+	return diffs
+}
+
+func booksByMarket(books []orderbook.MarketSnapshot) map[string]*orderbook.MarketSnapshot {
+	byMarket := make(map[string]*orderbook.MarketSnapshot)
+	for i := range books {
+		byMarket[books[i].Market.ToString()] = &books[i]
+	}
+
+	return byMarket
+}
+
+func marketNames(books ...map[string]*orderbook.MarketSnapshot) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, byMarket := range books {
+		for name := range byMarket {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func formatTrade(trade orderbook.TradeExecuted) string {
+	return fmt.Sprintf("#%d %s %v@%v ask=%d bid=%d taker=%s",
+		trade.Sequence, trade.Market.ToString(), trade.Trade.Size, trade.Trade.Price,
+		trade.Trade.AskOrderID, trade.Trade.BidOrderID, trade.Trade.TakerSide)
+}
+
+func formatBook(book *orderbook.MarketSnapshot) string {
+	if book == nil {
+		return "missing"
+	}
+
+	data, _ := json.Marshal(book)
+	return string(data)
+}
+
+func printRun(w io.Writer, run *Run) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Replayed %d commands, %d trades\n\n", run.Commands, len(run.Trades))
+
+	fmt.Fprintln(tw, "SEQ\tMARKET\tPRICE\tSIZE\tASK ID\tBID ID\tTAKER")
+	for _, trade := range run.Trades {
+		fmt.Fprintf(tw, "%d\t%s\t%v\t%v\t%d\t%d\t%s\n",
+			trade.Sequence, trade.Market.ToString(), trade.Trade.Price, trade.Trade.Size,
+			trade.Trade.AskOrderID, trade.Trade.BidOrderID, trade.Trade.TakerSide)
+	}
+
+	for _, book := range run.Books {
+		fmt.Fprintf(tw, "\n%s\n", book.Market.ToString())
+		fmt.Fprintln(tw, "SIDE\tPRICE\tVOLUME\tORDERS")
+		for _, limit := range book.Asks {
+			fmt.Fprintf(tw, "ask\t%v\t%v\t%d\n", limit.Price, limitVolume(limit), len(limit.Orders))
+		}
+		for _, limit := range book.Bids {
+			fmt.Fprintf(tw, "bid\t%v\t%v\t%d\n", limit.Price, limitVolume(limit), len(limit.Orders))
+		}
+	}
+
+	tw.Flush()
+}
+
+func limitVolume(limit orderbook.LimitSnapshot) float64 {
+	var volume float64
+	for _, order := range limit.Orders {
+		volume += order.Size
+	}
+
+	return volume
+}
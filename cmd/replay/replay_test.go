@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/internal/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recordLog(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	log, err := wal.Open(wal.Options{Dir: dir})
+	require.NoError(t, err)
+	defer log.Close()
+
+	pair := orderbook.NewTradingPair("BTC", "USD")
+	platform := orderbook.NewTradingPlatform()
+	platform.UseJournal(log)
+	platform.AddNewMarket(pair)
+	platform.PlaceLimitOrder(pair, 250, orderbook.NewOrder(orderbook.Ask, 8))
+	platform.PlaceLimitOrder(pair, 240, orderbook.NewOrder(orderbook.Ask, 2))
+	platform.PlaceMarketOrder(pair, orderbook.NewOrder(orderbook.Bid, 3))
+	platform.CreateAccount("alice")
+	platform.Deposit("alice", 100)
+
+	return dir
+}
+
+func replayDir(t *testing.T, dir string, until uint64) *Run {
+	t.Helper()
+
+	log, err := wal.Open(wal.Options{Dir: dir, ReadOnly: true})
+	require.NoError(t, err)
+
+	run, err := replay(log, until, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	return run
+}
+
+func TestReplayIsDeterministic(t *testing.T) {
+	dir := recordLog(t)
+
+	first := replayDir(t, dir, 0)
+	second := replayDir(t, dir, 0)
+
+	assert.Equal(t, uint64(6), first.Commands, "every command should be replayed")
+	assert.Equal(t, 2, len(first.Trades), "market order should produce 2 trades")
+	assert.Equal(t, float64(240), first.Trades[0].Trade.Price, "first trade should be at the best ask")
+	assert.Empty(t, diff(first, second), "replaying twice should give identical runs")
+}
+
+func TestReplayUntil(t *testing.T) {
+	dir := recordLog(t)
+
+	run := replayDir(t, dir, 3)
+
+	assert.Equal(t, uint64(3), run.Commands, "replay should stop at the requested command")
+	assert.Empty(t, run.Trades, "no trades should happen before the market order")
+}
+
+func TestReplayDiff(t *testing.T) {
+	dir := recordLog(t)
+
+	recorded := replayDir(t, dir, 0)
+	replayed := replayDir(t, dir, 0)
+	replayed.Trades[1].Trade.Price = 255
+	replayed.Accounts["alice"] = 90
+
+	diffs := diff(recorded, replayed)
+
+	assert.Equal(t, 2, len(diffs), "diff should report the trade and the account")
+	assert.Contains(t, diffs[0], "trade 1", "diff should report the first diverging trade")
+	assert.Contains(t, diffs[1], "account alice", "diff should report the diverging balance")
+}
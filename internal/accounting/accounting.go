@@ -1,5 +1,13 @@
 package accounting
 
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kr/pretty"
+)
+
 type TxAction string
 
 const (
@@ -8,33 +16,119 @@ const (
 )
 
 type Tx struct {
-	Action TxAction `json:"action"`
-	Signer string   `json:"signer"`
-	Amount float64  `json:"amount"`
+	ID        uint64   `json:"id"`
+	Action    TxAction `json:"action"`
+	Signer    string   `json:"signer"`
+	Amount    float64  `json:"amount"`
+	Timestamp int64    `json:"timestamp"`
 }
 
 type Accounts struct {
-	// Stores the total balance of each account.
+	// Stores the total balance of each account, derived from the ledger.
 	Accounts map[string]float64 `json:"accounts"`
+	Ledger   *Ledger            `json:"-"`
+
+	repository Repository
+
+	// mu serialises balance changes so that a send is checked and posted as
+	// a single step.
+	mu sync.Mutex
+}
+
+// SendOptions changes how a send is applied.
+type SendOptions struct {
+	// CreateRecipient opens an account for a recipient that does not have
+	// one. Without it a send to an unknown recipient is refused.
+	CreateRecipient bool
+	// IdempotencyKey identifies a send so that retrying it returns the
+	// original transfer rather than transferring again. Keys are scoped to
+	// the sender.
+	IdempotencyKey string
 }
 
 func NewAccounts() *Accounts {
 	return &Accounts{
 		Accounts: make(map[string]float64),
+		Ledger:   NewLedger(),
 	}
 }
 
+// UseRepository persists every balance change made from now on.
+func (a *Accounts) UseRepository(repository Repository) {
+	a.repository = repository
+}
+
+// Reset removes every account and the ledger, keeping the repository.
+func (a *Accounts) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.Accounts = make(map[string]float64)
+	a.Ledger = NewLedger()
+}
+
 func (a *Accounts) CreateAccount(signer string) error {
+	if err := ValidateSigner(signer); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	_, ok := a.Accounts[signer]
 	if ok {
 		return &AccountAlreadyExistsError{signer}
 	} else {
 		a.Accounts[signer] = 0
+		a.persist(signer, nil)
 		return nil
 	}
 }
 
+// persist writes the balance of signer, and the ledger entries that changed
+// it, to the repository. The in-memory ledger stays authoritative, so a failed
+// write is logged rather than undoing the change.
+func (a *Accounts) persist(signer string, entries []*Entry) {
+	if a.repository == nil {
+		return
+	}
+
+	if err := a.repository.SaveAccount(signer, a.Accounts[signer]); err != nil {
+		pretty.Log("Failed to persist account", signer, err.Error())
+		return
+	}
+
+	if len(entries) == 0 {
+		return
+	}
+
+	if err := a.repository.SaveEntries(entries); err != nil {
+		pretty.Log("Failed to persist entries", signer, err.Error())
+	}
+}
+
+// post records a balanced transfer of amount from debit to credit in the
+// ledger and applies it to the cached balances.
+func (a *Accounts) post(p posting) (uint64, []*Entry) {
+	txID, entries := a.Ledger.post(p)
+
+	for _, entry := range entries {
+		if !IsSystemAccount(entry.Account) {
+			a.Accounts[entry.Account] += entry.Credit - entry.Debit
+		}
+	}
+
+	return txID, entries
+}
+
 func (a *Accounts) BalanceOf(signer string) (float64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.balanceOf(signer)
+}
+
+func (a *Accounts) balanceOf(signer string) (float64, error) {
 	balance, ok := a.Accounts[signer]
 	if ok {
 		return balance, nil
@@ -43,51 +137,235 @@ func (a *Accounts) BalanceOf(signer string) (float64, error) {
 	}
 }
 
-func (a *Accounts) Deposit(signer string, amount float64) *Tx {
-	var newBalance float64
+// Balances returns a copy of the balance of every account.
+func (a *Accounts) Balances() map[string]float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	balance, err := a.BalanceOf(signer)
-	if err != nil {
-		newBalance = amount
-	} else {
-		newBalance = balance + amount
+	balances := make(map[string]float64, len(a.Accounts))
+	for signer, balance := range a.Accounts {
+		balances[signer] = balance
 	}
 
-	a.Accounts[signer] = newBalance
+	return balances
+}
 
-	return &Tx{
-		Action: Deposit,
-		Signer: signer,
-		Amount: amount,
+func (a *Accounts) Deposit(signer string, amount float64) (*Tx, error) {
+	return a.DepositAt(signer, amount, time.Now())
+}
+
+// DepositAt is Deposit with an explicit time, so that replaying the same
+// commands rebuilds an identical ledger.
+func (a *Accounts) DepositAt(signer string, amount float64, at time.Time) (*Tx, error) {
+	if err := ValidateAmount(amount); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := a.balanceOf(signer); err != nil {
+		return nil, err
 	}
+
+	txID, entries := a.post(posting{action: Deposit, debit: ExternalAccount, credit: signer, amount: amount, at: at})
+	a.persist(signer, entries)
+
+	return &Tx{
+		ID:        txID,
+		Action:    Deposit,
+		Signer:    signer,
+		Amount:    amount,
+		Timestamp: at.UnixNano(),
+	}, nil
 }
 
 func (a *Accounts) Withdraw(signer string, amount float64) (*Tx, error) {
-	balance, err := a.BalanceOf(signer)
+	return a.WithdrawAt(signer, amount, time.Now())
+}
+
+// WithdrawAt is Withdraw with an explicit time.
+func (a *Accounts) WithdrawAt(signer string, amount float64, at time.Time) (*Tx, error) {
+	if err := ValidateAmount(amount); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	balance, err := a.balanceOf(signer)
 	if err != nil {
 		return nil, &AccountNotFoundError{signer}
 	} else {
 		if balance < amount {
 			return nil, &AccountUnderFundedError{signer}
 		}
-		newBalance := balance - amount
-		a.Accounts[signer] = newBalance
+
+		txID, entries := a.post(posting{action: Withdraw, debit: signer, credit: ExternalAccount, amount: amount, at: at})
+		a.persist(signer, entries)
 
 		return &Tx{
-			Action: Withdraw,
-			Signer: signer,
-			Amount: amount,
+			ID:        txID,
+			Action:    Withdraw,
+			Signer:    signer,
+			Amount:    amount,
+			Timestamp: at.UnixNano(),
 		}, nil
 	}
 }
 
 func (a *Accounts) Send(sender string, recipient string, amount float64) ([]*Tx, error) {
-	wtx, err := a.Withdraw(sender, amount)
+	return a.SendAt(sender, recipient, amount, time.Now(), SendOptions{})
+}
+
+// SendAt is Send with an explicit time and options. The balance check and the
+// transfer happen under one lock and the transfer is posted to the ledger as a
+// single transaction, so a send either happens completely or not at all. The
+// returned withdraw and deposit share the transaction id.
+func (a *Accounts) SendAt(sender string, recipient string, amount float64, at time.Time, opts SendOptions) ([]*Tx, error) {
+	if err := ValidateAmount(amount); err != nil {
+		return nil, err
+	}
+	if err := ValidateSigner(recipient); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if opts.IdempotencyKey != "" {
+		if entries := a.Ledger.referenced(sender, opts.IdempotencyKey); entries != nil {
+			if entries[1].Account != recipient || !amountsEqual(entries[1].Credit, amount) {
+				return nil, &IdempotencyKeyReusedError{opts.IdempotencyKey}
+			}
+			return transferTxs(entries), nil
+		}
+	}
+
+	balance, err := a.balanceOf(sender)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := a.balanceOf(recipient); err != nil && !opts.CreateRecipient {
+		return nil, err
+	}
+	if balance < amount {
+		return nil, &AccountUnderFundedError{sender}
+	}
+
+	_, entries := a.post(posting{action: Transfer, debit: sender, credit: recipient, amount: amount, at: at, reference: opts.IdempotencyKey})
+	a.persist(sender, entries[:1])
+	a.persist(recipient, entries[1:])
+
+	return transferTxs(entries), nil
+}
+
+// transferTxs describes the debit and credit entries of a transfer as a
+// withdraw from the sender and a deposit to the recipient.
+func transferTxs(entries []*Entry) []*Tx {
+	debit, credit := entries[0], entries[1]
+
+	return []*Tx{
+		{ID: debit.TxID, Action: Withdraw, Signer: debit.Account, Amount: debit.Debit, Timestamp: debit.Timestamp},
+		{ID: credit.TxID, Action: Deposit, Signer: credit.Account, Amount: credit.Credit, Timestamp: credit.Timestamp},
+	}
+}
+
+// AdjustAt corrects the balance of signer by amount, which is negative to
+// reduce it, against the adjustments account. The reason is kept as the memo
+// of the ledger entries.
+func (a *Accounts) AdjustAt(signer string, amount float64, reason string, at time.Time) (*Tx, error) {
+	if err := ValidateAmount(math.Abs(amount)); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	balance, err := a.balanceOf(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	p := posting{action: Adjustment, debit: AdjustmentsAccount, credit: signer, amount: amount, at: at, memo: reason}
+	if amount < 0 {
+		if balance < -amount {
+			return nil, &AccountUnderFundedError{signer}
+		}
+		p.debit, p.credit, p.amount = signer, AdjustmentsAccount, -amount
+	}
 
-	dtx := a.Deposit(recipient, amount)
+	txID, entries := a.post(p)
+	a.persist(signer, entries)
+
+	return &Tx{
+		ID:        txID,
+		Action:    Adjustment,
+		Signer:    signer,
+		Amount:    amount,
+		Timestamp: at.UnixNano(),
+	}, nil
+}
+
+// ChargeFeeAt takes a trading fee of amount from signer into the fees
+// account, or pays a rebate when amount is negative. The trade has already
+// happened, so a fee is taken even when it overdraws the account.
+func (a *Accounts) ChargeFeeAt(signer string, amount float64, memo string, at time.Time) (*Tx, error) {
+	if err := ValidateAmount(math.Abs(amount)); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := a.balanceOf(signer); err != nil {
+		return nil, err
+	}
+
+	p := posting{action: Fee, debit: signer, credit: FeesAccount, amount: amount, at: at, memo: memo}
+	if amount < 0 {
+		p.debit, p.credit, p.amount = FeesAccount, signer, -amount
+	}
+
+	txID, entries := a.post(p)
+	a.persist(signer, entries)
+
+	return &Tx{
+		ID:        txID,
+		Action:    Fee,
+		Signer:    signer,
+		Amount:    amount,
+		Timestamp: at.UnixNano(),
+	}, nil
+}
+
+// Transactions pages through the ledger entries of signer, newest first.
+func (a *Accounts) Transactions(signer string, before uint64, limit int) ([]*Entry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.Accounts[signer]; !ok {
+		return nil, &AccountNotFoundError{signer}
+	}
+
+	return a.Ledger.EntriesOf(signer, before, limit), nil
+}
+
+// Reconcile checks the ledger balances and every cached balance is what the
+// ledger says it should be.
+func (a *Accounts) Reconcile() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.Ledger.Verify(); err != nil {
+		return err
+	}
+
+	for signer, balance := range a.Accounts {
+		if expected := a.Ledger.BalanceOf(signer); !amountsEqual(expected, balance) {
+			return &BalanceMismatchError{signer, expected, balance}
+		}
+	}
 
-	return []*Tx{wtx, dtx}, nil
+	return nil
 }
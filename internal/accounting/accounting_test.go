@@ -1,7 +1,9 @@
 package accounting
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -26,21 +28,20 @@ func TestBalanceOfAccountExists(t *testing.T) {
 func TestDepositAccountNotFound(t *testing.T) {
 	accounts := NewAccounts()
 
-	tx := accounts.Deposit("alice", 100)
-	assert.Equal(t, Deposit, tx.Action, "deposit(alice) should return a Deposit Tx")
-	assert.Equal(t, "alice", tx.Signer, "deposit(alice) should return a Tx with alice as signer")
-	assert.Equal(t, float64(100), tx.Amount, "deposit(alice) should return a Tx with 100 as amount")
-
-	balance, _ := accounts.BalanceOf("alice")
-	assert.Equal(t, float64(100), balance, "balanceOf(alice) should return 100")
+	_, err := accounts.Deposit("alice", 100)
+	assert.IsType(t, &AccountNotFoundError{}, err, "deposit(alice) should return an AccountNotFoundError")
 
+	_, err = accounts.BalanceOf("alice")
+	assert.IsType(t, &AccountNotFoundError{}, err, "deposit(alice) should not create an account")
 }
 
 func TestDepositExistingAccount(t *testing.T) {
 	accounts := NewAccounts()
-	accounts.Accounts["alice"] = 50
+	accounts.CreateAccount("alice")
+	accounts.Deposit("alice", 50)
 
-	tx := accounts.Deposit("alice", 100)
+	tx, err := accounts.Deposit("alice", 100)
+	assert.NoError(t, err, "deposit(alice) should not return an error")
 	assert.Equal(t, Deposit, tx.Action, "deposit(alice) should return a Deposit Tx")
 	assert.Equal(t, "alice", tx.Signer, "deposit(alice) should return a Tx with alice as signer")
 	assert.Equal(t, float64(100), tx.Amount, "deposit(alice) should return a Tx with 100 as amount")
@@ -93,6 +94,7 @@ func TestSendWithSenderNotFound(t *testing.T) {
 func TestSendWithSenderUnderFunded(t *testing.T) {
 	accounts := NewAccounts()
 	accounts.CreateAccount("alice")
+	accounts.CreateAccount("bob")
 	accounts.Deposit("alice", 25)
 
 	_, err := accounts.Send("alice", "bob", 50)
@@ -122,3 +124,107 @@ func TestSendWSuccess(t *testing.T) {
 	balance, _ = accounts.BalanceOf("bob")
 	assert.Equal(t, float64(40), balance, "balanceOf(bob) should return 40")
 }
+
+func TestSendWithRecipientNotFound(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	accounts.Deposit("alice", 100)
+
+	_, err := accounts.Send("alice", "bob", 30)
+	assert.IsType(t, &AccountNotFoundError{}, err, "send(alice, bob, 30) should return an AccountNotFoundError")
+
+	balance, _ := accounts.BalanceOf("alice")
+	assert.Equal(t, float64(100), balance, "refused send should not change the sender balance")
+	assert.Equal(t, 2, len(accounts.Ledger.Entries), "refused send should not post to the ledger")
+
+	_, err = accounts.SendAt("alice", "bob", 30, time.Now(), SendOptions{CreateRecipient: true})
+	assert.NoError(t, err, "send with CreateRecipient should not return an error")
+
+	balance, _ = accounts.BalanceOf("bob")
+	assert.Equal(t, float64(30), balance, "send with CreateRecipient should open the recipient account")
+}
+
+func TestSendIdempotencyKey(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	accounts.CreateAccount("bob")
+	accounts.CreateAccount("carol")
+	accounts.Deposit("alice", 100)
+	accounts.Deposit("carol", 100)
+
+	opts := SendOptions{IdempotencyKey: "abc"}
+	first, err := accounts.SendAt("alice", "bob", 30, time.Now(), opts)
+	assert.NoError(t, err, "first send should not return an error")
+
+	retry, err := accounts.SendAt("alice", "bob", 30, time.Now(), opts)
+	assert.NoError(t, err, "retried send should not return an error")
+	assert.Equal(t, first, retry, "retried send should return the original transfer")
+
+	balance, _ := accounts.BalanceOf("alice")
+	assert.Equal(t, float64(70), balance, "retried send should not transfer again")
+
+	_, err = accounts.SendAt("alice", "bob", 40, time.Now(), opts)
+	assert.IsType(t, &IdempotencyKeyReusedError{}, err, "reusing a key for a different amount should be refused")
+
+	_, err = accounts.SendAt("carol", "bob", 30, time.Now(), opts)
+	assert.NoError(t, err, "keys should be scoped to the sender")
+
+	restored := RestoreLedger(accounts.Ledger.Entries, accounts.Ledger.LastTxID)
+	assert.NotNil(t, restored.referenced("alice", "abc"), "restored ledger should index idempotency keys")
+}
+
+func TestSendConcurrent(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	accounts.CreateAccount("bob")
+	accounts.Deposit("alice", 100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accounts.Send("alice", "bob", 1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]float64{"alice": 0, "bob": 100}, accounts.Balances(), "concurrent sends should never overdraw")
+	assert.NoError(t, accounts.Reconcile(), "ledger should reconcile after concurrent sends")
+}
+
+type memoryRepository struct {
+	balances map[string]float64
+	entries  []*Entry
+}
+
+func (r *memoryRepository) SaveAccount(signer string, balance float64) error {
+	r.balances[signer] = balance
+	return nil
+}
+
+func (r *memoryRepository) SaveEntries(entries []*Entry) error {
+	r.entries = append(r.entries, entries...)
+	return nil
+}
+
+func TestAccountsRepository(t *testing.T) {
+	repository := &memoryRepository{balances: make(map[string]float64)}
+	accounts := NewAccounts()
+	accounts.UseRepository(repository)
+
+	accounts.CreateAccount("alice")
+	accounts.CreateAccount("bob")
+	accounts.Deposit("alice", 100)
+	accounts.Send("alice", "bob", 30)
+
+	assert.Equal(t, map[string]float64{"alice": 70, "bob": 30}, repository.balances, "repository should have the latest balances")
+	assert.Equal(t, 4, len(repository.entries), "repository should have both entries of each tx")
+	assert.Equal(t, Transfer, repository.entries[2].Action, "third entry should be the transfer")
+	assert.Equal(t, float64(70), repository.entries[2].Balance, "entry should record the balance after the tx")
+
+	accounts.Reset()
+	accounts.CreateAccount("carol")
+	accounts.Deposit("carol", 5)
+	assert.Equal(t, 6, len(repository.entries), "repository should be kept after a reset")
+}
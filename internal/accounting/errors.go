@@ -1,6 +1,9 @@
 package accounting
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+)
 
 type AccountNotFoundError struct {
 	signer string
@@ -10,6 +13,10 @@ func (e *AccountNotFoundError) Error() string {
 	return "AccountNotFound: " + e.signer
 }
 
+func (e *AccountNotFoundError) Reason() string {
+	return "AccountNotFound"
+}
+
 func (e *AccountNotFoundError) HTTPCode() int {
 	return http.StatusNotFound
 }
@@ -22,6 +29,10 @@ func (e *AccountAlreadyExistsError) Error() string {
 	return "AccountAlreadyExists: " + e.signer
 }
 
+func (e *AccountAlreadyExistsError) Reason() string {
+	return "AccountAlreadyExists"
+}
+
 func (e *AccountAlreadyExistsError) HTTPCode() int {
 	return http.StatusBadRequest
 }
@@ -34,6 +45,97 @@ func (e *AccountUnderFundedError) Error() string {
 	return "AccountUnderFunded: " + e.signer
 }
 
+func (e *AccountUnderFundedError) Reason() string {
+	return "AccountUnderFunded"
+}
+
 func (e *AccountUnderFundedError) HTTPCode() int {
 	return http.StatusBadRequest
 }
+
+type BalanceMismatchError struct {
+	account  string
+	expected float64
+	actual   float64
+}
+
+func (e *BalanceMismatchError) Error() string {
+	return "BalanceMismatch: " + e.account + " ledger " + fmt.Sprint(e.expected) + " != " + fmt.Sprint(e.actual)
+}
+
+func (e *BalanceMismatchError) Reason() string {
+	return "BalanceMismatch"
+}
+
+func (e *BalanceMismatchError) HTTPCode() int {
+	return http.StatusInternalServerError
+}
+
+type UnbalancedTxError struct {
+	txID   uint64
+	debit  float64
+	credit float64
+}
+
+func (e *UnbalancedTxError) Error() string {
+	return "UnbalancedTx: " + fmt.Sprint(e.txID) + " debits " + fmt.Sprint(e.debit) + " != credits " + fmt.Sprint(e.credit)
+}
+
+func (e *UnbalancedTxError) Reason() string {
+	return "UnbalancedTx"
+}
+
+func (e *UnbalancedTxError) HTTPCode() int {
+	return http.StatusInternalServerError
+}
+
+// IdempotencyKeyReusedError is returned when an idempotency key is sent again
+// with a different recipient or amount to the transfer it was first used for.
+type IdempotencyKeyReusedError struct {
+	key string
+}
+
+func (e *IdempotencyKeyReusedError) Error() string {
+	return "IdempotencyKeyReused: " + e.key
+}
+
+func (e *IdempotencyKeyReusedError) Reason() string {
+	return "IdempotencyKeyReused"
+}
+
+func (e *IdempotencyKeyReusedError) HTTPCode() int {
+	return http.StatusConflict
+}
+
+type InvalidAmountError struct {
+	amount float64
+	reason string
+}
+
+func (e *InvalidAmountError) Error() string {
+	return fmt.Sprintf("InvalidAmount: %v %s", e.amount, e.reason)
+}
+
+func (e *InvalidAmountError) Reason() string {
+	return "InvalidAmount"
+}
+
+func (e *InvalidAmountError) HTTPCode() int {
+	return http.StatusBadRequest
+}
+
+type InvalidSignerError struct {
+	signer string
+}
+
+func (e *InvalidSignerError) Error() string {
+	return fmt.Sprintf("InvalidSigner: %q", e.signer)
+}
+
+func (e *InvalidSignerError) Reason() string {
+	return "InvalidSigner"
+}
+
+func (e *InvalidSignerError) HTTPCode() int {
+	return http.StatusBadRequest
+}
@@ -0,0 +1,198 @@
+package accounting
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Transfer is the ledger action of a Send between two signers.
+const Transfer TxAction = "transfer"
+
+// Adjustment is the ledger action of a manual balance correction by an
+// administrator.
+const Adjustment TxAction = "adjustment"
+
+// Fee is the ledger action of a trading fee, or of a rebate when it is paid
+// to the signer.
+const Fee TxAction = "fee"
+
+// ExternalAccount is the ledger account on the other side of deposits and
+// withdrawals, i.e. money entering or leaving the platform.
+const ExternalAccount = "@external"
+
+// AdjustmentsAccount is the ledger account on the other side of adjustments.
+const AdjustmentsAccount = "@adjustments"
+
+// FeesAccount collects trading fees and pays out maker rebates.
+const FeesAccount = "@fees"
+
+// IsSystemAccount reports whether account belongs to the platform rather than
+// a signer. System accounts are prefixed with @.
+func IsSystemAccount(account string) bool {
+	return strings.HasPrefix(account, "@")
+}
+
+// Entry is one side of a ledger transaction. Every transaction posts a debit
+// and a credit of the same amount; a signer's balance is the sum of their
+// credits less their debits.
+type Entry struct {
+	ID        uint64   `json:"id"`
+	TxID      uint64   `json:"tx_id"`
+	Action    TxAction `json:"action"`
+	Account   string   `json:"account"`
+	Debit     float64  `json:"debit"`
+	Credit    float64  `json:"credit"`
+	Balance   float64  `json:"balance"`
+	Timestamp int64    `json:"timestamp"`
+	// Reference is the client supplied idempotency key of the transaction.
+	Reference string `json:"reference,omitempty"`
+	// Memo explains the transaction, e.g. the reason for an adjustment.
+	Memo string `json:"memo,omitempty"`
+}
+
+// posting is a transaction to be posted to the ledger: amount moves from the
+// debit account to the credit account.
+type posting struct {
+	action    TxAction
+	debit     string
+	credit    string
+	amount    float64
+	at        time.Time
+	reference string
+	memo      string
+}
+
+// Ledger is an append-only, double-entry record of every balance change.
+type Ledger struct {
+	Entries  []*Entry `json:"entries"`
+	LastTxID uint64   `json:"last_tx_id"`
+
+	balances    map[string]float64
+	byAccount   map[string][]*Entry
+	byReference map[string][]*Entry
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{
+		Entries:     []*Entry{},
+		balances:    make(map[string]float64),
+		byAccount:   make(map[string][]*Entry),
+		byReference: make(map[string][]*Entry),
+	}
+}
+
+// RestoreLedger rebuilds a ledger and its indexes from previously posted
+// entries, e.g. from a snapshot.
+func RestoreLedger(entries []*Entry, lastTxID uint64) *Ledger {
+	ledger := NewLedger()
+	ledger.LastTxID = lastTxID
+
+	for _, entry := range entries {
+		ledger.append(entry)
+	}
+
+	return ledger
+}
+
+func (l *Ledger) post(p posting) (uint64, []*Entry) {
+	l.LastTxID++
+
+	entries := []*Entry{
+		{TxID: l.LastTxID, Action: p.action, Account: p.debit, Debit: p.amount},
+		{TxID: l.LastTxID, Action: p.action, Account: p.credit, Credit: p.amount},
+	}
+
+	for _, entry := range entries {
+		entry.Timestamp = p.at.UnixNano()
+		entry.Reference = p.reference
+		entry.Memo = p.memo
+		entry.ID = uint64(len(l.Entries)) + 1
+		entry.Balance = l.balances[entry.Account] + entry.Credit - entry.Debit
+		l.append(entry)
+	}
+
+	return l.LastTxID, entries
+}
+
+func (l *Ledger) append(entry *Entry) {
+	l.Entries = append(l.Entries, entry)
+	l.balances[entry.Account] += entry.Credit - entry.Debit
+	l.byAccount[entry.Account] = append(l.byAccount[entry.Account], entry)
+
+	// A transaction is indexed by its reference once both of its entries, the
+	// debit then the credit, have been appended. References are scoped to the
+	// debited account so different signers can use the same key.
+	if entry.Reference != "" && len(l.Entries) >= 2 {
+		debit := l.Entries[len(l.Entries)-2]
+		if debit.TxID == entry.TxID {
+			l.byReference[referenceKey(debit.Account, entry.Reference)] = []*Entry{debit, entry}
+		}
+	}
+}
+
+func referenceKey(account string, reference string) string {
+	return account + "\x00" + reference
+}
+
+// referenced returns the debit and credit entries of the transaction posted by
+// account with reference, or nil if there is none.
+func (l *Ledger) referenced(account string, reference string) []*Entry {
+	return l.byReference[referenceKey(account, reference)]
+}
+
+func (l *Ledger) BalanceOf(account string) float64 {
+	return l.balances[account]
+}
+
+// EntriesOf returns up to limit entries of account with an id below before,
+// newest first. A before of 0 starts from the newest entry.
+func (l *Ledger) EntriesOf(account string, before uint64, limit int) []*Entry {
+	entries := l.byAccount[account]
+	page := []*Entry{}
+
+	for i := len(entries) - 1; i >= 0 && len(page) < limit; i-- {
+		if before == 0 || entries[i].ID < before {
+			page = append(page, entries[i])
+		}
+	}
+
+	return page
+}
+
+// Verify checks that every transaction balances and that the running balance
+// recorded on each entry matches the entries before it.
+func (l *Ledger) Verify() error {
+	debits := make(map[uint64]float64)
+	credits := make(map[uint64]float64)
+	txIDs := []uint64{}
+
+	for _, entry := range l.Entries {
+		if _, ok := debits[entry.TxID]; !ok {
+			txIDs = append(txIDs, entry.TxID)
+		}
+		debits[entry.TxID] += entry.Debit
+		credits[entry.TxID] += entry.Credit
+	}
+
+	for _, txID := range txIDs {
+		if !amountsEqual(debits[txID], credits[txID]) {
+			return &UnbalancedTxError{txID, debits[txID], credits[txID]}
+		}
+	}
+
+	balances := make(map[string]float64)
+	for _, entry := range l.Entries {
+		balances[entry.Account] += entry.Credit - entry.Debit
+
+		if !amountsEqual(balances[entry.Account], entry.Balance) {
+			return &BalanceMismatchError{entry.Account, balances[entry.Account], entry.Balance}
+		}
+	}
+
+	return nil
+}
+
+func amountsEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,115 @@
+package accounting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedgerPostsBalancedEntries(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+
+	tx, _ := accounts.Deposit("alice", 100)
+
+	entries := accounts.Ledger.Entries
+	assert.Equal(t, 2, len(entries), "deposit should post 2 entries")
+	assert.Equal(t, tx.ID, entries[0].TxID, "entries should share the tx id")
+	assert.Equal(t, tx.ID, entries[1].TxID, "entries should share the tx id")
+	assert.Equal(t, ExternalAccount, entries[0].Account, "deposit should debit the external account")
+	assert.Equal(t, float64(100), entries[0].Debit, "external account should be debited 100")
+	assert.Equal(t, "alice", entries[1].Account, "deposit should credit alice")
+	assert.Equal(t, float64(100), entries[1].Credit, "alice should be credited 100")
+	assert.Equal(t, float64(-100), accounts.Ledger.BalanceOf(ExternalAccount), "external account should balance the deposit")
+}
+
+func TestLedgerDerivesBalances(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	accounts.CreateAccount("bob")
+
+	accounts.Deposit("alice", 100)
+	accounts.Withdraw("alice", 20)
+	accounts.Send("alice", "bob", 30)
+
+	assert.Equal(t, float64(50), accounts.Ledger.BalanceOf("alice"), "ledger balance of alice should be 50")
+	assert.Equal(t, float64(30), accounts.Ledger.BalanceOf("bob"), "ledger balance of bob should be 30")
+	assert.Equal(t, map[string]float64{"alice": 50, "bob": 30}, accounts.Accounts, "cached balances should match the ledger")
+	assert.NotContains(t, accounts.Accounts, ExternalAccount, "system accounts should not be listed as accounts")
+	assert.NoError(t, accounts.Reconcile(), "ledger should reconcile")
+}
+
+func TestLedgerReconcileMismatch(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	accounts.Deposit("alice", 100)
+	accounts.Accounts["alice"] = 150
+
+	assert.IsType(t, &BalanceMismatchError{}, accounts.Reconcile(), "changing a balance outside the ledger should not reconcile")
+}
+
+func TestLedgerVerifyUnbalanced(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	accounts.Deposit("alice", 100)
+	accounts.Ledger.Entries[0].Debit = 90
+
+	assert.IsType(t, &UnbalancedTxError{}, accounts.Ledger.Verify(), "tx with unequal debits and credits should not verify")
+}
+
+func TestAccountsTransactionsPaging(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	for i := 1; i <= 5; i++ {
+		accounts.Deposit("alice", float64(i))
+	}
+
+	page := accounts.Ledger.EntriesOf("alice", 0, 2)
+	assert.Equal(t, 2, len(page), "first page should have 2 entries")
+	assert.Equal(t, float64(5), page[0].Credit, "first page should start with the newest entry")
+	assert.Equal(t, float64(15), page[0].Balance, "entry should have the running balance")
+
+	page, err := accounts.Transactions("alice", page[1].ID, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(page), "second page should have the remaining 3 entries")
+	assert.Equal(t, float64(3), page[0].Credit, "second page should continue after the cursor")
+
+	_, err = accounts.Transactions("bob", 0, 10)
+	assert.IsType(t, &AccountNotFoundError{}, err, "unknown signer should return AccountNotFoundError")
+}
+
+func TestRestoreLedger(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	accounts.CreateAccount("bob")
+	accounts.Deposit("alice", 100)
+	accounts.Send("alice", "bob", 40)
+
+	restored := RestoreLedger(accounts.Ledger.Entries, accounts.Ledger.LastTxID)
+
+	assert.Equal(t, float64(60), restored.BalanceOf("alice"), "restored ledger should derive balances")
+	assert.Equal(t, 2, len(restored.EntriesOf("alice", 0, 10)), "restored ledger should index entries")
+	assert.NoError(t, restored.Verify(), "restored ledger should verify")
+}
+
+func TestAccountsAdjust(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+
+	tx, err := accounts.AdjustAt("alice", 50, "goodwill credit", time.Unix(0, 0))
+	assert.NoError(t, err, "positive adjustment should not return an error")
+	assert.Equal(t, Adjustment, tx.Action, "adjustment should return an Adjustment Tx")
+
+	_, err = accounts.AdjustAt("alice", -20, "fee correction", time.Unix(0, 0))
+	assert.NoError(t, err, "negative adjustment should not return an error")
+
+	_, err = accounts.AdjustAt("alice", -100, "too much", time.Unix(0, 0))
+	assert.IsType(t, &AccountUnderFundedError{}, err, "adjustment should not take a balance below zero")
+
+	balance, _ := accounts.BalanceOf("alice")
+	assert.Equal(t, float64(30), balance, "balance should include both adjustments")
+	assert.Equal(t, float64(-30), accounts.Ledger.BalanceOf(AdjustmentsAccount), "adjustments account should balance the adjustments")
+	assert.Equal(t, "fee correction", accounts.Ledger.EntriesOf("alice", 0, 1)[0].Memo, "entry should keep the reason")
+	assert.NoError(t, accounts.Reconcile(), "ledger should reconcile")
+}
@@ -0,0 +1,8 @@
+package accounting
+
+// Repository persists account balances and the ledger entries that changed
+// them outside of memory, e.g. for back office reporting.
+type Repository interface {
+	SaveAccount(signer string, balance float64) error
+	SaveEntries(entries []*Entry) error
+}
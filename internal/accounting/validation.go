@@ -0,0 +1,56 @@
+package accounting
+
+import (
+	"math"
+	"regexp"
+)
+
+// MaxAmountDecimals is the finest precision an amount may have.
+const MaxAmountDecimals = 8
+
+// MaxAmount keeps amounts well inside the range a float64 holds exactly at
+// MaxAmountDecimals.
+const MaxAmount = 1e12
+
+// SignerPattern is what an account name must match.
+const SignerPattern = `^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`
+
+var signerPattern = regexp.MustCompile(SignerPattern)
+
+// ValidateAmount checks that amount is a positive, finite number of no more
+// than MaxAmount with at most MaxAmountDecimals decimal places.
+func ValidateAmount(amount float64) error {
+	switch {
+	case math.IsNaN(amount) || math.IsInf(amount, 0):
+		return &InvalidAmountError{amount, "must be finite"}
+	case amount <= 0:
+		return &InvalidAmountError{amount, "must be positive"}
+	case amount > MaxAmount:
+		return &InvalidAmountError{amount, "is too large"}
+	}
+
+	// Allow for the last bit of float rounding, e.g. 0.1 + 0.2.
+	if math.Abs(RoundAmount(amount)-amount) > amount*1e-15 {
+		return &InvalidAmountError{amount, "has too many decimal places"}
+	}
+
+	return nil
+}
+
+// ValidateSigner checks that signer is 1 to 64 letters, digits, dots, dashes
+// or underscores, not starting with a symbol. This keeps signers clear of the
+// @ prefix reserved for system accounts.
+func ValidateSigner(signer string) error {
+	if !signerPattern.MatchString(signer) {
+		return &InvalidSignerError{signer}
+	}
+
+	return nil
+}
+
+// RoundAmount rounds amount to MaxAmountDecimals decimal places.
+func RoundAmount(amount float64) float64 {
+	scale := math.Pow10(MaxAmountDecimals)
+
+	return math.Round(amount*scale) / scale
+}
@@ -0,0 +1,52 @@
+package accounting
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAmount(t *testing.T) {
+	valid := []float64{1, 0.00000001, 2350.59730117, 0.1 + 0.2, MaxAmount}
+	for _, amount := range valid {
+		assert.NoError(t, ValidateAmount(amount), "%v should be a valid amount", amount)
+	}
+
+	invalid := []float64{0, -5, math.NaN(), math.Inf(1), math.Inf(-1), 0.000000001, 1.123456789, MaxAmount * 10}
+	for _, amount := range invalid {
+		assert.IsType(t, &InvalidAmountError{}, ValidateAmount(amount), "%v should be an invalid amount", amount)
+	}
+}
+
+func TestValidateSigner(t *testing.T) {
+	valid := []string{"alice", "Bob_2", "carol.smith", "0xdead-beef"}
+	for _, signer := range valid {
+		assert.NoError(t, ValidateSigner(signer), "%q should be a valid signer", signer)
+	}
+
+	invalid := []string{"", "@external", "-alice", "alice bob", "alice/../bob", string(make([]byte, 65))}
+	for _, signer := range invalid {
+		assert.IsType(t, &InvalidSignerError{}, ValidateSigner(signer), "%q should be an invalid signer", signer)
+	}
+}
+
+func TestAccountsRejectInvalidAmounts(t *testing.T) {
+	accounts := NewAccounts()
+	accounts.CreateAccount("alice")
+	accounts.CreateAccount("bob")
+	accounts.Deposit("alice", 100)
+
+	_, err := accounts.Deposit("alice", -50)
+	assert.IsType(t, &InvalidAmountError{}, err, "negative deposit should be refused")
+
+	_, err = accounts.Withdraw("alice", math.NaN())
+	assert.IsType(t, &InvalidAmountError{}, err, "NaN withdraw should be refused")
+
+	_, err = accounts.Send("alice", "bob", -30)
+	assert.IsType(t, &InvalidAmountError{}, err, "negative send should be refused")
+
+	assert.IsType(t, &InvalidSignerError{}, accounts.CreateAccount("@external"), "system account names should be refused")
+
+	assert.Equal(t, map[string]float64{"alice": 100, "bob": 0}, accounts.Balances(), "refused changes should not move balances")
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kr/pretty"
+	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+)
+
+// requireAdmin lets through requests authenticated with an admin key. When
+// authentication is disabled, admin routes are only open in development mode.
+func requireAdmin(authenticated bool, devMode bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !authenticated && devMode {
+				return next(c)
+			}
+
+			key, _ := c.Get(apiKeyContextKey).(*auth.Key)
+			if err := auth.RequireRole(key, auth.AdminRole); err != nil {
+				return err
+			}
+
+			return next(c)
+		}
+	}
+}
+
+type auditRecord struct {
+	Time      time.Time       `json:"time"`
+	RequestID string          `json:"request_id"`
+	KeyID     string          `json:"key_id,omitempty"`
+	Signer    string          `json:"signer,omitempty"`
+	Method    string          `json:"method"`
+	URI       string          `json:"uri"`
+	Body      json.RawMessage `json:"body,omitempty"`
+	Status    int             `json:"status"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// auditLog writes a JSON line to w for every request, recording who made it,
+// what it asked for and whether it succeeded.
+func auditLog(w io.Writer) echo.MiddlewareFunc {
+	var mu sync.Mutex
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			body, _ := io.ReadAll(req.Body)
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			err := next(c)
+
+			record := auditRecord{
+				Time:      time.Now().UTC(),
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+				Method:    req.Method,
+				URI:       req.RequestURI,
+				Status:    c.Response().Status,
+			}
+			if json.Valid(body) {
+				record.Body = body
+			}
+			if key, ok := c.Get(apiKeyContextKey).(*auth.Key); ok {
+				record.KeyID = key.ID
+				record.Signer = key.Signer
+			}
+			if err != nil {
+				record.Status = errorStatus(err)
+				record.Error = err.Error()
+			}
+
+			line, _ := json.Marshal(&record)
+			mu.Lock()
+			_, werr := w.Write(append(line, '\n'))
+			mu.Unlock()
+			if werr != nil {
+				pretty.Log("Failed to write audit log", werr.Error())
+			}
+
+			return err
+		}
+	}
+}
+
+// Admin
+func (c *CustomContext) handleAdminReset() error {
+	if err := c.platform.Reset(); err != nil {
+		return err
+	}
+
+	return c.String(http.StatusOK, "Orderbooks reset successfully")
+}
+
+func (c *CustomContext) handleAdminHaltMarket() error {
+	params := MarketParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	if err := c.platform.HaltMarket(orderbook.NewTradingPair(params.Base, params.Quote)); err != nil {
+		return err
+	}
+
+	return c.String(http.StatusOK, "Market halted")
+}
+
+func (c *CustomContext) handleAdminResumeMarket() error {
+	params := MarketParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	if err := c.platform.ResumeMarket(orderbook.NewTradingPair(params.Base, params.Quote)); err != nil {
+		return err
+	}
+
+	return c.String(http.StatusOK, "Market resumed")
+}
+
+func (c *CustomContext) handleAdminSetMarketState() error {
+	params := MarketStateParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	pair := orderbook.NewTradingPair(params.Base, params.Quote)
+	if err := c.platform.SetMarketState(pair, params.State, params.Reason); err != nil {
+		return err
+	}
+
+	return c.String(http.StatusOK, "Market state set to "+string(params.State))
+}
+
+func (c *CustomContext) handleAdminSetFees() error {
+	params := FeeScheduleParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	var schedule *orderbook.FeeSchedule
+	if !params.Clear {
+		schedule = &orderbook.FeeSchedule{MakerRate: params.MakerRate, TakerRate: params.TakerRate, Tiers: params.Tiers}
+	}
+
+	pair := orderbook.NewTradingPair(params.Base, params.Quote)
+	if err := c.platform.SetFeeSchedule(pair, schedule); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+func (c *CustomContext) handleAdminImportOrderbook() error {
+	params := ImportParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	result, err := c.importOrders(orderbook.NewTradingPair(params.Base, params.Quote), params.CreateMarket)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// importOrders places the orders of the uploaded file field.
+func (c *CustomContext) importOrders(pair orderbook.TradingPair, createMarket bool) (*orderbook.ImportResult, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "file is required")
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if createMarket {
+		if _, err := c.platform.AddNewMarket(pair); err != nil {
+			if _, exists := err.(*orderbook.MarketAlreadyExistsError); !exists {
+				return nil, err
+			}
+		}
+	}
+
+	return c.platform.ImportOrders(pair, f)
+}
+
+func (c *CustomContext) handleAdminRemoveMarket() error {
+	params := MarketParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	cancelled, err := c.platform.RemoveMarket(orderbook.NewTradingPair(params.Base, params.Quote))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &cancelled)
+}
+
+func (c *CustomContext) handleAdminAdjustBalance() error {
+	params := AdjustBalanceParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	tx, err := c.platform.AdjustBalance(params.Signer, params.Amount, params.Reason)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &tx)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/auth"
+)
+
+const apiKeyContextKey = "api_key"
+
+// authenticate requires every request to be signed with an API key, and makes
+// the key available to authorize. With a nil verifier authentication is
+// disabled and every request is let through.
+func authenticate(verifier *auth.Verifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if verifier == nil {
+			return next
+		}
+
+		return func(c echo.Context) error {
+			key, err := verifier.Verify(c.Request())
+			if err != nil {
+				return err
+			}
+
+			c.Set(apiKeyContextKey, key)
+			return next(c)
+		}
+	}
+}
+
+// authorize checks that the authenticated signer may act for signer.
+func (c *CustomContext) authorize(signer string) error {
+	key, ok := c.Get(apiKeyContextKey).(*auth.Key)
+	if !ok {
+		return nil
+	}
+
+	return key.Authorize(signer)
+}
+
+// signer is the authenticated signer, or empty when authentication is
+// disabled.
+func (c *CustomContext) signer() string {
+	if key, ok := c.Get(apiKeyContextKey).(*auth.Key); ok {
+		return key.Signer
+	}
+
+	return ""
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -10,24 +12,71 @@ type HTTPError interface {
 	HTTPCode() int
 }
 
-func HTTPErrorHandler(err error, c echo.Context) {
-	type Response struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-	}
+// ReasonError is an error that names itself for the reason of a /v1 error,
+// for errors whose message is not "Name : detail".
+type ReasonError interface {
+	Reason() string
+}
+
+// RetryAfterError is an error the client should retry after a number of
+// seconds, sent as the Retry-After header.
+type RetryAfterError interface {
+	RetryAfterSeconds() int
+}
+
+// ErrorResponse is the body of every failed request.
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
 
-	code := http.StatusInternalServerError
+func HTTPErrorHandler(err error, c echo.Context) {
+	code := errorStatus(err)
 	message := err.Error()
 
 	if he, ok := err.(*echo.HTTPError); ok {
-		code = he.Code
 		message = he.Message.(string)
-	} else if he, ok := err.(HTTPError); ok {
-		code = he.HTTPCode()
 	}
 
-	c.JSON(code, &Response{
+	if re, ok := err.(RetryAfterError); ok {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(re.RetryAfterSeconds()))
+	}
+
+	if strings.HasPrefix(c.Request().URL.Path, v1Prefix+"/") {
+		c.JSON(code, &V1ErrorResponse{V1Error{
+			Code:    code,
+			Reason:  errorReason(err, code, message),
+			Message: message,
+		}})
+		return
+	}
+
+	c.JSON(code, &ErrorResponse{
 		Code:    code,
 		Message: message,
 	})
 }
+
+// errorReason is the reason an error gives, the name its message starts with,
+// as in "MarketNotfound : BTC/USD", or the name of its status.
+func errorReason(err error, code int, message string) string {
+	if re, ok := err.(ReasonError); ok {
+		return re.Reason()
+	}
+	if name, _, ok := strings.Cut(message, " : "); ok && !strings.Contains(name, " ") {
+		return name
+	}
+
+	return strings.ReplaceAll(http.StatusText(code), " ", "")
+}
+
+// errorStatus is the HTTP status code a handler error is answered with.
+func errorStatus(err error) int {
+	if he, ok := err.(*echo.HTTPError); ok {
+		return he.Code
+	} else if he, ok := err.(HTTPError); ok {
+		return he.HTTPCode()
+	}
+
+	return http.StatusInternalServerError
+}
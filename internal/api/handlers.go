@@ -1,10 +1,13 @@
 package api
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/accounting"
 	"github.com/richo225/octgopus/internal/orderbook"
 )
 
@@ -21,7 +24,13 @@ func (c *CustomContext) handleCreateOrderbook() error {
 	}
 
 	pair := orderbook.NewTradingPair(params.Base, params.Quote)
-	orderbook := c.platform.AddNewMarket(pair)
+	if _, err := c.platform.AddNewMarket(pair); err != nil {
+		return err
+	}
+	orderbook, err := c.platform.OrderBookCopy(pair)
+	if err != nil {
+		return err
+	}
 
 	return c.JSON(http.StatusOK, &orderbook)
 }
@@ -46,8 +55,43 @@ func (c *CustomContext) handleGetOrderbook() error {
 	return c.JSON(http.StatusOK, &orderbook)
 }
 
+func (c *CustomContext) handleGetMarkets() error {
+	return c.JSON(http.StatusOK, c.platform.Markets())
+}
+
+func (c *CustomContext) handleExportOrderbook() error {
+	params := ExportParams{ExportOptions: defaultExportOptions}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	return c.export(orderbook.NewTradingPair(params.Base, params.Quote), params.View, params.Format)
+}
+
+// export responds with a download of a market.
+func (c *CustomContext) export(pair orderbook.TradingPair, view orderbook.ExportView, format orderbook.ExportFormat) error {
+	// Exported to a buffer first so that errors are still reported as JSON.
+	var buf bytes.Buffer
+	if err := c.platform.Export(&buf, pair, view, format); err != nil {
+		return err
+	}
+
+	contentType := "text/csv; charset=UTF-8"
+	if format == orderbook.FormatJSON {
+		contentType = echo.MIMEApplicationJSONCharsetUTF8
+	}
+	filename := strings.ToLower(fmt.Sprintf("%s_%s_%s.%s", pair.Base, pair.Quote, view, format))
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
 func (c *CustomContext) handleResetOrderbooks() error {
-	c.platform.Reset()
+	if err := c.platform.Reset(); err != nil {
+		return err
+	}
+
 	return c.String(http.StatusOK, "Orderbooks reset successfully")
 }
 
@@ -61,17 +105,38 @@ func (c *CustomContext) handleCreateOrder() error {
 
 	pair := orderbook.NewTradingPair(params.Base, params.Quote)
 	order := orderbook.NewOrder(params.Side, params.Size)
+	order.Signer = c.signer()
+	order.SelfTradePrevention = params.SelfTradePrevention
 
+	placed, matches, err := c.platform.PlaceOrder(pair, params.Type, params.Price, order)
+	if err != nil {
+		return err
+	}
 	if params.Type == orderbook.MarketOrder {
-		matches, err := c.platform.PlaceMarketOrder(pair, order)
-		if err != nil {
-			return err
-		}
-
 		return c.JSON(http.StatusOK, &matches)
 	}
 
-	err := c.platform.PlaceLimitOrder(pair, params.Price, order)
+	return c.JSON(http.StatusOK, &placed)
+}
+
+func (c *CustomContext) handleCancelOrder() error {
+	params := CancelOrderRequestParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+
+	pair := orderbook.NewTradingPair(params.Base, params.Quote)
+
+	resting, err := c.platform.GetOrder(pair, params.ID)
+	if err != nil {
+		return err
+	}
+	if err := c.authorize(resting.Signer); err != nil {
+		return err
+	}
+
+	order, err := c.platform.CancelOrder(pair, params.ID)
 	if err != nil {
 		return err
 	}
@@ -82,8 +147,11 @@ func (c *CustomContext) handleCreateOrder() error {
 // Accounting
 func (c *CustomContext) handleCreateAccount() error {
 	signer := c.Param("signer")
+	if err := c.authorize(signer); err != nil {
+		return err
+	}
 
-	err := c.platform.Accounts.CreateAccount(signer)
+	err := c.platform.CreateAccount(signer)
 	if err != nil {
 		return err
 	}
@@ -91,10 +159,13 @@ func (c *CustomContext) handleCreateAccount() error {
 	return c.String(http.StatusOK, "Account created")
 }
 
-func (c *CustomContext) handleGetAccounts() error {
-	accounts := c.platform.Accounts
+// AccountBalances is the balance of every account.
+type AccountBalances struct {
+	Accounts map[string]float64 `json:"accounts"`
+}
 
-	return c.JSON(http.StatusOK, &accounts)
+func (c *CustomContext) handleGetAccounts() error {
+	return c.JSON(http.StatusOK, &AccountBalances{c.platform.Accounts.Balances()})
 }
 
 func (c *CustomContext) handleGetAccountBalance() error {
@@ -103,6 +174,9 @@ func (c *CustomContext) handleGetAccountBalance() error {
 	if err := c.Validate(&params); err != nil {
 		return err
 	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
 
 	balance, err := c.platform.Accounts.BalanceOf(params.Signer)
 	if err != nil {
@@ -112,14 +186,56 @@ func (c *CustomContext) handleGetAccountBalance() error {
 	return c.String(http.StatusOK, fmt.Sprintf("%f", balance))
 }
 
+const defaultTransactionsLimit = 50
+
+// TransactionsPage is a page of ledger entries. Next is passed as before to
+// fetch the following page, and is omitted on the last page.
+type TransactionsPage struct {
+	Entries []*accounting.Entry `json:"entries"`
+	Next    uint64              `json:"next,omitempty"`
+}
+
+func (c *CustomContext) handleGetAccountTransactions() error {
+	params := AccountTransactionsParams{}
+	c.Bind(&params)
+	if err := c.Validate(&params); err != nil {
+		return err
+	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultTransactionsLimit
+	}
+
+	entries, err := c.platform.Transactions(params.Signer, params.Before, params.Limit)
+	if err != nil {
+		return err
+	}
+
+	page := TransactionsPage{Entries: entries}
+	if len(entries) == params.Limit {
+		page.Next = entries[len(entries)-1].ID
+	}
+
+	return c.JSON(http.StatusOK, &page)
+}
+
 func (c *CustomContext) handleAccountDeposit() error {
 	params := AccountActionParams{}
 	c.Bind(&params)
 	if err := c.Validate(&params); err != nil {
 		return err
 	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+
+	tx, err := c.platform.Deposit(params.Signer, params.Amount)
+	if err != nil {
+		return err
+	}
 
-	tx := c.platform.Accounts.Deposit(params.Signer, params.Amount)
 	return c.JSON(http.StatusOK, &tx)
 }
 
@@ -129,8 +245,11 @@ func (c *CustomContext) handleAccountWithdraw() error {
 	if err := c.Validate(&params); err != nil {
 		return err
 	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
 
-	tx, err := c.platform.Accounts.Withdraw(params.Signer, params.Amount)
+	tx, err := c.platform.Withdraw(params.Signer, params.Amount)
 	if err != nil {
 		return err
 	}
@@ -138,14 +257,26 @@ func (c *CustomContext) handleAccountWithdraw() error {
 	return c.JSON(http.StatusOK, &tx)
 }
 
+// idempotencyKeyHeader carries a client chosen key so that a retried send
+// returns the original transfer instead of transferring again.
+const idempotencyKeyHeader = "Idempotency-Key"
+
 func (c *CustomContext) handleAccountSend() error {
 	params := AccountSendParams{}
 	c.Bind(&params)
 	if err := c.Validate(&params); err != nil {
 		return err
 	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+
+	opts := accounting.SendOptions{
+		CreateRecipient: params.CreateRecipient,
+		IdempotencyKey:  c.Request().Header.Get(idempotencyKeyHeader),
+	}
 
-	tx, err := c.platform.Accounts.Send(params.Signer, params.Recipient, params.Amount)
+	tx, err := c.platform.SendWithOptions(params.Signer, params.Recipient, params.Amount, opts)
 	if err != nil {
 		return err
 	}
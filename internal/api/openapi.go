@@ -0,0 +1,651 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/accounting"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+)
+
+// routeDoc describes a route registered in registerHandlers for the OpenAPI
+// document. TestOpenAPIDescribesEveryRoute fails when a route is missing.
+type routeDoc struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	// params is the struct the handler binds, nil when it binds nothing.
+	params interface{}
+	// response is a value of the JSON response, or a textResponse or
+	// fileResponse.
+	response interface{}
+	access   access
+	// devOnly routes are only registered in development mode.
+	devOnly bool
+	// multipart routes take their params as form fields and a file upload.
+	multipart bool
+	// idempotent routes accept an Idempotency-Key header.
+	idempotent bool
+	// status is the status of a successful response, 200 when zero.
+	status int
+	// paged routes take PageParams and respond with PageMeta.
+	paged bool
+}
+
+// versioned reports whether the route is part of the /v1 API, whose JSON
+// responses are wrapped in an Envelope.
+func (route routeDoc) versioned() bool {
+	return strings.HasPrefix(route.path, v1Prefix+"/")
+}
+
+// deprecated reports whether the route has been replaced by a /v1 route.
+func (route routeDoc) deprecated() bool {
+	return !route.versioned() && route.tag != "health"
+}
+
+type access int
+
+const (
+	public access = iota
+	signed
+	admin
+)
+
+// textResponse is a plain text response, with an example.
+type textResponse string
+
+// fileResponse is a download in one of several content types.
+type fileResponse []string
+
+// oneOf is a response that is one of several shapes.
+type oneOf []interface{}
+
+// noContent is an empty response.
+type noContent struct{}
+
+var routeDocs = []routeDoc{
+	{method: http.MethodGet, path: "/", tag: "health", summary: "Check the API is up", response: textResponse("Up")},
+	{method: http.MethodGet, path: "/openapi.json", tag: "health", summary: "This document", response: map[string]interface{}{}},
+
+	{method: http.MethodGet, path: "/orderbooks", tag: "orderbooks", summary: "Get the order book of a market", params: MarketParams{}, response: orderbook.Orderbook{}},
+	{method: http.MethodGet, path: "/orderbooks/markets", tag: "orderbooks", summary: "Summarise every market", response: []orderbook.MarketSummary{}},
+	{method: http.MethodGet, path: "/orderbooks/export", tag: "orderbooks", summary: "Download a book, its orders or its trades", params: ExportParams{}, response: fileResponse{"text/csv", "application/json"}},
+	{method: http.MethodGet, path: "/orderbooks/reset", tag: "orderbooks", summary: "Reset every market and account, replaced by POST /admin/reset", response: textResponse("Orderbooks reset successfully"), devOnly: true},
+	{method: http.MethodPost, path: "/orderbooks", tag: "orderbooks", summary: "Create a market, replaced by POST /admin/markets", params: MarketParams{}, response: orderbook.Orderbook{}, devOnly: true},
+	{method: http.MethodPost, path: "/orderbooks/import", tag: "orderbooks", summary: "Place the orders of a CSV file", params: ImportParams{}, response: orderbook.ImportResult{}, access: admin, multipart: true},
+
+	{method: http.MethodPost, path: "/orders", tag: "orders", summary: "Place an order; market orders respond with their matches", params: PlaceOrderRequestParams{}, response: oneOf{orderbook.Order{}, []orderbook.Match{}}, access: signed},
+	{method: http.MethodDelete, path: "/orders/:id", tag: "orders", summary: "Cancel a resting order", params: CancelOrderRequestParams{}, response: orderbook.Order{}, access: signed},
+
+	{method: http.MethodGet, path: "/accounts", tag: "accounts", summary: "List every balance, replaced by GET /admin/accounts", response: AccountBalances{}, access: signed, devOnly: true},
+	{method: http.MethodGet, path: "/accounts/:signer", tag: "accounts", summary: "Get the balance of an account", params: AccountBalanceParams{}, response: textResponse("100.000000"), access: signed},
+	{method: http.MethodGet, path: "/accounts/:signer/transactions", tag: "accounts", summary: "Page through the ledger entries of an account, newest first", params: AccountTransactionsParams{}, response: TransactionsPage{}, access: signed},
+	{method: http.MethodPost, path: "/accounts/:signer", tag: "accounts", summary: "Open an account", response: textResponse("Account created"), access: signed},
+	{method: http.MethodPost, path: "/accounts/:signer/deposit", tag: "accounts", summary: "Deposit into an account", params: AccountActionParams{}, response: accounting.Tx{}, access: signed},
+	{method: http.MethodPost, path: "/accounts/:signer/withdraw", tag: "accounts", summary: "Withdraw from an account", params: AccountActionParams{}, response: accounting.Tx{}, access: signed},
+	{method: http.MethodPost, path: "/accounts/:signer/send", tag: "accounts", summary: "Send to another account, as a withdraw and a deposit", params: AccountSendParams{}, response: []accounting.Tx{}, access: signed, idempotent: true},
+
+	{method: http.MethodPost, path: "/admin/reset", tag: "admin", summary: "Reset every market and account and open the configured markets", response: textResponse("Orderbooks reset successfully"), access: admin},
+	{method: http.MethodPost, path: "/admin/markets", tag: "admin", summary: "Create a market", params: MarketParams{}, response: orderbook.Orderbook{}, access: admin},
+	{method: http.MethodDelete, path: "/admin/markets", tag: "admin", summary: "Remove a market, cancelling its orders", params: MarketParams{}, response: []orderbook.Order{}, access: admin},
+	{method: http.MethodPost, path: "/admin/markets/halt", tag: "admin", summary: "Halt a market", params: MarketParams{}, response: textResponse("Market halted"), access: admin},
+	{method: http.MethodPost, path: "/admin/markets/resume", tag: "admin", summary: "Resume a halted market", params: MarketParams{}, response: textResponse("Market resumed"), access: admin},
+	{method: http.MethodPost, path: "/admin/markets/state", tag: "admin", summary: "Set the trading state of a market", params: MarketStateParams{}, response: textResponse("Market state set to halted"), access: admin},
+	{method: http.MethodPost, path: "/admin/markets/fees", tag: "admin", summary: "Set or clear the fees of a market", params: FeeScheduleParams{}, response: &orderbook.FeeSchedule{}, access: admin},
+	{method: http.MethodGet, path: "/admin/accounts", tag: "admin", summary: "List every balance", response: AccountBalances{}, access: admin},
+	{method: http.MethodPost, path: "/admin/accounts/:signer/adjust", tag: "admin", summary: "Correct a balance, recording the reason in the ledger", params: AdjustBalanceParams{}, response: accounting.Tx{}, access: admin},
+
+	{method: http.MethodGet, path: "/v1/health", tag: "health", summary: "Check the API is up", response: map[string]string{"status": "up"}},
+
+	{method: http.MethodGet, path: "/v1/markets", tag: "markets", summary: "Summarise the markets, in name order", params: PageParams{}, response: []orderbook.MarketSummary{}, paged: true},
+	{method: http.MethodPost, path: "/v1/markets", tag: "markets", summary: "Create a market", params: MarketParams{}, response: orderbook.Orderbook{}, access: admin, status: http.StatusCreated},
+	{method: http.MethodGet, path: "/v1/markets/:market", tag: "markets", summary: "Summarise a market", response: orderbook.MarketSummary{}},
+	{method: http.MethodDelete, path: "/v1/markets/:market", tag: "markets", summary: "Remove a market, cancelling its orders", response: []orderbook.Order{}, access: admin},
+	{method: http.MethodGet, path: "/v1/markets/:market/book", tag: "markets", summary: "Get the order book of a market", response: orderbook.Orderbook{}},
+	{method: http.MethodGet, path: "/v1/markets/:market/export", tag: "markets", summary: "Download a book, its orders or its trades", params: ExportOptions{}, response: fileResponse{"text/csv", "application/json"}},
+	{method: http.MethodPost, path: "/v1/markets/:market/import", tag: "markets", summary: "Place the orders of a CSV file", params: ImportOptions{}, response: orderbook.ImportResult{}, access: admin, multipart: true, status: http.StatusCreated},
+	{method: http.MethodPut, path: "/v1/markets/:market/state", tag: "markets", summary: "Set the trading state of a market", params: StateParams{}, response: orderbook.MarketSummary{}, access: admin},
+	{method: http.MethodPut, path: "/v1/markets/:market/fees", tag: "markets", summary: "Set the fees of a market", params: FeeParams{}, response: orderbook.FeeSchedule{}, access: admin},
+	{method: http.MethodDelete, path: "/v1/markets/:market/fees", tag: "markets", summary: "Clear the fees of a market", response: noContent{}, access: admin, status: http.StatusNoContent},
+
+	{method: http.MethodPost, path: "/v1/markets/:market/orders", tag: "orders", summary: "Place an order", params: OrderParams{}, response: OrderResult{}, access: signed, status: http.StatusCreated},
+	{method: http.MethodGet, path: "/v1/markets/:market/orders/:id", tag: "orders", summary: "Get a resting order", params: OrderIDParams{}, response: orderbook.Order{}, access: signed},
+	{method: http.MethodDelete, path: "/v1/markets/:market/orders/:id", tag: "orders", summary: "Cancel a resting order", params: OrderIDParams{}, response: orderbook.Order{}, access: signed},
+
+	{method: http.MethodPost, path: "/v1/accounts", tag: "accounts", summary: "Open an account", params: CreateAccountParams{}, response: AccountBalance{}, access: signed, status: http.StatusCreated},
+	{method: http.MethodGet, path: "/v1/accounts/:signer", tag: "accounts", summary: "Get the balance of an account", params: AccountBalanceParams{}, response: AccountBalance{}, access: signed},
+	{method: http.MethodGet, path: "/v1/accounts/:signer/transactions", tag: "accounts", summary: "Page through the ledger entries of an account, newest first", params: AccountEntriesParams{}, response: []accounting.Entry{}, access: signed, paged: true},
+	{method: http.MethodPost, path: "/v1/accounts/:signer/deposits", tag: "accounts", summary: "Deposit into an account", params: AccountActionParams{}, response: accounting.Tx{}, access: signed, status: http.StatusCreated},
+	{method: http.MethodPost, path: "/v1/accounts/:signer/withdrawals", tag: "accounts", summary: "Withdraw from an account", params: AccountActionParams{}, response: accounting.Tx{}, access: signed, status: http.StatusCreated},
+	{method: http.MethodPost, path: "/v1/accounts/:signer/transfers", tag: "accounts", summary: "Send to another account, as a withdraw and a deposit", params: AccountSendParams{}, response: []accounting.Tx{}, access: signed, idempotent: true, status: http.StatusCreated},
+
+	{method: http.MethodPost, path: "/v1/admin/reset", tag: "admin", summary: "Reset every market and account and open the configured markets", response: []orderbook.MarketSummary{}, access: admin},
+	{method: http.MethodGet, path: "/v1/admin/accounts", tag: "admin", summary: "List the balances, in signer order", params: PageParams{}, response: []AccountBalance{}, access: admin, paged: true},
+	{method: http.MethodPost, path: "/v1/admin/accounts/:signer/adjustments", tag: "admin", summary: "Correct a balance, recording the reason in the ledger", params: AdjustBalanceParams{}, response: accounting.Tx{}, access: admin, status: http.StatusCreated},
+}
+
+// pathParams are the schemas of path params no params struct binds.
+var pathParams = map[string]*schema{
+	"market": {Type: "string", Pattern: marketPattern, Example: "BTC-USD", Description: "BASE-QUOTE"},
+}
+
+// enums lists the values of the string types the API responds with.
+var enums = map[reflect.Type][]string{
+	reflect.TypeOf(orderbook.Bid):          {string(orderbook.Bid), string(orderbook.Ask)},
+	reflect.TypeOf(orderbook.LimitOrder):   {string(orderbook.LimitOrder), string(orderbook.MarketOrder)},
+	reflect.TypeOf(orderbook.StateOpen):    {string(orderbook.StateOpen), string(orderbook.StateHalted), string(orderbook.StateCancelOnly), string(orderbook.StateAuction)},
+	reflect.TypeOf(orderbook.LayoutPaired): {string(orderbook.LayoutPaired), string(orderbook.LayoutAsks), string(orderbook.LayoutBids), string(orderbook.LayoutOrders)},
+	reflect.TypeOf(orderbook.STPNone): {string(orderbook.STPNone), string(orderbook.STPCancelNewest), string(orderbook.STPCancelOldest),
+		string(orderbook.STPCancelBoth), string(orderbook.STPDecrementAndCancel)},
+	reflect.TypeOf(accounting.Deposit): {string(accounting.Deposit), string(accounting.Withdraw), string(accounting.Transfer),
+		string(accounting.Adjustment), string(accounting.Fee)},
+}
+
+// The parts of an OpenAPI 3.0 document the API uses.
+
+type openAPIDocument struct {
+	OpenAPI    string                       `json:"openapi"`
+	Info       openAPIInfo                  `json:"info"`
+	Tags       []openAPITag                 `json:"tags"`
+	Paths      map[string]map[string]*apiOp `json:"paths"`
+	Components openAPIComponents            `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type openAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*schema         `json:"schemas"`
+	Responses       map[string]*apiResponse    `json:"responses"`
+	SecuritySchemes map[string]*securityScheme `json:"securitySchemes"`
+}
+
+type apiOp struct {
+	Tags        []string                `json:"tags"`
+	Summary     string                  `json:"summary"`
+	Description string                  `json:"description,omitempty"`
+	Deprecated  bool                    `json:"deprecated,omitempty"`
+	Parameters  []*apiParameter         `json:"parameters,omitempty"`
+	RequestBody *apiRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*apiResponse `json:"responses"`
+	Security    []map[string][]string   `json:"security,omitempty"`
+}
+
+type apiParameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *schema `json:"schema"`
+}
+
+type apiRequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type apiResponse struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type securityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Example              interface{}        `json:"example,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*schema          `json:"oneOf,omitempty"`
+}
+
+const openAPIDescription = `Trading platform API for submitting and matching orders.
+
+Routes under /v1 wrap their JSON responses in {"data": ...}, with a "meta" of the page on lists, and their errors in {"error": {"code", "reason", "message"}}. The routes outside /v1 are deprecated.
+
+Requests to the orders, accounts and admin routes are signed when the server has API keys: X-Api-Signature is the hex HMAC-SHA256, keyed by the key's secret, of the method, the path with its query, the unix timestamp, the nonce and the hex SHA-256 of the body, joined by newlines. A nonce can only be used once.`
+
+// signatureHeaders are the security schemes a signed request sends together.
+var signatureHeaders = map[string]string{
+	"ApiKey":    auth.KeyHeader,
+	"Timestamp": auth.TimestampHeader,
+	"Nonce":     auth.NonceHeader,
+	"Signature": auth.SignatureHeader,
+}
+
+// newOpenAPI builds the OpenAPI document of routeDocs.
+func newOpenAPI() *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: "Octgopus", Version: "1.0.0", Description: openAPIDescription},
+		Tags: []openAPITag{
+			{"health", "Status of the API"},
+			{"markets", "Markets and their order books"},
+			{"orderbooks", "Markets and their order books, replaced by markets"},
+			{"orders", "Order entry"},
+			{"accounts", "Balances and the ledger"},
+			{"admin", "Operations that need an API key with the admin role"},
+		},
+		Paths: make(map[string]map[string]*apiOp),
+		Components: openAPIComponents{
+			Schemas:         make(map[string]*schema),
+			SecuritySchemes: make(map[string]*securityScheme),
+		},
+	}
+
+	errorSchema := doc.schemaOf(reflect.TypeOf(ErrorResponse{}))
+	v1ErrorSchema := doc.schemaOf(reflect.TypeOf(V1ErrorResponse{}))
+	doc.Components.Responses = map[string]*apiResponse{
+		"Error":   {Description: "The request failed", Content: map[string]*mediaType{"application/json": {errorSchema}}},
+		"V1Error": {Description: "The request failed", Content: map[string]*mediaType{"application/json": {v1ErrorSchema}}},
+	}
+	for name, header := range signatureHeaders {
+		doc.Components.SecuritySchemes[name] = &securityScheme{Type: "apiKey", In: "header", Name: header, Description: "Request signature header " + header}
+	}
+
+	for _, route := range routeDocs {
+		path := openAPIPath(route.path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*apiOp)
+		}
+		doc.Paths[path][strings.ToLower(route.method)] = doc.operation(route)
+	}
+
+	return doc
+}
+
+// openAPIPath converts an echo path such as /orders/:id to /orders/{id}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func (doc *openAPIDocument) operation(route routeDoc) *apiOp {
+	op := &apiOp{
+		Tags:       []string{route.tag},
+		Summary:    route.summary,
+		Deprecated: route.devOnly || route.deprecated(),
+		Responses:  map[string]*apiResponse{"default": {Ref: "#/components/responses/Error"}},
+	}
+	if route.versioned() {
+		op.Responses["default"].Ref = "#/components/responses/V1Error"
+	}
+	if route.devOnly {
+		op.Description = "Only registered in development mode."
+	}
+
+	switch route.access {
+	case signed:
+		op.Security = []map[string][]string{signatureRequirement()}
+		op.Description = strings.TrimSpace(op.Description + " Signed requests may only act for the key's signer.")
+	case admin:
+		op.Security = []map[string][]string{signatureRequirement()}
+		op.Description = strings.TrimSpace(op.Description + " Requires an API key with the admin role.")
+	}
+
+	// Path params come from the param tags of the params, or from the path
+	// for handlers that read them directly.
+	var params reflect.Type
+	if route.params != nil {
+		params = reflect.TypeOf(route.params)
+	}
+	for _, segment := range strings.Split(route.path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			name := segment[1:]
+			s := &schema{Type: "string"}
+			if known, ok := pathParams[name]; ok {
+				s = known
+			}
+			if params != nil {
+				if field, ok := findField(params, "param", name); ok {
+					s = doc.fieldSchema(field)
+				}
+			}
+			op.Parameters = append(op.Parameters, &apiParameter{Name: name, In: "path", Required: true, Schema: s})
+		}
+	}
+
+	if route.idempotent {
+		op.Parameters = append(op.Parameters, &apiParameter{
+			Name:   "Idempotency-Key",
+			In:     "header",
+			Schema: &schema{Type: "string", Description: "Makes a retried request return the original result"},
+		})
+	}
+
+	if params != nil {
+		switch {
+		case route.multipart:
+			body := doc.objectSchema(params, "form")
+			body.Properties["file"] = &schema{Type: "string", Format: "binary", Description: "CSV file"}
+			body.Required = append(body.Required, "file")
+			op.RequestBody = &apiRequestBody{Required: true, Content: map[string]*mediaType{"multipart/form-data": {body}}}
+		case route.method == http.MethodGet || route.method == http.MethodDelete:
+			query := doc.objectSchema(params, "query")
+			for _, name := range sortedKeys(query.Properties) {
+				op.Parameters = append(op.Parameters, &apiParameter{Name: name, In: "query", Required: contains(query.Required, name), Schema: query.Properties[name]})
+			}
+		default:
+			body := doc.objectSchema(params, "json")
+			if len(body.Properties) > 0 {
+				op.RequestBody = &apiRequestBody{Required: len(body.Required) > 0, Content: map[string]*mediaType{"application/json": {body}}}
+			}
+		}
+	}
+
+	status := route.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := doc.response(route.response)
+	if route.versioned() {
+		response = doc.envelope(response, route.paged)
+	}
+	op.Responses[strconv.Itoa(status)] = response
+
+	return op
+}
+
+// envelope wraps the JSON schema of a response in an Envelope.
+func (doc *openAPIDocument) envelope(response *apiResponse, paged bool) *apiResponse {
+	media, ok := response.Content["application/json"]
+	if !ok || len(response.Content) > 1 {
+		return response
+	}
+
+	s := &schema{Type: "object", Required: []string{"data"}, Properties: map[string]*schema{"data": media.Schema}}
+	if paged {
+		s.Properties["meta"] = doc.schemaOf(reflect.TypeOf(PageMeta{}))
+		s.Required = append(s.Required, "meta")
+	}
+
+	return &apiResponse{Description: response.Description, Content: map[string]*mediaType{"application/json": {s}}}
+}
+
+func signatureRequirement() map[string][]string {
+	requirement := make(map[string][]string)
+	for name := range signatureHeaders {
+		requirement[name] = []string{}
+	}
+
+	return requirement
+}
+
+func (doc *openAPIDocument) response(v interface{}) *apiResponse {
+	switch r := v.(type) {
+	case noContent:
+		return &apiResponse{Description: "No Content"}
+	case textResponse:
+		return &apiResponse{Description: "OK", Content: map[string]*mediaType{"text/plain": {&schema{Type: "string", Example: string(r)}}}}
+	case fileResponse:
+		content := make(map[string]*mediaType)
+		for _, contentType := range r {
+			content[contentType] = &mediaType{&schema{Type: "string", Format: "binary"}}
+		}
+		return &apiResponse{Description: "OK", Content: content}
+	case oneOf:
+		s := &schema{}
+		for _, shape := range r {
+			s.OneOf = append(s.OneOf, doc.schemaOf(reflect.TypeOf(shape)))
+		}
+		return &apiResponse{Description: "OK", Content: map[string]*mediaType{"application/json": {s}}}
+	}
+
+	return &apiResponse{Description: "OK", Content: map[string]*mediaType{"application/json": {doc.schemaOf(reflect.TypeOf(v))}}}
+}
+
+// schemaOf returns the schema of t, adding named structs to the components
+// and referring to them.
+func (doc *openAPIDocument) schemaOf(t reflect.Type) *schema {
+	if t.Kind() == reflect.Ptr {
+		s := doc.schemaOf(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	}
+
+	if values, ok := enums[t]; ok {
+		return &schema{Type: "string", Enum: values}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return doc.objectSchema(t, "json")
+		}
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			// Claim the name first so that recursive types terminate.
+			doc.Components.Schemas[t.Name()] = &schema{}
+			*doc.Components.Schemas[t.Name()] = *doc.objectSchema(t, "json")
+		}
+		return &schema{Ref: "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return &schema{Type: "array", Items: doc.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: doc.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return &schema{}
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number", Format: "double"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &schema{Type: "integer", Format: "int64"}
+	}
+
+	return &schema{}
+}
+
+// objectSchema describes the exported fields of t that have a tag name,
+// flattening embedded structs as the binder does. Fields bound from the path
+// are left out.
+func (doc *openAPIDocument) objectSchema(t reflect.Type, tag string) *schema {
+	s := &schema{Type: "object", Properties: make(map[string]*schema)}
+
+	for _, field := range fields(t) {
+		if _, ok := field.Tag.Lookup("param"); ok {
+			continue
+		}
+		name := fieldName(field, tag)
+		if name == "" {
+			continue
+		}
+
+		s.Properties[name] = doc.fieldSchema(field)
+		if hasRule(field, "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return s
+}
+
+// fields lists the exported fields of t with embedded structs flattened.
+func fields(t reflect.Type) []reflect.StructField {
+	list := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			list = append(list, fields(field.Type)...)
+			continue
+		}
+		if field.IsExported() {
+			list = append(list, field)
+		}
+	}
+
+	return list
+}
+
+func findField(t reflect.Type, tag string, name string) (reflect.StructField, bool) {
+	for _, field := range fields(t) {
+		if fieldName(field, tag) == name {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// fieldName is the name of field under tag, or empty when it is not bound
+// from there. JSON names default to the field name as encoding/json does.
+func fieldName(field reflect.StructField, tag string) string {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		if tag == "json" {
+			return field.Name
+		}
+		return ""
+	}
+
+	name, _, _ := strings.Cut(value, ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" && tag == "json" {
+		return field.Name
+	}
+
+	return name
+}
+
+// fieldSchema is the schema of field with its validation rules applied.
+func (doc *openAPIDocument) fieldSchema(field reflect.StructField) *schema {
+	s := doc.schemaOf(field.Type)
+	if s.Ref != "" {
+		return s
+	}
+
+	numeric := s.Type == "number" || s.Type == "integer"
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "oneof":
+			s.Enum = strings.Fields(value)
+		case "min", "gte":
+			setMinimum(s, numeric, value, false)
+		case "gt":
+			setMinimum(s, numeric, value, true)
+		case "max", "lte":
+			setMaximum(s, numeric, value, false)
+		case "lt":
+			setMaximum(s, numeric, value, true)
+		case "amount":
+			setMinimum(s, true, "0", true)
+			setMaximum(s, true, strconv.FormatFloat(accounting.MaxAmount, 'f', -1, 64), false)
+			s.Description = "At most " + strconv.Itoa(accounting.MaxAmountDecimals) + " decimal places"
+		case "signed_amount":
+			setMinimum(s, true, strconv.FormatFloat(-accounting.MaxAmount, 'f', -1, 64), false)
+			setMaximum(s, true, strconv.FormatFloat(accounting.MaxAmount, 'f', -1, 64), false)
+			s.Description = "Not zero, with at most " + strconv.Itoa(accounting.MaxAmountDecimals) + " decimal places"
+		case "signer":
+			s.Pattern = accounting.SignerPattern
+		}
+	}
+
+	return s
+}
+
+func setMinimum(s *schema, numeric bool, value string, exclusive bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	if !numeric {
+		n := int(f)
+		s.MinLength = &n
+		return
+	}
+	s.Minimum, s.ExclusiveMinimum = &f, exclusive
+}
+
+func setMaximum(s *schema, numeric bool, value string, exclusive bool) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	if !numeric {
+		n := int(f)
+		s.MaxLength = &n
+		return
+	}
+	s.Maximum, s.ExclusiveMaximum = &f, exclusive
+}
+
+func hasRule(field reflect.StructField, rule string) bool {
+	return contains(strings.Split(field.Tag.Get("validate"), ","), rule)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys(m map[string]*schema) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// handleOpenAPI serves the OpenAPI document.
+func handleOpenAPI(doc *openAPIDocument) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, doc)
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	// Development mode registers every route.
+	e := NewServer(orderbook.NewTradingPlatform(), Config{DevMode: true, AuditLog: io.Discard})
+	doc := newOpenAPI()
+
+	registered := make(map[string]bool)
+	for _, route := range e.Routes() {
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		path := openAPIPath(route.Path)
+		registered[route.Method+" "+path] = true
+
+		_, ok := doc.Paths[path][strings.ToLower(route.Method)]
+		assert.True(t, ok, "%s %s should be described in routeDocs", route.Method, route.Path)
+	}
+
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			assert.True(t, registered[strings.ToUpper(method)+" "+path], "%s %s is described but not registered", method, path)
+		}
+	}
+}
+
+func TestOpenAPIReferences(t *testing.T) {
+	data, err := json.Marshal(newOpenAPI())
+	require.NoError(t, err, "document should marshal")
+
+	doc := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	for _, name := range []string{"Orderbook", "Order", "Match", "Tx", "ErrorResponse"} {
+		assert.Contains(t, schemas, name, "response shape %s should be described", name)
+	}
+
+	var check func(v interface{})
+	check = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok && strings.HasPrefix(ref, "#/components/schemas/") {
+				assert.Contains(t, schemas, strings.TrimPrefix(ref, "#/components/schemas/"), "reference %s should resolve", ref)
+			}
+			for _, child := range v {
+				check(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				check(child)
+			}
+		}
+	}
+	check(doc)
+}
+
+func TestOpenAPIParams(t *testing.T) {
+	doc := newOpenAPI()
+
+	placeOrder := doc.Paths["/orders"]["post"]
+	body := placeOrder.RequestBody.Content["application/json"].Schema
+	assert.ElementsMatch(t, []string{"base", "quote", "side", "type", "price", "size"}, body.Required, "required tags should be required")
+	assert.Equal(t, []string{"bid", "ask"}, body.Properties["side"].Enum, "oneof should be an enum")
+	assert.True(t, body.Properties["size"].ExclusiveMinimum, "amounts should be positive")
+	assert.NotEmpty(t, placeOrder.Security, "order entry should be signed")
+
+	cancel := doc.Paths["/orders/{id}"]["delete"]
+	names := []string{}
+	for _, param := range cancel.Parameters {
+		names = append(names, param.In+":"+param.Name)
+	}
+	assert.ElementsMatch(t, []string{"path:id", "query:base", "query:quote"}, names, "cancel should take the id from the path and the market from the query")
+
+	transactions := doc.Paths["/accounts/{signer}/transactions"]["get"]
+	for _, param := range transactions.Parameters {
+		if param.Name == "limit" {
+			assert.Equal(t, 500.0, *param.Schema.Maximum, "max should be a maximum")
+		}
+		if param.Name == "signer" {
+			assert.NotEmpty(t, param.Schema.Pattern, "signers should have a pattern")
+		}
+	}
+
+	state := doc.Paths["/admin/markets/state"]["post"].RequestBody.Content["application/json"].Schema
+	assert.Equal(t, 256, *state.Properties["reason"].MaxLength, "max on a string should be a max length")
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	e := NewServer(orderbook.NewTradingPlatform(), Config{AuditLog: io.Discard})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	doc := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc), "document should be JSON")
+	assert.Equal(t, "3.0.3", doc["openapi"])
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/ratelimit"
+)
+
+// RateLimits are the limits of each class of request. Each is applied per
+// signer and per IP address.
+type RateLimits struct {
+	Orders  ratelimit.Policy
+	Cancels ratelimit.Policy
+	Reads   ratelimit.Policy
+}
+
+// rateLimit refuses requests once the signer or IP has used up its bucket. It
+// must run after authenticate so that the signer is known.
+func rateLimit(limiter *ratelimit.PolicyLimiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			signer := ""
+			if key, ok := c.Get(apiKeyContextKey).(*auth.Key); ok {
+				signer = key.Signer
+			}
+
+			if err := limiter.Allow(signer, c.RealIP()); err != nil {
+				return err
+			}
+
+			return next(c)
+		}
+	}
+}
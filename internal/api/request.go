@@ -4,10 +4,21 @@ import "github.com/richo225/octgopus/internal/orderbook"
 
 type PlaceOrderRequestParams struct {
 	MarketParams
-	Side  orderbook.Side      `json:"side" form:"side" query:"side" validate:"required"`
-	Type  orderbook.OrderType `json:"type" form:"type" query:"type" validate:"required"`
-	Price float64             `json:"price" form:"price" query:"price" validate:"required"`
-	Size  float64             `json:"size" form:"size" query:"size" validate:"required"`
+	OrderParams
+}
+
+type OrderParams struct {
+	Side  orderbook.Side      `json:"side" form:"side" query:"side" validate:"required,oneof=bid ask"`
+	Type  orderbook.OrderType `json:"type" form:"type" query:"type" validate:"required,oneof=limit market"`
+	Price float64             `json:"price" form:"price" query:"price" validate:"required,amount"`
+	Size  float64             `json:"size" form:"size" query:"size" validate:"required,amount"`
+	// SelfTradePrevention defaults to the platform mode when empty.
+	SelfTradePrevention orderbook.SelfTradePrevention `json:"self_trade_prevention" form:"self_trade_prevention" query:"self_trade_prevention" validate:"omitempty,oneof=none cancel_newest cancel_oldest cancel_both decrement_and_cancel"`
+}
+
+type CancelOrderRequestParams struct {
+	MarketParams
+	ID uint64 `param:"id" validate:"required"`
 }
 
 type MarketParams struct {
@@ -15,15 +26,102 @@ type MarketParams struct {
 	Base  string `json:"base" form:"base" query:"base" validate:"required"`
 }
 
+type ExportParams struct {
+	MarketParams
+	ExportOptions
+}
+
+type ExportOptions struct {
+	// Format defaults to csv and View to book.
+	Format orderbook.ExportFormat `query:"format" validate:"omitempty,oneof=csv json"`
+	View   orderbook.ExportView   `query:"view" validate:"omitempty,oneof=book orders trades"`
+}
+
+var defaultExportOptions = ExportOptions{Format: orderbook.FormatCSV, View: orderbook.ExportBook}
+
+// ImportParams accompany an order book CSV uploaded as the file field of a
+// multipart form.
+type ImportParams struct {
+	MarketParams
+	ImportOptions
+}
+
+type ImportOptions struct {
+	// CreateMarket creates the market when it does not exist.
+	CreateMarket bool `form:"create_market" query:"create_market"`
+}
+
+type MarketStateParams struct {
+	MarketParams
+	StateParams
+}
+
+type StateParams struct {
+	State  orderbook.TradingState `json:"state" form:"state" query:"state" validate:"required,oneof=open halted cancel_only auction"`
+	Reason string                 `json:"reason" form:"reason" query:"reason" validate:"max=256"`
+}
+
+// FeeScheduleParams sets the fees of a market. Clear removes them.
+type FeeScheduleParams struct {
+	MarketParams
+	FeeParams
+	Clear bool `json:"clear" form:"clear" query:"clear"`
+}
+
+type FeeParams struct {
+	MakerRate float64             `json:"maker_rate" form:"maker_rate" query:"maker_rate" validate:"gt=-1,lt=1"`
+	TakerRate float64             `json:"taker_rate" form:"taker_rate" query:"taker_rate" validate:"gte=0,lt=1"`
+	Tiers     []orderbook.FeeTier `json:"tiers"`
+}
+
 type AccountBalanceParams struct {
-	Signer string `json:"signer" form:"signer" query:"signer" validate:"required"`
+	Signer string `param:"signer" json:"signer" form:"signer" query:"signer" validate:"required,signer"`
+}
+
+type AccountTransactionsParams struct {
+	Signer string `param:"signer" validate:"required,signer"`
+	// Before is the id of the oldest entry of the previous page.
+	Before uint64 `query:"before"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=500"`
 }
 
 type AccountActionParams struct {
-	Signer string  `json:"signer" form:"signer" query:"signer" validate:"required"`
-	Amount float64 `json:"amount" form:"amount" query:"amount" validate:"required"`
+	Signer string  `param:"signer" json:"signer" form:"signer" query:"signer" validate:"required,signer"`
+	Amount float64 `json:"amount" form:"amount" query:"amount" validate:"required,amount"`
 }
 type AccountSendParams struct {
 	AccountActionParams
-	Recipient string `json:"recipient" form:"recipient" query:"recipient" validate:"required"`
+	Recipient string `json:"recipient" form:"recipient" query:"recipient" validate:"required,signer"`
+	// CreateRecipient opens an account for a recipient that does not have one.
+	CreateRecipient bool `json:"create_recipient" form:"create_recipient" query:"create_recipient"`
+}
+
+type AdjustBalanceParams struct {
+	Signer string `param:"signer" validate:"required,signer"`
+	// Amount is negative to reduce the balance.
+	Amount float64 `json:"amount" form:"amount" query:"amount" validate:"required,signed_amount"`
+	Reason string  `json:"reason" form:"reason" query:"reason" validate:"required,max=256"`
+}
+
+// The /v1 routes name the market in the path, so they bind the params above
+// without MarketParams.
+
+// PageParams page through a /v1 list. Cursor is the next_cursor of the
+// previous page.
+type PageParams struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=500"`
+	Cursor string `query:"cursor"`
+}
+
+type OrderIDParams struct {
+	ID uint64 `param:"id" validate:"required"`
+}
+
+type CreateAccountParams struct {
+	Signer string `json:"signer" form:"signer" query:"signer" validate:"required,signer"`
+}
+
+type AccountEntriesParams struct {
+	Signer string `param:"signer" validate:"required,signer"`
+	PageParams
 }
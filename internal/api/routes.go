@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/labstack/echo/v4"
 	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/internal/ratelimit"
 )
 
 type CustomContext struct {
@@ -10,7 +11,7 @@ type CustomContext struct {
 	platform *orderbook.TradingPlatform
 }
 
-func registerHandlers(e *echo.Echo, p *orderbook.TradingPlatform) {
+func registerHandlers(e *echo.Echo, p *orderbook.TradingPlatform, config Config) {
 	withPlatform := func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			cc := &CustomContext{c, p}
@@ -18,23 +19,106 @@ func registerHandlers(e *echo.Echo, p *orderbook.TradingPlatform) {
 		}
 	}
 
+	limitOrders := rateLimit(ratelimit.NewPolicyLimiter(config.RateLimits.Orders, config.Clock))
+	limitCancels := rateLimit(ratelimit.NewPolicyLimiter(config.RateLimits.Cancels, config.Clock))
+	limitReads := rateLimit(ratelimit.NewPolicyLimiter(config.RateLimits.Reads, config.Clock))
+
+	// Denied admin requests are audited as well as successful ones.
+	adminOnly := []echo.MiddlewareFunc{authenticate(config.Verifier),
+		auditLog(config.auditLog()), requireAdmin(config.Verifier != nil, config.DevMode)}
+
 	e.GET("/", CheckHealth)
+	e.GET("/openapi.json", handleOpenAPI(newOpenAPI()))
 
-	orderbooks := e.Group("/orderbooks", withPlatform)
-	orderbooks.GET("", withCustomContext((*CustomContext).handleGetOrderbook))
-	orderbooks.GET("/reset", withCustomContext((*CustomContext).handleResetOrderbooks))
-	orderbooks.POST("", withCustomContext((*CustomContext).handleCreateOrderbook))
+	registerV1Handlers(e.Group(v1Prefix, withPlatform), config, routeLimits{limitOrders, limitCancels, limitReads}, adminOnly)
 
-	orders := e.Group("/orders", withPlatform)
-	orders.POST("", withCustomContext((*CustomContext).handleCreateOrder))
+	// The routes before /v1, kept for existing clients.
+	orderbooks := e.Group("/orderbooks", withPlatform, deprecated)
+	orderbooks.GET("", withCustomContext((*CustomContext).handleGetOrderbook), limitReads)
+	orderbooks.GET("/markets", withCustomContext((*CustomContext).handleGetMarkets), limitReads)
+	orderbooks.GET("/export", withCustomContext((*CustomContext).handleExportOrderbook), limitReads)
+	// Unauthenticated shortcuts for local development, replaced by /admin.
+	if config.DevMode {
+		orderbooks.GET("/reset", withCustomContext((*CustomContext).handleResetOrderbooks))
+		orderbooks.POST("", withCustomContext((*CustomContext).handleCreateOrderbook))
+	}
+	orderbooks.POST("/import", withCustomContext((*CustomContext).handleAdminImportOrderbook), adminOnly...)
 
-	accounts := e.Group("/accounts", withPlatform)
-	accounts.GET("", withCustomContext((*CustomContext).handleGetAccounts))
-	accounts.GET("/:signer", withCustomContext((*CustomContext).handleGetAccountBalance))
+	orders := e.Group("/orders", withPlatform, deprecated, authenticate(config.Verifier))
+	orders.POST("", withCustomContext((*CustomContext).handleCreateOrder), limitOrders)
+	orders.DELETE("/:id", withCustomContext((*CustomContext).handleCancelOrder), limitCancels)
+
+	accounts := e.Group("/accounts", withPlatform, deprecated, authenticate(config.Verifier))
+	if config.DevMode {
+		accounts.GET("", withCustomContext((*CustomContext).handleGetAccounts))
+	}
+	accounts.GET("/:signer", withCustomContext((*CustomContext).handleGetAccountBalance), limitReads)
+	accounts.GET("/:signer/transactions", withCustomContext((*CustomContext).handleGetAccountTransactions), limitReads)
 	accounts.POST("/:signer", withCustomContext((*CustomContext).handleCreateAccount))
 	accounts.POST("/:signer/deposit", withCustomContext((*CustomContext).handleAccountDeposit))
 	accounts.POST("/:signer/withdraw", withCustomContext((*CustomContext).handleAccountWithdraw))
 	accounts.POST("/:signer/send", withCustomContext((*CustomContext).handleAccountSend))
+
+	admin := e.Group("/admin", append([]echo.MiddlewareFunc{withPlatform, deprecated}, adminOnly...)...)
+	admin.POST("/reset", withCustomContext((*CustomContext).handleAdminReset))
+	admin.POST("/markets", withCustomContext((*CustomContext).handleCreateOrderbook))
+	admin.DELETE("/markets", withCustomContext((*CustomContext).handleAdminRemoveMarket))
+	admin.POST("/markets/halt", withCustomContext((*CustomContext).handleAdminHaltMarket))
+	admin.POST("/markets/resume", withCustomContext((*CustomContext).handleAdminResumeMarket))
+	admin.POST("/markets/state", withCustomContext((*CustomContext).handleAdminSetMarketState))
+	admin.POST("/markets/fees", withCustomContext((*CustomContext).handleAdminSetFees))
+	admin.GET("/accounts", withCustomContext((*CustomContext).handleGetAccounts))
+	admin.POST("/accounts/:signer/adjust", withCustomContext((*CustomContext).handleAdminAdjustBalance))
+}
+
+// routeLimits are the rate limits shared by the /v1 routes and the routes
+// before them.
+type routeLimits struct {
+	orders  echo.MiddlewareFunc
+	cancels echo.MiddlewareFunc
+	reads   echo.MiddlewareFunc
+}
+
+func registerV1Handlers(v1 *echo.Group, config Config, limits routeLimits, adminOnly []echo.MiddlewareFunc) {
+	signed := authenticate(config.Verifier)
+
+	v1.GET("/health", CheckHealthV1)
+
+	v1.GET("/markets", withCustomContext((*CustomContext).handleListMarketsV1), limits.reads)
+	v1.POST("/markets", withCustomContext((*CustomContext).handleCreateMarketV1), adminOnly...)
+	v1.GET("/markets/:market", withCustomContext((*CustomContext).handleGetMarketV1), limits.reads)
+	v1.DELETE("/markets/:market", withCustomContext((*CustomContext).handleRemoveMarketV1), adminOnly...)
+	v1.GET("/markets/:market/book", withCustomContext((*CustomContext).handleGetBookV1), limits.reads)
+	v1.GET("/markets/:market/export", withCustomContext((*CustomContext).handleExportMarketV1), limits.reads)
+	v1.POST("/markets/:market/import", withCustomContext((*CustomContext).handleImportMarketV1), adminOnly...)
+	v1.PUT("/markets/:market/state", withCustomContext((*CustomContext).handleSetMarketStateV1), adminOnly...)
+	v1.PUT("/markets/:market/fees", withCustomContext((*CustomContext).handleSetFeesV1), adminOnly...)
+	v1.DELETE("/markets/:market/fees", withCustomContext((*CustomContext).handleClearFeesV1), adminOnly...)
+
+	v1.POST("/markets/:market/orders", withCustomContext((*CustomContext).handlePlaceOrderV1), signed, limits.orders)
+	v1.GET("/markets/:market/orders/:id", withCustomContext((*CustomContext).handleGetOrderV1), signed, limits.reads)
+	v1.DELETE("/markets/:market/orders/:id", withCustomContext((*CustomContext).handleCancelOrderV1), signed, limits.cancels)
+
+	v1.POST("/accounts", withCustomContext((*CustomContext).handleCreateAccountV1), signed)
+	v1.GET("/accounts/:signer", withCustomContext((*CustomContext).handleGetAccountV1), signed, limits.reads)
+	v1.GET("/accounts/:signer/transactions", withCustomContext((*CustomContext).handleListTransactionsV1), signed, limits.reads)
+	v1.POST("/accounts/:signer/deposits", withCustomContext((*CustomContext).handleDepositV1), signed)
+	v1.POST("/accounts/:signer/withdrawals", withCustomContext((*CustomContext).handleWithdrawV1), signed)
+	v1.POST("/accounts/:signer/transfers", withCustomContext((*CustomContext).handleTransferV1), signed)
+
+	v1.POST("/admin/reset", withCustomContext((*CustomContext).handleResetV1), adminOnly...)
+	v1.GET("/admin/accounts", withCustomContext((*CustomContext).handleListAccountsV1), adminOnly...)
+	v1.POST("/admin/accounts/:signer/adjustments", withCustomContext((*CustomContext).handleAdjustBalanceV1), adminOnly...)
+}
+
+// deprecated marks the responses of the routes replaced by /v1, linking to
+// the document that describes their replacements.
+func deprecated(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Deprecation", "true")
+		c.Response().Header().Set("Link", `</openapi.json>; rel="deprecation"`)
+		return next(c)
+	}
 }
 
 func withCustomContext(handler func(c *CustomContext) error) echo.HandlerFunc {
@@ -2,16 +2,45 @@ package api
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/kr/pretty"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/richo225/octgopus/internal/auth"
 	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/internal/ratelimit"
 )
 
-func Start(p *orderbook.TradingPlatform) {
+type Config struct {
+	// Verifier authenticates requests to the orders, accounts and admin
+	// routes. Authentication is disabled when it is nil.
+	Verifier *auth.Verifier
+	// DevMode opens the admin routes when authentication is disabled and
+	// keeps the unauthenticated reset and market creation routes.
+	DevMode bool
+	// AuditLog receives a JSON line for every admin request, defaulting to
+	// stdout.
+	AuditLog io.Writer
+	// RateLimits throttles order entry and reads. Zero limits are unlimited.
+	RateLimits RateLimits
+	// Clock drives the rate limits, defaulting to the system clock.
+	Clock ratelimit.Clock
+}
+
+func (config Config) auditLog() io.Writer {
+	if config.AuditLog == nil {
+		return os.Stdout
+	}
+
+	return config.AuditLog
+}
+
+// NewServer returns the API for p with its middleware and routes, ready to
+// be started or served by an httptest.Server.
+func NewServer(p *orderbook.TradingPlatform, config Config) *echo.Echo {
 	e := echo.New()
 
 	e.Use(middleware.RequestID())
@@ -27,7 +56,13 @@ func Start(p *orderbook.TradingPlatform) {
 	e.Validator = NewValidator()
 	e.HTTPErrorHandler = HTTPErrorHandler
 
-	registerHandlers(e, p)
+	registerHandlers(e, p, config)
+
+	return e
+}
+
+func Start(p *orderbook.TradingPlatform, config Config) {
+	e := NewServer(p, config)
 
 	pretty.Log("Starting server...")
 
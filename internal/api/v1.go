@@ -0,0 +1,545 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/accounting"
+	"github.com/richo225/octgopus/internal/orderbook"
+)
+
+// The /v1 API wraps every JSON response in an Envelope, responds 201 to
+// requests that create something and names markets in the path as
+// BASE-QUOTE, e.g. /v1/markets/BTC-USD/book.
+
+const v1Prefix = "/v1"
+
+// Envelope is the body of every successful /v1 JSON response.
+type Envelope struct {
+	Data interface{} `json:"data"`
+	// Meta is only present on lists.
+	Meta *PageMeta `json:"meta,omitempty"`
+}
+
+// PageMeta describes a page of a list. NextCursor is passed as cursor to
+// fetch the following page, and is omitted on the last page.
+type PageMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// V1ErrorResponse is the body of every failed /v1 request.
+type V1ErrorResponse struct {
+	Error V1Error `json:"error"`
+}
+
+type V1Error struct {
+	Code int `json:"code"`
+	// Reason is a stable name for the error, e.g. MarketNotfound.
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+const defaultPageLimit = 50
+
+// OrderResult is a placed order and the matches it made.
+type OrderResult struct {
+	Order   orderbook.Order   `json:"order"`
+	Matches []orderbook.Match `json:"matches"`
+}
+
+type AccountBalance struct {
+	Signer  string  `json:"signer"`
+	Balance float64 `json:"balance"`
+}
+
+type InvalidMarketError struct {
+	market string
+}
+
+func (e *InvalidMarketError) Error() string {
+	return "InvalidMarket : " + e.market + " is not BASE-QUOTE"
+}
+
+func (e *InvalidMarketError) HTTPCode() int {
+	return http.StatusBadRequest
+}
+
+type InvalidCursorError struct {
+	cursor string
+}
+
+func (e *InvalidCursorError) Error() string {
+	return "InvalidCursor : " + e.cursor
+}
+
+func (e *InvalidCursorError) HTTPCode() int {
+	return http.StatusBadRequest
+}
+
+// marketPattern is the form of the market path param.
+const marketPattern = `^[^-/]+-[^-/]+$`
+
+func parseMarket(market string) (orderbook.TradingPair, error) {
+	base, quote, ok := strings.Cut(market, "-")
+	if !ok || base == "" || quote == "" || strings.Contains(quote, "-") {
+		return orderbook.TradingPair{}, &InvalidMarketError{market}
+	}
+
+	return orderbook.NewTradingPair(base, quote), nil
+}
+
+func marketName(pair orderbook.TradingPair) string {
+	return pair.Base + "-" + pair.Quote
+}
+
+// market is the market named in the path.
+func (c *CustomContext) market() (orderbook.TradingPair, error) {
+	return parseMarket(c.Param("market"))
+}
+
+// bind binds and validates the params of a request.
+func (c *CustomContext) bind(params interface{}) error {
+	c.Bind(params)
+	return c.Validate(params)
+}
+
+func (c *CustomContext) data(code int, data interface{}) error {
+	return c.JSON(code, &Envelope{Data: data})
+}
+
+func (c *CustomContext) page(data interface{}, meta PageMeta) error {
+	return c.JSON(http.StatusOK, &Envelope{Data: data, Meta: &meta})
+}
+
+func (page PageParams) limit() int {
+	if page.Limit == 0 {
+		return defaultPageLimit
+	}
+
+	return page.Limit
+}
+
+func CheckHealthV1(c echo.Context) error {
+	return c.JSON(http.StatusOK, &Envelope{Data: map[string]string{"status": "up"}})
+}
+
+// Markets
+func (c *CustomContext) handleListMarketsV1() error {
+	params := PageParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	after := ""
+	if params.Cursor != "" {
+		pair, err := parseMarket(params.Cursor)
+		if err != nil {
+			return &InvalidCursorError{params.Cursor}
+		}
+		after = pair.ToString()
+	}
+
+	// Markets are sorted by name, so the page starts after the cursor.
+	markets := c.platform.Markets()
+	start := sort.Search(len(markets), func(i int) bool {
+		return markets[i].Market.ToString() > after
+	})
+	markets = markets[start:]
+
+	meta := PageMeta{Limit: params.limit()}
+	if len(markets) > meta.Limit {
+		markets = markets[:meta.Limit]
+		meta.NextCursor = marketName(markets[len(markets)-1].Market)
+	}
+
+	return c.page(markets, meta)
+}
+
+func (c *CustomContext) handleCreateMarketV1() error {
+	params := MarketParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	pair := orderbook.NewTradingPair(params.Base, params.Quote)
+	if _, err := c.platform.AddNewMarket(pair); err != nil {
+		return err
+	}
+	book, err := c.platform.OrderBookCopy(pair)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, v1Prefix+"/markets/"+url.PathEscape(marketName(pair)))
+	return c.data(http.StatusCreated, book)
+}
+
+func (c *CustomContext) handleGetMarketV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+
+	summary, err := c.platform.Market(pair)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusOK, summary)
+}
+
+func (c *CustomContext) handleRemoveMarketV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := c.platform.RemoveMarket(pair)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusOK, cancelled)
+}
+
+func (c *CustomContext) handleGetBookV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+
+	book, err := c.platform.OrderBookCopy(pair)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusOK, book)
+}
+
+func (c *CustomContext) handleExportMarketV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+	params := defaultExportOptions
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	return c.export(pair, params.View, params.Format)
+}
+
+func (c *CustomContext) handleImportMarketV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+	params := ImportOptions{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	result, err := c.importOrders(pair, params.CreateMarket)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusCreated, result)
+}
+
+func (c *CustomContext) handleSetMarketStateV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+	params := StateParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	if err := c.platform.SetMarketState(pair, params.State, params.Reason); err != nil {
+		return err
+	}
+
+	return c.handleGetMarketV1()
+}
+
+func (c *CustomContext) handleSetFeesV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+	params := FeeParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	schedule := &orderbook.FeeSchedule{MakerRate: params.MakerRate, TakerRate: params.TakerRate, Tiers: params.Tiers}
+	if err := c.platform.SetFeeSchedule(pair, schedule); err != nil {
+		return err
+	}
+
+	return c.data(http.StatusOK, schedule)
+}
+
+func (c *CustomContext) handleClearFeesV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+
+	if err := c.platform.SetFeeSchedule(pair, nil); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Orders
+func (c *CustomContext) handlePlaceOrderV1() error {
+	pair, err := c.market()
+	if err != nil {
+		return err
+	}
+	params := OrderParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	order := orderbook.NewOrder(params.Side, params.Size)
+	order.Signer = c.signer()
+	order.SelfTradePrevention = params.SelfTradePrevention
+
+	placed, matches, err := c.platform.PlaceOrder(pair, params.Type, params.Price, order)
+	if err != nil {
+		return err
+	}
+	if params.Type == orderbook.MarketOrder {
+		return c.data(http.StatusCreated, &OrderResult{Order: placed, Matches: matches})
+	}
+
+	// Only limit orders rest on the book to be fetched again.
+	c.Response().Header().Set(echo.HeaderLocation, v1Prefix+"/markets/"+url.PathEscape(marketName(pair))+"/orders/"+strconv.FormatUint(placed.ID, 10))
+	return c.data(http.StatusCreated, &OrderResult{Order: placed, Matches: []orderbook.Match{}})
+}
+
+// restingOrder is the order named in the path, if the signer may act for it.
+func (c *CustomContext) restingOrder() (orderbook.TradingPair, orderbook.Order, error) {
+	pair, err := c.market()
+	if err != nil {
+		return pair, orderbook.Order{}, err
+	}
+	params := OrderIDParams{}
+	if err := c.bind(&params); err != nil {
+		return pair, orderbook.Order{}, err
+	}
+
+	order, err := c.platform.GetOrder(pair, params.ID)
+	if err != nil {
+		return pair, order, err
+	}
+
+	return pair, order, c.authorize(order.Signer)
+}
+
+func (c *CustomContext) handleGetOrderV1() error {
+	_, order, err := c.restingOrder()
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusOK, &order)
+}
+
+func (c *CustomContext) handleCancelOrderV1() error {
+	pair, resting, err := c.restingOrder()
+	if err != nil {
+		return err
+	}
+
+	order, err := c.platform.CancelOrder(pair, resting.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusOK, order)
+}
+
+// Accounting
+func (c *CustomContext) handleCreateAccountV1() error {
+	params := CreateAccountParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+
+	if err := c.platform.CreateAccount(params.Signer); err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, v1Prefix+"/accounts/"+url.PathEscape(params.Signer))
+	return c.data(http.StatusCreated, &AccountBalance{Signer: params.Signer})
+}
+
+func (c *CustomContext) handleGetAccountV1() error {
+	params := AccountBalanceParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+
+	balance, err := c.platform.Accounts.BalanceOf(params.Signer)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusOK, &AccountBalance{Signer: params.Signer, Balance: balance})
+}
+
+func (c *CustomContext) handleListTransactionsV1() error {
+	params := AccountEntriesParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+
+	var before uint64
+	if params.Cursor != "" {
+		var err error
+		if before, err = strconv.ParseUint(params.Cursor, 10, 64); err != nil {
+			return &InvalidCursorError{params.Cursor}
+		}
+	}
+
+	// One more entry than the page is fetched to tell whether there are more.
+	meta := PageMeta{Limit: params.limit()}
+	entries, err := c.platform.Transactions(params.Signer, before, meta.Limit+1)
+	if err != nil {
+		return err
+	}
+	if len(entries) > meta.Limit {
+		entries = entries[:meta.Limit]
+		meta.NextCursor = strconv.FormatUint(entries[len(entries)-1].ID, 10)
+	}
+
+	return c.page(entries, meta)
+}
+
+func (c *CustomContext) handleDepositV1() error {
+	params := AccountActionParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+
+	tx, err := c.platform.Deposit(params.Signer, params.Amount)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusCreated, tx)
+}
+
+func (c *CustomContext) handleWithdrawV1() error {
+	params := AccountActionParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+
+	tx, err := c.platform.Withdraw(params.Signer, params.Amount)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusCreated, tx)
+}
+
+func (c *CustomContext) handleTransferV1() error {
+	params := AccountSendParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+	if err := c.authorize(params.Signer); err != nil {
+		return err
+	}
+
+	opts := accounting.SendOptions{
+		CreateRecipient: params.CreateRecipient,
+		IdempotencyKey:  c.Request().Header.Get(idempotencyKeyHeader),
+	}
+
+	txs, err := c.platform.SendWithOptions(params.Signer, params.Recipient, params.Amount, opts)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusCreated, txs)
+}
+
+// Admin
+func (c *CustomContext) handleResetV1() error {
+	if err := c.platform.Reset(); err != nil {
+		return err
+	}
+
+	return c.data(http.StatusOK, c.platform.Markets())
+}
+
+func (c *CustomContext) handleListAccountsV1() error {
+	params := PageParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	balances := c.platform.Accounts.Balances()
+	signers := make([]string, 0, len(balances))
+	for signer := range balances {
+		if signer > params.Cursor {
+			signers = append(signers, signer)
+		}
+	}
+	sort.Strings(signers)
+
+	meta := PageMeta{Limit: params.limit()}
+	if len(signers) > meta.Limit {
+		signers = signers[:meta.Limit]
+		meta.NextCursor = signers[len(signers)-1]
+	}
+
+	accounts := make([]AccountBalance, len(signers))
+	for i, signer := range signers {
+		accounts[i] = AccountBalance{Signer: signer, Balance: balances[signer]}
+	}
+
+	return c.page(accounts, meta)
+}
+
+func (c *CustomContext) handleAdjustBalanceV1() error {
+	params := AdjustBalanceParams{}
+	if err := c.bind(&params); err != nil {
+		return err
+	}
+
+	tx, err := c.platform.AdjustBalance(params.Signer, params.Amount, params.Reason)
+	if err != nil {
+		return err
+	}
+
+	return c.data(http.StatusCreated, tx)
+}
@@ -0,0 +1,399 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/accounting"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	aliceKey = &auth.Key{ID: "alice-key", Secret: "alice-secret", Signer: "alice"}
+	bobKey   = &auth.Key{ID: "bob-key", Secret: "bob-secret", Signer: "bob"}
+	adminKey = &auth.Key{ID: "admin-key", Secret: "admin-secret", Signer: "admin", Roles: []string{auth.AdminRole}}
+)
+
+// v1Server serves the API with keys for alice, bob and an admin, and BTC/USD
+// configured to open empty.
+type v1Server struct {
+	t        *testing.T
+	e        *echo.Echo
+	platform *orderbook.TradingPlatform
+	nonces   int64
+}
+
+func newV1Server(t *testing.T) *v1Server {
+	return newV1ServerWith(t, Config{})
+}
+
+// newV1ServerWith serves the API with config, adding the keys and audit log.
+func newV1ServerWith(t *testing.T, config Config) *v1Server {
+	platform := orderbook.NewTradingPlatform()
+	require.NoError(t, platform.SetMarkets([]orderbook.MarketSpec{{Market: orderbook.NewTradingPair("BTC", "USD")}}))
+	require.NoError(t, platform.OpenMarkets())
+
+	config.Verifier = auth.NewVerifier(auth.NewKeyStore(aliceKey, bobKey, adminKey))
+	config.AuditLog = io.Discard
+	return &v1Server{t: t, e: NewServer(platform, config), platform: platform}
+}
+
+// do makes a request signed with key, or unsigned when key is nil, and
+// decodes the data of its envelope into out.
+func (s *v1Server) do(key *auth.Key, method string, path string, body interface{}, out interface{}) (*httptest.ResponseRecorder, *Envelope) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(s.t, err)
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	if key != nil {
+		nonce := fmt.Sprint("nonce-", atomic.AddInt64(&s.nonces, 1))
+		require.NoError(s.t, auth.SignRequest(req, key, time.Now(), nonce))
+	}
+
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	if rec.Code >= http.StatusBadRequest || rec.Code == http.StatusNoContent || !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return rec, nil
+	}
+
+	envelope := &Envelope{Data: out}
+	require.NoError(s.t, json.Unmarshal(rec.Body.Bytes(), envelope), "response should be an envelope")
+	return rec, envelope
+}
+
+// upload posts a CSV file as the file field of a form signed with key.
+func (s *v1Server) upload(key *auth.Key, path string, csv string, nonce string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	file, err := form.CreateFormFile("file", "orders.csv")
+	require.NoError(s.t, err)
+	io.WriteString(file, csv)
+	require.NoError(s.t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+	require.NoError(s.t, auth.SignRequest(req, key, time.Now(), nonce))
+
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func (s *v1Server) error(rec *httptest.ResponseRecorder) V1Error {
+	response := V1ErrorResponse{}
+	require.NoError(s.t, json.Unmarshal(rec.Body.Bytes(), &response), "error should be an envelope")
+	assert.Equal(s.t, rec.Code, response.Error.Code)
+
+	return response.Error
+}
+
+func TestV1Trading(t *testing.T) {
+	s := newV1Server(t)
+
+	rec, _ := s.do(nil, http.MethodGet, "/v1/health", nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data": {"status": "up"}}`, rec.Body.String())
+
+	for _, key := range []*auth.Key{aliceKey, bobKey} {
+		account := AccountBalance{}
+		rec, _ = s.do(key, http.MethodPost, "/v1/accounts", map[string]string{"signer": key.Signer}, &account)
+		require.Equal(t, http.StatusCreated, rec.Code, "account should be created")
+		assert.Equal(t, AccountBalance{Signer: key.Signer}, account)
+		assert.Equal(t, "/v1/accounts/"+key.Signer, rec.Header().Get(echo.HeaderLocation))
+
+		tx := accounting.Tx{}
+		rec, _ = s.do(key, http.MethodPost, "/v1/accounts/"+key.Signer+"/deposits", map[string]float64{"amount": 1000}, &tx)
+		require.Equal(t, http.StatusCreated, rec.Code, "deposit should be created")
+		assert.Equal(t, accounting.Deposit, tx.Action)
+	}
+
+	placed := OrderResult{}
+	rec, _ = s.do(aliceKey, http.MethodPost, "/v1/markets/BTC-USD/orders", map[string]interface{}{"side": "ask", "type": "limit", "price": 100, "size": 2}, &placed)
+	require.Equal(t, http.StatusCreated, rec.Code, "limit order should be placed")
+	assert.Equal(t, "alice", placed.Order.Signer)
+	assert.Empty(t, placed.Matches, "a resting order should not match")
+	location := rec.Header().Get(echo.HeaderLocation)
+	assert.Equal(t, fmt.Sprint("/v1/markets/BTC-USD/orders/", placed.Order.ID), location)
+
+	filled := OrderResult{}
+	rec, _ = s.do(bobKey, http.MethodPost, "/v1/markets/BTC-USD/orders", map[string]interface{}{"side": "bid", "type": "market", "price": 100, "size": 0.5}, &filled)
+	require.Equal(t, http.StatusCreated, rec.Code, "market order should be placed")
+	require.Len(t, filled.Matches, 1, "market order should match the ask")
+	assert.Equal(t, placed.Order.ID, filled.Matches[0].Ask.ID)
+
+	summary := orderbook.MarketSummary{}
+	rec, _ = s.do(nil, http.MethodGet, "/v1/markets/BTC-USD", nil, &summary)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, orderbook.MarketSummary{Market: orderbook.NewTradingPair("BTC", "USD"), State: orderbook.StateOpen, BestAsk: 100, LastTradePrice: 100}, summary)
+
+	rec, _ = s.do(nil, http.MethodGet, "/v1/markets/BTC-USD/book", nil, &orderbook.Orderbook{})
+	assert.Equal(t, http.StatusOK, rec.Code, "book should be returned")
+
+	rec, _ = s.do(bobKey, http.MethodGet, location, nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code, "another signer's order should be forbidden")
+	assert.Equal(t, "Forbidden", s.error(rec).Reason)
+
+	order := orderbook.Order{}
+	rec, _ = s.do(aliceKey, http.MethodGet, location, nil, &order)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1.5, order.Size, "resting order should be partly filled")
+
+	rec, _ = s.do(aliceKey, http.MethodDelete, location, nil, &order)
+	require.Equal(t, http.StatusOK, rec.Code, "order should be cancelled")
+
+	rec, _ = s.do(aliceKey, http.MethodGet, location, nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "cancelled order should be gone")
+
+	account := AccountBalance{}
+	rec, _ = s.do(bobKey, http.MethodGet, "/v1/accounts/bob", nil, &account)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "bob", account.Signer)
+	assert.Equal(t, 1000.0, account.Balance)
+
+	txs := []accounting.Tx{}
+	rec, _ = s.do(bobKey, http.MethodPost, "/v1/accounts/bob/transfers", map[string]interface{}{"recipient": "alice", "amount": 10}, &txs)
+	require.Equal(t, http.StatusCreated, rec.Code, "transfer should be created")
+	assert.Len(t, txs, 2, "transfer should be a withdraw and a deposit")
+}
+
+func TestV1OrdersWhileTrading(t *testing.T) {
+	s := newV1Server(t)
+	for _, key := range []*auth.Key{aliceKey, bobKey} {
+		s.do(key, http.MethodPost, "/v1/accounts", map[string]string{"signer": key.Signer}, nil)
+		s.do(key, http.MethodPost, "/v1/accounts/"+key.Signer+"/deposits", map[string]float64{"amount": 100000}, nil)
+	}
+
+	// Bob's market orders fill alice's asks while her orders, and his
+	// matches, are being encoded.
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				rec, _ := s.do(aliceKey, http.MethodPost, "/v1/markets/BTC-USD/orders", map[string]interface{}{"side": "ask", "type": "limit", "price": 100, "size": 2}, nil)
+				assert.Equal(t, http.StatusCreated, rec.Code, "limit order should be placed")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				s.do(bobKey, http.MethodPost, "/v1/markets/BTC-USD/orders", map[string]interface{}{"side": "bid", "type": "market", "price": 100, "size": 1}, nil)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestV1Pagination(t *testing.T) {
+	s := newV1Server(t)
+
+	s.do(aliceKey, http.MethodPost, "/v1/accounts", map[string]string{"signer": "alice"}, nil)
+	for i := 1; i <= 5; i++ {
+		rec, _ := s.do(aliceKey, http.MethodPost, "/v1/accounts/alice/deposits", map[string]float64{"amount": float64(i)}, nil)
+		require.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	amounts := []float64{}
+	path := "/v1/accounts/alice/transactions?limit=2"
+	for pages := 0; pages < 5; pages++ {
+		entries := []accounting.Entry{}
+		rec, envelope := s.do(aliceKey, http.MethodGet, path, nil, &entries)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, envelope.Meta, "lists should have a meta")
+		assert.Equal(t, 2, envelope.Meta.Limit)
+
+		for _, entry := range entries {
+			amounts = append(amounts, entry.Credit)
+		}
+		if envelope.Meta.NextCursor == "" {
+			break
+		}
+		path = "/v1/accounts/alice/transactions?limit=2&cursor=" + envelope.Meta.NextCursor
+	}
+	assert.Equal(t, []float64{5, 4, 3, 2, 1}, amounts, "pages should hold every entry, newest first")
+
+	for _, market := range []map[string]string{{"base": "ETH", "quote": "USD"}, {"base": "ADA", "quote": "USD"}} {
+		rec, _ := s.do(adminKey, http.MethodPost, "/v1/markets", market, nil)
+		require.Equal(t, http.StatusCreated, rec.Code, "market should be created")
+	}
+
+	markets := []orderbook.MarketSummary{}
+	rec, envelope := s.do(nil, http.MethodGet, "/v1/markets?limit=2", nil, &markets)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, markets, 2)
+	assert.Equal(t, "ADA", markets[0].Market.Base, "markets should be in name order")
+	assert.Equal(t, "BTC-USD", envelope.Meta.NextCursor)
+
+	rec, envelope = s.do(nil, http.MethodGet, "/v1/markets?limit=2&cursor=BTC-USD", nil, &markets)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, markets, 1)
+	assert.Equal(t, "ETH", markets[0].Market.Base)
+	assert.Empty(t, envelope.Meta.NextCursor, "the last page should have no cursor")
+
+	rec, _ = s.do(nil, http.MethodGet, "/v1/markets?limit=501", nil, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "limit should be at most 500")
+
+	rec, _ = s.do(aliceKey, http.MethodGet, "/v1/accounts/alice/transactions?cursor=newest", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "InvalidCursor", s.error(rec).Reason)
+}
+
+func TestV1Admin(t *testing.T) {
+	s := newV1Server(t)
+
+	rec, _ := s.do(aliceKey, http.MethodPost, "/v1/markets", map[string]string{"base": "ETH", "quote": "USD"}, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code, "only admins should create markets")
+
+	summary := orderbook.MarketSummary{}
+	rec, _ = s.do(adminKey, http.MethodPut, "/v1/markets/BTC-USD/state", map[string]string{"state": "halted", "reason": "maintenance"}, &summary)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, orderbook.StateHalted, summary.State, "state should be set")
+
+	s.do(aliceKey, http.MethodPost, "/v1/accounts", map[string]string{"signer": "alice"}, nil)
+	rec, _ = s.do(aliceKey, http.MethodPost, "/v1/markets/BTC-USD/orders", map[string]interface{}{"side": "ask", "type": "limit", "price": 100, "size": 1}, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code, "a halted market should refuse orders")
+	assert.Equal(t, "MarketHalted", s.error(rec).Reason)
+
+	fees := orderbook.FeeSchedule{}
+	rec, _ = s.do(adminKey, http.MethodPut, "/v1/markets/BTC-USD/fees", map[string]float64{"maker_rate": 0.001, "taker_rate": 0.002}, &fees)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 0.002, fees.TakerRate)
+
+	rec, _ = s.do(adminKey, http.MethodDelete, "/v1/markets/BTC-USD/fees", nil, nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code, "fees should be cleared")
+
+	tx := accounting.Tx{}
+	rec, _ = s.do(adminKey, http.MethodPost, "/v1/admin/accounts/alice/adjustments", map[string]interface{}{"amount": 25, "reason": "goodwill"}, &tx)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, accounting.Adjustment, tx.Action)
+
+	accounts := []AccountBalance{}
+	rec, envelope := s.do(adminKey, http.MethodGet, "/v1/admin/accounts", nil, &accounts)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []AccountBalance{{Signer: "alice", Balance: 25}}, accounts)
+	assert.Equal(t, defaultPageLimit, envelope.Meta.Limit)
+
+	markets := []orderbook.MarketSummary{}
+	rec, _ = s.do(adminKey, http.MethodPost, "/v1/admin/reset", nil, &markets)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, markets, 1, "reset should open the configured markets")
+	assert.Equal(t, orderbook.StateOpen, markets[0].State)
+}
+
+func TestV1ImportWhileAddingMarkets(t *testing.T) {
+	s := newV1Server(t)
+
+	wg := sync.WaitGroup{}
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			rec := s.upload(adminKey, "/v1/markets/ETH-USD/import?create_market=true", "side,price,size\nask,100,1\n", fmt.Sprint("import-", i))
+			codes[i] = rec.Code
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			s.platform.AddNewMarket(orderbook.NewTradingPair(fmt.Sprint("COIN", i), "USD"))
+		}(i)
+	}
+	wg.Wait()
+
+	for _, code := range codes {
+		assert.Equal(t, http.StatusCreated, code, "import should create the market or use the existing one")
+	}
+	book, err := s.platform.OrderBookCopy(orderbook.NewTradingPair("ETH", "USD"))
+	require.NoError(t, err)
+	assert.Len(t, book.Asks, 1, "every import should rest its ask at the same level")
+}
+
+func TestV1Errors(t *testing.T) {
+	s := newV1Server(t)
+
+	rec, _ := s.do(nil, http.MethodGet, "/v1/markets/BTCUSD", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "InvalidMarket", s.error(rec).Reason, "a market should be BASE-QUOTE")
+
+	rec, _ = s.do(nil, http.MethodGet, "/v1/markets/ETH-USD/book", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	err := s.error(rec)
+	assert.Equal(t, "MarketNotfound", err.Reason)
+	assert.Equal(t, "MarketNotfound : ETH/USD", err.Message)
+
+	rec, _ = s.do(nil, http.MethodPost, "/v1/accounts", map[string]string{"signer": "alice"}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Unauthorized", s.error(rec).Reason, "unsigned requests should be refused")
+
+	rec, _ = s.do(nil, http.MethodGet, "/v1/nowhere", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "NotFound", s.error(rec).Reason, "unknown routes should answer with an error envelope")
+}
+
+func TestV1ErrorReasons(t *testing.T) {
+	s := newV1ServerWith(t, Config{RateLimits: RateLimits{Cancels: ratelimit.Policy{PerIP: ratelimit.Limit{Rate: 1, Burst: 1}}}})
+
+	rec, _ := s.do(aliceKey, http.MethodGet, "/v1/accounts/alice", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "AccountNotFound", s.error(rec).Reason, "accounting errors should give their own reason")
+
+	rec, _ = s.do(aliceKey, http.MethodPost, "/v1/accounts", map[string]string{"signer": "alice"}, nil)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	rec, _ = s.do(aliceKey, http.MethodPost, "/v1/accounts/alice/withdrawals", map[string]float64{"amount": 10}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "AccountUnderFunded", s.error(rec).Reason, "underfunded accounts should not be a generic bad request")
+
+	rec, _ = s.do(aliceKey, http.MethodPost, "/v1/markets", map[string]string{"base": "ETH", "quote": "USD"}, nil)
+	assert.Equal(t, "Forbidden", s.error(rec).Reason, "auth errors should give their own reason")
+
+	rec, _ = s.do(aliceKey, http.MethodDelete, "/v1/markets/BTC-USD/orders/1", nil, nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	rec, _ = s.do(aliceKey, http.MethodDelete, "/v1/markets/BTC-USD/orders/1", nil, nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "RateLimited", s.error(rec).Reason, "rate limited requests should give their own reason")
+}
+
+func TestV1BodyTooLarge(t *testing.T) {
+	s := newV1Server(t)
+
+	body := map[string]string{"signer": strings.Repeat("a", auth.DefaultMaxBodySize)}
+	rec, _ := s.do(aliceKey, http.MethodPost, "/v1/accounts", body, nil)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "oversized body should be refused before it is authenticated")
+	assert.Equal(t, "BodyTooLarge", s.error(rec).Reason)
+}
+
+func TestDeprecatedRoutes(t *testing.T) {
+	s := newV1Server(t)
+
+	rec, _ := s.do(nil, http.MethodGet, "/orderbooks?base=BTC&quote=USD", nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, "the routes before /v1 should still work")
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Contains(t, rec.Header().Get("Link"), `rel="deprecation"`)
+
+	rec, _ = s.do(nil, http.MethodGet, "/orderbooks?base=ETH&quote=USD", nil, nil)
+	assert.JSONEq(t, `{"code": 404, "message": "MarketNotfound : ETH/USD"}`, rec.Body.String(), "errors should keep their shape")
+
+	rec, _ = s.do(nil, http.MethodGet, "/v1/markets/BTC-USD", nil, nil)
+	assert.Empty(t, rec.Header().Get("Deprecation"), "/v1 should not be deprecated")
+}
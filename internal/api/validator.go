@@ -1,10 +1,12 @@
 package api
 
 import (
+	"math"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/richo225/octgopus/internal/accounting"
 )
 
 type Validator struct {
@@ -12,11 +14,31 @@ type Validator struct {
 }
 
 func NewValidator() *Validator {
+	v := validator.New()
+	v.RegisterValidation("amount", validateAmount)
+	v.RegisterValidation("signed_amount", validateSignedAmount)
+	v.RegisterValidation("signer", validateSigner)
+
 	return &Validator{
-		validator: validator.New(),
+		validator: v,
 	}
 }
 
+// validateAmount applies the accounting rules for amounts: positive, finite
+// and no more precise than accounting.MaxAmountDecimals.
+func validateAmount(fl validator.FieldLevel) bool {
+	return accounting.ValidateAmount(fl.Field().Float()) == nil
+}
+
+// validateSignedAmount is validateAmount for amounts that may be negative.
+func validateSignedAmount(fl validator.FieldLevel) bool {
+	return accounting.ValidateAmount(math.Abs(fl.Field().Float())) == nil
+}
+
+func validateSigner(fl validator.FieldLevel) bool {
+	return accounting.ValidateSigner(fl.Field().String()) == nil
+}
+
 func (v *Validator) Validate(i interface{}) error {
 	if err := v.validator.Struct(i); err != nil {
 		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
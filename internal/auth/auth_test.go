@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var alice = &Key{ID: "key-alice", Secret: "s3cret", Signer: "alice"}
+
+func testVerifier(now time.Time) *Verifier {
+	verifier := NewVerifier(NewKeyStore(alice))
+	verifier.now = func() time.Time { return now }
+
+	return verifier
+}
+
+func signedRequest(t *testing.T, key *Key, at time.Time, nonce string) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/accounts/alice/deposit?x=1", strings.NewReader(`{"amount":10}`))
+	require.NoError(t, SignRequest(req, key, at, nonce))
+
+	return req
+}
+
+func TestVerifySignedRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	req := signedRequest(t, alice, now, "n1")
+
+	key, err := testVerifier(now).Verify(req)
+	require.NoError(t, err, "correctly signed request should verify")
+	assert.Equal(t, "alice", key.Signer, "request should act as the key signer")
+
+	body, _ := readBody(req, 0)
+	assert.Equal(t, `{"amount":10}`, string(body), "body should still be readable after verifying")
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tampered := signedRequest(t, alice, now, "n1")
+	tampered.URL.Path = "/accounts/bob/deposit"
+
+	wrongSecret := signedRequest(t, &Key{ID: alice.ID, Secret: "guess", Signer: "alice"}, now, "n2")
+	unknownKey := signedRequest(t, &Key{ID: "key-mallory", Secret: "s3cret"}, now, "n3")
+	stale := signedRequest(t, alice, now.Add(-DefaultMaxSkew-time.Second), "n4")
+	future := signedRequest(t, alice, now.Add(DefaultMaxSkew+time.Second), "n5")
+	unsigned := httptest.NewRequest(http.MethodGet, "/accounts/alice", nil)
+
+	cases := map[string]*http.Request{
+		"tampered path": tampered,
+		"wrong secret":  wrongSecret,
+		"unknown key":   unknownKey,
+		"stale":         stale,
+		"future":        future,
+		"unsigned":      unsigned,
+	}
+	for name, req := range cases {
+		_, err := testVerifier(now).Verify(req)
+		assert.IsType(t, &UnauthorizedError{}, err, "%s request should be unauthorized", name)
+	}
+}
+
+func TestVerifyReplayedNonce(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	verifier := testVerifier(now)
+
+	_, err := verifier.Verify(signedRequest(t, alice, now, "n1"))
+	require.NoError(t, err, "first request should verify")
+
+	_, err = verifier.Verify(signedRequest(t, alice, now, "n1"))
+	assert.IsType(t, &UnauthorizedError{}, err, "replayed nonce should be unauthorized")
+
+	verifier.now = func() time.Time { return now.Add(3 * DefaultMaxSkew) }
+	_, err = verifier.Verify(signedRequest(t, alice, now.Add(3*DefaultMaxSkew), "n2"))
+	assert.NoError(t, err, "later request should verify")
+	assert.Equal(t, 1, len(verifier.nonces), "expired nonces should be forgotten")
+}
+
+func TestVerifyBodyTooLarge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	verifier := testVerifier(now)
+	verifier.SetMaxBodySize(13)
+
+	_, err := verifier.Verify(signedRequest(t, alice, now, "n1"))
+	require.NoError(t, err, "body at the limit should verify")
+
+	verifier.SetMaxBodySize(12)
+	_, err = verifier.Verify(signedRequest(t, alice, now, "n2"))
+	require.IsType(t, &BodyTooLargeError{}, err, "body over the limit should be refused")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*BodyTooLargeError).HTTPCode())
+}
+
+func TestKeyAuthorize(t *testing.T) {
+	assert.NoError(t, alice.Authorize("alice"), "key should act for its own signer")
+	assert.IsType(t, &ForbiddenError{}, alice.Authorize("bob"), "key should not act for another signer")
+}
+
+func TestLoadKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.json")
+
+	os.WriteFile(path, []byte(`{"keys": [{"id": "k1", "secret": "s", "signer": "alice"}]}`), 0o600)
+	store, err := LoadKeyStore(path)
+	require.NoError(t, err, "key store should load")
+	key, ok := store.Get("k1")
+	assert.True(t, ok, "key should be found")
+	assert.Equal(t, "alice", key.Signer, "key should have its signer")
+
+	os.WriteFile(path, []byte(`{"keys": [{"id": "k1", "secret": "s", "signer": "alice"}, {"id": "k1", "secret": "t", "signer": "bob"}]}`), 0o600)
+	_, err = LoadKeyStore(path)
+	assert.IsType(t, &InvalidKeyStoreError{}, err, "duplicate key ids should be refused")
+
+	os.WriteFile(path, []byte(`{"keys": [{"id": "k1", "signer": "alice"}]}`), 0o600)
+	_, err = LoadKeyStore(path)
+	assert.IsType(t, &InvalidKeyStoreError{}, err, "keys without a secret should be refused")
+}
+
+func TestDevKeyStore(t *testing.T) {
+	_, err := LoadKeyStore("../../api_keys.dev.json")
+	assert.NoError(t, err, "development key store should load")
+}
+
+func TestRequireRole(t *testing.T) {
+	admin := &Key{ID: "key-admin", Secret: "s3cret", Signer: "ops", Roles: []string{AdminRole}}
+
+	assert.NoError(t, RequireRole(admin, AdminRole), "admin key should have the admin role")
+	assert.IsType(t, &MissingRoleError{}, RequireRole(alice, AdminRole), "signer key should not have the admin role")
+	assert.IsType(t, &UnauthorizedError{}, RequireRole(nil, AdminRole), "unauthenticated request should be unauthorized")
+}
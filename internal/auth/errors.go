@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+)
+
+type UnauthorizedError struct {
+	reason string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return "Unauthorized: " + e.reason
+}
+
+func (e *UnauthorizedError) Reason() string {
+	return "Unauthorized"
+}
+
+func (e *UnauthorizedError) HTTPCode() int {
+	return http.StatusUnauthorized
+}
+
+// ForbiddenError is returned when an authenticated signer acts on an account
+// or order that belongs to another signer.
+type ForbiddenError struct {
+	signer string
+}
+
+func (e *ForbiddenError) Error() string {
+	return "Forbidden: not permitted to act for " + e.signer
+}
+
+func (e *ForbiddenError) Reason() string {
+	return "Forbidden"
+}
+
+func (e *ForbiddenError) HTTPCode() int {
+	return http.StatusForbidden
+}
+
+type MissingRoleError struct {
+	role string
+}
+
+func (e *MissingRoleError) Error() string {
+	return "Forbidden: requires the " + e.role + " role"
+}
+
+func (e *MissingRoleError) Reason() string {
+	return "Forbidden"
+}
+
+func (e *MissingRoleError) HTTPCode() int {
+	return http.StatusForbidden
+}
+
+// BodyTooLargeError is returned when a request body is too large to be read
+// to check its signature.
+type BodyTooLargeError struct {
+	limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return "BodyTooLarge: request body exceeds " + strconv.FormatInt(e.limit, 10) + " bytes"
+}
+
+func (e *BodyTooLargeError) Reason() string {
+	return "BodyTooLarge"
+}
+
+func (e *BodyTooLargeError) HTTPCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+type InvalidKeyStoreError struct {
+	path   string
+	reason string
+}
+
+func (e *InvalidKeyStoreError) Error() string {
+	return "InvalidKeyStore: " + e.path + ": " + e.reason
+}
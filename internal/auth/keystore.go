@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// AdminRole allows a key to use the admin endpoints.
+const AdminRole = "admin"
+
+// Key is an API key. Requests signed with its secret act as its signer.
+type Key struct {
+	ID     string   `json:"id"`
+	Secret string   `json:"secret"`
+	Signer string   `json:"signer"`
+	Roles  []string `json:"roles,omitempty"`
+}
+
+// Authorize checks that the key may act for signer.
+func (k *Key) Authorize(signer string) error {
+	if k.Signer != signer {
+		return &ForbiddenError{signer}
+	}
+
+	return nil
+}
+
+func (k *Key) HasRole(role string) bool {
+	for _, r := range k.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequireRole checks that a request was authenticated with a key that has
+// role. A nil key means the request was not authenticated.
+func RequireRole(key *Key, role string) error {
+	if key == nil {
+		return &UnauthorizedError{"authentication required"}
+	}
+	if !key.HasRole(role) {
+		return &MissingRoleError{role}
+	}
+
+	return nil
+}
+
+// KeyStore holds the API keys known to the server.
+type KeyStore struct {
+	keys map[string]*Key
+}
+
+func NewKeyStore(keys ...*Key) *KeyStore {
+	store := &KeyStore{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		store.keys[key.ID] = key
+	}
+
+	return store
+}
+
+// LoadKeyStore reads keys from a JSON file of the form
+// {"keys": [{"id": ..., "secret": ..., "signer": ...}]}. It is intended for
+// local development; secrets are stored in plain text.
+func LoadKeyStore(path string) (*KeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Keys []*Key `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, &InvalidKeyStoreError{path, err.Error()}
+	}
+
+	store := NewKeyStore()
+	for _, key := range file.Keys {
+		if key.ID == "" || key.Secret == "" || key.Signer == "" {
+			return nil, &InvalidKeyStoreError{path, "every key needs an id, secret and signer"}
+		}
+		if _, ok := store.keys[key.ID]; ok {
+			return nil, &InvalidKeyStoreError{path, "duplicate key " + key.ID}
+		}
+		store.keys[key.ID] = key
+	}
+
+	return store, nil
+}
+
+func (s *KeyStore) Get(id string) (*Key, bool) {
+	key, ok := s.keys[id]
+	return key, ok
+}
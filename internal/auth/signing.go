@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	KeyHeader       = "X-Api-Key"
+	TimestampHeader = "X-Api-Timestamp"
+	NonceHeader     = "X-Api-Nonce"
+	SignatureHeader = "X-Api-Signature"
+)
+
+// StringToSign is what a request signature covers: the method, the path with
+// its query, the unix timestamp in seconds, the nonce and a SHA-256 of the
+// body, separated by newlines.
+func StringToSign(method string, uri string, timestamp int64, nonce string, body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return method + "\n" + uri + "\n" + strconv.FormatInt(timestamp, 10) + "\n" + nonce + "\n" + hex.EncodeToString(sum[:])
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the request with secret.
+func Sign(secret string, method string, uri string, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(StringToSign(method, uri, timestamp, nonce, body)))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets the authentication headers of req for key. The nonce must
+// not be reused with the same key.
+func SignRequest(req *http.Request, key *Key, at time.Time, nonce string) error {
+	body, err := readBody(req, 0)
+	if err != nil {
+		return err
+	}
+
+	timestamp := at.Unix()
+	req.Header.Set(KeyHeader, key.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(NonceHeader, nonce)
+	req.Header.Set(SignatureHeader, Sign(key.Secret, req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+
+	return nil
+}
+
+// readBody reads the whole body of req and puts it back so that it can be
+// read again. A body longer than limit is refused, unless limit is 0.
+func readBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	r := io.Reader(req.Body)
+	if limit > 0 {
+		r = io.LimitReader(req.Body, limit+1)
+	}
+	body, err := io.ReadAll(r)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(body)) > limit {
+		return nil, &BodyTooLargeError{limit}
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
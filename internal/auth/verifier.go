@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSkew is how far a request timestamp may be from the server clock.
+const DefaultMaxSkew = 5 * time.Minute
+
+// DefaultMaxBodySize is the largest request body read to check a signature.
+const DefaultMaxBodySize = 10 << 20
+
+// Verifier authenticates signed requests. A request is only accepted once:
+// its nonce is remembered for as long as its timestamp would be accepted.
+type Verifier struct {
+	keys    *KeyStore
+	maxSkew time.Duration
+	maxBody int64
+	now     func() time.Time
+
+	nonces map[string]time.Time
+	// seen lists the remembered nonces in the order they expire.
+	seen []string
+	mu   sync.Mutex
+}
+
+func NewVerifier(keys *KeyStore) *Verifier {
+	return &Verifier{
+		keys:    keys,
+		maxSkew: DefaultMaxSkew,
+		maxBody: DefaultMaxBodySize,
+		now:     time.Now,
+		nonces:  make(map[string]time.Time),
+	}
+}
+
+// SetMaxBodySize sets the largest request body Verify reads. Larger bodies
+// are refused before the signature is checked.
+func (v *Verifier) SetMaxBodySize(n int64) {
+	v.maxBody = n
+}
+
+// Verify checks the signature, timestamp and nonce of req and returns the key
+// it was signed with. The body is left readable.
+func (v *Verifier) Verify(req *http.Request) (*Key, error) {
+	credentials := Credentials{
+		KeyID:     req.Header.Get(KeyHeader),
+		Timestamp: req.Header.Get(TimestampHeader),
+		Nonce:     req.Header.Get(NonceHeader),
+		Signature: req.Header.Get(SignatureHeader),
+	}
+
+	body, err := readBody(req, v.maxBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.VerifyCredentials(credentials, req.Method, req.URL.RequestURI(), body)
+}
+
+// Credentials are the values of the authentication headers.
+type Credentials struct {
+	KeyID     string
+	Timestamp string
+	Nonce     string
+	Signature string
+}
+
+// VerifyCredentials is Verify for a request that is not an *http.Request,
+// e.g. a gRPC call, signed over method, uri and body.
+func (v *Verifier) VerifyCredentials(credentials Credentials, method string, uri string, body []byte) (*Key, error) {
+	if credentials.KeyID == "" || credentials.Nonce == "" || credentials.Signature == "" || credentials.Timestamp == "" {
+		return nil, &UnauthorizedError{"missing authentication headers"}
+	}
+
+	key, ok := v.keys.Get(credentials.KeyID)
+	if !ok {
+		return nil, &UnauthorizedError{"unknown api key"}
+	}
+
+	timestamp, err := strconv.ParseInt(credentials.Timestamp, 10, 64)
+	if err != nil {
+		return nil, &UnauthorizedError{"invalid timestamp"}
+	}
+
+	expected := Sign(key.Secret, method, uri, timestamp, credentials.Nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(credentials.Signature)) {
+		return nil, &UnauthorizedError{"invalid signature"}
+	}
+
+	// The timestamp and nonce are only checked once the signature proves they
+	// came from the key holder.
+	now := v.now()
+	at := time.Unix(timestamp, 0)
+	if at.Before(now.Add(-v.maxSkew)) || at.After(now.Add(v.maxSkew)) {
+		return nil, &UnauthorizedError{"timestamp outside allowed window"}
+	}
+
+	if !v.remember(credentials.KeyID+"\x00"+credentials.Nonce, now) {
+		return nil, &UnauthorizedError{"nonce already used"}
+	}
+
+	return key, nil
+}
+
+// remember records a nonce, returning false if it has been seen before.
+// Nonces are forgotten once a request carrying them would be refused for its
+// timestamp anyway.
+func (v *Verifier) remember(nonce string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for len(v.seen) > 0 && now.Sub(v.nonces[v.seen[0]]) > 2*v.maxSkew {
+		delete(v.nonces, v.seen[0])
+		v.seen = v.seen[1:]
+	}
+
+	if _, ok := v.nonces[nonce]; ok {
+		return false
+	}
+	v.nonces[nonce] = now
+	v.seen = append(v.seen, nonce)
+
+	return true
+}
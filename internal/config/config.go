@@ -0,0 +1,171 @@
+// Package config loads the markets the server runs from a YAML or JSON file.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/richo225/octgopus/internal/orderbook"
+	"gopkg.in/yaml.v3"
+)
+
+var assetPattern = regexp.MustCompile(`^[A-Z0-9]{2,10}$`)
+
+// Config declares the markets of the platform:
+//
+//	seed: true
+//	markets:
+//	  - base: BTC
+//	    quote: USD
+//	    seed: data/btc_usd_order_book.csv
+//	    tick_size: 0.01
+//	    lot_size: 0.0001
+//	    fees: {maker_rate: -0.0001, taker_rate: 0.002}
+type Config struct {
+	// Seed places the orders of each market's seed file when the market is
+	// created. It defaults to true.
+	Seed    *bool    `json:"seed"`
+	Markets []Market `json:"markets"`
+}
+
+type Market struct {
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+	// Seed is a CSV file relative to the config file.
+	Seed string `json:"seed"`
+	orderbook.MarketRules
+	Fees *orderbook.FeeSchedule `json:"fees"`
+}
+
+func (m Market) pair() orderbook.TradingPair {
+	return orderbook.NewTradingPair(m.Base, m.Quote)
+}
+
+// Load reads and validates the config file at path. Files ending in .json
+// are read as JSON and anything else as YAML. Seed files are resolved
+// relative to the config file so the server can be started from any
+// directory.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parse(data, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return nil, &InvalidConfigError{path, []string{err.Error()}}
+	}
+
+	dir := filepath.Dir(path)
+	for i := range config.Markets {
+		if seed := config.Markets[i].Seed; seed != "" && !filepath.IsAbs(seed) {
+			config.Markets[i].Seed = filepath.Join(dir, seed)
+		}
+	}
+
+	if problems := config.validate(); len(problems) > 0 {
+		return nil, &InvalidConfigError{path, problems}
+	}
+
+	return config, nil
+}
+
+// parse decodes YAML by converting it to JSON first, so that both formats
+// share the json tags of the orderbook types and reject unknown fields alike.
+func parse(data []byte, isJSON bool) (*Config, error) {
+	if !isJSON {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	config := &Config{}
+	if err := decoder.Decode(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// validate returns every problem with the config rather than just the first,
+// so they can all be fixed at once.
+func (c *Config) validate() []string {
+	problems := []string{}
+	if len(c.Markets) == 0 {
+		problems = append(problems, "no markets declared")
+	}
+
+	seen := make(map[orderbook.TradingPair]bool)
+	for i, market := range c.Markets {
+		pair := market.pair()
+		name := fmt.Sprintf("markets[%d] %s", i, pair.ToString())
+		fail := func(format string, args ...interface{}) {
+			problems = append(problems, name+": "+fmt.Sprintf(format, args...))
+		}
+
+		if !assetPattern.MatchString(market.Base) {
+			fail("base %q must be 2 to 10 upper case letters or digits", market.Base)
+		}
+		if !assetPattern.MatchString(market.Quote) {
+			fail("quote %q must be 2 to 10 upper case letters or digits", market.Quote)
+		}
+		if market.Base == market.Quote {
+			fail("base and quote must differ")
+		}
+		if seen[pair] {
+			fail("declared more than once")
+		}
+		seen[pair] = true
+
+		if market.Seed != "" && c.seeding() {
+			if info, err := os.Stat(market.Seed); err != nil {
+				fail("seed file %s", err.Error())
+			} else if info.IsDir() {
+				fail("seed file %s is a directory", market.Seed)
+			}
+		}
+		if err := market.MarketRules.Validate(); err != nil {
+			fail("%s", err.Error())
+		}
+		if market.Fees != nil {
+			if err := market.Fees.Validate(); err != nil {
+				fail("fees: %s", err.Error())
+			}
+		}
+	}
+
+	return problems
+}
+
+func (c *Config) seeding() bool {
+	return c.Seed == nil || *c.Seed
+}
+
+// Specs returns the markets to give to TradingPlatform.SetMarkets. Seed files
+// are left out when seeding is turned off.
+func (c *Config) Specs() []orderbook.MarketSpec {
+	specs := make([]orderbook.MarketSpec, 0, len(c.Markets))
+	for _, market := range c.Markets {
+		spec := orderbook.MarketSpec{Market: market.pair(), Rules: market.MarketRules, Fees: market.Fees}
+		if c.seeding() {
+			spec.Seed = market.Seed
+		}
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestLoadYAML(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "data", "btc_usd.csv"), "Ask Price,Ask Amount,Bid Price,Bid Amount\n")
+	writeFile(t, filepath.Join(dir, "markets.yaml"), `
+markets:
+  - base: BTC
+    quote: USD
+    seed: data/btc_usd.csv
+    tick_size: 0.01
+    min_size: 0.001
+    fees:
+      maker_rate: -0.0001
+      taker_rate: 0.002
+      tiers:
+        - {min_volume: 100000, maker_rate: -0.0002, taker_rate: 0.001}
+  - base: ETH
+    quote: USD
+`)
+
+	config, err := Load(filepath.Join(dir, "markets.yaml"))
+	require.NoError(t, err, "valid config should load")
+
+	specs := config.Specs()
+	require.Equal(t, 2, len(specs), "every market should be loaded")
+	assert.Equal(t, orderbook.NewTradingPair("BTC", "USD"), specs[0].Market)
+	assert.Equal(t, filepath.Join(dir, "data", "btc_usd.csv"), specs[0].Seed, "seed should be relative to the config file")
+	assert.Equal(t, orderbook.MarketRules{TickSize: 0.01, MinSize: 0.001}, specs[0].Rules, "rules should be read")
+	assert.Equal(t, -0.0001, specs[0].Fees.MakerRate, "fees should be read")
+	assert.Equal(t, 100000.0, specs[0].Fees.Tiers[0].MinVolume, "fee tiers should be read")
+	assert.Equal(t, "", specs[1].Seed, "markets without a seed should start empty")
+	assert.Nil(t, specs[1].Fees, "markets without fees should keep their schedule")
+}
+
+func TestLoadJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "markets.json")
+	writeFile(t, path, `{"seed": false, "markets": [{"base": "BTC", "quote": "GBP", "seed": "missing.csv", "lot_size": 0.5}]}`)
+
+	config, err := Load(path)
+	require.NoError(t, err, "missing seed files should be allowed when seeding is off")
+
+	specs := config.Specs()
+	assert.Equal(t, 0.5, specs[0].Rules.LotSize, "rules should be read")
+	assert.Equal(t, "", specs[0].Seed, "seed files should be dropped when seeding is off")
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "markets.yaml")
+	writeFile(t, path, `
+markets:
+  - base: btc
+    quote: USD
+  - base: ETH
+    quote: USD
+    seed: missing.csv
+    min_size: 2
+    max_size: 1
+  - base: ETH
+    quote: USD
+    fees: {maker_rate: 0, taker_rate: 1}
+`)
+
+	_, err := Load(path)
+	var invalid *InvalidConfigError
+	require.ErrorAs(t, err, &invalid, "invalid config should be refused")
+	assert.Equal(t, []string{
+		`markets[0] btc/USD: base "btc" must be 2 to 10 upper case letters or digits`,
+		"markets[1] ETH/USD: seed file stat " + filepath.Join(dir, "missing.csv") + ": no such file or directory",
+		"markets[1] ETH/USD: min_size must not be above max_size",
+		"markets[2] ETH/USD: declared more than once",
+		"markets[2] ETH/USD: fees: InvalidFeeSchedule : taker rate must be at least 0 and below 1",
+	}, invalid.Problems(), "every problem should be reported")
+}
+
+func TestLoadUnknownField(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "markets.yaml")
+	writeFile(t, path, "markets:\n  - base: BTC\n    quote: USD\n    tick: 0.01\n")
+
+	_, err := Load(path)
+	assert.ErrorContains(t, err, `unknown field "tick"`, "misspelt fields should be refused")
+}
+
+func TestLoadNoMarkets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "markets.yaml")
+	writeFile(t, path, "seed: true\n")
+
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "no markets declared", "config without markets should be refused")
+}
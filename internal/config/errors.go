@@ -0,0 +1,17 @@
+package config
+
+import "strings"
+
+// InvalidConfigError lists every problem found in a config file.
+type InvalidConfigError struct {
+	path     string
+	problems []string
+}
+
+func (e *InvalidConfigError) Error() string {
+	return "InvalidConfig : " + e.path + ":\n  " + strings.Join(e.problems, "\n  ")
+}
+
+func (e *InvalidConfigError) Problems() []string {
+	return e.problems
+}
@@ -0,0 +1,203 @@
+package fix
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kr/pretty"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+)
+
+// DefaultCompID is the CompID of the gateway when none is configured.
+const DefaultCompID = "OCTGOPUS"
+
+const (
+	// logonTimeout is how long a new connection has to log on.
+	logonTimeout = 10 * time.Second
+	// writeTimeout drops initiators that stop reading.
+	writeTimeout = 10 * time.Second
+)
+
+type Config struct {
+	// CompID is the SenderCompID of the gateway's messages, which initiators
+	// must send as their TargetCompID.
+	CompID string
+	// Keys authenticates logons, with the key ID as Username and its secret
+	// as Password. When nil, logons are not authenticated and the
+	// initiator's SenderCompID is used as its signer.
+	Keys *auth.KeyStore
+	// Store persists sequence numbers, defaulting to memory.
+	Store SeqStore
+	// TLS serves sessions over TLS when set. Start requires it with Keys, as
+	// the Password of a logon is the key's secret.
+	TLS *tls.Config
+}
+
+// Acceptor accepts FIX sessions for a TradingPlatform. An initiator may have
+// one connection at a time, and its orders are remembered across
+// connections so that they can be cancelled after a reconnect.
+type Acceptor struct {
+	platform *orderbook.TradingPlatform
+	config   Config
+
+	// execIDs are unique within the prefix, which is the start time.
+	execPrefix string
+	execIDs    uint64
+
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	orders   map[string]*orders
+	// connected holds the initiators that are logged on.
+	connected map[string]bool
+	closed    bool
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+}
+
+func NewAcceptor(p *orderbook.TradingPlatform, config Config) *Acceptor {
+	if config.CompID == "" {
+		config.CompID = DefaultCompID
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+
+	return &Acceptor{
+		platform:   p,
+		config:     config,
+		execPrefix: strconv.FormatInt(time.Now().Unix(), 36),
+		conns:      make(map[net.Conn]struct{}),
+		orders:     make(map[string]*orders),
+		connected:  make(map[string]bool),
+	}
+}
+
+// Start serves FIX sessions for p on addr until the listener fails. It
+// refuses to authenticate logons without TLS.
+func Start(p *orderbook.TradingPlatform, config Config, addr string) error {
+	if config.Keys != nil && config.TLS == nil {
+		return &InsecureConfigError{"API key logons require TLS"}
+	}
+
+	var lis net.Listener
+	var err error
+	if config.TLS != nil {
+		lis, err = tls.Listen("tcp", addr, config.TLS)
+	} else {
+		lis, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	pretty.Log("Starting FIX acceptor on", lis.Addr().String())
+	return NewAcceptor(p, config).Serve(lis)
+}
+
+// Serve accepts connections on lis until it fails or the acceptor is closed.
+func (a *Acceptor) Serve(lis net.Listener) error {
+	a.mu.Lock()
+	a.listener = lis
+	a.mu.Unlock()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			a.mu.Lock()
+			closed := a.closed
+			a.mu.Unlock()
+			if closed {
+				return nil
+			}
+
+			return err
+		}
+
+		a.mu.Lock()
+		if a.closed {
+			a.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		a.conns[conn] = struct{}{}
+		a.wg.Add(1)
+		a.mu.Unlock()
+
+		go a.handle(conn)
+	}
+}
+
+// Close stops accepting connections, drops those that are open and waits for
+// their sessions to end.
+func (a *Acceptor) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	var err error
+	if a.listener != nil {
+		err = a.listener.Close()
+	}
+	for conn := range a.conns {
+		conn.Close()
+	}
+	a.mu.Unlock()
+
+	a.wg.Wait()
+	return err
+}
+
+func (a *Acceptor) handle(conn net.Conn) {
+	defer a.wg.Done()
+	defer func() {
+		a.mu.Lock()
+		delete(a.conns, conn)
+		a.mu.Unlock()
+		conn.Close()
+	}()
+
+	s := &session{acceptor: a, conn: conn, reader: bufio.NewReader(conn)}
+	if err := s.logon(); err != nil {
+		pretty.Log("FIX logon failed", conn.RemoteAddr().String(), err.Error())
+		return
+	}
+	defer a.release(s.id)
+
+	pretty.Log("FIX session logged on", s.id)
+	s.run()
+	pretty.Log("FIX session logged out", s.id)
+}
+
+// acquire marks an initiator as logged on and returns its orders, unless it
+// is already logged on from another connection.
+func (a *Acceptor) acquire(id string) (*orders, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.connected[id] {
+		return nil, &LogonError{"session " + id + " is already logged on"}
+	}
+	a.connected[id] = true
+
+	o, ok := a.orders[id]
+	if !ok {
+		o = newOrders()
+		a.orders[id] = o
+	}
+
+	return o, nil
+}
+
+func (a *Acceptor) release(id string) {
+	a.mu.Lock()
+	delete(a.connected, id)
+	a.mu.Unlock()
+}
+
+func (a *Acceptor) nextExecID() string {
+	return a.execPrefix + "-" + strconv.FormatUint(atomic.AddUint64(&a.execIDs, 1), 10)
+}
@@ -0,0 +1,221 @@
+package fix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogon(t *testing.T) {
+	addr := newTestAcceptor(t, newTestPlatform(t), nil)
+
+	alice := dial(t, addr, "ALICE")
+	reply := alice.logon(aliceKey, false)
+	assert.Equal(t, MsgLogon, reply.Type(), "logon should be accepted")
+	assert.Equal(t, "1", reply.Get(TagMsgSeqNum))
+	assert.Equal(t, "30", reply.Get(TagHeartBtInt), "heartbeat interval should be echoed")
+
+	again := dial(t, addr, "ALICE")
+	reply = again.logon(aliceKey, false)
+	assert.Equal(t, MsgLogout, reply.Type(), "second connection of a session should be refused")
+	assert.Contains(t, reply.Get(TagText), "already logged on")
+
+	forged := dial(t, addr, "EVE")
+	key := *aliceKey
+	key.Secret = "wrong"
+	reply = forged.logon(&key, false)
+	assert.Equal(t, MsgLogout, reply.Type(), "wrong password should be refused")
+	assert.Contains(t, reply.Get(TagText), "invalid Username or Password")
+
+	anonymous := dial(t, addr, "ANON")
+	reply = anonymous.logon(nil, false)
+	assert.Equal(t, MsgLogout, reply.Type(), "logon without a key should be refused")
+}
+
+func TestStartRequiresTLS(t *testing.T) {
+	err := Start(newTestPlatform(t), Config{Keys: auth.NewKeyStore(aliceKey)}, "127.0.0.1:0")
+	assert.IsType(t, &InsecureConfigError{}, err, "API key logons should not be served without TLS")
+}
+
+func TestOrderEntry(t *testing.T) {
+	addr := newTestAcceptor(t, newTestPlatform(t), nil)
+	alice := dial(t, addr, "ALICE")
+	require.Equal(t, MsgLogon, alice.logon(aliceKey, false).Type())
+	bob := dial(t, addr, "BOB")
+	require.Equal(t, MsgLogon, bob.logon(bobKey, false).Type())
+
+	alice.send(newOrderSingle("a1", sideSell, ordTypeLimit, 5, 100))
+	ack := alice.expect(MsgExecutionReport)
+	assert.Equal(t, execNew, ack.Get(TagExecType))
+	assert.Equal(t, statusNew, ack.Get(TagOrdStatus))
+	assert.Equal(t, "a1", ack.Get(TagClOrdID))
+	assert.Equal(t, "5", ack.Get(TagLeavesQty))
+	assert.NotEmpty(t, ack.Get(TagOrderID), "ack should carry the platform order id")
+
+	bob.send(newOrderSingle("b1", sideBuy, ordTypeMarket, 2, 0))
+	assert.Equal(t, execNew, bob.expect(MsgExecutionReport).Get(TagExecType), "market order should be acknowledged first")
+	fill := bob.expect(MsgExecutionReport)
+	assert.Equal(t, execTrade, fill.Get(TagExecType))
+	assert.Equal(t, statusFilled, fill.Get(TagOrdStatus))
+	assert.Equal(t, "2", fill.Get(TagLastQty))
+	assert.Equal(t, "100", fill.Get(TagLastPx))
+	assert.Equal(t, "100", fill.Get(TagAvgPx))
+
+	fill = alice.expect(MsgExecutionReport)
+	assert.Equal(t, execTrade, fill.Get(TagExecType), "resting order should report its fill")
+	assert.Equal(t, statusPartiallyFilled, fill.Get(TagOrdStatus))
+	assert.Equal(t, "2", fill.Get(TagCumQty))
+	assert.Equal(t, "3", fill.Get(TagLeavesQty))
+
+	replace := newOrderSingle("a2", sideSell, ordTypeLimit, 6, 101)
+	replace.Fields[0].Value = MsgOrderCancelReplaceRequest
+	replace.Set(TagOrigClOrdID, "a1")
+	alice.send(replace)
+	replaced := alice.expect(MsgExecutionReport)
+	assert.Equal(t, execReplaced, replaced.Get(TagExecType))
+	assert.Equal(t, statusPartiallyFilled, replaced.Get(TagOrdStatus), "replacement should keep the fills")
+	assert.Equal(t, "a2", replaced.Get(TagClOrdID))
+	assert.Equal(t, "a1", replaced.Get(TagOrigClOrdID))
+	assert.Equal(t, "101", replaced.Get(TagPrice))
+	assert.Equal(t, "2", replaced.Get(TagCumQty))
+	assert.Equal(t, "4", replaced.Get(TagLeavesQty), "replacement should rest OrderQty less CumQty")
+
+	alice.send(NewMessage(MsgOrderCancelRequest).Set(TagClOrdID, "a3").Set(TagOrigClOrdID, "a2").Set(TagSymbol, "BTC/USD").Set(TagSide, sideSell))
+	cancelled := alice.expect(MsgExecutionReport)
+	assert.Equal(t, execCanceled, cancelled.Get(TagExecType))
+	assert.Equal(t, statusCanceled, cancelled.Get(TagOrdStatus))
+	assert.Equal(t, "a3", cancelled.Get(TagClOrdID))
+	assert.Equal(t, "a2", cancelled.Get(TagOrigClOrdID))
+	assert.Equal(t, "0", cancelled.Get(TagLeavesQty))
+
+	alice.send(NewMessage(MsgOrderCancelRequest).Set(TagClOrdID, "a4").Set(TagOrigClOrdID, "a2").Set(TagSymbol, "BTC/USD").Set(TagSide, sideSell))
+	reject := alice.expect(MsgOrderCancelReject)
+	assert.Equal(t, "0", reject.Get(TagCxlRejReason), "cancelled order should be too late to cancel")
+	assert.Equal(t, statusCanceled, reject.Get(TagOrdStatus))
+
+	alice.send(NewMessage(MsgOrderCancelRequest).Set(TagClOrdID, "a5").Set(TagOrigClOrdID, "nope").Set(TagSymbol, "BTC/USD").Set(TagSide, sideSell))
+	assert.Equal(t, "1", alice.expect(MsgOrderCancelReject).Get(TagCxlRejReason), "unknown order should be rejected")
+}
+
+func TestOrderRejects(t *testing.T) {
+	p := newTestPlatform(t)
+	require.NoError(t, p.HaltMarket(orderbook.NewTradingPair("BTC", "USD")))
+	alice := dial(t, newTestAcceptor(t, p, nil), "ALICE")
+	require.Equal(t, MsgLogon, alice.logon(aliceKey, false).Type())
+
+	alice.send(newOrderSingle("a1", sideBuy, ordTypeLimit, 1, 100))
+	reject := alice.expect(MsgExecutionReport)
+	assert.Equal(t, execRejected, reject.Get(TagExecType))
+	assert.Equal(t, statusRejected, reject.Get(TagOrdStatus))
+	assert.Contains(t, reject.Get(TagText), "MarketHalted", "reject should carry the platform error")
+
+	alice.send(newOrderSingle("a1", sideBuy, ordTypeLimit, 1, 100))
+	assert.Equal(t, "6", alice.expect(MsgExecutionReport).Get(TagOrdRejReason), "ClOrdID should not be reused")
+
+	unknown := newOrderSingle("a2", sideBuy, ordTypeLimit, 1, 100).Set(TagSymbol, "ETH/USD")
+	alice.send(unknown)
+	assert.Equal(t, "1", alice.expect(MsgExecutionReport).Get(TagOrdRejReason), "unknown market should be rejected as an unknown symbol")
+
+	missing := NewMessage(MsgNewOrderSingle).Set(TagClOrdID, "a3").Set(TagSymbol, "BTC/USD").Set(TagSide, sideBuy).Set(TagOrdType, ordTypeMarket)
+	alice.send(missing)
+	sessionReject := alice.expect(MsgReject)
+	assert.Equal(t, "1", sessionReject.Get(TagSessionRejectReason), "missing OrderQty should be a session reject")
+	assert.Equal(t, "38", sessionReject.Get(TagRefTagID))
+
+	alice.send(NewMessage("Z"))
+	assert.Equal(t, "11", alice.expect(MsgReject).Get(TagSessionRejectReason), "unknown MsgType should be rejected")
+}
+
+func TestUnsolicitedCancel(t *testing.T) {
+	p := newTestPlatform(t)
+	alice := dial(t, newTestAcceptor(t, p, nil), "ALICE")
+	require.Equal(t, MsgLogon, alice.logon(aliceKey, false).Type())
+
+	alice.send(newOrderSingle("a1", sideBuy, ordTypeLimit, 1, 100))
+	alice.expect(MsgExecutionReport)
+
+	_, err := p.RemoveMarket(orderbook.NewTradingPair("BTC", "USD"))
+	require.NoError(t, err)
+
+	cancelled := alice.expect(MsgExecutionReport)
+	assert.Equal(t, execCanceled, cancelled.Get(TagExecType), "orders cancelled by the platform should be reported")
+	assert.Equal(t, "a1", cancelled.Get(TagClOrdID))
+}
+
+func TestHeartbeats(t *testing.T) {
+	alice := dial(t, newTestAcceptor(t, newTestPlatform(t), nil), "ALICE")
+	alice.send(NewMessage(MsgLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, 1).
+		Set(TagUsername, aliceKey.ID).Set(TagPassword, aliceKey.Secret))
+	require.Equal(t, MsgLogon, alice.read().Type())
+
+	alice.send(NewMessage(MsgTestRequest).Set(TagTestReqID, "ping"))
+	alice.conn.SetReadDeadline(time.Now().Add(time.Second))
+	heartbeat, err := ReadMessage(alice.reader)
+	require.NoError(t, err)
+	assert.Equal(t, MsgHeartbeat, heartbeat.Type(), "TestRequest should be answered with a Heartbeat")
+	assert.Equal(t, "ping", heartbeat.Get(TagTestReqID))
+
+	alice.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	heartbeat, err = ReadMessage(alice.reader)
+	require.NoError(t, err)
+	assert.Equal(t, MsgHeartbeat, heartbeat.Type(), "acceptor should send a Heartbeat when idle")
+
+	testRequest := alice.expect(MsgTestRequest)
+	alice.send(NewMessage(MsgHeartbeat).Set(TagTestReqID, testRequest.Get(TagTestReqID)))
+
+	alice.expect(MsgTestRequest)
+	logout := alice.expect(MsgLogout)
+	assert.Equal(t, "Heartbeat timeout", logout.Get(TagText), "unanswered TestRequest should end the session")
+}
+
+func TestSequenceNumbers(t *testing.T) {
+	p := newTestPlatform(t)
+	store := &FileStore{Dir: t.TempDir()}
+
+	addr := newTestAcceptor(t, p, store)
+	alice := dial(t, addr, "ALICE")
+	require.Equal(t, MsgLogon, alice.logon(aliceKey, false).Type())
+	alice.send(newOrderSingle("a1", sideBuy, ordTypeLimit, 1, 100))
+	alice.expect(MsgExecutionReport)
+	alice.send(NewMessage(MsgLogout))
+	alice.expect(MsgLogout)
+
+	seqNums, err := store.Load("ALICE")
+	require.NoError(t, err)
+	assert.Equal(t, SeqNums{NextSender: 4, NextTarget: 4}, seqNums, "sequence numbers should be saved")
+
+	// A restarted acceptor continues the session where it left off.
+	restarted := dial(t, newTestAcceptor(t, p, store), "ALICE")
+	restarted.seq = 1
+	reply := restarted.logon(aliceKey, false)
+	assert.Equal(t, MsgLogout, reply.Type(), "logon with a used MsgSeqNum should be refused")
+	assert.Contains(t, reply.Get(TagText), "MsgSeqNum too low, expecting 4")
+
+	restarted = dial(t, newTestAcceptor(t, p, store), "ALICE")
+	restarted.seq = 4
+	reply = restarted.logon(aliceKey, false)
+	require.Equal(t, MsgLogon, reply.Type())
+	assert.Equal(t, "4", reply.Get(TagMsgSeqNum), "acceptor should continue its sequence")
+
+	restarted.sendSeq(NewMessage(MsgTestRequest).Set(TagTestReqID, "gap"), 7)
+	resend := restarted.expect(MsgResendRequest)
+	assert.Equal(t, "5", resend.Get(TagBeginSeqNo), "gap should be asked for")
+	assert.Equal(t, "0", resend.Get(TagEndSeqNo))
+
+	restarted.send(NewMessage(MsgSequenceReset).Set(TagGapFillFlag, "Y").SetInt(TagNewSeqNo, 8))
+	restarted.seq = 8
+	restarted.send(NewMessage(MsgResendRequest).SetInt(TagBeginSeqNo, 1).SetInt(TagEndSeqNo, 0))
+	gapFill := restarted.expect(MsgSequenceReset)
+	assert.Equal(t, "Y", gapFill.Get(TagGapFillFlag), "resend should be answered with a gap fill")
+	assert.Equal(t, "1", gapFill.Get(TagMsgSeqNum))
+	assert.Equal(t, "Y", gapFill.Get(TagPossDupFlag))
+	assert.Equal(t, "6", gapFill.Get(TagNewSeqNo), "gap fill should skip to the next MsgSeqNum")
+
+	reset := dial(t, addr, "RESET")
+	reset.seq = 1
+	require.Equal(t, MsgLogon, reset.logon(aliceKey, true).Type())
+}
@@ -0,0 +1,61 @@
+package fix
+
+import "strconv"
+
+// InvalidMessageError is returned for bytes that are not a FIX message. The
+// session cannot continue after one, as the stream can't be resynchronised.
+type InvalidMessageError struct {
+	reason string
+}
+
+func (e *InvalidMessageError) Error() string {
+	return "InvalidMessage : " + e.reason
+}
+
+type RequiredTagMissingError struct {
+	tag int
+}
+
+func (e *RequiredTagMissingError) Error() string {
+	return "RequiredTagMissing : " + strconv.Itoa(e.tag)
+}
+
+type IncorrectTagValueError struct {
+	tag   int
+	value string
+}
+
+func (e *IncorrectTagValueError) Error() string {
+	return "IncorrectTagValue : " + strconv.Itoa(e.tag) + "=" + e.value
+}
+
+// InsecureConfigError refuses to serve with a configuration that would send
+// API secrets in the clear.
+type InsecureConfigError struct {
+	reason string
+}
+
+func (e *InsecureConfigError) Error() string {
+	return "InsecureConfig : " + e.reason
+}
+
+// LogonError refuses a logon, which is answered with a Logout.
+type LogonError struct {
+	reason string
+}
+
+func (e *LogonError) Error() string {
+	return "LogonRefused : " + e.reason
+}
+
+// rejectReason is the SessionRejectReason and RefTagID of a field error.
+func rejectReason(err error) (reason int, tag int, ok bool) {
+	switch e := err.(type) {
+	case *RequiredTagMissingError:
+		return 1, e.tag, true
+	case *IncorrectTagValueError:
+		return 5, e.tag, true
+	}
+
+	return 0, 0, false
+}
@@ -0,0 +1,129 @@
+package fix
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	aliceKey = &auth.Key{ID: "alice-key", Secret: "alice-secret", Signer: "alice"}
+	bobKey   = &auth.Key{ID: "bob-key", Secret: "bob-secret", Signer: "bob"}
+)
+
+// newTestAcceptor serves p on a local port with keys for alice and bob, and
+// returns its address.
+func newTestAcceptor(t *testing.T, p *orderbook.TradingPlatform, store SeqStore) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	acceptor := NewAcceptor(p, Config{Keys: auth.NewKeyStore(aliceKey, bobKey), Store: store})
+	go acceptor.Serve(lis)
+	t.Cleanup(func() { acceptor.Close() })
+
+	return lis.Addr().String()
+}
+
+func newTestPlatform(t *testing.T) *orderbook.TradingPlatform {
+	p := orderbook.NewTradingPlatform()
+	_, err := p.AddNewMarket(orderbook.NewTradingPair("BTC", "USD"))
+	require.NoError(t, err)
+
+	return p
+}
+
+// initiator is the client side of a FIX session, as a counterparty would
+// run it.
+type initiator struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	compID string
+	seq    int
+}
+
+func dial(t *testing.T, addr string, compID string) *initiator {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &initiator{t: t, conn: conn, reader: bufio.NewReader(conn), compID: compID, seq: 1}
+}
+
+// send sends msg with the next MsgSeqNum.
+func (i *initiator) send(msg *Message) {
+	i.sendSeq(msg, i.seq)
+	i.seq++
+}
+
+// sendSeq sends msg with MsgSeqNum seq, without moving the next one.
+func (i *initiator) sendSeq(msg *Message, seq int) {
+	header := []Field{
+		{TagMsgType, msg.Type()},
+		{TagSenderCompID, i.compID},
+		{TagTargetCompID, DefaultCompID},
+		{TagMsgSeqNum, strconv.Itoa(seq)},
+		{TagSendingTime, time.Now().UTC().Format(timestampFormat)},
+	}
+	msg.Fields = append(header, msg.Fields[1:]...)
+
+	_, err := i.conn.Write(msg.Bytes())
+	require.NoError(i.t, err)
+}
+
+// read returns the next message, skipping heartbeats.
+func (i *initiator) read() *Message {
+	for {
+		i.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		msg, err := ReadMessage(i.reader)
+		require.NoError(i.t, err, "acceptor should send a message")
+		require.Equal(i.t, DefaultCompID, msg.Get(TagSenderCompID))
+		require.Equal(i.t, i.compID, msg.Get(TagTargetCompID))
+
+		if msg.Type() != MsgHeartbeat {
+			return msg
+		}
+	}
+}
+
+// expect reads the next message and checks its type.
+func (i *initiator) expect(msgType string) *Message {
+	msg := i.read()
+	require.Equal(i.t, msgType, msg.Type(), "unexpected message %s", msg)
+
+	return msg
+}
+
+func (i *initiator) logon(key *auth.Key, reset bool) *Message {
+	msg := NewMessage(MsgLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, 30)
+	if key != nil {
+		msg.Set(TagUsername, key.ID).Set(TagPassword, key.Secret)
+	}
+	if reset {
+		msg.Set(TagResetSeqNumFlag, "Y")
+	}
+	i.send(msg)
+
+	return i.read()
+}
+
+func newOrderSingle(clOrdID string, side string, ordType string, qty float64, price float64) *Message {
+	msg := NewMessage(MsgNewOrderSingle).
+		Set(TagClOrdID, clOrdID).
+		Set(TagSymbol, "BTC/USD").
+		Set(TagSide, side).
+		SetFloat(TagOrderQty, qty).
+		Set(TagOrdType, ordType).
+		SetTime(TagTransactTime, time.Now())
+	if ordType == ordTypeLimit {
+		msg.SetFloat(TagPrice, price)
+	}
+
+	return msg
+}
@@ -0,0 +1,306 @@
+// Package fix is a FIX 4.4 order entry gateway. It accepts initiator sessions
+// over TCP and turns NewOrderSingle, OrderCancelRequest and
+// OrderCancelReplaceRequest into TradingPlatform calls, answering with
+// ExecutionReports.
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	BeginString = "FIX.4.4"
+	soh         = '\x01'
+	// maxBodyLength bounds the memory a single message can take.
+	maxBodyLength = 64 * 1024
+	// timestampFormat is UTCTimestamp with milliseconds.
+	timestampFormat = "20060102-15:04:05.000"
+)
+
+// Tags used by the gateway.
+const (
+	TagAvgPx                = 6
+	TagBeginSeqNo           = 7
+	TagBeginString          = 8
+	TagBodyLength           = 9
+	TagCheckSum             = 10
+	TagClOrdID              = 11
+	TagCumQty               = 14
+	TagEndSeqNo             = 16
+	TagExecID               = 17
+	TagLastPx               = 31
+	TagLastQty              = 32
+	TagMsgSeqNum            = 34
+	TagMsgType              = 35
+	TagNewSeqNo             = 36
+	TagOrderID              = 37
+	TagOrderQty             = 38
+	TagOrdStatus            = 39
+	TagOrdType              = 40
+	TagOrigClOrdID          = 41
+	TagPossDupFlag          = 43
+	TagPrice                = 44
+	TagRefSeqNum            = 45
+	TagSenderCompID         = 49
+	TagSendingTime          = 52
+	TagSide                 = 54
+	TagSymbol               = 55
+	TagTargetCompID         = 56
+	TagText                 = 58
+	TagTransactTime         = 60
+	TagEncryptMethod        = 98
+	TagCxlRejReason         = 102
+	TagOrdRejReason         = 103
+	TagHeartBtInt           = 108
+	TagTestReqID            = 112
+	TagGapFillFlag          = 123
+	TagResetSeqNumFlag      = 141
+	TagExecType             = 150
+	TagLeavesQty            = 151
+	TagRefTagID             = 371
+	TagRefMsgType           = 372
+	TagSessionRejectReason  = 373
+	TagBusinessRejectReason = 380
+	TagCxlRejResponseTo     = 434
+	TagUsername             = 553
+	TagPassword             = 554
+)
+
+// Message types used by the gateway.
+const (
+	MsgHeartbeat                 = "0"
+	MsgTestRequest               = "1"
+	MsgResendRequest             = "2"
+	MsgReject                    = "3"
+	MsgSequenceReset             = "4"
+	MsgLogout                    = "5"
+	MsgExecutionReport           = "8"
+	MsgOrderCancelReject         = "9"
+	MsgLogon                     = "A"
+	MsgNewOrderSingle            = "D"
+	MsgOrderCancelRequest        = "F"
+	MsgOrderCancelReplaceRequest = "G"
+	MsgBusinessMessageReject     = "j"
+)
+
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message is a FIX message as its fields in order, without BeginString,
+// BodyLength and CheckSum, which are added when it is written.
+type Message struct {
+	Fields []Field
+}
+
+// NewMessage starts a message of msgType.
+func NewMessage(msgType string) *Message {
+	return &Message{Fields: []Field{{TagMsgType, msgType}}}
+}
+
+func (m *Message) Type() string {
+	return m.Get(TagMsgType)
+}
+
+// Get returns the value of the first field with tag, or empty.
+func (m *Message) Get(tag int) string {
+	value, _ := m.Lookup(tag)
+	return value
+}
+
+func (m *Message) Lookup(tag int) (string, bool) {
+	for _, field := range m.Fields {
+		if field.Tag == tag {
+			return field.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Set replaces the value of tag, or appends it.
+func (m *Message) Set(tag int, value string) *Message {
+	for i, field := range m.Fields {
+		if field.Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	m.Fields = append(m.Fields, Field{tag, value})
+
+	return m
+}
+
+func (m *Message) SetInt(tag int, value int) *Message {
+	return m.Set(tag, strconv.Itoa(value))
+}
+
+func (m *Message) SetFloat(tag int, value float64) *Message {
+	return m.Set(tag, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func (m *Message) SetTime(tag int, t time.Time) *Message {
+	return m.Set(tag, t.UTC().Format(timestampFormat))
+}
+
+// Int returns the value of tag as an integer, or a RequiredTagMissingError or
+// IncorrectTagValueError.
+func (m *Message) Int(tag int) (int, error) {
+	value, ok := m.Lookup(tag)
+	if !ok {
+		return 0, &RequiredTagMissingError{tag}
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &IncorrectTagValueError{tag, value}
+	}
+
+	return i, nil
+}
+
+func (m *Message) Float(tag int) (float64, error) {
+	value, ok := m.Lookup(tag)
+	if !ok {
+		return 0, &RequiredTagMissingError{tag}
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, &IncorrectTagValueError{tag, value}
+	}
+
+	return f, nil
+}
+
+// Required returns the value of tag, or a RequiredTagMissingError.
+func (m *Message) Required(tag int) (string, error) {
+	value, ok := m.Lookup(tag)
+	if !ok || value == "" {
+		return "", &RequiredTagMissingError{tag}
+	}
+
+	return value, nil
+}
+
+// Bytes encodes the message with its BodyLength and CheckSum.
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	for _, field := range m.Fields {
+		body.WriteString(strconv.Itoa(field.Tag))
+		body.WriteByte('=')
+		body.WriteString(field.Value)
+		body.WriteByte(soh)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "8=%s\x019=%d\x01", BeginString, body.Len())
+	out.Write(body.Bytes())
+	fmt.Fprintf(&out, "10=%03d\x01", checksum(out.Bytes()))
+
+	return out.Bytes()
+}
+
+// String shows the message with | in place of SOH, e.g. for logs.
+func (m *Message) String() string {
+	return strings.ReplaceAll(string(m.Bytes()), string(soh), "|")
+}
+
+func checksum(data []byte) int {
+	sum := 0
+	for _, b := range data {
+		sum += int(b)
+	}
+
+	return sum % 256
+}
+
+// ReadMessage reads the next message from r, checking its BeginString,
+// BodyLength and CheckSum.
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	beginString, err := readField(r)
+	if err != nil {
+		return nil, err
+	}
+	if beginString.Tag != TagBeginString || beginString.Value != BeginString {
+		return nil, &InvalidMessageError{"expected BeginString " + BeginString}
+	}
+
+	bodyLength, err := readField(r)
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(bodyLength.Value)
+	if bodyLength.Tag != TagBodyLength || err != nil || length <= 0 || length > maxBodyLength {
+		return nil, &InvalidMessageError{"invalid BodyLength"}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	trailer, err := readField(r)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("8=%s\x019=%s\x01", beginString.Value, bodyLength.Value)
+	sum, err := strconv.Atoi(trailer.Value)
+	if trailer.Tag != TagCheckSum || err != nil || sum != checksum(append([]byte(header), body...)) {
+		return nil, &InvalidMessageError{"invalid CheckSum"}
+	}
+
+	return parseBody(body)
+}
+
+func parseBody(body []byte) (*Message, error) {
+	m := &Message{}
+	for _, raw := range bytes.Split(bytes.TrimSuffix(body, []byte{soh}), []byte{soh}) {
+		field, err := parseField(string(raw))
+		if err != nil {
+			return nil, err
+		}
+		m.Fields = append(m.Fields, field)
+	}
+
+	if len(m.Fields) == 0 || m.Fields[0].Tag != TagMsgType {
+		return nil, &InvalidMessageError{"MsgType must be the first field of the body"}
+	}
+
+	return m, nil
+}
+
+// readField reads a header or trailer field. ReadSlice bounds its length by
+// the size of the buffer.
+func readField(r *bufio.Reader) (Field, error) {
+	raw, err := r.ReadSlice(soh)
+	if err == bufio.ErrBufferFull {
+		return Field{}, &InvalidMessageError{"field too long"}
+	}
+	if err != nil {
+		return Field{}, err
+	}
+
+	return parseField(string(raw[:len(raw)-1]))
+}
+
+func parseField(raw string) (Field, error) {
+	tag, value, ok := strings.Cut(raw, "=")
+	if !ok {
+		return Field{}, &InvalidMessageError{"field without =: " + raw}
+	}
+
+	t, err := strconv.Atoi(tag)
+	if err != nil || t <= 0 {
+		return Field{}, &InvalidMessageError{"invalid tag: " + tag}
+	}
+
+	return Field{t, value}, nil
+}
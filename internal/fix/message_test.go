@@ -0,0 +1,43 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageEncoding(t *testing.T) {
+	msg := NewMessage(MsgHeartbeat).Set(TagSenderCompID, "A").Set(TagTargetCompID, "B").SetInt(TagMsgSeqNum, 1)
+	assert.Equal(t, "8=FIX.4.4|9=20|35=0|49=A|56=B|34=1|10=125|", msg.String(), "message should have its BodyLength and CheckSum")
+
+	read, err := ReadMessage(bufio.NewReader(bytes.NewReader(msg.Bytes())))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Fields, read.Fields, "message should read back")
+
+	seq, err := read.Int(TagMsgSeqNum)
+	require.NoError(t, err)
+	assert.Equal(t, 1, seq)
+
+	_, err = read.Float(TagPrice)
+	assert.IsType(t, &RequiredTagMissingError{}, err, "missing field should be reported")
+	_, err = read.Int(TagSenderCompID)
+	assert.IsType(t, &IncorrectTagValueError{}, err, "non-numeric field should be reported")
+}
+
+func TestReadMessageInvalid(t *testing.T) {
+	tests := map[string]string{
+		"wrong version":   "8=FIX.4.2|9=5|35=0|10=000|",
+		"wrong checksum":  "8=FIX.4.4|9=5|35=0|10=000|",
+		"bad body length": "8=FIX.4.4|9=x|35=0|10=000|",
+		"no msg type":     "8=FIX.4.4|9=5|49=A|10=000|",
+	}
+
+	for name, raw := range tests {
+		_, err := ReadMessage(bufio.NewReader(strings.NewReader(strings.ReplaceAll(raw, "|", "\x01"))))
+		assert.IsType(t, &InvalidMessageError{}, err, name)
+	}
+}
@@ -0,0 +1,445 @@
+package fix
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richo225/octgopus/internal/orderbook"
+)
+
+// OrdType, Side, ExecType and OrdStatus values used by the gateway.
+const (
+	ordTypeMarket = "1"
+	ordTypeLimit  = "2"
+
+	sideBuy  = "1"
+	sideSell = "2"
+
+	execNew      = "0"
+	execCanceled = "4"
+	execReplaced = "5"
+	execRejected = "8"
+	execTrade    = "F"
+
+	statusNew             = "0"
+	statusPartiallyFilled = "1"
+	statusFilled          = "2"
+	statusCanceled        = "4"
+	statusRejected        = "8"
+)
+
+// OrdRejReason, CxlRejReason and CxlRejResponseTo values.
+const (
+	ordRejUnknownSymbol  = 1
+	ordRejDuplicateOrder = 6
+	ordRejUnsupported    = 11
+	ordRejOther          = 99
+
+	cxlRejTooLate        = 0
+	cxlRejUnknownOrder   = 1
+	cxlRejPending        = 3
+	cxlRejDuplicateClOrd = 6
+	cxlRejOther          = 99
+
+	cxlRejResponseToCancel  = "1"
+	cxlRejResponseToReplace = "2"
+)
+
+// order is an order placed through a session, tracked to report its fills
+// and cancels.
+type order struct {
+	id          uint64
+	clOrdID     string
+	origClOrdID string
+	pair        orderbook.TradingPair
+	side        orderbook.Side
+	ordType     string
+	price       float64
+	// qty is the OrderQty, which includes the fills before a replace.
+	qty      float64
+	cumQty   float64
+	notional float64
+	leaves   float64
+	filled   bool
+	// cancel is set once the order is being cancelled or replaced, until the
+	// platform reports it cancelled.
+	cancel *pendingCancel
+}
+
+type pendingCancel struct {
+	clOrdID string
+	// replacement is the order that replaces this one, if any.
+	replacement *order
+	text        string
+}
+
+func (o *order) avgPx() float64 {
+	if o.cumQty == 0 {
+		return 0
+	}
+
+	return o.notional / o.cumQty
+}
+
+func (o *order) status() string {
+	switch {
+	case o.filled:
+		return statusFilled
+	case o.cumQty > 0:
+		return statusPartiallyFilled
+	}
+
+	return statusNew
+}
+
+// orders are those of an initiator, kept across its connections.
+type orders struct {
+	// byClOrdID holds every ClOrdID the initiator has used, so that none is
+	// reused, and byID the orders that may still be resting.
+	byClOrdID map[string]*order
+	byID      map[uint64]*order
+}
+
+func newOrders() *orders {
+	return &orders{byClOrdID: make(map[string]*order), byID: make(map[uint64]*order)}
+}
+
+func (s *session) newOrderSingle(msg *Message) {
+	o, err := parseOrder(msg)
+	if err != nil {
+		s.rejectField(msg, err)
+		return
+	}
+
+	if _, used := s.orders.byClOrdID[o.clOrdID]; used {
+		s.rejectOrder(o, ordRejDuplicateOrder, "DuplicateClOrdID : "+o.clOrdID)
+		return
+	}
+	s.orders.byClOrdID[o.clOrdID] = o
+
+	pair, ok := parseSymbol(msg.Get(TagSymbol))
+	if !ok {
+		s.rejectOrder(o, ordRejUnknownSymbol, "UnknownSymbol : "+msg.Get(TagSymbol))
+		return
+	}
+	o.pair = pair
+
+	placed := orderbook.NewOrder(o.side, o.qty)
+	placed.Signer = s.signer
+	switch o.ordType {
+	case ordTypeLimit:
+		err = s.acceptor.platform.PlaceLimitOrder(pair, o.price, placed)
+	case ordTypeMarket:
+		_, err = s.acceptor.platform.PlaceMarketOrder(pair, placed)
+	default:
+		s.rejectOrder(o, ordRejUnsupported, "UnsupportedOrdType : "+o.ordType)
+		return
+	}
+	if err != nil {
+		reason := ordRejOther
+		if _, ok := err.(*orderbook.OrderbookNotFoundError); ok {
+			reason = ordRejUnknownSymbol
+		}
+		s.rejectOrder(o, reason, err.Error())
+		return
+	}
+
+	// The platform reports fills through events, which are handled after
+	// this, so the order is acknowledged first.
+	o.id = placed.ID
+	s.orders.byID[o.id] = o
+	s.report(o, execNew, "")
+}
+
+func (s *session) orderCancelRequest(msg *Message) {
+	clOrdID, err := msg.Required(TagClOrdID)
+	if err != nil {
+		s.rejectField(msg, err)
+		return
+	}
+	origClOrdID, err := msg.Required(TagOrigClOrdID)
+	if err != nil {
+		s.rejectField(msg, err)
+		return
+	}
+
+	o, reason, text := s.cancellable(clOrdID, origClOrdID)
+	if o == nil {
+		s.rejectCancel(clOrdID, origClOrdID, cxlRejResponseToCancel, reason, text)
+		return
+	}
+
+	if _, err := s.acceptor.platform.CancelOrder(o.pair, o.id); err != nil {
+		s.rejectCancel(clOrdID, origClOrdID, cxlRejResponseToCancel, cancelRejectReason(err), err.Error())
+		return
+	}
+
+	s.orders.byClOrdID[clOrdID] = o
+	o.cancel = &pendingCancel{clOrdID: clOrdID}
+}
+
+// orderCancelReplaceRequest replaces a limit order by cancelling it and
+// placing the remaining quantity at the new price, which loses its time
+// priority.
+func (s *session) orderCancelReplaceRequest(msg *Message) {
+	r, err := parseOrder(msg)
+	if err != nil {
+		s.rejectField(msg, err)
+		return
+	}
+	origClOrdID, err := msg.Required(TagOrigClOrdID)
+	if err != nil {
+		s.rejectField(msg, err)
+		return
+	}
+
+	o, reason, text := s.cancellable(r.clOrdID, origClOrdID)
+	switch {
+	case o == nil:
+	case r.ordType != ordTypeLimit || o.ordType != ordTypeLimit:
+		reason, text = cxlRejOther, "only limit orders can be replaced"
+	case r.side != o.side || msg.Get(TagSymbol) != symbol(o.pair):
+		reason, text = cxlRejOther, "Side and Symbol cannot be replaced"
+	case r.qty <= o.cumQty:
+		reason, text = cxlRejOther, "OrderQty must exceed CumQty"
+	default:
+		text = ""
+	}
+	if text != "" {
+		s.rejectCancel(r.clOrdID, origClOrdID, cxlRejResponseToReplace, reason, text)
+		return
+	}
+
+	cancelled, err := s.acceptor.platform.CancelOrder(o.pair, o.id)
+	if err != nil {
+		s.rejectCancel(r.clOrdID, origClOrdID, cxlRejResponseToReplace, cancelRejectReason(err), err.Error())
+		return
+	}
+	s.orders.byClOrdID[r.clOrdID] = r
+	o.cancel = &pendingCancel{clOrdID: r.clOrdID}
+
+	// Fills still to be reported may have reduced the order since cumQty was
+	// checked, so what was filled is taken from the platform.
+	filled := o.qty - cancelled.Size
+	leaves := r.qty - filled
+	if leaves <= 0 {
+		o.cancel.text = "replace failed: OrderQty must exceed CumQty"
+		return
+	}
+
+	placed := orderbook.NewOrder(r.side, leaves)
+	placed.Signer = s.signer
+	if err := s.acceptor.platform.PlaceLimitOrder(o.pair, r.price, placed); err != nil {
+		o.cancel.text = "replace failed: " + err.Error()
+		return
+	}
+
+	r.id, r.pair, r.origClOrdID, r.leaves = placed.ID, o.pair, origClOrdID, leaves
+	s.orders.byID[r.id] = r
+	o.cancel.replacement = r
+}
+
+// cancellable finds the live order a cancel or replace refers to, or returns
+// why it cannot be cancelled.
+func (s *session) cancellable(clOrdID string, origClOrdID string) (*order, int, string) {
+	if _, used := s.orders.byClOrdID[clOrdID]; used {
+		return nil, cxlRejDuplicateClOrd, "DuplicateClOrdID : " + clOrdID
+	}
+
+	o, ok := s.orders.byClOrdID[origClOrdID]
+	if !ok || o.id == 0 {
+		return nil, cxlRejUnknownOrder, "UnknownOrder : " + origClOrdID
+	}
+	if _, live := s.orders.byID[o.id]; !live {
+		return nil, cxlRejTooLate, "order is no longer resting"
+	}
+	if o.cancel != nil {
+		return nil, cxlRejPending, "order is already being cancelled or replaced"
+	}
+
+	return o, 0, ""
+}
+
+func cancelRejectReason(err error) int {
+	if _, ok := err.(*orderbook.OrderNotFoundError); ok {
+		return cxlRejTooLate
+	}
+
+	return cxlRejOther
+}
+
+func (s *session) orderFilled(e *orderbook.OrderFilled) {
+	o, ok := s.orders.byID[e.OrderID]
+	if !ok {
+		return
+	}
+
+	o.cumQty += e.SizeFilled
+	o.notional += e.SizeFilled * e.Price
+	o.leaves = e.Remaining
+	if o.leaves <= 0 {
+		o.filled = true
+		delete(s.orders.byID, o.id)
+	}
+
+	report := s.executionReport(o, execTrade, o.status())
+	report.SetFloat(TagLastQty, e.SizeFilled).SetFloat(TagLastPx, e.Price)
+	s.send(report)
+}
+
+// orderCancelled reports an order cancelled at the initiator's request, as
+// part of a replace, or by the platform, e.g. the unfilled part of a market
+// order.
+func (s *session) orderCancelled(e *orderbook.OrderCancelled) {
+	o, ok := s.orders.byID[e.Order.ID]
+	if !ok {
+		return
+	}
+	delete(s.orders.byID, o.id)
+	o.leaves = 0
+
+	switch {
+	case o.cancel == nil:
+		s.report(o, execCanceled, "")
+	case o.cancel.replacement != nil:
+		r := o.cancel.replacement
+		r.cumQty, r.notional = o.cumQty, o.notional
+		r.leaves = r.qty - r.cumQty
+		s.report(r, execReplaced, "")
+	default:
+		report := s.executionReport(o, execCanceled, statusCanceled)
+		report.Set(TagClOrdID, o.cancel.clOrdID).Set(TagOrigClOrdID, o.clOrdID)
+		if o.cancel.text != "" {
+			report.Set(TagText, o.cancel.text)
+		}
+		s.send(report)
+	}
+}
+
+func (s *session) report(o *order, execType string, text string) {
+	status := o.status()
+	if execType == execCanceled {
+		status = statusCanceled
+	}
+
+	report := s.executionReport(o, execType, status)
+	if text != "" {
+		report.Set(TagText, text)
+	}
+	s.send(report)
+}
+
+func (s *session) rejectOrder(o *order, reason int, text string) {
+	report := s.executionReport(o, execRejected, statusRejected)
+	report.Set(TagOrderID, "NONE").SetInt(TagOrdRejReason, reason).Set(TagText, text)
+	s.send(report)
+}
+
+func (s *session) rejectCancel(clOrdID string, origClOrdID string, responseTo string, reason int, text string) {
+	// The status is that of the order the request referred to, as far as
+	// the session knows it.
+	status := statusRejected
+	if o, ok := s.orders.byClOrdID[origClOrdID]; ok && o.id != 0 {
+		status = o.status()
+		if _, live := s.orders.byID[o.id]; !live && status != statusFilled {
+			status = statusCanceled
+		}
+	}
+
+	s.send(NewMessage(MsgOrderCancelReject).
+		Set(TagOrderID, "NONE").
+		Set(TagClOrdID, clOrdID).
+		Set(TagOrigClOrdID, origClOrdID).
+		Set(TagOrdStatus, status).
+		Set(TagCxlRejResponseTo, responseTo).
+		SetInt(TagCxlRejReason, reason).
+		Set(TagText, text))
+}
+
+func (s *session) executionReport(o *order, execType string, status string) *Message {
+	report := NewMessage(MsgExecutionReport).
+		Set(TagOrderID, strconv.FormatUint(o.id, 10)).
+		Set(TagClOrdID, o.clOrdID)
+	if o.origClOrdID != "" {
+		report.Set(TagOrigClOrdID, o.origClOrdID)
+	}
+	report.Set(TagExecID, s.acceptor.nextExecID()).
+		Set(TagExecType, execType).
+		Set(TagOrdStatus, status).
+		Set(TagSymbol, symbol(o.pair)).
+		Set(TagSide, fixSide(o.side)).
+		SetFloat(TagOrderQty, o.qty).
+		Set(TagOrdType, o.ordType)
+	if o.ordType == ordTypeLimit {
+		report.SetFloat(TagPrice, o.price)
+	}
+
+	return report.
+		SetFloat(TagLeavesQty, o.leaves).
+		SetFloat(TagCumQty, o.cumQty).
+		SetFloat(TagAvgPx, o.avgPx()).
+		SetTime(TagTransactTime, time.Now())
+}
+
+// parseOrder reads the order fields shared by NewOrderSingle and
+// OrderCancelReplaceRequest. The symbol is left to the caller.
+func parseOrder(msg *Message) (*order, error) {
+	clOrdID, err := msg.Required(TagClOrdID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := msg.Required(TagSymbol); err != nil {
+		return nil, err
+	}
+
+	o := &order{clOrdID: clOrdID}
+	switch side := msg.Get(TagSide); side {
+	case sideBuy:
+		o.side = orderbook.Bid
+	case sideSell:
+		o.side = orderbook.Ask
+	case "":
+		return nil, &RequiredTagMissingError{TagSide}
+	default:
+		return nil, &IncorrectTagValueError{TagSide, side}
+	}
+
+	if o.qty, err = msg.Float(TagOrderQty); err != nil {
+		return nil, err
+	}
+	o.leaves = o.qty
+
+	if o.ordType, err = msg.Required(TagOrdType); err != nil {
+		return nil, err
+	}
+	if o.ordType == ordTypeLimit {
+		if o.price, err = msg.Float(TagPrice); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+// parseSymbol reads a Symbol of the form BASE/QUOTE.
+func parseSymbol(s string) (orderbook.TradingPair, bool) {
+	base, quote, ok := strings.Cut(s, "/")
+	if !ok || base == "" || quote == "" {
+		return orderbook.TradingPair{}, false
+	}
+
+	return orderbook.NewTradingPair(base, quote), true
+}
+
+func symbol(pair orderbook.TradingPair) string {
+	return pair.ToString()
+}
+
+func fixSide(side orderbook.Side) string {
+	if side == orderbook.Ask {
+		return sideSell
+	}
+
+	return sideBuy
+}
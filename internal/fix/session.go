@@ -0,0 +1,401 @@
+package fix
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/kr/pretty"
+	"github.com/richo225/octgopus/internal/orderbook"
+)
+
+// compIDPattern keeps CompIDs safe to use as file names.
+var compIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// session is one logged on connection of an initiator. Everything but
+// reading from the connection happens on the goroutine that calls run, so
+// the session needs no locking.
+type session struct {
+	acceptor *Acceptor
+	conn     net.Conn
+	reader   *bufio.Reader
+
+	// id is the initiator's SenderCompID, and signer the account its orders
+	// are placed for.
+	id      string
+	signer  string
+	orders  *orders
+	seqNums SeqNums
+
+	heartBtInt   time.Duration
+	lastSent     time.Time
+	lastReceived time.Time
+	// testRequested is set while a TestRequest is unanswered.
+	testRequested bool
+	// resendUpTo is the MsgSeqNum that made the gateway ask for a resend,
+	// until the gap is filled.
+	resendUpTo uint64
+}
+
+// logon reads the Logon that must open a connection and answers it. A
+// refused logon is answered with a Logout.
+func (s *session) logon() error {
+	s.conn.SetReadDeadline(time.Now().Add(logonTimeout))
+	msg, err := ReadMessage(s.reader)
+	if err != nil {
+		return err
+	}
+	s.conn.SetReadDeadline(time.Time{})
+
+	if msg.Type() != MsgLogon {
+		return &LogonError{"first message must be a Logon"}
+	}
+
+	if err := s.accept(msg); err != nil {
+		logout := NewMessage(MsgLogout).Set(TagText, err.Error())
+		s.write(logout, 1, msg.Get(TagSenderCompID))
+		return err
+	}
+
+	return nil
+}
+
+// accept checks a Logon and, when it is accepted, loads the session and
+// replies with a Logon.
+func (s *session) accept(msg *Message) error {
+	id := msg.Get(TagSenderCompID)
+	if !compIDPattern.MatchString(id) {
+		return &LogonError{"invalid SenderCompID"}
+	}
+	if target := msg.Get(TagTargetCompID); target != s.acceptor.config.CompID {
+		return &LogonError{"unknown TargetCompID " + target}
+	}
+	if method := msg.Get(TagEncryptMethod); method != "" && method != "0" {
+		return &LogonError{"EncryptMethod must be 0"}
+	}
+	heartBtInt, err := msg.Int(TagHeartBtInt)
+	if err != nil || heartBtInt <= 0 {
+		return &LogonError{"HeartBtInt must be a positive number of seconds"}
+	}
+	seq, err := msg.Int(TagMsgSeqNum)
+	if err != nil || seq <= 0 {
+		return &LogonError{"invalid MsgSeqNum"}
+	}
+
+	signer := id
+	if keys := s.acceptor.config.Keys; keys != nil {
+		key, ok := keys.Get(msg.Get(TagUsername))
+		if !ok || !hmac.Equal([]byte(key.Secret), []byte(msg.Get(TagPassword))) {
+			return &LogonError{"invalid Username or Password"}
+		}
+		signer = key.Signer
+	}
+
+	orders, err := s.acceptor.acquire(id)
+	if err != nil {
+		return err
+	}
+
+	reset := msg.Get(TagResetSeqNumFlag) == "Y"
+	seqNums := initialSeqNums
+	if !reset {
+		if seqNums, err = s.acceptor.config.Store.Load(id); err != nil {
+			s.acceptor.release(id)
+			return err
+		}
+	}
+	if uint64(seq) < seqNums.NextTarget {
+		s.acceptor.release(id)
+		return &LogonError{"MsgSeqNum too low, expecting " + strconv.FormatUint(seqNums.NextTarget, 10) + " but received " + strconv.Itoa(seq)}
+	}
+
+	s.id, s.signer, s.orders, s.seqNums = id, signer, orders, seqNums
+	s.heartBtInt = time.Duration(heartBtInt) * time.Second
+	s.lastReceived = time.Now()
+
+	reply := NewMessage(MsgLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, heartBtInt)
+	if reset {
+		reply.Set(TagResetSeqNumFlag, "Y")
+	}
+	s.send(reply)
+
+	if uint64(seq) > s.seqNums.NextTarget {
+		s.requestResend(uint64(seq))
+	} else {
+		s.seqNums.NextTarget++
+		s.save()
+	}
+
+	return nil
+}
+
+// run processes messages from the initiator and platform events until the
+// session logs out or the connection fails.
+func (s *session) run() {
+	sub := s.acceptor.platform.Subscribe()
+	defer sub.Unsubscribe()
+
+	messages := make(chan *Message)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			msg, err := ReadMessage(s.reader)
+			if err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+	// The reader may be blocked sending a message when the session ends.
+	defer func() {
+		s.conn.Close()
+		for {
+			select {
+			case <-messages:
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(s.heartBtInt / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-messages:
+			s.lastReceived = time.Now()
+			s.testRequested = false
+			if !s.receive(msg) {
+				return
+			}
+		case event := <-sub.C:
+			s.handleEvent(event)
+		case <-stopped:
+			return
+		case now := <-ticker.C:
+			if !s.heartbeat(now) {
+				return
+			}
+		}
+	}
+}
+
+// heartbeat sends a Heartbeat when nothing has been sent for HeartBtInt, and
+// a TestRequest when nothing has been received. It returns false once the
+// TestRequest goes unanswered.
+func (s *session) heartbeat(now time.Time) bool {
+	if now.Sub(s.lastSent) >= s.heartBtInt {
+		s.send(NewMessage(MsgHeartbeat))
+	}
+
+	// Allow a fifth of the interval for the heartbeat to arrive.
+	silence := now.Sub(s.lastReceived)
+	grace := s.heartBtInt + s.heartBtInt/5
+	switch {
+	case !s.testRequested && silence >= grace:
+		s.send(NewMessage(MsgTestRequest).Set(TagTestReqID, now.UTC().Format(timestampFormat)))
+		s.testRequested = true
+	case s.testRequested && silence >= grace+s.heartBtInt:
+		s.send(NewMessage(MsgLogout).Set(TagText, "Heartbeat timeout"))
+		return false
+	}
+
+	return true
+}
+
+// receive checks the sequence number of a message and processes it. It
+// returns false when the session should end.
+func (s *session) receive(msg *Message) bool {
+	if msg.Get(TagSenderCompID) != s.id || msg.Get(TagTargetCompID) != s.acceptor.config.CompID {
+		s.reject(msg, 9, TagSenderCompID, "CompID problem")
+		s.send(NewMessage(MsgLogout).Set(TagText, "CompID problem"))
+		return false
+	}
+
+	seq, err := msg.Int(TagMsgSeqNum)
+	if err != nil || seq <= 0 {
+		s.send(NewMessage(MsgLogout).Set(TagText, "MsgSeqNum missing"))
+		return false
+	}
+
+	if msg.Type() == MsgSequenceReset && msg.Get(TagGapFillFlag) != "Y" {
+		s.sequenceReset(msg)
+		return true
+	}
+
+	switch expected := s.seqNums.NextTarget; {
+	case uint64(seq) < expected:
+		if msg.Get(TagPossDupFlag) == "Y" {
+			return true
+		}
+		s.send(NewMessage(MsgLogout).Set(TagText, "MsgSeqNum too low, expecting "+strconv.FormatUint(expected, 10)+" but received "+strconv.Itoa(seq)))
+		return false
+	case uint64(seq) > expected:
+		// Messages after a gap are dropped, to be resent with the gap.
+		if msg.Type() == MsgLogout {
+			s.send(NewMessage(MsgLogout))
+			return false
+		}
+		s.requestResend(uint64(seq))
+		return true
+	}
+
+	if msg.Type() == MsgSequenceReset {
+		s.sequenceReset(msg)
+		return true
+	}
+
+	s.seqNums.NextTarget++
+	if s.seqNums.NextTarget > s.resendUpTo {
+		s.resendUpTo = 0
+	}
+	s.save()
+
+	return s.dispatch(msg)
+}
+
+func (s *session) dispatch(msg *Message) bool {
+	switch msg.Type() {
+	case MsgHeartbeat, MsgReject:
+	case MsgTestRequest:
+		s.send(NewMessage(MsgHeartbeat).Set(TagTestReqID, msg.Get(TagTestReqID)))
+	case MsgResendRequest:
+		s.gapFill(msg)
+	case MsgLogout:
+		s.send(NewMessage(MsgLogout))
+		return false
+	case MsgNewOrderSingle:
+		s.newOrderSingle(msg)
+	case MsgOrderCancelRequest:
+		s.orderCancelRequest(msg)
+	case MsgOrderCancelReplaceRequest:
+		s.orderCancelReplaceRequest(msg)
+	case MsgLogon:
+		s.reject(msg, 11, TagMsgType, "already logged on")
+	default:
+		s.reject(msg, 11, TagMsgType, "unsupported MsgType "+msg.Type())
+	}
+
+	return true
+}
+
+// sequenceReset moves the expected MsgSeqNum forward, either past a gap the
+// initiator will not resend or, without GapFillFlag, unconditionally.
+func (s *session) sequenceReset(msg *Message) {
+	newSeqNo, err := msg.Int(TagNewSeqNo)
+	if err != nil {
+		s.rejectField(msg, err)
+		return
+	}
+
+	if uint64(newSeqNo) <= s.seqNums.NextTarget {
+		s.reject(msg, 5, TagNewSeqNo, "NewSeqNo must be greater than the expected MsgSeqNum")
+		return
+	}
+
+	s.seqNums.NextTarget = uint64(newSeqNo)
+	if s.seqNums.NextTarget > s.resendUpTo {
+		s.resendUpTo = 0
+	}
+	s.save()
+}
+
+// requestResend asks for the messages from the expected MsgSeqNum on, unless
+// they have already been asked for.
+func (s *session) requestResend(seq uint64) {
+	if s.resendUpTo != 0 {
+		return
+	}
+
+	s.resendUpTo = seq
+	s.send(NewMessage(MsgResendRequest).Set(TagBeginSeqNo, strconv.FormatUint(s.seqNums.NextTarget, 10)).SetInt(TagEndSeqNo, 0))
+}
+
+// gapFill answers a ResendRequest. Sent messages are not kept, so the whole
+// range is skipped with a SequenceReset; initiators reconcile their orders
+// with the HTTP API if they missed reports.
+func (s *session) gapFill(msg *Message) {
+	begin, err := msg.Int(TagBeginSeqNo)
+	if err != nil {
+		s.rejectField(msg, err)
+		return
+	}
+	if begin <= 0 || uint64(begin) >= s.seqNums.NextSender {
+		s.reject(msg, 5, TagBeginSeqNo, "BeginSeqNo out of range")
+		return
+	}
+
+	reset := NewMessage(MsgSequenceReset).
+		Set(TagGapFillFlag, "Y").
+		Set(TagNewSeqNo, strconv.FormatUint(s.seqNums.NextSender, 10)).
+		Set(TagPossDupFlag, "Y")
+	s.write(reset, uint64(begin), s.id)
+}
+
+// reject sends a session level Reject of msg.
+func (s *session) reject(msg *Message, reason int, tag int, text string) {
+	s.send(NewMessage(MsgReject).
+		Set(TagRefSeqNum, msg.Get(TagMsgSeqNum)).
+		SetInt(TagRefTagID, tag).
+		Set(TagRefMsgType, msg.Type()).
+		SetInt(TagSessionRejectReason, reason).
+		Set(TagText, text))
+}
+
+// rejectField rejects msg for a field error returned by Message.
+func (s *session) rejectField(msg *Message, err error) {
+	reason, tag, ok := rejectReason(err)
+	if !ok {
+		reason, tag = 99, 0
+	}
+
+	s.reject(msg, reason, tag, err.Error())
+}
+
+// send writes msg with the next MsgSeqNum. The sequence numbers are saved
+// first, so a MsgSeqNum is never reused after a restart.
+func (s *session) send(msg *Message) {
+	seq := s.seqNums.NextSender
+	s.seqNums.NextSender++
+	s.save()
+	s.write(msg, seq, s.id)
+}
+
+// write writes msg with its header. Write errors end the session through the
+// reader, which fails once the connection is broken.
+func (s *session) write(msg *Message, seq uint64, target string) {
+	header := []Field{
+		{TagMsgType, msg.Type()},
+		{TagSenderCompID, s.acceptor.config.CompID},
+		{TagTargetCompID, target},
+		{TagMsgSeqNum, strconv.FormatUint(seq, 10)},
+		{TagSendingTime, time.Now().UTC().Format(timestampFormat)},
+	}
+	msg.Fields = append(header, msg.Fields[1:]...)
+
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := s.conn.Write(msg.Bytes()); err != nil {
+		s.conn.Close()
+	}
+	s.lastSent = time.Now()
+}
+
+func (s *session) save() {
+	if err := s.acceptor.config.Store.Save(s.id, s.seqNums); err != nil {
+		pretty.Log("Failed to save FIX sequence numbers", s.id, err.Error())
+	}
+}
+
+func (s *session) handleEvent(event orderbook.Event) {
+	switch e := event.(type) {
+	case *orderbook.OrderFilled:
+		s.orderFilled(e)
+	case *orderbook.OrderCancelled:
+		s.orderCancelled(e)
+	}
+}
@@ -0,0 +1,115 @@
+package fix
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SeqNums are the next MsgSeqNum the gateway will send on a session and the
+// next it expects to receive.
+type SeqNums struct {
+	NextSender uint64 `json:"next_sender"`
+	NextTarget uint64 `json:"next_target"`
+}
+
+// initialSeqNums start a session that has never logged on, or was reset.
+var initialSeqNums = SeqNums{NextSender: 1, NextTarget: 1}
+
+// SeqStore persists the sequence numbers of each session, keyed by the
+// initiator's SenderCompID, so that a session continues where it left off
+// after a reconnect or a restart.
+type SeqStore interface {
+	Load(session string) (SeqNums, error)
+	Save(session string, seqNums SeqNums) error
+}
+
+// MemoryStore keeps sequence numbers until the process exits.
+type MemoryStore struct {
+	sessions map[string]SeqNums
+	mu       sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]SeqNums)}
+}
+
+func (s *MemoryStore) Load(session string) (SeqNums, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if seqNums, ok := s.sessions[session]; ok {
+		return seqNums, nil
+	}
+
+	return initialSeqNums, nil
+}
+
+func (s *MemoryStore) Save(session string, seqNums SeqNums) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session] = seqNums
+	return nil
+}
+
+// FileStore keeps the sequence numbers of each session in a JSON file named
+// after it in Dir.
+type FileStore struct {
+	Dir string
+}
+
+func (s *FileStore) path(session string) string {
+	return filepath.Join(s.Dir, filepath.Base(session)+".seqnums.json")
+}
+
+func (s *FileStore) Load(session string) (SeqNums, error) {
+	data, err := os.ReadFile(s.path(session))
+	if os.IsNotExist(err) {
+		return initialSeqNums, nil
+	}
+	if err != nil {
+		return SeqNums{}, err
+	}
+
+	var seqNums SeqNums
+	if err := json.Unmarshal(data, &seqNums); err != nil {
+		return SeqNums{}, err
+	}
+
+	return seqNums, nil
+}
+
+// Save writes the sequence numbers atomically, so a crash leaves either the
+// old or the new ones.
+func (s *FileStore) Save(session string, seqNums SeqNums) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(seqNums)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, "seqnums-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(session))
+}
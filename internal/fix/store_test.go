@@ -0,0 +1,25 @@
+package fix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	store := &FileStore{Dir: t.TempDir()}
+
+	seqNums, err := store.Load("ALICE")
+	require.NoError(t, err)
+	assert.Equal(t, initialSeqNums, seqNums, "new session should start at 1")
+
+	require.NoError(t, store.Save("ALICE", SeqNums{NextSender: 7, NextTarget: 3}))
+	seqNums, err = (&FileStore{Dir: store.Dir}).Load("ALICE")
+	require.NoError(t, err)
+	assert.Equal(t, SeqNums{NextSender: 7, NextTarget: 3}, seqNums, "sequence numbers should survive a restart")
+
+	seqNums, err = store.Load("BOB")
+	require.NoError(t, err)
+	assert.Equal(t, initialSeqNums, seqNums, "sessions should be kept apart")
+}
@@ -0,0 +1,108 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/ratelimit"
+	"github.com/richo225/octgopus/pkg/tradingpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type apiKeyContextKey struct{}
+
+// authenticate requires every unary call to be signed with an API key, and
+// makes the key available to authorize. With a nil verifier authentication is
+// disabled and every call is let through.
+func authenticate(verifier *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if verifier == nil {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		credentials := auth.Credentials{
+			KeyID:     first(md, tradingpb.KeyMetadata),
+			Timestamp: first(md, tradingpb.TimestampMetadata),
+			Nonce:     first(md, tradingpb.NonceMetadata),
+			Signature: first(md, tradingpb.SignatureMetadata),
+		}
+
+		body, err := tradingpb.SignedBody(req)
+		if err != nil {
+			return nil, invalidArgument(err.Error())
+		}
+
+		key, err := verifier.VerifyCredentials(credentials, http.MethodPost, info.FullMethod, body)
+		if err != nil {
+			return nil, statusOf(err)
+		}
+
+		return handler(context.WithValue(ctx, apiKeyContextKey{}, key), req)
+	}
+}
+
+// rateLimit refuses calls once the signer or IP has used up the bucket of
+// the method. Methods without a limiter are not limited. It must run after
+// authenticate so that the signer is known.
+func rateLimit(limiters map[string]*ratelimit.PolicyLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if limiter, ok := limiters[info.FullMethod]; ok {
+			if err := limiter.Allow(signer(ctx), peerIP(ctx)); err != nil {
+				return nil, statusOf(err)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// authorize checks that the authenticated signer may act for signer.
+func authorize(ctx context.Context, signer string) error {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*auth.Key)
+	if !ok {
+		return nil
+	}
+
+	if err := key.Authorize(signer); err != nil {
+		return statusOf(err)
+	}
+
+	return nil
+}
+
+// signer is the authenticated signer, or empty when authentication is
+// disabled.
+func signer(ctx context.Context) string {
+	if key, ok := ctx.Value(apiKeyContextKey{}).(*auth.Key); ok {
+		return key.Signer
+	}
+
+	return ""
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,113 @@
+package grpcapi
+
+import (
+	"net/http"
+
+	"github.com/richo225/octgopus/internal/accounting"
+	"github.com/richo225/octgopus/internal/api"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/pkg/tradingpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusOf turns a platform error into a gRPC status, with the code closest to
+// the HTTP status the HTTP API would respond with.
+func statusOf(err error) error {
+	code := codes.Internal
+	if he, ok := err.(api.HTTPError); ok {
+		switch he.HTTPCode() {
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			code = codes.InvalidArgument
+		case http.StatusUnauthorized:
+			code = codes.Unauthenticated
+		case http.StatusForbidden:
+			code = codes.PermissionDenied
+		case http.StatusNotFound:
+			code = codes.NotFound
+		case http.StatusConflict:
+			code = codes.FailedPrecondition
+		case http.StatusTooManyRequests:
+			code = codes.ResourceExhausted
+		}
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func invalidArgument(message string) error {
+	return status.Error(codes.InvalidArgument, message)
+}
+
+func marketOf(market *tradingpb.Market) (orderbook.TradingPair, error) {
+	if market.GetBase() == "" || market.GetQuote() == "" {
+		return orderbook.TradingPair{}, invalidArgument("InvalidMarket : market must have a base and a quote")
+	}
+
+	return orderbook.NewTradingPair(market.GetBase(), market.GetQuote()), nil
+}
+
+// sideOf leaves an unspecified side empty, for the platform to reject.
+func sideOf(side tradingpb.Side) orderbook.Side {
+	switch side {
+	case tradingpb.Side_SIDE_BID:
+		return orderbook.Bid
+	case tradingpb.Side_SIDE_ASK:
+		return orderbook.Ask
+	}
+
+	return ""
+}
+
+func sideToProto(side orderbook.Side) tradingpb.Side {
+	switch side {
+	case orderbook.Bid:
+		return tradingpb.Side_SIDE_BID
+	case orderbook.Ask:
+		return tradingpb.Side_SIDE_ASK
+	}
+
+	return tradingpb.Side_SIDE_UNSPECIFIED
+}
+
+func orderOf(order *orderbook.Order) *tradingpb.Order {
+	if order == nil {
+		return nil
+	}
+
+	return &tradingpb.Order{
+		Id:                  order.ID,
+		Side:                sideToProto(order.Side),
+		Price:               order.Price,
+		Size:                order.Size,
+		Timestamp:           order.Timestamp,
+		Signer:              order.Signer,
+		SelfTradePrevention: string(order.SelfTradePrevention),
+	}
+}
+
+func matchesOf(matches []orderbook.Match) []*tradingpb.Match {
+	var out []*tradingpb.Match
+	for _, match := range matches {
+		out = append(out, &tradingpb.Match{
+			Ask:        orderOf(match.Ask),
+			Bid:        orderOf(match.Bid),
+			SizeFilled: match.SizeFilled,
+			Price:      match.Price,
+			AskFee:     match.AskFee,
+			BidFee:     match.BidFee,
+		})
+	}
+
+	return out
+}
+
+func txOf(tx *accounting.Tx) *tradingpb.Tx {
+	return &tradingpb.Tx{
+		Id:        tx.ID,
+		Action:    string(tx.Action),
+		Signer:    tx.Signer,
+		Amount:    tx.Amount,
+		Timestamp: tx.Timestamp,
+	}
+}
@@ -0,0 +1,191 @@
+// Package grpcapi serves order entry, accounts and market data over gRPC,
+// alongside the HTTP API and from the same TradingPlatform.
+package grpcapi
+
+import (
+	"context"
+	"net"
+
+	"github.com/kr/pretty"
+	"github.com/richo225/octgopus/internal/accounting"
+	"github.com/richo225/octgopus/internal/api"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/internal/ratelimit"
+	"github.com/richo225/octgopus/pkg/tradingpb"
+	"google.golang.org/grpc"
+)
+
+type Config struct {
+	// Verifier authenticates every call but StreamMarketData. Authentication
+	// is disabled when it is nil.
+	Verifier *auth.Verifier
+	// RateLimits applies the HTTP API policies to the matching calls. The
+	// buckets are separate from those of the HTTP API.
+	RateLimits api.RateLimits
+	// Clock drives the rate limits, defaulting to the system clock.
+	Clock ratelimit.Clock
+}
+
+type server struct {
+	tradingpb.UnimplementedTradingServer
+	platform *orderbook.TradingPlatform
+	reads    *ratelimit.PolicyLimiter
+}
+
+// NewServer returns a gRPC server for p, ready to Serve a listener.
+func NewServer(p *orderbook.TradingPlatform, config Config) *grpc.Server {
+	reads := ratelimit.NewPolicyLimiter(config.RateLimits.Reads, config.Clock)
+	limits := map[string]*ratelimit.PolicyLimiter{
+		tradingpb.Trading_PlaceOrder_FullMethodName:  ratelimit.NewPolicyLimiter(config.RateLimits.Orders, config.Clock),
+		tradingpb.Trading_CancelOrder_FullMethodName: ratelimit.NewPolicyLimiter(config.RateLimits.Cancels, config.Clock),
+		tradingpb.Trading_GetAccount_FullMethodName:  reads,
+	}
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(authenticate(config.Verifier), rateLimit(limits)))
+	tradingpb.RegisterTradingServer(s, &server{platform: p, reads: reads})
+
+	return s
+}
+
+// Start serves p on addr until the listener fails.
+func Start(p *orderbook.TradingPlatform, config Config, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	pretty.Log("Starting gRPC server on", lis.Addr().String())
+	return NewServer(p, config).Serve(lis)
+}
+
+// Orders
+func (s *server) PlaceOrder(ctx context.Context, req *tradingpb.PlaceOrderRequest) (*tradingpb.PlaceOrderResponse, error) {
+	pair, err := marketOf(req.GetMarket())
+	if err != nil {
+		return nil, err
+	}
+
+	order := orderbook.NewOrder(sideOf(req.GetSide()), req.GetSize())
+	order.Signer = signer(ctx)
+	order.SelfTradePrevention = orderbook.SelfTradePrevention(req.GetSelfTradePrevention())
+
+	var orderType orderbook.OrderType
+	switch req.GetType() {
+	case tradingpb.OrderType_ORDER_TYPE_LIMIT:
+		orderType = orderbook.LimitOrder
+	case tradingpb.OrderType_ORDER_TYPE_MARKET:
+		orderType = orderbook.MarketOrder
+	default:
+		return nil, invalidArgument("InvalidOrder : type must be limit or market")
+	}
+
+	placed, matches, err := s.platform.PlaceOrder(pair, orderType, req.GetPrice(), order)
+	if err != nil {
+		return nil, statusOf(err)
+	}
+
+	return &tradingpb.PlaceOrderResponse{Order: orderOf(&placed), Matches: matchesOf(matches)}, nil
+}
+
+func (s *server) CancelOrder(ctx context.Context, req *tradingpb.CancelOrderRequest) (*tradingpb.Order, error) {
+	pair, err := marketOf(req.GetMarket())
+	if err != nil {
+		return nil, err
+	}
+
+	resting, err := s.platform.GetOrder(pair, req.GetId())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	if err := authorize(ctx, resting.Signer); err != nil {
+		return nil, err
+	}
+
+	order, err := s.platform.CancelOrder(pair, req.GetId())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+
+	return orderOf(order), nil
+}
+
+// Accounting
+func (s *server) CreateAccount(ctx context.Context, req *tradingpb.AccountRequest) (*tradingpb.Account, error) {
+	if err := authorize(ctx, req.GetSigner()); err != nil {
+		return nil, err
+	}
+	if err := accounting.ValidateSigner(req.GetSigner()); err != nil {
+		return nil, statusOf(err)
+	}
+
+	if err := s.platform.CreateAccount(req.GetSigner()); err != nil {
+		return nil, statusOf(err)
+	}
+
+	return &tradingpb.Account{Signer: req.GetSigner()}, nil
+}
+
+func (s *server) GetAccount(ctx context.Context, req *tradingpb.AccountRequest) (*tradingpb.Account, error) {
+	if err := authorize(ctx, req.GetSigner()); err != nil {
+		return nil, err
+	}
+
+	balance, err := s.platform.Accounts.BalanceOf(req.GetSigner())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+
+	return &tradingpb.Account{Signer: req.GetSigner(), Balance: balance}, nil
+}
+
+func (s *server) Deposit(ctx context.Context, req *tradingpb.AmountRequest) (*tradingpb.Tx, error) {
+	if err := authorize(ctx, req.GetSigner()); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.platform.Deposit(req.GetSigner(), req.GetAmount())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+
+	return txOf(tx), nil
+}
+
+func (s *server) Withdraw(ctx context.Context, req *tradingpb.AmountRequest) (*tradingpb.Tx, error) {
+	if err := authorize(ctx, req.GetSigner()); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.platform.Withdraw(req.GetSigner(), req.GetAmount())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+
+	return txOf(tx), nil
+}
+
+func (s *server) Send(ctx context.Context, req *tradingpb.SendRequest) (*tradingpb.SendResponse, error) {
+	if err := authorize(ctx, req.GetSigner()); err != nil {
+		return nil, err
+	}
+	if err := accounting.ValidateSigner(req.GetRecipient()); err != nil {
+		return nil, statusOf(err)
+	}
+
+	opts := accounting.SendOptions{
+		CreateRecipient: req.GetCreateRecipient(),
+		IdempotencyKey:  req.GetIdempotencyKey(),
+	}
+	txs, err := s.platform.SendWithOptions(req.GetSigner(), req.GetRecipient(), req.GetAmount(), opts)
+	if err != nil {
+		return nil, statusOf(err)
+	}
+
+	resp := &tradingpb.SendResponse{}
+	for _, tx := range txs {
+		resp.Txs = append(resp.Txs, txOf(tx))
+	}
+
+	return resp, nil
+}
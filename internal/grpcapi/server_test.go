@@ -0,0 +1,258 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/richo225/octgopus/internal/api"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/internal/ratelimit"
+	"github.com/richo225/octgopus/pkg/tradingpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var (
+	aliceKey = &auth.Key{ID: "alice-key", Secret: "alice-secret", Signer: "alice"}
+	bobKey   = &auth.Key{ID: "bob-key", Secret: "bob-secret", Signer: "bob"}
+	btcUSD   = &tradingpb.Market{Base: "BTC", Quote: "USD"}
+)
+
+// newTestServer serves p in memory with keys for alice and bob, and returns a
+// dial function for clients that sign with key, or do not sign when it is nil.
+func newTestServer(t *testing.T, p *orderbook.TradingPlatform, config Config) func(key *auth.Key) tradingpb.TradingClient {
+	config.Verifier = auth.NewVerifier(auth.NewKeyStore(aliceKey, bobKey))
+
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(p, config)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return func(key *auth.Key) tradingpb.TradingClient {
+		opts := []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}
+		if key != nil {
+			opts = append(opts, grpc.WithUnaryInterceptor(tradingpb.UnarySigner(key.ID, key.Secret)))
+		}
+
+		conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		return tradingpb.NewTradingClient(conn)
+	}
+}
+
+func newTestPlatform(t *testing.T) *orderbook.TradingPlatform {
+	p := orderbook.NewTradingPlatform()
+	_, err := p.AddNewMarket(orderbook.NewTradingPair("BTC", "USD"))
+	require.NoError(t, err)
+
+	return p
+}
+
+func TestOrders(t *testing.T) {
+	ctx := context.Background()
+	dial := newTestServer(t, newTestPlatform(t), Config{})
+	alice, bob := dial(aliceKey), dial(bobKey)
+
+	placed, err := alice.PlaceOrder(ctx, &tradingpb.PlaceOrderRequest{
+		Market: btcUSD, Side: tradingpb.Side_SIDE_ASK, Type: tradingpb.OrderType_ORDER_TYPE_LIMIT, Price: 100, Size: 5,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, placed.Order.Id, "order should be given an id")
+	assert.Equal(t, "alice", placed.Order.Signer, "order should belong to the key's signer")
+
+	filled, err := bob.PlaceOrder(ctx, &tradingpb.PlaceOrderRequest{
+		Market: btcUSD, Side: tradingpb.Side_SIDE_BID, Type: tradingpb.OrderType_ORDER_TYPE_MARKET, Size: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, filled.Matches, 1, "market order should match the resting ask")
+	assert.Equal(t, 100.0, filled.Matches[0].Price)
+	assert.Equal(t, 2.0, filled.Matches[0].SizeFilled)
+
+	_, err = bob.CancelOrder(ctx, &tradingpb.CancelOrderRequest{Market: btcUSD, Id: placed.Order.Id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "bob should not cancel alice's order")
+
+	cancelled, err := alice.CancelOrder(ctx, &tradingpb.CancelOrderRequest{Market: btcUSD, Id: placed.Order.Id})
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, cancelled.Size, "cancelled order should have its unfilled size")
+
+	_, err = alice.CancelOrder(ctx, &tradingpb.CancelOrderRequest{Market: btcUSD, Id: placed.Order.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err), "cancelled order should be gone")
+
+	_, err = alice.PlaceOrder(ctx, &tradingpb.PlaceOrderRequest{Market: btcUSD, Side: tradingpb.Side_SIDE_BID, Size: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "order without a type should be refused")
+
+	_, err = alice.PlaceOrder(ctx, &tradingpb.PlaceOrderRequest{
+		Market: btcUSD, Type: tradingpb.OrderType_ORDER_TYPE_LIMIT, Price: 100, Size: 1,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "order without a side should be refused")
+}
+
+func TestOrdersWhileTrading(t *testing.T) {
+	ctx := context.Background()
+	dial := newTestServer(t, newTestPlatform(t), Config{})
+	alice, bob := dial(aliceKey), dial(bobKey)
+
+	// Bob's market orders fill alice's asks while her orders, and his
+	// matches, are being converted.
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := alice.PlaceOrder(ctx, &tradingpb.PlaceOrderRequest{
+				Market: btcUSD, Side: tradingpb.Side_SIDE_ASK, Type: tradingpb.OrderType_ORDER_TYPE_LIMIT, Price: 100, Size: 2,
+			})
+			assert.NoError(t, err, "limit order should be placed")
+		}()
+		go func() {
+			defer wg.Done()
+			bob.PlaceOrder(ctx, &tradingpb.PlaceOrderRequest{
+				Market: btcUSD, Side: tradingpb.Side_SIDE_BID, Type: tradingpb.OrderType_ORDER_TYPE_MARKET, Size: 1,
+			})
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAccounts(t *testing.T) {
+	ctx := context.Background()
+	dial := newTestServer(t, newTestPlatform(t), Config{})
+	alice, bob := dial(aliceKey), dial(bobKey)
+
+	_, err := alice.CreateAccount(ctx, &tradingpb.AccountRequest{Signer: "alice"})
+	require.NoError(t, err)
+	_, err = alice.CreateAccount(ctx, &tradingpb.AccountRequest{Signer: "bob"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "alice should not open bob's account")
+
+	tx, err := alice.Deposit(ctx, &tradingpb.AmountRequest{Signer: "alice", Amount: 100})
+	require.NoError(t, err)
+	assert.Equal(t, "deposit", tx.Action)
+
+	_, err = alice.Withdraw(ctx, &tradingpb.AmountRequest{Signer: "alice", Amount: 500})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "withdrawing more than the balance should be refused")
+
+	sent, err := alice.Send(ctx, &tradingpb.SendRequest{Signer: "alice", Recipient: "bob", Amount: 40, CreateRecipient: true})
+	require.NoError(t, err)
+	assert.Len(t, sent.Txs, 2, "send should be a withdraw and a deposit")
+
+	account, err := alice.GetAccount(ctx, &tradingpb.AccountRequest{Signer: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, account.Balance)
+
+	account, err = bob.GetAccount(ctx, &tradingpb.AccountRequest{Signer: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, account.Balance)
+}
+
+func TestAuthentication(t *testing.T) {
+	ctx := context.Background()
+	dial := newTestServer(t, newTestPlatform(t), Config{})
+
+	_, err := dial(nil).GetAccount(ctx, &tradingpb.AccountRequest{Signer: "alice"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "unsigned call should be refused")
+
+	forged := dial(&auth.Key{ID: aliceKey.ID, Secret: "wrong-secret"})
+	_, err = forged.GetAccount(ctx, &tradingpb.AccountRequest{Signer: "alice"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "call signed with the wrong secret should be refused")
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestRateLimits(t *testing.T) {
+	ctx := context.Background()
+	limits := api.RateLimits{Reads: ratelimit.Policy{PerSigner: ratelimit.Limit{Rate: 1, Burst: 1}}}
+	alice := newTestServer(t, newTestPlatform(t), Config{RateLimits: limits, Clock: &fakeClock{time.Now()}})(aliceKey)
+
+	_, err := alice.CreateAccount(ctx, &tradingpb.AccountRequest{Signer: "alice"})
+	require.NoError(t, err)
+
+	_, err = alice.GetAccount(ctx, &tradingpb.AccountRequest{Signer: "alice"})
+	require.NoError(t, err)
+	_, err = alice.GetAccount(ctx, &tradingpb.AccountRequest{Signer: "alice"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "second read should be rate limited")
+}
+
+func TestStreamMarketData(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := newTestPlatform(t)
+	dial := newTestServer(t, p, Config{})
+	alice, bob, anonymous := dial(aliceKey), dial(bobKey), dial(nil)
+
+	_, err := alice.PlaceOrder(ctx, &tradingpb.PlaceOrderRequest{
+		Market: btcUSD, Side: tradingpb.Side_SIDE_ASK, Type: tradingpb.OrderType_ORDER_TYPE_LIMIT, Price: 100, Size: 5,
+	})
+	require.NoError(t, err)
+
+	stream, err := anonymous.StreamMarketData(ctx, &tradingpb.MarketDataRequest{Market: btcUSD})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	snapshot := first.GetSnapshot()
+	require.NotNil(t, snapshot, "stream should start with a snapshot")
+	assert.NotZero(t, first.Sequence, "snapshot should carry the sequence of the last event it reflects")
+	assert.Equal(t, "open", snapshot.State)
+	require.Len(t, snapshot.Asks, 1)
+	assert.Equal(t, 100.0, snapshot.Asks[0].Price)
+	assert.Equal(t, 5.0, snapshot.Asks[0].Size)
+
+	_, err = bob.PlaceOrder(ctx, &tradingpb.PlaceOrderRequest{
+		Market: btcUSD, Side: tradingpb.Side_SIDE_BID, Type: tradingpb.OrderType_ORDER_TYPE_MARKET, Size: 2,
+	})
+	require.NoError(t, err)
+
+	var trade *tradingpb.Trade
+	var level *tradingpb.LevelUpdate
+	for trade == nil || level == nil {
+		data, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Greater(t, data.Sequence, first.Sequence, "updates should follow the snapshot")
+
+		if data.GetTrade() != nil {
+			trade = data.GetTrade()
+		}
+		if l := data.GetLevel(); l != nil && l.Side == tradingpb.Side_SIDE_ASK {
+			level = l
+		}
+	}
+	assert.Equal(t, tradingpb.Side_SIDE_BID, trade.TakerSide)
+	assert.Equal(t, 2.0, trade.Size)
+	assert.Equal(t, 3.0, level.Size, "ask level should have the unfilled size")
+
+	_, err = p.RemoveMarket(orderbook.NewTradingPair("BTC", "USD"))
+	require.NoError(t, err)
+	for {
+		if _, err = stream.Recv(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, io.EOF, err, "stream should end when the market is removed")
+
+	stream, err = anonymous.StreamMarketData(ctx, &tradingpb.MarketDataRequest{Market: &tradingpb.Market{Base: "ETH", Quote: "USD"}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err), "unknown market should not be streamed")
+}
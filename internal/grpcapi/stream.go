@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/richo225/octgopus/pkg/tradingpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamBuffer is how many events a market data stream may fall behind before
+// it is ended, so a client that stops reading cannot grow the server's memory.
+const streamBuffer = 4096
+
+// StreamMarketData subscribes before taking the snapshot, so no change is
+// missed, and skips the events the snapshot already reflects.
+func (s *server) StreamMarketData(req *tradingpb.MarketDataRequest, stream tradingpb.Trading_StreamMarketDataServer) error {
+	pair, err := marketOf(req.GetMarket())
+	if err != nil {
+		return err
+	}
+	if err := s.reads.Allow("", peerIP(stream.Context())); err != nil {
+		return statusOf(err)
+	}
+
+	sub := s.platform.SubscribeLimit(streamBuffer)
+	defer sub.Unsubscribe()
+
+	snapshot, err := s.snapshot(pair)
+	if err != nil {
+		return statusOf(err)
+	}
+	if err := stream.Send(snapshot); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				if sub.Overflowed() {
+					return status.Error(codes.ResourceExhausted, "market data stream fell too far behind")
+				}
+				return nil
+			}
+			if event.Meta().Market != pair || event.Meta().Sequence <= snapshot.Sequence {
+				continue
+			}
+			if _, removed := event.(*orderbook.MarketRemoved); removed {
+				return nil
+			}
+
+			if update := marketDataOf(event); update != nil {
+				if err := stream.Send(update); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (s *server) snapshot(pair orderbook.TradingPair) (*tradingpb.MarketData, error) {
+	book, err := s.platform.SequencedBook(pair)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &tradingpb.BookSnapshot{State: string(book.State), LastTradePrice: book.LastTradePrice}
+	for _, level := range book.Bids {
+		snapshot.Bids = append(snapshot.Bids, &tradingpb.Level{Price: level.Price, Size: level.Amount})
+	}
+	for _, level := range book.Asks {
+		snapshot.Asks = append(snapshot.Asks, &tradingpb.Level{Price: level.Price, Size: level.Amount})
+	}
+
+	return &tradingpb.MarketData{Sequence: book.Sequence, Update: &tradingpb.MarketData_Snapshot{Snapshot: snapshot}}, nil
+}
+
+// marketDataOf returns the update for an event, or nil for events that are
+// not market data, e.g. those about individual orders.
+func marketDataOf(event orderbook.Event) *tradingpb.MarketData {
+	data := &tradingpb.MarketData{Sequence: event.Meta().Sequence, Timestamp: event.Meta().Timestamp}
+
+	switch e := event.(type) {
+	case *orderbook.BookLevelChanged:
+		data.Update = &tradingpb.MarketData_Level{Level: &tradingpb.LevelUpdate{
+			Side:  sideToProto(e.Side),
+			Price: e.Price,
+			Size:  e.TotalVolume,
+		}}
+	case *orderbook.TradeExecuted:
+		data.Update = &tradingpb.MarketData_Trade{Trade: &tradingpb.Trade{
+			AskOrderId: e.Trade.AskOrderID,
+			BidOrderId: e.Trade.BidOrderID,
+			TakerSide:  sideToProto(e.Trade.TakerSide),
+			Price:      e.Trade.Price,
+			Size:       e.Trade.Size,
+		}}
+	case *orderbook.MarketStatusChanged:
+		data.Update = &tradingpb.MarketData_Status{Status: &tradingpb.StatusUpdate{
+			State:  string(e.State),
+			Reason: e.Reason,
+		}}
+	default:
+		return nil
+	}
+
+	return data
+}
@@ -0,0 +1,257 @@
+package orderbook
+
+import (
+	"math"
+	"time"
+)
+
+// Uncross is the price at which the crossed part of an orderbook in auction
+// would trade, and how much would trade there.
+type Uncross struct {
+	Price  float64 `json:"price"`
+	Volume float64 `json:"volume"`
+	// Imbalance is the bid volume at the price less the ask volume, the part
+	// left unfilled.
+	Imbalance float64 `json:"imbalance"`
+}
+
+// AuctionSchedule sets how long auctions last. Markets open with an auction
+// of Opening after they are created, and circuit breaker halts end with an
+// auction of Reopening. A zero duration skips that auction.
+type AuctionSchedule struct {
+	Opening   time.Duration
+	Reopening time.Duration
+}
+
+// SetAuctionSchedule sets the auctions used for markets created and halted
+// from now on.
+func (platform *TradingPlatform) SetAuctionSchedule(schedule AuctionSchedule) {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	platform.auctions = schedule
+}
+
+// Tick ends every halt and auction whose time is up. Expired phases are also
+// ended when the next order for the market arrives, so Tick only needs to run
+// for markets to change state while they are quiet.
+func (platform *TradingPlatform) Tick() error {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	for pair := range platform.Orderbooks {
+		if err := platform.endExpiredPhase(pair); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endExpiredPhase moves a market on from a halt or auction that has reached
+// its ResumeAt. Halts end in a reopening auction when one is scheduled.
+func (platform *TradingPlatform) endExpiredPhase(pair TradingPair) error {
+	orderbook := platform.Orderbooks[pair]
+	if orderbook == nil || orderbook.ResumeAt == 0 || platform.clock.Now().UnixNano() < orderbook.ResumeAt {
+		return nil
+	}
+
+	cmd := Command{Type: SetMarketStateCommand, Market: &pair, State: StateOpen}
+	switch orderbook.State {
+	case StateHalted:
+		cmd.Reason = "circuit breaker halt ended"
+		if platform.auctions.Reopening > 0 {
+			cmd.State = StateAuction
+			cmd.ResumeAt = platform.clock.Now().Add(platform.auctions.Reopening).UnixNano()
+		}
+	case StateAuction:
+		cmd.Reason = "auction ended"
+	default:
+		return nil
+	}
+
+	_, err := platform.commit(cmd)
+	return err
+}
+
+// startOpeningAuction puts a new market into its opening auction.
+func (platform *TradingPlatform) startOpeningAuction(pair TradingPair) error {
+	if platform.auctions.Opening == 0 {
+		return nil
+	}
+
+	resumeAt := platform.clock.Now().Add(platform.auctions.Opening).UnixNano()
+	_, err := platform.commit(Command{Type: SetMarketStateCommand, Market: &pair, State: StateAuction, Reason: "opening auction", ResumeAt: resumeAt})
+	return err
+}
+
+// indicativeUncross finds the price that would execute the most volume if
+// the auction ended now. Ties go to the smallest imbalance, then the price
+// closest to the last trade, then the lowest price. It is nil when the book
+// is not crossed.
+func (book *Orderbook) indicativeUncross() *Uncross {
+	var best *Uncross
+
+	for _, limits := range [][]*Limit{book.Asks, book.Bids} {
+		for _, limit := range limits {
+			candidate := book.uncrossAt(limit.Price)
+			if candidate.Volume > 0 && (best == nil || book.betterUncross(candidate, best)) {
+				best = candidate
+			}
+		}
+	}
+
+	return best
+}
+
+func (book *Orderbook) uncrossAt(price float64) *Uncross {
+	var bidVolume, askVolume float64
+	for _, limit := range book.Bids {
+		if limit.Price >= price {
+			bidVolume += limit.TotalVolume
+		}
+	}
+	for _, limit := range book.Asks {
+		if limit.Price <= price {
+			askVolume += limit.TotalVolume
+		}
+	}
+
+	return &Uncross{
+		Price:     price,
+		Volume:    math.Min(bidVolume, askVolume),
+		Imbalance: bidVolume - askVolume,
+	}
+}
+
+func (book *Orderbook) betterUncross(a, b *Uncross) bool {
+	if a.Volume != b.Volume {
+		return a.Volume > b.Volume
+	}
+	if math.Abs(a.Imbalance) != math.Abs(b.Imbalance) {
+		return math.Abs(a.Imbalance) < math.Abs(b.Imbalance)
+	}
+	if book.LastTradePrice > 0 {
+		da, db := math.Abs(a.Price-book.LastTradePrice), math.Abs(b.Price-book.LastTradePrice)
+		if da != db {
+			return da < db
+		}
+	}
+
+	return a.Price < b.Price
+}
+
+type level struct {
+	side  Side
+	price float64
+}
+
+// uncross executes the auction at a single price, matching the best bids and
+// asks in price then time priority until the volume has traded. It also
+// returns the levels it traded on, in the order they were first touched.
+func (book *Orderbook) uncross(uncross *Uncross) ([]Match, []level) {
+	book.mu.Lock()
+	defer book.mu.Unlock()
+
+	matches := []Match{}
+	levels := []level{}
+	touched := make(map[level]bool)
+	touch := func(l level) {
+		if !touched[l] {
+			touched[l] = true
+			levels = append(levels, l)
+		}
+	}
+	remaining := uncross.Volume
+
+	for remaining > 0 {
+		bidLimit, askLimit := book.bestBid(), book.bestAsk()
+		if bidLimit == nil || askLimit == nil || bidLimit.Price < uncross.Price || askLimit.Price > uncross.Price {
+			break
+		}
+
+		bid, ask := bidLimit.Orders[0], askLimit.Orders[0]
+		size := math.Min(remaining, math.Min(bid.Size, ask.Size))
+		bid.Size -= size
+		ask.Size -= size
+		bidLimit.TotalVolume -= size
+		askLimit.TotalVolume -= size
+		remaining -= size
+
+		matches = append(matches, Match{Ask: ask, Bid: bid, SizeFilled: size, Price: uncross.Price})
+		touch(level{Bid, bidLimit.Price})
+		touch(level{Ask, askLimit.Price})
+
+		book.forgetFilled(matches[len(matches)-1:])
+		if bid.Size == 0 {
+			bidLimit.removeOrder(bid)
+		}
+		if ask.Size == 0 {
+			askLimit.removeOrder(ask)
+		}
+		if len(bidLimit.Orders) == 0 {
+			book.removeLimit(Bid, bidLimit)
+		}
+		if len(askLimit.Orders) == 0 {
+			book.removeLimit(Ask, askLimit)
+		}
+	}
+
+	if len(matches) > 0 {
+		book.LastTradePrice = uncross.Price
+	}
+
+	return matches, levels
+}
+
+// uncrossEvents ends the auction of a market, returning the events for the
+// trades it made.
+func (platform *TradingPlatform) uncrossEvents(orderbook *Orderbook, at time.Time) []Event {
+	pair := *orderbook.Market
+	uncross := orderbook.indicativeUncross()
+	if uncross == nil {
+		return nil
+	}
+
+	matches, levels := orderbook.uncross(uncross)
+	platform.trackPositions(pair, matches)
+	platform.chargeFees(orderbook, matches, "", at)
+	orderbook.addTrades(matches, "", at)
+
+	events := []Event{&AuctionUncrossed{EventMeta: platform.eventMeta(pair), Uncross: *uncross}}
+	for _, match := range matches {
+		events = append(events, &TradeExecuted{EventMeta: platform.eventMeta(pair), Trade: newTrade(match, "")})
+		for _, order := range []*Order{match.Bid, match.Ask} {
+			events = append(events, &OrderFilled{
+				EventMeta:  platform.eventMeta(pair),
+				OrderID:    order.ID,
+				Side:       order.Side,
+				Price:      match.Price,
+				SizeFilled: match.SizeFilled,
+				Remaining:  order.Size,
+			})
+		}
+	}
+	for _, level := range levels {
+		events = append(events, platform.levelChanged(orderbook, level.side, level.price))
+	}
+
+	return events
+}
+
+// indicativeEvent updates and describes the indicative uncross of a market in
+// auction. It is nil for markets in any other state.
+func (platform *TradingPlatform) indicativeEvent(orderbook *Orderbook) Event {
+	if orderbook.State != StateAuction {
+		orderbook.Indicative = nil
+		return nil
+	}
+
+	orderbook.Indicative = orderbook.indicativeUncross()
+	event := &IndicativeUncross{EventMeta: platform.eventMeta(*orderbook.Market)}
+	if orderbook.Indicative != nil {
+		event.Uncross = *orderbook.Indicative
+	}
+
+	return event
+}
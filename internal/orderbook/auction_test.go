@@ -0,0 +1,136 @@
+package orderbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderbookIndicativeUncross(t *testing.T) {
+	orderbook := newOrderBook()
+	orderbook.placeLimitOrder(102, NewOrder(Bid, 5))
+	orderbook.placeLimitOrder(101, NewOrder(Bid, 3))
+	orderbook.placeLimitOrder(100, NewOrder(Bid, 2))
+	orderbook.placeLimitOrder(99, NewOrder(Ask, 4))
+	orderbook.placeLimitOrder(100, NewOrder(Ask, 3))
+	orderbook.placeLimitOrder(101, NewOrder(Ask, 6))
+
+	uncross := orderbook.indicativeUncross()
+
+	assert.Equal(t, &Uncross{Price: 101, Volume: 8, Imbalance: -5}, uncross, "uncross should maximise the executed volume")
+}
+
+func TestOrderbookIndicativeUncrossTieBreak(t *testing.T) {
+	orderbook := newOrderBook()
+	orderbook.placeLimitOrder(100, NewOrder(Bid, 5))
+	orderbook.placeLimitOrder(99, NewOrder(Ask, 5))
+
+	assert.Equal(t, float64(99), orderbook.indicativeUncross().Price, "equal volume and imbalance should take the lowest price")
+
+	orderbook.LastTradePrice = 100
+	assert.Equal(t, float64(100), orderbook.indicativeUncross().Price, "equal volume and imbalance should take the price closest to the last trade")
+
+	orderbook = newOrderBook()
+	orderbook.placeLimitOrder(99, NewOrder(Bid, 5))
+	orderbook.placeLimitOrder(100, NewOrder(Ask, 5))
+	assert.Nil(t, orderbook.indicativeUncross(), "book that is not crossed should have no uncross")
+}
+
+func TestOrderbookUncross(t *testing.T) {
+	orderbook := newOrderBook()
+	bid1 := NewOrder(Bid, 5)
+	bid2 := NewOrder(Bid, 3)
+	ask1 := NewOrder(Ask, 4)
+	ask2 := NewOrder(Ask, 6)
+	orderbook.placeLimitOrder(102, bid1)
+	orderbook.placeLimitOrder(101, bid2)
+	orderbook.placeLimitOrder(99, ask1)
+	orderbook.placeLimitOrder(101, ask2)
+
+	matches, levels := orderbook.uncross(orderbook.indicativeUncross())
+
+	assert.Equal(t, 3, len(matches), "uncross should match in price then time priority")
+	for _, match := range matches {
+		assert.Equal(t, float64(101), match.Price, "every match should be at the uncross price")
+	}
+	assert.Equal(t, []level{{Bid, 102}, {Ask, 99}, {Ask, 101}, {Bid, 101}}, levels, "uncross should report the levels it traded on")
+	assert.Equal(t, float64(2), ask2.Size, "last ask should be partially filled")
+	assert.Nil(t, orderbook.bestBid(), "every bid should be filled")
+	assert.Equal(t, float64(101), orderbook.LastTradePrice, "uncross should set the last trade price")
+	assert.Nil(t, orderbook.indicativeUncross(), "book should no longer be crossed")
+}
+
+func TestTradingPlatformOpeningAuction(t *testing.T) {
+	clock := &manualClock{time.Unix(1700000000, 0)}
+	dir := t.TempDir()
+	pair := TradingPair{"BTC", "USD"}
+
+	tradingPlatform := recoverPlatform(t, dir)
+	tradingPlatform.SetClock(clock)
+	tradingPlatform.SetAuctionSchedule(AuctionSchedule{Opening: time.Minute})
+	orderbook, _ := tradingPlatform.AddNewMarket(pair)
+
+	assert.Equal(t, StateAuction, orderbook.State, "new market should open with an auction")
+
+	bid := signedOrder(Bid, 5, "alice")
+	ask := signedOrder(Ask, 3, "bob")
+	require.NoError(t, tradingPlatform.PlaceLimitOrder(pair, 101, bid), "auction should accept limit orders")
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	require.NoError(t, tradingPlatform.PlaceLimitOrder(pair, 100, ask), "crossing order should rest during the auction")
+	events := receiveEvents(t, sub, 3)
+	indicative := events[2].(*IndicativeUncross)
+	assert.Equal(t, Uncross{Price: 100, Volume: 3, Imbalance: 2}, indicative.Uncross, "indicative uncross should be published")
+	assert.Equal(t, &indicative.Uncross, orderbook.Indicative, "indicative uncross should be shown on the book")
+
+	_, err := tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1))
+	assert.IsType(t, &MarketInAuctionError{}, err, "auction should refuse market orders")
+	receiveEvents(t, sub, 1)
+
+	clock.advance(time.Minute)
+	require.NoError(t, tradingPlatform.Tick(), "tick should end the auction")
+	assert.Equal(t, StateOpen, orderbook.State, "market should be open after the auction")
+	assert.Nil(t, orderbook.Indicative, "open market should have no indicative uncross")
+	assert.Equal(t, float64(2), bid.Size, "bid should be partially filled")
+	assert.Equal(t, float64(3), tradingPlatform.Position("alice", "BTC"), "uncross should move positions")
+
+	events = receiveEvents(t, sub, 7)
+	uncrossed := events[0].(*AuctionUncrossed)
+	assert.Equal(t, float64(3), uncrossed.Uncross.Volume, "uncross should be published first")
+	trade := events[1].(*TradeExecuted)
+	assert.Equal(t, Trade{ask.ID, bid.ID, "", 100, 3, 0, 0}, trade.Trade, "auction trade should have no taker side")
+	assert.Equal(t, StateOpen, events[6].(*MarketStatusChanged).State, "market should reopen after the trades")
+
+	recovered := recoverPlatform(t, dir)
+	recoveredBook, _ := recovered.GetOrderBook(pair)
+	assert.Equal(t, StateOpen, recoveredBook.State, "replay should end the auction")
+	assert.Equal(t, float64(2), recoveredBook.GetBids()[0].TotalVolume, "replay should uncross the book")
+	assert.Nil(t, recoveredBook.bestAsk(), "replay should fill the ask")
+}
+
+func TestTradingPlatformReopeningAuction(t *testing.T) {
+	clock := &manualClock{time.Unix(1700000000, 0)}
+	tradingPlatform := NewTradingPlatform()
+	tradingPlatform.SetClock(clock)
+	tradingPlatform.SetCircuitBreaker(&CircuitBreaker{Percent: 10, Window: time.Minute, HaltFor: time.Minute})
+	tradingPlatform.SetAuctionSchedule(AuctionSchedule{Reopening: time.Minute})
+	pair := TradingPair{"BTC", "USD"}
+	orderbook, _ := tradingPlatform.AddNewMarket(pair)
+
+	tradingPlatform.PlaceLimitOrder(pair, 100, NewOrder(Ask, 1))
+	tradingPlatform.PlaceLimitOrder(pair, 200, NewOrder(Ask, 1))
+	tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 2))
+	require.Equal(t, StateHalted, orderbook.State, "market should be halted")
+
+	clock.advance(time.Minute)
+	tradingPlatform.Tick()
+	assert.Equal(t, StateAuction, orderbook.State, "halt should end in a reopening auction")
+
+	clock.advance(time.Minute)
+	tradingPlatform.Tick()
+	assert.Equal(t, StateOpen, orderbook.State, "market should reopen after the auction")
+}
@@ -0,0 +1,263 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/kr/pretty"
+	"github.com/richo225/octgopus/internal/accounting"
+)
+
+type CommandType string
+
+const (
+	AddMarketCommand     CommandType = "add_market"
+	PlaceOrderCommand    CommandType = "place_order"
+	CancelOrderCommand   CommandType = "cancel_order"
+	CreateAccountCommand CommandType = "create_account"
+	DepositCommand       CommandType = "deposit"
+	WithdrawCommand      CommandType = "withdraw"
+	SendCommand          CommandType = "send"
+	ResetCommand         CommandType = "reset"
+	HaltMarketCommand    CommandType = "halt_market"
+	ResumeMarketCommand  CommandType = "resume_market"
+	RemoveMarketCommand  CommandType = "remove_market"
+	AdjustBalanceCommand CommandType = "adjust_balance"
+	// SetMarketStateCommand replaces the halt and resume commands, which are
+	// still replayed from older journals.
+	SetMarketStateCommand CommandType = "set_market_state"
+	SetFeeScheduleCommand CommandType = "set_fee_schedule"
+)
+
+// Command is a single state change accepted by the TradingPlatform. Replaying
+// the same commands in the same order against an empty platform rebuilds the
+// same orderbooks and accounts.
+type Command struct {
+	Type      CommandType  `json:"type"`
+	Market    *TradingPair `json:"market,omitempty"`
+	OrderType OrderType    `json:"order_type,omitempty"`
+	Price     float64      `json:"price,omitempty"`
+	Order     *Order       `json:"order,omitempty"`
+	OrderID   uint64       `json:"order_id,omitempty"`
+	Signer    string       `json:"signer,omitempty"`
+	Recipient string       `json:"recipient,omitempty"`
+	Amount    float64      `json:"amount,omitempty"`
+	Timestamp int64        `json:"timestamp,omitempty"`
+
+	CreateRecipient bool   `json:"create_recipient,omitempty"`
+	IdempotencyKey  string `json:"idempotency_key,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+
+	State    TradingState `json:"state,omitempty"`
+	ResumeAt int64        `json:"resume_at,omitempty"`
+	Fees     *FeeSchedule `json:"fees,omitempty"`
+}
+
+// Journal durably records accepted commands, e.g. a *wal.Log.
+type Journal interface {
+	Append(payload []byte) (uint64, error)
+}
+
+// JournalReader replays recorded commands in order, e.g. a *wal.Log.
+type JournalReader interface {
+	Replay(from uint64, fn func(seq uint64, payload []byte) error) error
+}
+
+type commandResult struct {
+	orderbook *Orderbook
+	order     *Order
+	placed    Order
+	orders    []Order
+	matches   []Match
+	txs       []*accounting.Tx
+}
+
+// UseJournal records every command accepted from now on to journal.
+func (platform *TradingPlatform) UseJournal(journal Journal) {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	platform.journal = journal
+}
+
+// Recover replays every command in the journal after the last one applied,
+// which is the snapshot sequence if the platform was restored from one. It
+// should be called before UseJournal so replayed commands are not recorded
+// again.
+func (platform *TradingPlatform) Recover(reader JournalReader) error {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	// A journal compacted past the last applied command means the snapshot
+	// that covered the missing commands has been lost.
+	if compacted, ok := reader.(interface{ FirstSequence() uint64 }); ok {
+		if first := compacted.FirstSequence(); first > platform.sequence+1 {
+			return &JournalGapError{platform.sequence + 1, first}
+		}
+	}
+
+	return reader.Replay(platform.sequence+1, func(seq uint64, payload []byte) error {
+		if seq != platform.sequence+1 {
+			return &JournalGapError{platform.sequence + 1, seq}
+		}
+
+		var cmd Command
+		if err := json.Unmarshal(payload, &cmd); err != nil {
+			return &CorruptCommandError{seq, err}
+		}
+
+		_, err := platform.apply(cmd)
+		if err != nil {
+			return &ReplayError{seq, err}
+		}
+
+		platform.sequence = seq
+		return nil
+	})
+}
+
+// submit applies a command and, once it has been accepted, appends it to the
+// journal. Commands are applied one at a time so the journal order is the
+// order in which they took effect.
+func (platform *TradingPlatform) submit(cmd Command) (commandResult, error) {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	if platform.journalErr != nil {
+		return commandResult{}, &JournalError{platform.journalErr}
+	}
+
+	// Orders are given their id and self-trade prevention mode before being
+	// journaled so that replay assigns the same ids and modes.
+	if cmd.Type == PlaceOrderCommand {
+		platform.assignOrderID(cmd.Order)
+		if cmd.Order.SelfTradePrevention == "" {
+			cmd.Order.SelfTradePrevention = platform.selfTradePrevention
+		}
+
+		if err := platform.endExpiredPhase(*cmd.Market); err != nil {
+			return commandResult{}, err
+		}
+
+		if err := platform.checkRules(*cmd.Market, cmd.OrderType, cmd.Price, cmd.Order); err != nil {
+			return commandResult{}, err
+		}
+
+		// Risk checks only run on new orders, so that replaying the journal
+		// with different limits still reproduces the same state.
+		if err := platform.checkRisk(*cmd.Market, cmd.OrderType, cmd.Price, cmd.Order); err != nil {
+			return commandResult{}, err
+		}
+	}
+
+	result, err := platform.commit(cmd)
+	if err != nil {
+		return result, err
+	}
+
+	switch cmd.Type {
+	case PlaceOrderCommand:
+		platform.checkCircuitBreaker(*cmd.Market, result.matches)
+		// Resting orders keep filling once the lock is released, so callers
+		// are given copies.
+		result.placed, result.matches = *cmd.Order, copyMatches(result.matches)
+	case AddMarketCommand:
+		err = platform.startOpeningAuction(*cmd.Market)
+	}
+
+	return result, err
+}
+
+// commit timestamps, applies and journals a command, so that replay uses the
+// same ledger timestamps. It is called with the platform locked.
+//
+// Commands are only journaled once they have been applied, as applying is
+// what validates them. A failed append therefore cannot be undone: the
+// command stands, but is reported as failed since it would be lost on a
+// restart, and the platform stops accepting commands until it is restarted
+// from the journal.
+func (platform *TradingPlatform) commit(cmd Command) (commandResult, error) {
+	if platform.journalErr != nil {
+		return commandResult{}, &JournalError{platform.journalErr}
+	}
+
+	cmd.Timestamp = platform.clock.Now().UnixNano()
+
+	var payload []byte
+	if platform.journal != nil {
+		payload, _ = json.Marshal(cmd)
+	}
+
+	result, err := platform.apply(cmd)
+	if err != nil {
+		return result, err
+	}
+
+	if platform.journal != nil {
+		seq, err := platform.journal.Append(payload)
+		if err != nil {
+			pretty.Log("Failed to journal command, refusing further commands", string(cmd.Type), err.Error())
+			platform.journalErr = err
+			return result, &JournalError{err}
+		}
+		platform.sequence = seq
+	}
+
+	return result, nil
+}
+
+func (platform *TradingPlatform) apply(cmd Command) (commandResult, error) {
+	at := time.Unix(0, cmd.Timestamp)
+
+	switch cmd.Type {
+	case AddMarketCommand:
+		if _, ok := platform.Orderbooks[*cmd.Market]; ok {
+			return commandResult{}, &MarketAlreadyExistsError{*cmd.Market}
+		}
+		return commandResult{orderbook: platform.addNewMarket(*cmd.Market)}, nil
+	case HaltMarketCommand:
+		return commandResult{}, platform.setState(*cmd.Market, StateHalted, "", 0, at)
+	case ResumeMarketCommand:
+		return commandResult{}, platform.setState(*cmd.Market, StateOpen, "", 0, at)
+	case SetMarketStateCommand:
+		return commandResult{}, platform.setState(*cmd.Market, cmd.State, cmd.Reason, cmd.ResumeAt, at)
+	case SetFeeScheduleCommand:
+		return commandResult{}, platform.setFeeSchedule(*cmd.Market, cmd.Fees)
+	case RemoveMarketCommand:
+		orders, err := platform.removeMarket(*cmd.Market)
+		return commandResult{orders: orders}, err
+	case PlaceOrderCommand:
+		platform.assignOrderID(cmd.Order)
+		if cmd.OrderType == MarketOrder {
+			matches, err := platform.placeMarketOrder(*cmd.Market, cmd.Order, at)
+			return commandResult{order: cmd.Order, matches: matches}, err
+		}
+		err := platform.placeLimitOrder(*cmd.Market, cmd.Price, cmd.Order)
+		return commandResult{order: cmd.Order}, err
+	case CancelOrderCommand:
+		order, err := platform.cancelOrder(*cmd.Market, cmd.OrderID)
+		return commandResult{order: order}, err
+	case CreateAccountCommand:
+		return commandResult{}, platform.Accounts.CreateAccount(cmd.Signer)
+	case DepositCommand:
+		tx, err := platform.Accounts.DepositAt(cmd.Signer, cmd.Amount, at)
+		return commandResult{txs: []*accounting.Tx{tx}}, err
+	case WithdrawCommand:
+		tx, err := platform.Accounts.WithdrawAt(cmd.Signer, cmd.Amount, at)
+		return commandResult{txs: []*accounting.Tx{tx}}, err
+	case SendCommand:
+		opts := accounting.SendOptions{CreateRecipient: cmd.CreateRecipient, IdempotencyKey: cmd.IdempotencyKey}
+		txs, err := platform.Accounts.SendAt(cmd.Signer, cmd.Recipient, cmd.Amount, at, opts)
+		return commandResult{txs: txs}, err
+	case AdjustBalanceCommand:
+		tx, err := platform.Accounts.AdjustAt(cmd.Signer, cmd.Amount, cmd.Reason, at)
+		return commandResult{txs: []*accounting.Tx{tx}}, err
+	case ResetCommand:
+		platform.Orderbooks = make(map[TradingPair]*Orderbook)
+		platform.positions = make(map[string]map[string]float64)
+		platform.Accounts.Reset()
+		return commandResult{}, nil
+	default:
+		return commandResult{}, &UnknownCommandError{cmd.Type}
+	}
+}
@@ -0,0 +1,164 @@
+package orderbook
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/richo225/octgopus/internal/accounting"
+	"github.com/richo225/octgopus/internal/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestJournal(t *testing.T, dir string) *wal.Log {
+	t.Helper()
+
+	log, err := wal.Open(wal.Options{Dir: dir})
+	require.NoError(t, err, "journal should open")
+	t.Cleanup(func() { log.Close() })
+
+	return log
+}
+
+func recoverPlatform(t *testing.T, dir string) *TradingPlatform {
+	t.Helper()
+
+	log := openTestJournal(t, dir)
+	platform := NewTradingPlatform()
+	require.NoError(t, platform.Recover(log), "recover should not return an error")
+	platform.UseJournal(log)
+
+	return platform
+}
+
+func TestTradingPlatformRecover(t *testing.T) {
+	dir := t.TempDir()
+	pair := TradingPair{"BTC", "USD"}
+
+	platform := recoverPlatform(t, dir)
+	platform.AddNewMarket(pair)
+	platform.PlaceLimitOrder(pair, 250, NewOrder(Ask, 8))
+	platform.PlaceLimitOrder(pair, 240, NewOrder(Ask, 2))
+	cancelled := NewOrder(Bid, 5)
+	platform.PlaceLimitOrder(pair, 200, cancelled)
+	platform.CancelOrder(pair, cancelled.ID)
+	filled := NewOrder(Bid, 3)
+	platform.PlaceMarketOrder(pair, filled)
+	platform.PlaceMarketOrder(pair, NewOrder(Bid, 300))
+	platform.CreateAccount("alice")
+	platform.CreateAccount("bob")
+	platform.Deposit("alice", 100)
+	platform.Deposit("bob", 10)
+	platform.Withdraw("alice", 20)
+	platform.Send("alice", "bob", 30)
+
+	recovered := recoverPlatform(t, dir)
+
+	orderbook, err := recovered.GetOrderBook(pair)
+	require.NoError(t, err, "recovered platform should have the market")
+	assert.Equal(t, float64(7), orderbook.totalAskVolume(), "recovered book should have the correct total ask volume")
+	assert.Equal(t, float64(0), orderbook.totalBidVolume(), "recovered book should not have the cancelled bid")
+	assert.Equal(t, 1, len(orderbook.Asks), "recovered book should have 1 limit left")
+	assert.Equal(t, 1, len(orderbook.orders), "recovered book should index the resting order")
+
+	original, _ := platform.GetOrderBook(pair)
+	assert.Equal(t, original.Asks[0].Orders[0], orderbook.Asks[0].Orders[0], "resting order should be recovered exactly")
+	assert.Equal(t, platform.Accounts.Accounts, recovered.Accounts.Accounts, "balances should be recovered exactly")
+	assert.Equal(t, filled.ID, recovered.lastOrderID, "order ids should continue from the last accepted order")
+}
+
+func TestTradingPlatformRecoverIdempotentSend(t *testing.T) {
+	dir := t.TempDir()
+
+	platform := recoverPlatform(t, dir)
+	platform.CreateAccount("alice")
+	platform.CreateAccount("bob")
+	platform.Deposit("alice", 100)
+	opts := accounting.SendOptions{IdempotencyKey: "abc"}
+	first, _ := platform.SendWithOptions("alice", "bob", 30, opts)
+
+	recovered := recoverPlatform(t, dir)
+	retry, err := recovered.SendWithOptions("alice", "bob", 30, opts)
+
+	assert.NoError(t, err, "retried send should not return an error")
+	assert.Equal(t, first, retry, "retried send should return the original transfer after recovery")
+	assert.Equal(t, map[string]float64{"alice": 70, "bob": 30}, recovered.Accounts.Balances(), "retried send should not transfer again")
+}
+
+func TestTradingPlatformRecoverAfterReset(t *testing.T) {
+	dir := t.TempDir()
+	pair := TradingPair{"BTC", "USD"}
+
+	platform := recoverPlatform(t, dir)
+	platform.AddNewMarket(pair)
+	platform.CreateAccount("alice")
+	platform.Deposit("alice", 100)
+	platform.submit(Command{Type: ResetCommand})
+	platform.AddNewMarket(TradingPair{"ETH", "USD"})
+
+	recovered := recoverPlatform(t, dir)
+
+	_, err := recovered.GetOrderBook(pair)
+	assert.Error(t, err, "markets from before the reset should be gone")
+	assert.Empty(t, recovered.Accounts.Accounts, "accounts from before the reset should be gone")
+	assert.Equal(t, 1, len(recovered.Orderbooks), "markets after the reset should be recovered")
+}
+
+func TestTradingPlatformRecoverTornTail(t *testing.T) {
+	dir := t.TempDir()
+	pair := TradingPair{"BTC", "USD"}
+
+	platform := recoverPlatform(t, dir)
+	platform.AddNewMarket(pair)
+	platform.PlaceLimitOrder(pair, 250, NewOrder(Ask, 8))
+	platform.PlaceLimitOrder(pair, 260, NewOrder(Ask, 4))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.wal"))
+	info, _ := os.Stat(files[0])
+	require.NoError(t, os.Truncate(files[0], info.Size()-5), "simulated crash should truncate the journal")
+
+	recovered := recoverPlatform(t, dir)
+
+	orderbook, _ := recovered.GetOrderBook(pair)
+	assert.Equal(t, float64(8), orderbook.totalAskVolume(), "recovered book should exclude the torn command")
+}
+
+func TestTradingPlatformRejectedCommandsNotJournaled(t *testing.T) {
+	dir := t.TempDir()
+	pair := TradingPair{"BTC", "USD"}
+
+	platform := recoverPlatform(t, dir)
+	platform.PlaceLimitOrder(pair, 250, NewOrder(Ask, 8))
+	platform.Withdraw("alice", 10)
+
+	log := openTestJournal(t, t.TempDir())
+	platform.UseJournal(log)
+	platform.AddNewMarket(pair)
+	platform.PlaceMarketOrder(pair, NewOrder(Bid, 3))
+
+	assert.Equal(t, uint64(1), log.LastSequence(), "only the accepted command should be journaled")
+}
+
+type failingJournal struct{}
+
+func (failingJournal) Append(payload []byte) (uint64, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestTradingPlatformJournalError(t *testing.T) {
+	platform := NewTradingPlatform()
+	platform.UseJournal(failingJournal{})
+
+	pair := TradingPair{"BTC", "USD"}
+	_, err := platform.AddNewMarket(pair)
+	assert.IsType(t, &JournalError{}, err, "command that could not be journaled should be reported as failed")
+	_, err = platform.GetOrderBook(pair)
+	assert.NoError(t, err, "command applied before the append failed should stand")
+
+	_, err = platform.AddNewMarket(TradingPair{"ETH", "USD"})
+	assert.IsType(t, &JournalError{}, err, "commands after a failed append should be refused")
+	_, err = platform.GetOrderBook(TradingPair{"ETH", "USD"})
+	assert.Error(t, err, "refused command should not be applied")
+}
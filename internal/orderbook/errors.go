@@ -3,6 +3,8 @@ package orderbook
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type InsufficientVolumeError struct {
@@ -29,3 +31,290 @@ func (e *OrderbookNotFoundError) Error() string {
 func (e *OrderbookNotFoundError) HTTPCode() int {
 	return http.StatusNotFound
 }
+
+type MarketAlreadyExistsError struct {
+	pair TradingPair
+}
+
+func (e *MarketAlreadyExistsError) Error() string {
+	return "MarketAlreadyExists : " + e.pair.ToString()
+}
+
+func (e *MarketAlreadyExistsError) HTTPCode() int {
+	return http.StatusConflict
+}
+
+type MarketHaltedError struct {
+	pair TradingPair
+}
+
+func (e *MarketHaltedError) Error() string {
+	return "MarketHalted : " + e.pair.ToString()
+}
+
+func (e *MarketHaltedError) HTTPCode() int {
+	return http.StatusConflict
+}
+
+type MarketCancelOnlyError struct {
+	pair TradingPair
+}
+
+func (e *MarketCancelOnlyError) Error() string {
+	return "MarketCancelOnly : " + e.pair.ToString()
+}
+
+func (e *MarketCancelOnlyError) HTTPCode() int {
+	return http.StatusConflict
+}
+
+type MarketInAuctionError struct {
+	pair TradingPair
+}
+
+func (e *MarketInAuctionError) Error() string {
+	return "MarketInAuction : " + e.pair.ToString()
+}
+
+func (e *MarketInAuctionError) HTTPCode() int {
+	return http.StatusConflict
+}
+
+type InvalidTradingStateError struct {
+	state TradingState
+}
+
+func (e *InvalidTradingStateError) Error() string {
+	return "InvalidTradingState : " + string(e.state)
+}
+
+func (e *InvalidTradingStateError) HTTPCode() int {
+	return http.StatusBadRequest
+}
+
+type InvalidFeeScheduleError struct {
+	err error
+}
+
+func (e *InvalidFeeScheduleError) Error() string {
+	return "InvalidFeeSchedule : " + e.err.Error()
+}
+
+func (e *InvalidFeeScheduleError) HTTPCode() int {
+	return http.StatusBadRequest
+}
+
+type OrderNotFoundError struct {
+	id uint64
+}
+
+func (e *OrderNotFoundError) Error() string {
+	return "OrderNotFound : " + fmt.Sprint(e.id)
+}
+
+func (e *OrderNotFoundError) HTTPCode() int {
+	return http.StatusNotFound
+}
+
+type InvalidOrderError struct {
+	field string
+	err   error
+}
+
+func (e *InvalidOrderError) Error() string {
+	return "InvalidOrder : " + e.field + " " + e.err.Error()
+}
+
+func (e *InvalidOrderError) Unwrap() error {
+	return e.err
+}
+
+func (e *InvalidOrderError) HTTPCode() int {
+	return http.StatusBadRequest
+}
+
+type JournalError struct {
+	err error
+}
+
+func (e *JournalError) Error() string {
+	return "JournalWriteFailed : " + e.err.Error()
+}
+
+func (e *JournalError) Unwrap() error {
+	return e.err
+}
+
+func (e *JournalError) HTTPCode() int {
+	return http.StatusInternalServerError
+}
+
+type CorruptCommandError struct {
+	seq uint64
+	err error
+}
+
+func (e *CorruptCommandError) Error() string {
+	return "CorruptCommand : " + fmt.Sprint(e.seq) + " : " + e.err.Error()
+}
+
+type ReplayError struct {
+	seq uint64
+	err error
+}
+
+func (e *ReplayError) Error() string {
+	return "ReplayFailed : " + fmt.Sprint(e.seq) + " : " + e.err.Error()
+}
+
+func (e *ReplayError) Unwrap() error {
+	return e.err
+}
+
+type UnknownCommandError struct {
+	command CommandType
+}
+
+func (e *UnknownCommandError) Error() string {
+	return "UnknownCommand : " + string(e.command)
+}
+
+type JournalGapError struct {
+	expected uint64
+	found    uint64
+}
+
+func (e *JournalGapError) Error() string {
+	return "JournalGap : expected command " + fmt.Sprint(e.expected) + " but found " + fmt.Sprint(e.found)
+}
+
+type SnapshotVersionError struct {
+	version int
+}
+
+func (e *SnapshotVersionError) Error() string {
+	return "UnsupportedSnapshotVersion : " + fmt.Sprint(e.version)
+}
+
+type OrderNotionalExceededError struct {
+	notional float64
+	limit    float64
+}
+
+func (e *OrderNotionalExceededError) Error() string {
+	return "OrderNotionalExceeded : " + fmt.Sprint(e.notional) + " > " + fmt.Sprint(e.limit)
+}
+
+func (e *OrderNotionalExceededError) HTTPCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+type OpenOrdersExceededError struct {
+	signer string
+	limit  int
+}
+
+func (e *OpenOrdersExceededError) Error() string {
+	return "OpenOrdersExceeded : " + e.signer + " has " + fmt.Sprint(e.limit) + " open orders"
+}
+
+func (e *OpenOrdersExceededError) HTTPCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+type PositionLimitExceededError struct {
+	signer   string
+	asset    string
+	position float64
+	limit    float64
+}
+
+func (e *PositionLimitExceededError) Error() string {
+	return "PositionLimitExceeded : " + e.signer + " " + e.asset + " " + fmt.Sprint(e.position) + " beyond " + fmt.Sprint(e.limit)
+}
+
+func (e *PositionLimitExceededError) HTTPCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+type PriceOutsideBandError struct {
+	price float64
+	low   float64
+	high  float64
+}
+
+func (e *PriceOutsideBandError) Error() string {
+	return "PriceOutsideBand : " + fmt.Sprint(e.price) + " not in " + fmt.Sprint(e.low) + " - " + fmt.Sprint(e.high)
+}
+
+func (e *PriceOutsideBandError) HTTPCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// InvalidImportError lists the invalid rows of an imported file, up to
+// maxImportErrors of them.
+type InvalidImportError struct {
+	Rows  []ImportRowError
+	count int
+}
+
+func (e *InvalidImportError) add(row ImportRowError) {
+	e.count++
+	if len(e.Rows) < maxImportErrors {
+		e.Rows = append(e.Rows, row)
+	}
+}
+
+func (e *InvalidImportError) Error() string {
+	rows := make([]string, len(e.Rows))
+	for i, row := range e.Rows {
+		rows[i] = row.String()
+	}
+
+	msg := "InvalidImport : " + strings.Join(rows, "; ")
+	if more := e.count - len(e.Rows); more > 0 {
+		msg += fmt.Sprintf("; and %d more", more)
+	}
+
+	return msg
+}
+
+func (e *InvalidImportError) HTTPCode() int {
+	return http.StatusBadRequest
+}
+
+// ImportFailedError is returned when the market refuses an imported order.
+type ImportFailedError struct {
+	Line   int
+	Placed int
+	err    error
+}
+
+func (e *ImportFailedError) Error() string {
+	return fmt.Sprintf("ImportFailed : line %d, after placing %d orders: %s", e.Line, e.Placed, e.err.Error())
+}
+
+func (e *ImportFailedError) Unwrap() error {
+	return e.err
+}
+
+func (e *ImportFailedError) HTTPCode() int {
+	if httpErr, ok := e.err.(interface{ HTTPCode() int }); ok {
+		return httpErr.HTTPCode()
+	}
+
+	return http.StatusUnprocessableEntity
+}
+
+type InvalidExportError struct {
+	param string
+	value string
+}
+
+func (e *InvalidExportError) Error() string {
+	return "InvalidExport : unknown " + e.param + " " + strconv.Quote(e.value)
+}
+
+func (e *InvalidExportError) HTTPCode() int {
+	return http.StatusBadRequest
+}
@@ -0,0 +1,302 @@
+package orderbook
+
+import "sync"
+
+// EventMeta is shared by every event published on the EventBus. Sequence is
+// assigned by the bus and is strictly increasing across all markets.
+type EventMeta struct {
+	Sequence  uint64      `json:"sequence"`
+	Market    TradingPair `json:"market"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+func (m *EventMeta) Meta() *EventMeta {
+	return m
+}
+
+type Event interface {
+	Meta() *EventMeta
+}
+
+type OrderAccepted struct {
+	EventMeta
+	Order Order `json:"order"`
+}
+
+type OrderRejected struct {
+	EventMeta
+	Order  Order  `json:"order"`
+	Reason string `json:"reason"`
+}
+
+type OrderFilled struct {
+	EventMeta
+	OrderID    uint64  `json:"order_id"`
+	Side       Side    `json:"side"`
+	Price      float64 `json:"price"`
+	SizeFilled float64 `json:"size_filled"`
+	Remaining  float64 `json:"remaining"`
+}
+
+type OrderCancelled struct {
+	EventMeta
+	Order Order `json:"order"`
+}
+
+// SelfTradePrevented is published instead of a trade when both sides of a
+// match belong to Signer. A resting order cancelled as a result is also
+// reported with OrderCancelled.
+type SelfTradePrevented struct {
+	EventMeta
+	Signer         string              `json:"signer"`
+	Mode           SelfTradePrevention `json:"mode"`
+	Price          float64             `json:"price"`
+	MakerOrderID   uint64              `json:"maker_order_id"`
+	MakerCancelled float64             `json:"maker_cancelled"`
+	MakerRemaining float64             `json:"maker_remaining"`
+	TakerOrderID   uint64              `json:"taker_order_id"`
+	TakerCancelled float64             `json:"taker_cancelled"`
+	TakerRemaining float64             `json:"taker_remaining"`
+}
+
+type TradeExecuted struct {
+	EventMeta
+	Trade Trade `json:"trade"`
+}
+
+type BookLevelChanged struct {
+	EventMeta
+	Side        Side    `json:"side"`
+	Price       float64 `json:"price"`
+	TotalVolume float64 `json:"total_volume"`
+}
+
+// MarketStatusChanged is published when a market changes trading state.
+type MarketStatusChanged struct {
+	EventMeta
+	State  TradingState `json:"state"`
+	Reason string       `json:"reason,omitempty"`
+	// ResumeAt is when a circuit breaker halt ends, in unix nanoseconds.
+	ResumeAt int64 `json:"resume_at,omitempty"`
+}
+
+// IndicativeUncross is published whenever the book of a market in auction
+// changes. Uncross is zero while the book is not crossed.
+type IndicativeUncross struct {
+	EventMeta
+	Uncross Uncross `json:"uncross"`
+}
+
+// AuctionUncrossed is published when an auction ends, before the trades it
+// made. Auction trades have no taker side.
+type AuctionUncrossed struct {
+	EventMeta
+	Uncross Uncross `json:"uncross"`
+}
+
+// MarketRemoved is published after the orders resting on a removed market
+// have been cancelled.
+type MarketRemoved struct {
+	EventMeta
+}
+
+// Trade is an immutable record of a Match, safe to hand to subscribers after
+// the orders involved have moved on.
+type Trade struct {
+	AskOrderID uint64  `json:"ask_order_id"`
+	BidOrderID uint64  `json:"bid_order_id"`
+	TakerSide  Side    `json:"taker_side"`
+	Price      float64 `json:"price"`
+	Size       float64 `json:"size"`
+	AskFee     float64 `json:"ask_fee"`
+	BidFee     float64 `json:"bid_fee"`
+}
+
+func newTrade(match Match, takerSide Side) Trade {
+	return Trade{
+		AskOrderID: match.Ask.ID,
+		BidOrderID: match.Bid.ID,
+		TakerSide:  takerSide,
+		Price:      match.Price,
+		Size:       match.SizeFilled,
+		AskFee:     match.AskFee,
+		BidFee:     match.BidFee,
+	}
+}
+
+type EventBus struct {
+	sequence    uint64
+	subscribers map[*Subscription]struct{}
+
+	mu sync.Mutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber. Delivery to each subscriber happens on
+// its own goroutine through an unbounded queue, so a slow subscriber never
+// blocks matching or other subscribers.
+func (bus *EventBus) Subscribe() *Subscription {
+	return bus.SubscribeLimit(0)
+}
+
+// SubscribeLimit registers a subscriber whose queue holds at most limit
+// events, e.g. for a remote client that may stop reading. A subscriber that
+// falls further behind is unsubscribed, closing C, and reports Overflowed. A
+// limit of zero leaves the queue unbounded.
+func (bus *EventBus) SubscribeLimit(limit int) *Subscription {
+	out := make(chan Event)
+	sub := &Subscription{
+		C:     out,
+		bus:   bus,
+		limit: limit,
+		out:   out,
+		done:  make(chan struct{}),
+	}
+	sub.cond = sync.NewCond(&sub.mu)
+
+	bus.mu.Lock()
+	bus.subscribers[sub] = struct{}{}
+	bus.mu.Unlock()
+
+	go sub.pump()
+
+	return sub
+}
+
+// publish stamps the events with consecutive sequence numbers and queues them
+// for every subscriber. Events published together are never interleaved with
+// another batch.
+func (bus *EventBus) publish(events ...Event) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	for _, event := range events {
+		bus.sequence++
+		event.Meta().Sequence = bus.sequence
+	}
+
+	for sub := range bus.subscribers {
+		if !sub.enqueue(events) {
+			delete(bus.subscribers, sub)
+		}
+	}
+}
+
+// lastSequence is the sequence of the last event published.
+func (bus *EventBus) lastSequence() uint64 {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	return bus.sequence
+}
+
+func (bus *EventBus) unsubscribe(sub *Subscription) {
+	bus.mu.Lock()
+	delete(bus.subscribers, sub)
+	bus.mu.Unlock()
+}
+
+type Subscription struct {
+	// C receives events in sequence order until Unsubscribe is called, or
+	// until every queued event has been received after Close.
+	C <-chan Event
+
+	bus        *EventBus
+	queue      []Event
+	limit      int
+	closed     bool
+	draining   bool
+	overflowed bool
+	out        chan Event
+	done       chan struct{}
+
+	mu   sync.Mutex
+	cond *sync.Cond
+}
+
+func (sub *Subscription) Unsubscribe() {
+	sub.bus.unsubscribe(sub)
+
+	sub.mu.Lock()
+	if !sub.closed {
+		sub.closed = true
+		sub.queue = nil
+		close(sub.done)
+		sub.cond.Signal()
+	}
+	sub.mu.Unlock()
+}
+
+// Close stops the subscription receiving new events, but unlike Unsubscribe
+// still delivers those already published before closing C.
+func (sub *Subscription) Close() {
+	sub.bus.unsubscribe(sub)
+
+	sub.mu.Lock()
+	sub.draining = true
+	sub.cond.Signal()
+	sub.mu.Unlock()
+}
+
+// Overflowed reports whether the subscription was dropped for falling more
+// than its limit behind.
+func (sub *Subscription) Overflowed() bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	return sub.overflowed
+}
+
+// enqueue queues events for delivery. It returns false once the subscription
+// has overflowed, which closes it.
+func (sub *Subscription) enqueue(events []Event) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed || sub.draining {
+		return true
+	}
+
+	if sub.limit > 0 && len(sub.queue)+len(events) > sub.limit {
+		sub.overflowed = true
+		sub.closed = true
+		sub.queue = nil
+		close(sub.done)
+		sub.cond.Signal()
+		return false
+	}
+
+	sub.queue = append(sub.queue, events...)
+	sub.cond.Signal()
+	return true
+}
+
+func (sub *Subscription) pump() {
+	defer close(sub.out)
+
+	for {
+		sub.mu.Lock()
+		for len(sub.queue) == 0 && !sub.closed && !sub.draining {
+			sub.cond.Wait()
+		}
+		if sub.closed || len(sub.queue) == 0 {
+			sub.mu.Unlock()
+			return
+		}
+		event := sub.queue[0]
+		sub.queue[0] = nil
+		sub.queue = sub.queue[1:]
+		sub.mu.Unlock()
+
+		select {
+		case sub.out <- event:
+		case <-sub.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package orderbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func receiveEvents(t *testing.T, sub *Subscription, n int) []Event {
+	t.Helper()
+
+	events := []Event{}
+	for len(events) < n {
+		select {
+		case event := <-sub.C:
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for event %d of %d", len(events)+1, n)
+		}
+	}
+
+	return events
+}
+
+func TestEventBusSequence(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe()
+	defer sub.Unsubscribe()
+
+	bus.publish(&OrderAccepted{}, &OrderCancelled{})
+	bus.publish(&OrderRejected{})
+
+	events := receiveEvents(t, sub, 3)
+
+	assert.IsType(t, &OrderAccepted{}, events[0], "first event should be OrderAccepted")
+	assert.IsType(t, &OrderCancelled{}, events[1], "second event should be OrderCancelled")
+	assert.IsType(t, &OrderRejected{}, events[2], "third event should be OrderRejected")
+	for i, event := range events {
+		assert.Equal(t, uint64(i+1), event.Meta().Sequence, "events should be numbered in publish order")
+	}
+}
+
+func TestEventBusSlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := NewEventBus()
+	slow := bus.Subscribe()
+	defer slow.Unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			bus.publish(&OrderAccepted{})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish should not block on a subscriber that is not reading")
+	}
+
+	events := receiveEvents(t, slow, 1000)
+	assert.Equal(t, uint64(1000), events[999].Meta().Sequence, "slow subscriber should still receive every event")
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe()
+
+	sub.Unsubscribe()
+	bus.publish(&OrderAccepted{})
+
+	_, open := <-sub.C
+	assert.False(t, open, "channel should be closed after unsubscribe")
+	assert.Empty(t, bus.subscribers, "bus should forget the subscriber")
+}
+
+func TestEventBusClose(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe()
+
+	bus.publish(&OrderAccepted{}, &OrderAccepted{})
+	sub.Close()
+	bus.publish(&OrderAccepted{})
+
+	events := []Event{}
+	for event := range sub.C {
+		events = append(events, event)
+	}
+
+	assert.Equal(t, 2, len(events), "close should deliver events published before it and no others")
+}
+
+func TestEventBusSubscribeLimit(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.SubscribeLimit(10)
+	defer sub.Unsubscribe()
+
+	for i := 0; i < 10; i++ {
+		bus.publish(&OrderAccepted{})
+	}
+	assert.False(t, sub.Overflowed(), "subscriber within its limit should be kept")
+
+	for i := 0; i < 10; i++ {
+		bus.publish(&OrderAccepted{})
+	}
+	assert.True(t, sub.Overflowed(), "subscriber past its limit should be dropped")
+	assert.Empty(t, bus.subscribers, "bus should forget the dropped subscriber")
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.LessOrEqual(t, received, 11, "dropped subscriber should not receive the queued events")
+}
+
+func TestTradingPlatformLimitOrderEvents(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	order := NewOrder(Bid, 5)
+	tradingPlatform.PlaceLimitOrder(pair, 250, order)
+
+	events := receiveEvents(t, sub, 2)
+
+	accepted := events[0].(*OrderAccepted)
+	assert.Equal(t, order.ID, accepted.Order.ID, "accepted event should carry the order")
+	assert.Equal(t, pair, accepted.Market, "accepted event should carry the market")
+
+	level := events[1].(*BookLevelChanged)
+	assert.Equal(t, Bid, level.Side, "level change should be on the bid side")
+	assert.Equal(t, float64(250), level.Price, "level change should be at 250")
+	assert.Equal(t, float64(5), level.TotalVolume, "level should have a total volume of 5")
+}
+
+func TestTradingPlatformMarketOrderEvents(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	sellOrder1 := NewOrder(Ask, 2)
+	sellOrder2 := NewOrder(Ask, 8)
+	tradingPlatform.PlaceLimitOrder(pair, 240, sellOrder1)
+	tradingPlatform.PlaceLimitOrder(pair, 250, sellOrder2)
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	buyOrder := NewOrder(Bid, 3)
+	tradingPlatform.PlaceMarketOrder(pair, buyOrder)
+
+	events := receiveEvents(t, sub, 9)
+
+	assert.IsType(t, &OrderAccepted{}, events[0], "market order should be accepted first")
+
+	trade := events[1].(*TradeExecuted)
+	assert.Equal(t, Trade{sellOrder1.ID, buyOrder.ID, Bid, 240, 2, 0, 0}, trade.Trade, "first trade should be at the best ask")
+
+	makerFill := events[2].(*OrderFilled)
+	assert.Equal(t, sellOrder1.ID, makerFill.OrderID, "maker should be filled")
+	assert.Equal(t, float64(0), makerFill.Remaining, "maker should be fully filled")
+
+	takerFill := events[3].(*OrderFilled)
+	assert.Equal(t, buyOrder.ID, takerFill.OrderID, "taker should be filled")
+	assert.Equal(t, float64(1), takerFill.Remaining, "taker should have 1 remaining")
+
+	trade = events[4].(*TradeExecuted)
+	assert.Equal(t, Trade{sellOrder2.ID, buyOrder.ID, Bid, 250, 1, 0, 0}, trade.Trade, "second trade should be at the next level")
+
+	takerFill = events[6].(*OrderFilled)
+	assert.Equal(t, float64(0), takerFill.Remaining, "taker should be fully filled")
+
+	level := events[7].(*BookLevelChanged)
+	assert.Equal(t, float64(240), level.Price, "first touched level should be 240")
+	assert.Equal(t, float64(0), level.TotalVolume, "240 level should be empty")
+
+	level = events[8].(*BookLevelChanged)
+	assert.Equal(t, float64(250), level.Price, "second touched level should be 250")
+	assert.Equal(t, float64(7), level.TotalVolume, "250 level should have 7 left")
+
+	for i := 1; i < len(events); i++ {
+		assert.Equal(t, events[i-1].Meta().Sequence+1, events[i].Meta().Sequence, "sequence should have no gaps")
+	}
+}
+
+func TestTradingPlatformRejectedOrderEvents(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	buyOrder := NewOrder(Bid, 3)
+	_, err := tradingPlatform.PlaceMarketOrder(pair, buyOrder)
+
+	events := receiveEvents(t, sub, 1)
+
+	rejected := events[0].(*OrderRejected)
+	assert.Equal(t, buyOrder.ID, rejected.Order.ID, "rejected event should carry the order")
+	assert.Equal(t, err.Error(), rejected.Reason, "rejected event should carry the reason")
+}
+
+func TestTradingPlatformCancelOrderEvents(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	order := NewOrder(Ask, 4)
+	tradingPlatform.PlaceLimitOrder(pair, 300, order)
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	tradingPlatform.CancelOrder(pair, order.ID)
+
+	events := receiveEvents(t, sub, 2)
+
+	cancelled := events[0].(*OrderCancelled)
+	assert.Equal(t, order.ID, cancelled.Order.ID, "cancelled event should carry the order")
+	assert.Equal(t, float64(300), cancelled.Order.Price, "cancelled event should carry the resting price")
+
+	level := events[1].(*BookLevelChanged)
+	assert.Equal(t, float64(0), level.TotalVolume, "cancelled level should be empty")
+}
+
+func TestTradingPlatformSelfTradeEvents(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	own := NewOrder(Ask, 2)
+	own.Signer = "alice"
+	other := NewOrder(Ask, 8)
+	other.Signer = "bob"
+	tradingPlatform.PlaceLimitOrder(pair, 240, own)
+	tradingPlatform.PlaceLimitOrder(pair, 250, other)
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	buyOrder := NewOrder(Bid, 3)
+	buyOrder.Signer = "alice"
+	buyOrder.SelfTradePrevention = STPCancelOldest
+	matches, err := tradingPlatform.PlaceMarketOrder(pair, buyOrder)
+
+	assert.Nil(t, err, "market order should be placed")
+	assert.Equal(t, 1, len(matches), "taker should only match bob")
+
+	events := receiveEvents(t, sub, 8)
+
+	assert.IsType(t, &OrderAccepted{}, events[0], "market order should be accepted first")
+
+	prevented := events[1].(*SelfTradePrevented)
+	assert.Equal(t, own.ID, prevented.MakerOrderID, "alice's resting order should be the maker")
+	assert.Equal(t, float64(2), prevented.MakerCancelled, "resting order should be cancelled")
+	assert.Equal(t, float64(3), prevented.TakerRemaining, "taker should keep its size")
+
+	cancelled := events[2].(*OrderCancelled)
+	assert.Equal(t, own.ID, cancelled.Order.ID, "resting order should be cancelled")
+	assert.Equal(t, float64(240), cancelled.Order.Price, "cancelled order should carry its price")
+
+	trade := events[3].(*TradeExecuted)
+	assert.Equal(t, Trade{other.ID, buyOrder.ID, Bid, 250, 3, 0, 0}, trade.Trade, "taker should trade with bob")
+
+	takerFill := events[5].(*OrderFilled)
+	assert.Equal(t, float64(0), takerFill.Remaining, "taker should be fully filled")
+
+	level := events[6].(*BookLevelChanged)
+	assert.Equal(t, float64(240), level.Price, "first touched level should be 240")
+	assert.Equal(t, float64(0), level.TotalVolume, "240 level should be empty")
+
+	level = events[7].(*BookLevelChanged)
+	assert.Equal(t, float64(5), level.TotalVolume, "250 level should have 5 left")
+
+	_, err = tradingPlatform.GetOrder(pair, own.ID)
+	assert.IsType(t, &OrderNotFoundError{}, err, "cancelled order should be forgotten")
+}
+
+func TestTradingPlatformSelfTradeCancelNewest(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	tradingPlatform.SetSelfTradePrevention(STPCancelNewest)
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	own := NewOrder(Ask, 2)
+	own.Signer = "alice"
+	tradingPlatform.PlaceLimitOrder(pair, 240, own)
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	buyOrder := NewOrder(Bid, 2)
+	buyOrder.Signer = "alice"
+	matches, err := tradingPlatform.PlaceMarketOrder(pair, buyOrder)
+
+	assert.Nil(t, err, "market order should be placed")
+	assert.Equal(t, 0, len(matches), "taker should not match")
+	assert.Equal(t, STPCancelNewest, buyOrder.SelfTradePrevention, "order should be given the platform default")
+
+	events := receiveEvents(t, sub, 4)
+
+	prevented := events[1].(*SelfTradePrevented)
+	assert.Equal(t, float64(2), prevented.TakerCancelled, "taker should be cancelled")
+	assert.Equal(t, float64(2), prevented.MakerRemaining, "resting order should be untouched")
+
+	cancelled := events[2].(*OrderCancelled)
+	assert.Equal(t, buyOrder.ID, cancelled.Order.ID, "taker should be cancelled")
+
+	level := events[3].(*BookLevelChanged)
+	assert.Equal(t, float64(2), level.TotalVolume, "240 level should be unchanged")
+}
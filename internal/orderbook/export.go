@@ -0,0 +1,286 @@
+package orderbook
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ExportView is the data of a market that Export writes.
+type ExportView string
+
+const (
+	// ExportBook is the total size at each price level, in the paired layout
+	// of the seed files.
+	ExportBook ExportView = "book"
+	// ExportOrders is every resting order in priority order, in the per order
+	// import layout.
+	ExportOrders ExportView = "orders"
+	// ExportTrades is the trade history of the market, oldest first.
+	ExportTrades ExportView = "trades"
+)
+
+type ExportFormat string
+
+const (
+	FormatCSV  ExportFormat = "csv"
+	FormatJSON ExportFormat = "json"
+)
+
+// LevelExport is the total size resting at a price.
+type LevelExport struct {
+	Price  float64 `json:"price"`
+	Amount float64 `json:"amount"`
+}
+
+type BookExport struct {
+	Market TradingPair   `json:"market"`
+	Asks   []LevelExport `json:"asks"`
+	Bids   []LevelExport `json:"bids"`
+}
+
+// OrderExport is a resting order without its owner, so exported books can be
+// shared and imported elsewhere.
+type OrderExport struct {
+	Side  Side    `json:"side"`
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+// Export writes a view of a market as CSV or JSON. CSV books and orders can
+// be imported again with ImportOrders.
+func (platform *TradingPlatform) Export(w io.Writer, pair TradingPair, view ExportView, format ExportFormat) error {
+	if format != FormatCSV && format != FormatJSON {
+		return &InvalidExportError{"format", string(format)}
+	}
+
+	var data interface{}
+	var rows [][]string
+	var err error
+	switch view {
+	case ExportBook:
+		var book BookExport
+		book, err = platform.exportBook(pair)
+		data, rows = book, bookRows(book)
+	case ExportOrders:
+		var orders []OrderExport
+		orders, err = platform.exportOrders(pair)
+		data, rows = orders, orderRows(orders)
+	case ExportTrades:
+		var trades []TradeRecord
+		trades, err = platform.exportTrades(pair)
+		data, rows = trades, tradeRows(trades)
+	default:
+		return &InvalidExportError{"view", string(view)}
+	}
+	if err != nil {
+		return err
+	}
+
+	if format == FormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+// lockedOrderBook calls fn with the orderbook of a market while nothing can
+// change it.
+func (platform *TradingPlatform) lockedOrderBook(pair TradingPair, fn func(orderbook *Orderbook)) error {
+	platform.mu.RLock()
+	defer platform.mu.RUnlock()
+
+	orderbook, ok := platform.Orderbooks[pair]
+	if !ok {
+		return &OrderbookNotFoundError{pair}
+	}
+
+	orderbook.mu.Lock()
+	defer orderbook.mu.Unlock()
+
+	fn(orderbook)
+	return nil
+}
+
+// Book returns the price levels of a market, best first.
+func (platform *TradingPlatform) Book(pair TradingPair) (BookExport, error) {
+	return platform.exportBook(pair)
+}
+
+// OrderBookCopy returns a copy of the orderbook of a market, with its levels
+// sorted best first, that can be read and encoded while the market trades.
+func (platform *TradingPlatform) OrderBookCopy(pair TradingPair) (*Orderbook, error) {
+	var book *Orderbook
+	err := platform.lockedOrderBook(pair, func(orderbook *Orderbook) {
+		book = &Orderbook{
+			Market:         orderbook.Market,
+			Asks:           copyLimits(orderbook.GetAsks()),
+			Bids:           copyLimits(orderbook.GetBids()),
+			State:          orderbook.State,
+			ResumeAt:       orderbook.ResumeAt,
+			LastTradePrice: orderbook.LastTradePrice,
+			Fees:           orderbook.Fees,
+		}
+		if orderbook.Indicative != nil {
+			indicative := *orderbook.Indicative
+			book.Indicative = &indicative
+		}
+	})
+
+	return book, err
+}
+
+func copyLimits(limits []*Limit) []*Limit {
+	copied := make([]*Limit, len(limits))
+	for i, limit := range limits {
+		orders := make([]*Order, len(limit.Orders))
+		for j, order := range limit.Orders {
+			o := *order
+			orders[j] = &o
+		}
+		copied[i] = &Limit{Price: limit.Price, TotalVolume: limit.TotalVolume, Orders: orders}
+	}
+
+	return copied
+}
+
+// copyMatches copies matches and the orders they point to.
+func copyMatches(matches []Match) []Match {
+	if matches == nil {
+		return nil
+	}
+
+	copied := make([]Match, len(matches))
+	for i, match := range matches {
+		ask, bid := *match.Ask, *match.Bid
+		match.Ask, match.Bid = &ask, &bid
+		copied[i] = match
+	}
+
+	return copied
+}
+
+// SequencedBook is the book and state of a market as of the event numbered
+// Sequence, so that a subscriber from before it was taken can skip the events
+// it already reflects.
+type SequencedBook struct {
+	BookExport
+	State          TradingState
+	LastTradePrice float64
+	Sequence       uint64
+}
+
+// SequencedBook returns the book of a market with the sequence of the last
+// event published. Events are published while the platform is locked, so the
+// book reflects exactly the events up to Sequence.
+func (platform *TradingPlatform) SequencedBook(pair TradingPair) (SequencedBook, error) {
+	book := SequencedBook{BookExport: BookExport{Market: pair}}
+	err := platform.lockedOrderBook(pair, func(orderbook *Orderbook) {
+		book.Asks, book.Bids = levels(orderbook)
+		book.State, book.LastTradePrice = orderbook.State, orderbook.LastTradePrice
+		book.Sequence = platform.events.lastSequence()
+	})
+
+	return book, err
+}
+
+func (platform *TradingPlatform) exportBook(pair TradingPair) (BookExport, error) {
+	book := BookExport{Market: pair}
+	err := platform.lockedOrderBook(pair, func(orderbook *Orderbook) {
+		book.Asks, book.Bids = levels(orderbook)
+	})
+
+	return book, err
+}
+
+func levels(orderbook *Orderbook) ([]LevelExport, []LevelExport) {
+	asks, bids := []LevelExport{}, []LevelExport{}
+	for _, limit := range orderbook.GetAsks() {
+		asks = append(asks, LevelExport{limit.Price, limit.TotalVolume})
+	}
+	for _, limit := range orderbook.GetBids() {
+		bids = append(bids, LevelExport{limit.Price, limit.TotalVolume})
+	}
+
+	return asks, bids
+}
+
+func (platform *TradingPlatform) exportOrders(pair TradingPair) ([]OrderExport, error) {
+	orders := []OrderExport{}
+	err := platform.lockedOrderBook(pair, func(orderbook *Orderbook) {
+		for _, limits := range [][]*Limit{orderbook.GetAsks(), orderbook.GetBids()} {
+			for _, limit := range limits {
+				for _, order := range limit.Orders {
+					orders = append(orders, OrderExport{order.Side, limit.Price, order.Size})
+				}
+			}
+		}
+	})
+
+	return orders, err
+}
+
+func (platform *TradingPlatform) exportTrades(pair TradingPair) ([]TradeRecord, error) {
+	var trades []TradeRecord
+	err := platform.lockedOrderBook(pair, func(orderbook *Orderbook) {
+		trades = append([]TradeRecord{}, orderbook.trades...)
+	})
+
+	return trades, err
+}
+
+func bookRows(book BookExport) [][]string {
+	rows := [][]string{{"Ask Price", "Ask Amount", "Bid Price", "Bid Amount"}}
+	for i := 0; i < len(book.Asks) || i < len(book.Bids); i++ {
+		row := make([]string, 4)
+		if i < len(book.Asks) {
+			row[0], row[1] = formatAmount(book.Asks[i].Price), formatAmount(book.Asks[i].Amount)
+		}
+		if i < len(book.Bids) {
+			row[2], row[3] = formatAmount(book.Bids[i].Price), formatAmount(book.Bids[i].Amount)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func orderRows(orders []OrderExport) [][]string {
+	rows := [][]string{{"Side", "Price", "Size"}}
+	for _, order := range orders {
+		rows = append(rows, []string{string(order.Side), formatAmount(order.Price), formatAmount(order.Size)})
+	}
+
+	return rows
+}
+
+func tradeRows(trades []TradeRecord) [][]string {
+	rows := [][]string{{"Executed At", "Price", "Size", "Taker Side", "Ask Order ID", "Bid Order ID", "Ask Fee", "Bid Fee"}}
+	for _, trade := range trades {
+		rows = append(rows, []string{
+			time.Unix(0, trade.ExecutedAt).UTC().Format(time.RFC3339Nano),
+			formatAmount(trade.Price),
+			formatAmount(trade.Size),
+			string(trade.TakerSide),
+			strconv.FormatUint(trade.AskOrderID, 10),
+			strconv.FormatUint(trade.BidOrderID, 10),
+			formatAmount(trade.AskFee),
+			formatAmount(trade.BidFee),
+		})
+	}
+
+	return rows
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
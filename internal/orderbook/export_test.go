@@ -0,0 +1,164 @@
+package orderbook
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportPlatform(t *testing.T) (*TradingPlatform, TradingPair) {
+	t.Helper()
+
+	tradingPlatform := NewTradingPlatform()
+	tradingPlatform.SetClock(&manualClock{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)})
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+	tradingPlatform.PlaceLimitOrder(pair, 101, NewOrder(Ask, 1))
+	tradingPlatform.PlaceLimitOrder(pair, 101, NewOrder(Ask, 2.5))
+	tradingPlatform.PlaceLimitOrder(pair, 102, NewOrder(Ask, 1))
+	tradingPlatform.PlaceLimitOrder(pair, 99, NewOrder(Bid, 4))
+
+	return tradingPlatform, pair
+}
+
+func export(t *testing.T, platform *TradingPlatform, pair TradingPair, view ExportView, format ExportFormat) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, platform.Export(&buf, pair, view, format), "export should not return an error")
+
+	return buf.String()
+}
+
+func TestExportBookCSV(t *testing.T) {
+	tradingPlatform, pair := exportPlatform(t)
+
+	book := export(t, tradingPlatform, pair, ExportBook, FormatCSV)
+	assert.Equal(t, "Ask Price,Ask Amount,Bid Price,Bid Amount\n101,3.5,99,4\n102,1,,\n", book, "book should be exported in the seed layout")
+
+	imported := NewTradingPlatform()
+	imported.AddNewMarket(pair)
+	_, err := imported.ImportOrders(pair, strings.NewReader(book))
+	require.NoError(t, err, "exported book should import")
+	assert.Equal(t, book, export(t, imported, pair, ExportBook, FormatCSV), "book should round trip")
+}
+
+func TestOrderBookCopy(t *testing.T) {
+	tradingPlatform, pair := exportPlatform(t)
+
+	book, err := tradingPlatform.OrderBookCopy(pair)
+	require.NoError(t, err)
+	require.Len(t, book.Asks, 2)
+	assert.Equal(t, float64(101), book.Asks[0].Price, "asks should be sorted best first")
+	assert.Equal(t, 3.5, book.Asks[0].TotalVolume)
+
+	tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1))
+	assert.Equal(t, 3.5, book.Asks[0].TotalVolume, "copy should not change as the market trades")
+	assert.Equal(t, float64(1), book.Asks[0].Orders[0].Size)
+
+	_, err = tradingPlatform.OrderBookCopy(TradingPair{"ETH", "USD"})
+	assert.IsType(t, &OrderbookNotFoundError{}, err)
+}
+
+func TestSequencedBook(t *testing.T) {
+	tradingPlatform, pair := exportPlatform(t)
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1))
+	book, err := tradingPlatform.SequencedBook(pair)
+	require.NoError(t, err)
+
+	assert.Equal(t, []LevelExport{{101, 2.5}, {102, 1}}, book.Asks, "book should reflect the fill")
+	assert.Equal(t, float64(101), book.LastTradePrice)
+	assert.Equal(t, StateOpen, book.State)
+
+	var last uint64
+	for last < book.Sequence {
+		select {
+		case event := <-sub.C:
+			last = event.Meta().Sequence
+		case <-time.After(time.Second):
+			t.Fatalf("no event with sequence %d", book.Sequence)
+		}
+	}
+	assert.Equal(t, book.Sequence, last, "sequence should be that of the market order's last event")
+
+	_, err = tradingPlatform.SequencedBook(TradingPair{"ETH", "USD"})
+	assert.IsType(t, &OrderbookNotFoundError{}, err)
+}
+
+func TestExportOrdersCSV(t *testing.T) {
+	tradingPlatform, pair := exportPlatform(t)
+
+	orders := export(t, tradingPlatform, pair, ExportOrders, FormatCSV)
+	assert.Equal(t, "Side,Price,Size\nask,101,1\nask,101,2.5\nask,102,1\nbid,99,4\n", orders, "orders should be exported in priority order")
+
+	imported := NewTradingPlatform()
+	imported.AddNewMarket(pair)
+	_, err := imported.ImportOrders(pair, strings.NewReader(orders))
+	require.NoError(t, err, "exported orders should import")
+	assert.Equal(t, orders, export(t, imported, pair, ExportOrders, FormatCSV), "orders should round trip")
+}
+
+func TestExportBookJSON(t *testing.T) {
+	tradingPlatform, pair := exportPlatform(t)
+
+	var book BookExport
+	require.NoError(t, json.Unmarshal([]byte(export(t, tradingPlatform, pair, ExportBook, FormatJSON)), &book))
+	assert.Equal(t, BookExport{
+		Market: pair,
+		Asks:   []LevelExport{{101, 3.5}, {102, 1}},
+		Bids:   []LevelExport{{99, 4}},
+	}, book, "book should be exported as JSON")
+}
+
+func TestExportTrades(t *testing.T) {
+	tradingPlatform, pair := exportPlatform(t)
+	tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1.5))
+
+	trades := export(t, tradingPlatform, pair, ExportTrades, FormatCSV)
+	assert.Equal(t, strings.Join([]string{
+		"Executed At,Price,Size,Taker Side,Ask Order ID,Bid Order ID,Ask Fee,Bid Fee",
+		"2024-01-02T03:04:05Z,101,1,bid,1,5,0,0",
+		"2024-01-02T03:04:05Z,101,0.5,bid,2,5,0,0",
+		"",
+	}, "\n"), trades, "trades should be exported oldest first")
+
+	var records []TradeRecord
+	require.NoError(t, json.Unmarshal([]byte(export(t, tradingPlatform, pair, ExportTrades, FormatJSON)), &records))
+	require.Equal(t, 2, len(records), "every trade should be exported as JSON")
+	assert.Equal(t, uint64(2), records[1].AskOrderID, "trades should keep their orders")
+
+	restored := NewTradingPlatform()
+	require.NoError(t, restored.Restore(tradingPlatform.Snapshot()))
+	assert.Equal(t, trades, export(t, restored, pair, ExportTrades, FormatCSV), "trade history should survive a snapshot")
+}
+
+func TestTradeHistoryLimit(t *testing.T) {
+	orderbook := newOrderBook()
+	matches := make([]Match, maxTradeHistory+5)
+	for i := range matches {
+		matches[i] = Match{Ask: &Order{ID: uint64(i)}, Bid: &Order{}, SizeFilled: 1, Price: 1}
+	}
+
+	orderbook.addTrades(matches, Bid, time.Unix(0, 0))
+	assert.Equal(t, maxTradeHistory, len(orderbook.trades), "history should be capped")
+	assert.Equal(t, uint64(5), orderbook.trades[0].AskOrderID, "oldest trades should be dropped")
+}
+
+func TestExportInvalid(t *testing.T) {
+	tradingPlatform, pair := exportPlatform(t)
+
+	err := tradingPlatform.Export(&bytes.Buffer{}, pair, "levels", FormatCSV)
+	assert.IsType(t, &InvalidExportError{}, err, "unknown view should be refused")
+	err = tradingPlatform.Export(&bytes.Buffer{}, pair, ExportBook, "xml")
+	assert.IsType(t, &InvalidExportError{}, err, "unknown format should be refused")
+	err = tradingPlatform.Export(&bytes.Buffer{}, TradingPair{"ETH", "USD"}, ExportBook, FormatCSV)
+	assert.IsType(t, &OrderbookNotFoundError{}, err, "unknown market should be refused")
+}
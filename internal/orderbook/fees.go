@@ -0,0 +1,157 @@
+package orderbook
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/kr/pretty"
+	"github.com/richo225/octgopus/internal/accounting"
+)
+
+// FeeTier replaces the base rates of a FeeSchedule for signers who have
+// traded at least MinVolume, in the quote asset, on the market.
+type FeeTier struct {
+	MinVolume float64 `json:"min_volume"`
+	MakerRate float64 `json:"maker_rate"`
+	TakerRate float64 `json:"taker_rate"`
+}
+
+// FeeSchedule sets the fees of a market as a fraction of the quote value of
+// each fill. A negative maker rate pays makers a rebate.
+type FeeSchedule struct {
+	MakerRate float64 `json:"maker_rate"`
+	TakerRate float64 `json:"taker_rate"`
+	// Tiers are ordered by increasing MinVolume.
+	Tiers []FeeTier `json:"tiers,omitempty"`
+}
+
+// Validate checks the rates and tiers of a schedule.
+func (schedule *FeeSchedule) Validate() error {
+	if err := validateRates(schedule.MakerRate, schedule.TakerRate); err != nil {
+		return err
+	}
+
+	for i, tier := range schedule.Tiers {
+		if tier.MinVolume < 0 || (i > 0 && tier.MinVolume <= schedule.Tiers[i-1].MinVolume) {
+			return &InvalidFeeScheduleError{fmt.Errorf("tier %d must have a larger min_volume than the one before", i)}
+		}
+		if err := validateRates(tier.MakerRate, tier.TakerRate); err != nil {
+			return fmt.Errorf("tier %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateRates keeps rates below 100% and rebates no larger than the taker
+// fee, so the platform never pays out more than it takes on a trade.
+func validateRates(maker float64, taker float64) error {
+	switch {
+	case taker < 0 || taker >= 1:
+		return &InvalidFeeScheduleError{errors.New("taker rate must be at least 0 and below 1")}
+	case maker >= 1:
+		return &InvalidFeeScheduleError{errors.New("maker rate must be below 1")}
+	case maker+taker < 0:
+		return &InvalidFeeScheduleError{errors.New("maker rebate must not be larger than the taker rate")}
+	}
+
+	return nil
+}
+
+// rates returns the maker and taker rates of a signer who has traded volume.
+func (schedule *FeeSchedule) rates(volume float64) (float64, float64) {
+	maker, taker := schedule.MakerRate, schedule.TakerRate
+	for _, tier := range schedule.Tiers {
+		if volume >= tier.MinVolume {
+			maker, taker = tier.MakerRate, tier.TakerRate
+		}
+	}
+
+	return maker, taker
+}
+
+// SetFeeSchedule sets the fees of a market. A nil schedule makes trading on it
+// free. Setting the schedule a market already has is not journaled again.
+func (platform *TradingPlatform) SetFeeSchedule(pair TradingPair, schedule *FeeSchedule) error {
+	platform.mu.RLock()
+	orderbook, ok := platform.Orderbooks[pair]
+	unchanged := ok && reflect.DeepEqual(orderbook.Fees, schedule)
+	platform.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	_, err := platform.submit(Command{Type: SetFeeScheduleCommand, Market: &pair, Fees: schedule})
+	return err
+}
+
+func (platform *TradingPlatform) setFeeSchedule(pair TradingPair, schedule *FeeSchedule) error {
+	if schedule != nil {
+		if err := schedule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	orderbook, err := platform.GetOrderBook(pair)
+	if err != nil {
+		return err
+	}
+
+	orderbook.Fees = schedule
+	return nil
+}
+
+// chargeFees works out the fee of both sides of every match, charges them to
+// the signers' accounts and adds the trades to the signers' volumes. Auction
+// trades have no taker side and both sides pay the maker rate. Orders without
+// a signer, or whose signer has no account, pay no fee, and a fee is capped at
+// the largest amount the ledger accepts.
+func (platform *TradingPlatform) chargeFees(orderbook *Orderbook, matches []Match, takerSide Side, at time.Time) {
+	for i := range matches {
+		match := &matches[i]
+		notional := match.SizeFilled * match.Price
+
+		match.AskFee = platform.chargeFee(orderbook, match.Ask, takerSide == Ask, notional, at)
+		match.BidFee = platform.chargeFee(orderbook, match.Bid, takerSide == Bid, notional, at)
+
+		for _, order := range []*Order{match.Ask, match.Bid} {
+			if order.Signer != "" {
+				orderbook.volumes[order.Signer] += notional
+			}
+		}
+	}
+}
+
+func (platform *TradingPlatform) chargeFee(orderbook *Orderbook, order *Order, taker bool, notional float64, at time.Time) float64 {
+	if orderbook.Fees == nil || order.Signer == "" {
+		return 0
+	}
+
+	maker, takerRate := orderbook.Fees.rates(orderbook.volumes[order.Signer])
+	rate := maker
+	if taker {
+		rate = takerRate
+	}
+
+	fee := accounting.RoundAmount(notional * rate)
+	if fee == 0 {
+		return 0
+	}
+	if _, err := platform.Accounts.BalanceOf(order.Signer); err != nil {
+		return 0
+	}
+	fee = math.Max(-accounting.MaxAmount, math.Min(fee, accounting.MaxAmount))
+
+	// The fee has been checked, so an error is unexpected. The trade stands
+	// without it, as the match cannot be undone.
+	memo := fmt.Sprintf("%s order %d", orderbook.Market.ToString(), order.ID)
+	if _, err := platform.Accounts.ChargeFeeAt(order.Signer, fee, memo, at); err != nil {
+		pretty.Log("Failed to charge fee", memo, order.Signer, err.Error())
+		return 0
+	}
+
+	return fee
+}
@@ -0,0 +1,149 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/richo225/octgopus/internal/accounting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func feePlatform(t *testing.T, platform *TradingPlatform, pair TradingPair) {
+	t.Helper()
+
+	platform.AddNewMarket(pair)
+	for _, signer := range []string{"alice", "bob"} {
+		platform.CreateAccount(signer)
+		platform.Deposit(signer, 1000)
+	}
+
+	require.NoError(t, platform.SetFeeSchedule(pair, &FeeSchedule{
+		MakerRate: -0.001,
+		TakerRate: 0.002,
+		Tiers:     []FeeTier{{MinVolume: 200, MakerRate: 0, TakerRate: 0.001}},
+	}), "setting a valid schedule should not return an error")
+}
+
+func TestTradingPlatformFees(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	feePlatform(t, tradingPlatform, pair)
+
+	tradingPlatform.PlaceLimitOrder(pair, 100, signedOrder(Ask, 5, "alice"))
+
+	matches, err := tradingPlatform.PlaceMarketOrder(pair, signedOrder(Bid, 2, "bob"))
+	require.NoError(t, err, "market order should be accepted")
+	assert.Equal(t, -0.2, matches[0].AskFee, "maker should be paid the rebate")
+	assert.Equal(t, 0.4, matches[0].BidFee, "taker should pay the taker rate")
+
+	// Both signers have now traded 200 and moved up a tier.
+	matches, _ = tradingPlatform.PlaceMarketOrder(pair, signedOrder(Bid, 1, "bob"))
+	assert.Equal(t, 0.0, matches[0].AskFee, "maker on the tier should pay nothing")
+	assert.Equal(t, 0.1, matches[0].BidFee, "taker on the tier should pay the tier rate")
+
+	alice, _ := tradingPlatform.Accounts.BalanceOf("alice")
+	bob, _ := tradingPlatform.Accounts.BalanceOf("bob")
+	assert.Equal(t, 1000.2, alice, "rebate should be credited to the maker")
+	assert.Equal(t, 999.5, bob, "fees should be taken from the taker")
+	assert.InDelta(t, 0.3, tradingPlatform.Accounts.Ledger.BalanceOf(accounting.FeesAccount), 1e-9, "fee account should hold the fees less the rebates")
+	assert.NoError(t, tradingPlatform.Accounts.Reconcile(), "ledger should stay balanced")
+}
+
+func TestTradingPlatformFeesOnTradeEvents(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	feePlatform(t, tradingPlatform, pair)
+	tradingPlatform.PlaceLimitOrder(pair, 100, signedOrder(Ask, 5, "alice"))
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	tradingPlatform.PlaceMarketOrder(pair, signedOrder(Bid, 2, "bob"))
+
+	var trade *TradeExecuted
+	for _, event := range receiveEvents(t, sub, 5) {
+		if executed, ok := event.(*TradeExecuted); ok {
+			trade = executed
+		}
+	}
+	require.NotNil(t, trade, "market order should publish a trade")
+	assert.Equal(t, -0.2, trade.Trade.AskFee, "trade should report the maker rebate")
+	assert.Equal(t, 0.4, trade.Trade.BidFee, "trade should report the taker fee")
+}
+
+func TestTradingPlatformFeesWithoutAccount(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	feePlatform(t, tradingPlatform, pair)
+	tradingPlatform.PlaceLimitOrder(pair, 100, NewOrder(Ask, 5))
+
+	matches, err := tradingPlatform.PlaceMarketOrder(pair, signedOrder(Bid, 1, "carol"))
+	require.NoError(t, err, "signers without an account should still trade")
+	assert.Equal(t, 0.0, matches[0].AskFee, "unsigned orders should pay no fee")
+	assert.Equal(t, 0.0, matches[0].BidFee, "signers without an account should pay no fee")
+}
+
+func TestTradingPlatformFeeCap(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	feePlatform(t, tradingPlatform, pair)
+	tradingPlatform.PlaceLimitOrder(pair, 1e6, signedOrder(Ask, 1e9, "alice"))
+
+	matches, err := tradingPlatform.PlaceMarketOrder(pair, signedOrder(Bid, 1e9, "bob"))
+	require.NoError(t, err)
+	assert.Equal(t, accounting.MaxAmount, matches[0].BidFee, "fee above the largest ledger amount should be capped, not dropped")
+
+	bob, _ := tradingPlatform.Accounts.BalanceOf("bob")
+	assert.Equal(t, 1000-accounting.MaxAmount, bob, "capped fee should be charged")
+	assert.NoError(t, tradingPlatform.Accounts.Reconcile(), "ledger should stay balanced")
+}
+
+func TestFeeScheduleValidation(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	tests := map[string]*FeeSchedule{
+		"negative taker rate":    {MakerRate: 0, TakerRate: -0.001},
+		"taker rate of 100%":     {MakerRate: 0, TakerRate: 1},
+		"rebate over taker rate": {MakerRate: -0.003, TakerRate: 0.002},
+		"tiers out of order": {MakerRate: 0, TakerRate: 0.002, Tiers: []FeeTier{
+			{MinVolume: 1000, MakerRate: 0, TakerRate: 0.001},
+			{MinVolume: 500, MakerRate: 0, TakerRate: 0.0015},
+		}},
+		"invalid tier rates": {MakerRate: 0, TakerRate: 0.002, Tiers: []FeeTier{{MinVolume: 1000, TakerRate: 2}}},
+	}
+
+	for name, schedule := range tests {
+		err := tradingPlatform.SetFeeSchedule(pair, schedule)
+		assert.ErrorAs(t, err, new(*InvalidFeeScheduleError), name+" should be refused")
+	}
+
+	orderbook, _ := tradingPlatform.GetOrderBook(pair)
+	assert.Nil(t, orderbook.Fees, "refused schedules should not be set")
+
+	err := tradingPlatform.SetFeeSchedule(TradingPair{"ETH", "USD"}, &FeeSchedule{TakerRate: 0.001})
+	assert.IsType(t, &OrderbookNotFoundError{}, err, "unknown market should be refused")
+}
+
+func TestTradingPlatformFeesRecover(t *testing.T) {
+	dir := t.TempDir()
+	pair := TradingPair{"BTC", "USD"}
+
+	platform := recoverPlatform(t, dir)
+	feePlatform(t, platform, pair)
+	platform.PlaceLimitOrder(pair, 100, signedOrder(Ask, 5, "alice"))
+	platform.PlaceMarketOrder(pair, signedOrder(Bid, 2, "bob"))
+
+	restored := recoverPlatform(t, dir)
+	orderbook, _ := restored.GetOrderBook(pair)
+	assert.Equal(t, 0.002, orderbook.Fees.TakerRate, "fee schedule should be replayed")
+	assert.Equal(t, 200.0, orderbook.volumes["bob"], "traded volume should be replayed")
+
+	bob, _ := restored.Accounts.BalanceOf("bob")
+	assert.Equal(t, 999.6, bob, "fees should be replayed")
+
+	snapshot := NewTradingPlatform()
+	require.NoError(t, snapshot.Restore(restored.Snapshot()), "restoring a snapshot should not return an error")
+	assert.Equal(t, restored.Snapshot(), snapshot.Snapshot(), "fees and volumes should survive a snapshot")
+}
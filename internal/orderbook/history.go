@@ -0,0 +1,121 @@
+package orderbook
+
+import (
+	"time"
+
+	"github.com/kr/pretty"
+)
+
+type OrderStatus string
+
+const (
+	StatusOpen            OrderStatus = "open"
+	StatusPartiallyFilled OrderStatus = "partially_filled"
+	StatusFilled          OrderStatus = "filled"
+	StatusCancelled       OrderStatus = "cancelled"
+	StatusRejected        OrderStatus = "rejected"
+)
+
+// OrderRecord is the latest known state of an order in the order history.
+type OrderRecord struct {
+	Market    TradingPair `json:"market"`
+	ID        uint64      `json:"id"`
+	Side      Side        `json:"side"`
+	Price     float64     `json:"price"`
+	Size      float64     `json:"size"`
+	Signer    string      `json:"signer,omitempty"`
+	Remaining float64     `json:"remaining"`
+	Status    OrderStatus `json:"status"`
+	Reason    string      `json:"reason,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// Repository persists the order and trade history of every market outside of
+// memory, e.g. for back office reporting.
+type Repository interface {
+	SaveOrder(record *OrderRecord) error
+	UpdateOrder(id uint64, remaining float64, status OrderStatus, updatedAt time.Time) error
+	// ReduceOrder lowers the remaining size of an order without changing its
+	// status, e.g. when self-trade prevention decrements it.
+	ReduceOrder(id uint64, remaining float64, updatedAt time.Time) error
+	SaveTrade(trade *TradeExecuted) error
+}
+
+// HistoryRecorder subscribes to the platform events and writes the order and
+// trade history to a Repository, away from the matching loop.
+type HistoryRecorder struct {
+	repository Repository
+	sub        *Subscription
+	done       chan struct{}
+}
+
+func NewHistoryRecorder(platform *TradingPlatform, repository Repository) *HistoryRecorder {
+	return &HistoryRecorder{
+		repository: repository,
+		sub:        platform.Subscribe(),
+		done:       make(chan struct{}),
+	}
+}
+
+func (r *HistoryRecorder) Start() {
+	go func() {
+		defer close(r.done)
+
+		for event := range r.sub.C {
+			if err := r.record(event); err != nil {
+				pretty.Log("Failed to record event", event.Meta().Sequence, err.Error())
+			}
+		}
+	}()
+}
+
+// Stop waits for every event published so far to be recorded.
+func (r *HistoryRecorder) Stop() {
+	r.sub.Close()
+	<-r.done
+}
+
+func (r *HistoryRecorder) record(event Event) error {
+	at := time.Unix(0, event.Meta().Timestamp)
+
+	switch e := event.(type) {
+	case *OrderAccepted:
+		return r.repository.SaveOrder(newOrderRecord(e.Market, e.Order, StatusOpen, at))
+	case *OrderRejected:
+		record := newOrderRecord(e.Market, e.Order, StatusRejected, at)
+		record.Reason = e.Reason
+		return r.repository.SaveOrder(record)
+	case *OrderFilled:
+		status := StatusPartiallyFilled
+		if e.Remaining == 0 {
+			status = StatusFilled
+		}
+		return r.repository.UpdateOrder(e.OrderID, e.Remaining, status, at)
+	case *OrderCancelled:
+		return r.repository.UpdateOrder(e.Order.ID, e.Order.Size, StatusCancelled, at)
+	case *SelfTradePrevented:
+		if e.MakerCancelled > 0 && e.MakerRemaining > 0 {
+			return r.repository.ReduceOrder(e.MakerOrderID, e.MakerRemaining, at)
+		}
+	case *TradeExecuted:
+		return r.repository.SaveTrade(e)
+	}
+
+	return nil
+}
+
+func newOrderRecord(market TradingPair, order Order, status OrderStatus, at time.Time) *OrderRecord {
+	return &OrderRecord{
+		Market:    market,
+		ID:        order.ID,
+		Side:      order.Side,
+		Price:     order.Price,
+		Size:      order.Size,
+		Signer:    order.Signer,
+		Remaining: order.Size,
+		Status:    status,
+		CreatedAt: time.Unix(0, order.Timestamp),
+		UpdatedAt: at,
+	}
+}
@@ -0,0 +1,85 @@
+package orderbook
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryRepository struct {
+	orders map[uint64]*OrderRecord
+	trades []*TradeExecuted
+
+	mu sync.Mutex
+}
+
+func (r *memoryRepository) SaveOrder(record *OrderRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orders[record.ID] = record
+	return nil
+}
+
+func (r *memoryRepository) UpdateOrder(id uint64, remaining float64, status OrderStatus, updatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orders[id].Remaining = remaining
+	r.orders[id].Status = status
+	return nil
+}
+
+func (r *memoryRepository) ReduceOrder(id uint64, remaining float64, updatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orders[id].Remaining = remaining
+	return nil
+}
+
+func (r *memoryRepository) SaveTrade(trade *TradeExecuted) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.trades = append(r.trades, trade)
+	return nil
+}
+
+func TestHistoryRecorder(t *testing.T) {
+	repository := &memoryRepository{orders: make(map[uint64]*OrderRecord)}
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	recorder := NewHistoryRecorder(tradingPlatform, repository)
+	recorder.Start()
+
+	sellOrder1 := NewOrder(Ask, 2)
+	sellOrder2 := NewOrder(Ask, 8)
+	cancelled := NewOrder(Bid, 1)
+	buyOrder := NewOrder(Bid, 3)
+	rejected := NewOrder(Bid, 100)
+
+	tradingPlatform.PlaceLimitOrder(pair, 240, sellOrder1)
+	tradingPlatform.PlaceLimitOrder(pair, 250, sellOrder2)
+	tradingPlatform.PlaceLimitOrder(pair, 200, cancelled)
+	tradingPlatform.CancelOrder(pair, cancelled.ID)
+	tradingPlatform.PlaceMarketOrder(pair, buyOrder)
+	tradingPlatform.PlaceMarketOrder(pair, rejected)
+
+	recorder.Stop()
+
+	assert.Equal(t, StatusFilled, repository.orders[sellOrder1.ID].Status, "fully matched maker should be filled")
+	assert.Equal(t, StatusPartiallyFilled, repository.orders[sellOrder2.ID].Status, "partly matched maker should be partially filled")
+	assert.Equal(t, float64(7), repository.orders[sellOrder2.ID].Remaining, "partly matched maker should have 7 remaining")
+	assert.Equal(t, float64(250), repository.orders[sellOrder2.ID].Price, "order record should have the limit price")
+	assert.Equal(t, StatusCancelled, repository.orders[cancelled.ID].Status, "cancelled order should be cancelled")
+	assert.Equal(t, StatusFilled, repository.orders[buyOrder.ID].Status, "market order should be filled")
+	assert.Equal(t, float64(3), repository.orders[buyOrder.ID].Size, "market order should keep its original size")
+	assert.Equal(t, StatusRejected, repository.orders[rejected.ID].Status, "rejected order should be rejected")
+	assert.NotEmpty(t, repository.orders[rejected.ID].Reason, "rejected order should have a reason")
+	assert.Equal(t, 2, len(repository.trades), "both trades should be recorded")
+}
@@ -0,0 +1,334 @@
+package orderbook
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kr/pretty"
+	"github.com/richo225/octgopus/internal/accounting"
+)
+
+// ImportLayout is the shape of an order book CSV file, recognised from its
+// header row.
+type ImportLayout string
+
+const (
+	// LayoutPaired has an ask and a bid on each row, as in data/*.csv:
+	// Ask Price,Ask Amount,Bid Price,Bid Amount. Either side of a row may be
+	// left empty when one side of the book is deeper than the other.
+	LayoutPaired ImportLayout = "paired"
+	// LayoutAsks and LayoutBids hold a single side: Ask Price,Ask Amount or
+	// Bid Price,Bid Amount.
+	LayoutAsks ImportLayout = "asks"
+	LayoutBids ImportLayout = "bids"
+	// LayoutOrders has one order per row: Side,Price,Size and an optional
+	// Signer, in any column order.
+	LayoutOrders ImportLayout = "orders"
+)
+
+// maxImportErrors caps the rows reported by an InvalidImportError.
+const maxImportErrors = 100
+
+// ImportRowError is a problem with one line of an imported file.
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ImportRowError) String() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ImportResult describes a completed import.
+type ImportResult struct {
+	Market TradingPair  `json:"market"`
+	Layout ImportLayout `json:"layout"`
+	Orders int          `json:"orders"`
+}
+
+type importOrder struct {
+	line  int
+	price float64
+	order *Order
+}
+
+// ImportOrders places the limit orders of an order book CSV file on a market.
+// Every row is checked before any order is placed, so a file with invalid
+// rows places nothing and returns an InvalidImportError listing them. An
+// order refused by the market, e.g. for breaking its rules, stops the import
+// with the line it was on; the orders before it stay on the book.
+func (platform *TradingPlatform) ImportOrders(pair TradingPair, r io.Reader) (*ImportResult, error) {
+	if !platform.hasMarket(pair) {
+		return nil, &OrderbookNotFoundError{pair}
+	}
+
+	layout, orders, err := readImport(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Market: pair, Layout: layout}
+	for _, o := range orders {
+		if err := platform.PlaceLimitOrder(pair, o.price, o.order); err != nil {
+			return result, &ImportFailedError{o.line, result.Orders, err}
+		}
+		result.Orders++
+	}
+
+	return result, nil
+}
+
+// importFile creates a market and seeds it from a CSV file.
+func (platform *TradingPlatform) importFile(pair TradingPair, path string) error {
+	pretty.Log("Seeding data for " + pair.ToString() + "...")
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := platform.AddNewMarket(pair); err != nil {
+		return err
+	}
+
+	result, err := platform.ImportOrders(pair, f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	pretty.Log("Seeded "+pair.ToString()+" with", result.Orders, "orders")
+	return nil
+}
+
+// readImport parses a whole file, collecting the errors of every row.
+func readImport(r io.Reader) (ImportLayout, []importOrder, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return "", nil, &InvalidImportError{[]ImportRowError{{1, "file is empty"}}, 1}
+	}
+	if err != nil {
+		return "", nil, &InvalidImportError{[]ImportRowError{rowError(1, err)}, 1}
+	}
+
+	layout, parse, err := importLayout(header)
+	if err != nil {
+		return "", nil, &InvalidImportError{[]ImportRowError{{1, err.Error()}}, 1}
+	}
+
+	orders := []importOrder{}
+	invalid := &InvalidImportError{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		// FieldPos is only valid for records read without an error.
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			invalid.add(rowError(parseErr.StartLine, err))
+			continue
+		case err != nil:
+			return "", nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		parsed, err := parse(record)
+		if err != nil {
+			invalid.add(ImportRowError{line, err.Error()})
+			continue
+		}
+		for _, order := range parsed {
+			order.line = line
+			orders = append(orders, order)
+		}
+	}
+
+	if invalid.count > 0 {
+		return "", nil, invalid
+	}
+
+	return layout, orders, nil
+}
+
+func rowError(line int, err error) ImportRowError {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return ImportRowError{line, parseErr.Err.Error()}
+	}
+
+	return ImportRowError{line, err.Error()}
+}
+
+type rowParser func(record []string) ([]importOrder, error)
+
+// importLayout recognises the layout of a file from its header and returns
+// the parser of its rows. Column names are case insensitive and may be
+// followed by a unit, e.g. Ask Price (USD).
+func importLayout(header []string) (ImportLayout, rowParser, error) {
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff")
+		if unit := strings.Index(name, "("); unit > 0 && strings.HasSuffix(name, ")") {
+			name = name[:unit]
+		}
+		columns[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+
+	switch strings.Join(columns, ",") {
+	case "ask price,ask amount,bid price,bid amount":
+		return LayoutPaired, parseSides(Ask, Bid), nil
+	case "ask price,ask amount":
+		return LayoutAsks, parseSides(Ask), nil
+	case "bid price,bid amount":
+		return LayoutBids, parseSides(Bid), nil
+	}
+
+	index := make(map[string]int)
+	for i, name := range columns {
+		if name == "amount" {
+			name = "size"
+		}
+		index[name] = i
+	}
+	for _, name := range []string{"side", "price", "size"} {
+		if _, ok := index[name]; !ok {
+			return "", nil, fmt.Errorf("unrecognised header %q, expected paired, single sided or per order columns", strings.Join(header, ","))
+		}
+	}
+	for i, name := range columns {
+		if name == "amount" {
+			name = "size"
+		}
+		switch {
+		case index[name] != i:
+			return "", nil, fmt.Errorf("column %q appears more than once", header[i])
+		case name != "side" && name != "price" && name != "size" && name != "signer":
+			return "", nil, fmt.Errorf("unknown column %q", header[i])
+		}
+	}
+
+	if _, ok := index["signer"]; !ok {
+		index["signer"] = -1
+	}
+
+	return LayoutOrders, parseOrder(index), nil
+}
+
+// parseSides reads a price and amount column pair for each side. A side with
+// both columns empty is skipped.
+func parseSides(sides ...Side) rowParser {
+	return func(record []string) ([]importOrder, error) {
+		if len(record) != 2*len(sides) {
+			return nil, fmt.Errorf("expected %d columns, got %d", 2*len(sides), len(record))
+		}
+
+		orders := []importOrder{}
+		for i, side := range sides {
+			price, size := strings.TrimSpace(record[2*i]), strings.TrimSpace(record[2*i+1])
+			if price == "" && size == "" {
+				continue
+			}
+
+			order, err := newImportOrder(side, price, size, "")
+			if err != nil {
+				return nil, err
+			}
+			orders = append(orders, order)
+		}
+
+		if len(orders) == 0 {
+			return nil, errors.New("row has no orders")
+		}
+
+		return orders, nil
+	}
+}
+
+// parseOrder reads the side, price, size and signer columns at index.
+func parseOrder(index map[string]int) rowParser {
+	return func(record []string) ([]importOrder, error) {
+		column := func(name string) string {
+			i := index[name]
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		var side Side
+		switch strings.ToLower(column("side")) {
+		case "ask", "sell":
+			side = Ask
+		case "bid", "buy":
+			side = Bid
+		default:
+			return nil, fmt.Errorf("side %q must be ask or bid", column("side"))
+		}
+
+		order, err := newImportOrder(side, column("price"), column("size"), column("signer"))
+		if err != nil {
+			return nil, err
+		}
+
+		return []importOrder{order}, nil
+	}
+}
+
+// newImportOrder parses a price and size, rounded to the precision orders
+// allow, for an order on side.
+func newImportOrder(side Side, price string, size string, signer string) (importOrder, error) {
+	p, err := parseImportAmount(side, "price", price)
+	if err != nil {
+		return importOrder{}, err
+	}
+	s, err := parseImportAmount(side, "size", size)
+	if err != nil {
+		return importOrder{}, err
+	}
+
+	if signer != "" {
+		if err := accounting.ValidateSigner(signer); err != nil {
+			return importOrder{}, err
+		}
+	}
+
+	order := NewOrder(side, s)
+	order.Signer = signer
+
+	return importOrder{price: p, order: order}, nil
+}
+
+func parseImportAmount(side Side, field string, s string) (float64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("%s %s is missing", side, field)
+	}
+
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s %s %q is not a number", side, field, s)
+	}
+
+	switch {
+	case math.IsNaN(amount) || math.IsInf(amount, 0):
+		return 0, fmt.Errorf("%s %s %q must be finite", side, field, s)
+	case accounting.RoundAmount(amount) <= 0:
+		return 0, fmt.Errorf("%s %s %q must be positive", side, field, s)
+	case amount > accounting.MaxAmount:
+		return 0, fmt.Errorf("%s %s %q is too large", side, field, s)
+	}
+
+	return accounting.RoundAmount(amount), nil
+}
@@ -0,0 +1,164 @@
+package orderbook
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func importPlatform(t *testing.T) (*TradingPlatform, TradingPair) {
+	t.Helper()
+
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	_, err := tradingPlatform.AddNewMarket(pair)
+	require.NoError(t, err)
+
+	return tradingPlatform, pair
+}
+
+func TestImportLayouts(t *testing.T) {
+	tests := []struct {
+		name   string
+		csv    string
+		layout ImportLayout
+		asks   []float64
+		bids   []float64
+	}{
+		{
+			name:   "paired",
+			csv:    "Ask Price,Ask Amount,Bid Price,Bid Amount\n101,1,99,2\n102,3,,\n",
+			layout: LayoutPaired,
+			asks:   []float64{101, 102},
+			bids:   []float64{99},
+		},
+		{
+			name:   "asks",
+			csv:    "ask price, ask amount\n101,1\n103,2\n",
+			layout: LayoutAsks,
+			asks:   []float64{101, 103},
+		},
+		{
+			name:   "bids",
+			csv:    "\ufeffBid Price,Bid Amount\n99,2\n",
+			layout: LayoutBids,
+			bids:   []float64{99},
+		},
+		{
+			name:   "orders",
+			csv:    "Price,Side,Amount,Signer\n101,ask,1,alice\n99,buy,2,\n98,BID,1,bob\n",
+			layout: LayoutOrders,
+			asks:   []float64{101},
+			bids:   []float64{99, 98},
+		},
+	}
+
+	for _, tt := range tests {
+		tradingPlatform, pair := importPlatform(t)
+		result, err := tradingPlatform.ImportOrders(pair, strings.NewReader(tt.csv))
+		require.NoError(t, err, tt.name+" file should import")
+		assert.Equal(t, tt.layout, result.Layout, tt.name+" layout should be recognised")
+		assert.Equal(t, len(tt.asks)+len(tt.bids), result.Orders, tt.name+" orders should be counted")
+
+		orderbook, _ := tradingPlatform.GetOrderBook(pair)
+		asks, bids := []float64{}, []float64{}
+		for _, limit := range orderbook.GetAsks() {
+			asks = append(asks, limit.Price)
+		}
+		for _, limit := range orderbook.GetBids() {
+			bids = append(bids, limit.Price)
+		}
+		assert.ElementsMatch(t, tt.asks, asks, tt.name+" asks should rest on the book")
+		assert.ElementsMatch(t, tt.bids, bids, tt.name+" bids should rest on the book")
+	}
+}
+
+func TestImportSigners(t *testing.T) {
+	tradingPlatform, pair := importPlatform(t)
+	_, err := tradingPlatform.ImportOrders(pair, strings.NewReader("side,price,size,signer\nask,101,1,alice\n"))
+	require.NoError(t, err)
+
+	orderbook, _ := tradingPlatform.GetOrderBook(pair)
+	assert.Equal(t, "alice", orderbook.GetAsks()[0].Orders[0].Signer, "signer column should own the order")
+}
+
+func TestImportInvalidRows(t *testing.T) {
+	tradingPlatform, pair := importPlatform(t)
+	csv := strings.Join([]string{
+		"Ask Price,Ask Amount,Bid Price,Bid Amount",
+		"101,1,99,2",
+		"abc,1,98,1",
+		"102,-1,97,1",
+		"103,1,96",
+		"104,1,,1",
+		`a"b,1,99,2`,
+		`105,1,"95,1`,
+	}, "\n")
+
+	result, err := tradingPlatform.ImportOrders(pair, strings.NewReader(csv))
+	assert.Nil(t, result, "invalid file should not return a result")
+
+	var invalid *InvalidImportError
+	require.ErrorAs(t, err, &invalid, "invalid rows should be reported")
+	assert.Equal(t, []ImportRowError{
+		{3, `ask price "abc" is not a number`},
+		{4, `ask size "-1" must be positive`},
+		{5, "expected 4 columns, got 3"},
+		{6, "bid price is missing"},
+		{7, `bare " in non-quoted-field`},
+		{8, `extraneous or missing " in quoted-field`},
+	}, invalid.Rows, "every invalid row should be reported with its line")
+
+	orderbook, _ := tradingPlatform.GetOrderBook(pair)
+	assert.Equal(t, 0, len(orderbook.orders), "invalid file should place no orders")
+}
+
+func TestImportInvalidHeader(t *testing.T) {
+	tradingPlatform, pair := importPlatform(t)
+
+	tests := map[string]string{
+		"":                                 "file is empty",
+		"Price,Amount\n1,1\n":              `unrecognised header "Price,Amount", expected paired, single sided or per order columns`,
+		"side,price,size,size\n":           `column "size" appears more than once`,
+		"side,price,size,venue\nask,1,1,x": `unknown column "venue"`,
+	}
+	for csv, message := range tests {
+		_, err := tradingPlatform.ImportOrders(pair, strings.NewReader(csv))
+		var invalid *InvalidImportError
+		require.ErrorAs(t, err, &invalid, "invalid header should be refused")
+		assert.Equal(t, []ImportRowError{{1, message}}, invalid.Rows, "header error should be on line 1")
+	}
+}
+
+func TestImportRefusedOrder(t *testing.T) {
+	tradingPlatform, pair := importPlatform(t)
+	tradingPlatform.SetMarkets([]MarketSpec{{Market: pair, Rules: MarketRules{TickSize: 1}}})
+
+	result, err := tradingPlatform.ImportOrders(pair, strings.NewReader("Ask Price,Ask Amount\n101,1\n101.5,1\n102,1\n"))
+	var failed *ImportFailedError
+	require.ErrorAs(t, err, &failed, "order refused by the market should stop the import")
+	assert.Equal(t, 3, failed.Line, "refused order should be reported with its line")
+	assert.Equal(t, 1, result.Orders, "orders before the refused one should be placed")
+	assert.IsType(t, &InvalidOrderError{}, failed.Unwrap(), "cause should be kept")
+}
+
+func TestImportUnknownMarket(t *testing.T) {
+	_, err := NewTradingPlatform().ImportOrders(TradingPair{"BTC", "USD"}, strings.NewReader(""))
+	assert.IsType(t, &OrderbookNotFoundError{}, err, "unknown market should be refused")
+}
+
+func TestImportSeedData(t *testing.T) {
+	for _, spec := range DefaultMarkets() {
+		f, err := os.Open("../../" + spec.Seed)
+		require.NoError(t, err)
+
+		tradingPlatform := NewTradingPlatform()
+		tradingPlatform.AddNewMarket(spec.Market)
+		_, err = tradingPlatform.ImportOrders(spec.Market, f)
+		f.Close()
+		assert.NoError(t, err, spec.Seed+" should import cleanly")
+	}
+}
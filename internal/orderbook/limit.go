@@ -1,12 +1,31 @@
 package orderbook
 
-import "sort"
+import (
+	"math"
+	"sort"
+)
 
 type Match struct {
 	Ask        *Order  `json:"ask"`
 	Bid        *Order  `json:"bid"`
 	SizeFilled float64 `json:"size_filled"`
 	Price      float64 `json:"price"`
+	// AskFee and BidFee are charged to each side, negative for a rebate.
+	AskFee float64 `json:"ask_fee"`
+	BidFee float64 `json:"bid_fee"`
+}
+
+// SelfTrade records a match that was prevented because both orders belong to
+// the same signer. Maker and Taker are left with their remaining sizes.
+type SelfTrade struct {
+	Maker          *Order
+	Taker          *Order
+	Mode           SelfTradePrevention
+	Price          float64
+	MakerCancelled float64
+	TakerCancelled float64
+	// AfterMatches is the number of matches the taker made before this.
+	AfterMatches int
 }
 
 type Limit struct {
@@ -42,25 +61,60 @@ func (limit *Limit) removeOrder(order *Order) {
 	})
 }
 
-func (limit *Limit) matchOrder(order *Order) []Match {
+// matchOrder fills order against the resting orders in time priority. Self
+// trades are prevented before a Match is produced for them.
+func (limit *Limit) matchOrder(order *Order) ([]Match, []SelfTrade) {
 	matches := []Match{}
-
-	for _, limitOrder := range limit.Orders {
-		match := limit.fillOrders(limitOrder, order)
-		matches = append(matches, match)
-
-		limit.TotalVolume -= match.SizeFilled
+	selfTrades := []SelfTrade{}
+
+	for len(limit.Orders) > 0 && order.Size > 0 {
+		limitOrder := limit.Orders[0]
+
+		if order.selfTrades(limitOrder) {
+			selfTrade := limit.preventSelfTrade(limitOrder, order)
+			selfTrade.AfterMatches = len(matches)
+			selfTrades = append(selfTrades, selfTrade)
+		} else {
+			match := limit.fillOrders(limitOrder, order)
+			matches = append(matches, match)
+			limit.TotalVolume -= match.SizeFilled
+		}
 
 		if limitOrder.Size == 0 {
 			limit.removeOrder(limitOrder)
 		}
+	}
 
-		if order.Size == 0 {
-			break
-		}
+	return matches, selfTrades
+}
+
+func (limit *Limit) preventSelfTrade(limitOrder, order *Order) SelfTrade {
+	selfTrade := SelfTrade{
+		Maker: limitOrder,
+		Taker: order,
+		Mode:  order.SelfTradePrevention,
+		Price: limit.Price,
 	}
 
-	return matches
+	switch order.SelfTradePrevention {
+	case STPCancelNewest:
+		selfTrade.TakerCancelled = order.Size
+	case STPCancelOldest:
+		selfTrade.MakerCancelled = limitOrder.Size
+	case STPCancelBoth:
+		selfTrade.MakerCancelled = limitOrder.Size
+		selfTrade.TakerCancelled = order.Size
+	case STPDecrementAndCancel:
+		size := math.Min(limitOrder.Size, order.Size)
+		selfTrade.MakerCancelled = size
+		selfTrade.TakerCancelled = size
+	}
+
+	limitOrder.Size -= selfTrade.MakerCancelled
+	order.Size -= selfTrade.TakerCancelled
+	limit.TotalVolume -= selfTrade.MakerCancelled
+
+	return selfTrade
 }
 
 func (limit *Limit) fillOrders(limitOrder, order *Order) Match {
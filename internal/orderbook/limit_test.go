@@ -51,7 +51,7 @@ func TestLimitMatchOrder(t *testing.T) {
 
 	limit.addOrder(sellOrder)
 
-	matches := limit.matchOrder(buyOrder)
+	matches, _ := limit.matchOrder(buyOrder)
 
 	assert.Equal(t, 1, len(matches), "limit should have 1 match")
 	assert.Equal(t, buyOrder, matches[0].Bid, "match bid should be order")
@@ -78,3 +78,86 @@ func TestLimitFillOrders(t *testing.T) {
 	assert.Equal(t, float64(1), match.SizeFilled, "match size filled should be 1")
 	assert.Equal(t, float64(250), match.Price, "match price should be 250")
 }
+
+func TestLimitMatchOrderAcrossOrders(t *testing.T) {
+	limit := newLimit(250)
+	sellOrder1 := NewOrder(Ask, 2)
+	sellOrder2 := NewOrder(Ask, 4)
+	sellOrder3 := NewOrder(Ask, 3)
+	buyOrder := NewOrder(Bid, 7)
+
+	limit.addOrder(sellOrder1)
+	limit.addOrder(sellOrder2)
+	limit.addOrder(sellOrder3)
+
+	matches, _ := limit.matchOrder(buyOrder)
+
+	assert.Equal(t, 3, len(matches), "limit should match every order in time priority")
+	assert.Equal(t, sellOrder2, matches[1].Ask, "second match should be the second order")
+	assert.Equal(t, float64(1), matches[2].SizeFilled, "third order should be partially filled")
+	assert.Equal(t, []*Order{sellOrder3}, limit.Orders, "limit should keep the partially filled order")
+	assert.Equal(t, float64(2), limit.TotalVolume, "limit should have the correct total volume")
+}
+
+func TestLimitSelfTradePrevention(t *testing.T) {
+	tests := []struct {
+		mode           SelfTradePrevention
+		makerSize      float64
+		takerSize      float64
+		makerRemaining float64
+		takerRemaining float64
+		matched        float64
+	}{
+		{STPCancelNewest, 3, 5, 3, 0, 0},
+		{STPCancelOldest, 3, 5, 0, 0, 5},
+		{STPCancelBoth, 3, 5, 0, 0, 0},
+		{STPDecrementAndCancel, 3, 5, 0, 0, 2},
+		{STPDecrementAndCancel, 6, 5, 1, 0, 0},
+	}
+
+	for _, test := range tests {
+		limit := newLimit(250)
+		own := NewOrder(Ask, test.makerSize)
+		own.Signer = "alice"
+		other := NewOrder(Ask, 10)
+		other.Signer = "bob"
+		limit.addOrder(own)
+		limit.addOrder(other)
+
+		buyOrder := NewOrder(Bid, test.takerSize)
+		buyOrder.Signer = "alice"
+		buyOrder.SelfTradePrevention = test.mode
+
+		matches, selfTrades := limit.matchOrder(buyOrder)
+
+		var matched float64
+		for _, match := range matches {
+			if match.Ask == own {
+				t.Errorf("%s: alice should not trade with themselves", test.mode)
+			}
+			matched += match.SizeFilled
+		}
+		assert.Equal(t, 1, len(selfTrades), "%s: self trade should be recorded", test.mode)
+
+		assert.Equal(t, test.matched, matched, "%s: taker should match the other signer", test.mode)
+		assert.Equal(t, test.makerRemaining, own.Size, "%s: resting order should have the correct size", test.mode)
+		assert.Equal(t, test.takerRemaining, buyOrder.Size, "%s: taker should have the correct size", test.mode)
+		assert.Equal(t, test.makerRemaining+10-test.matched, limit.TotalVolume, "%s: limit should have the correct total volume", test.mode)
+	}
+}
+
+func TestLimitSelfTradeAllowed(t *testing.T) {
+	limit := newLimit(250)
+	sellOrder := NewOrder(Ask, 3)
+	sellOrder.Signer = "alice"
+	limit.addOrder(sellOrder)
+
+	buyOrder := NewOrder(Bid, 3)
+	buyOrder.Signer = "alice"
+	buyOrder.SelfTradePrevention = STPNone
+
+	matches, selfTrades := limit.matchOrder(buyOrder)
+
+	assert.Equal(t, 1, len(matches), "none should let the signer trade with themselves")
+	assert.Equal(t, 0, len(selfTrades), "none should not prevent the trade")
+}
@@ -0,0 +1,171 @@
+package orderbook
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// MarketRules limit the orders a market accepts. Zero values are not checked.
+type MarketRules struct {
+	// TickSize is the step limit order prices must be a multiple of.
+	TickSize float64 `json:"tick_size,omitempty"`
+	// LotSize is the step order sizes must be a multiple of.
+	LotSize float64 `json:"lot_size,omitempty"`
+	MinSize float64 `json:"min_size,omitempty"`
+	MaxSize float64 `json:"max_size,omitempty"`
+}
+
+// Validate checks that the rules are not negative and that MinSize is not
+// above MaxSize.
+func (rules MarketRules) Validate() error {
+	values := []struct {
+		name  string
+		value float64
+	}{
+		{"tick_size", rules.TickSize},
+		{"lot_size", rules.LotSize},
+		{"min_size", rules.MinSize},
+		{"max_size", rules.MaxSize},
+	}
+	for _, v := range values {
+		if v.value < 0 || math.IsNaN(v.value) || math.IsInf(v.value, 0) {
+			return fmt.Errorf("%s must be a positive number", v.name)
+		}
+	}
+
+	if rules.MaxSize > 0 && rules.MinSize > rules.MaxSize {
+		return errors.New("min_size must not be above max_size")
+	}
+
+	return nil
+}
+
+func (rules MarketRules) check(orderType OrderType, price float64, size float64) error {
+	switch {
+	case orderType == LimitOrder && !isMultiple(price, rules.TickSize):
+		return &InvalidOrderError{"price", fmt.Errorf("must be a multiple of the tick size %v", rules.TickSize)}
+	case !isMultiple(size, rules.LotSize):
+		return &InvalidOrderError{"size", fmt.Errorf("must be a multiple of the lot size %v", rules.LotSize)}
+	case size < rules.MinSize:
+		return &InvalidOrderError{"size", fmt.Errorf("must be at least %v", rules.MinSize)}
+	case rules.MaxSize > 0 && size > rules.MaxSize:
+		return &InvalidOrderError{"size", fmt.Errorf("must be at most %v", rules.MaxSize)}
+	}
+
+	return nil
+}
+
+// isMultiple reports whether value is a whole number of steps, allowing for
+// float rounding. Any value is a multiple of a zero step.
+func isMultiple(value float64, step float64) bool {
+	if step == 0 {
+		return true
+	}
+
+	steps := value / step
+	return math.Abs(steps-math.Round(steps)) < 1e-9
+}
+
+// MarketSpec declares a market that the platform opens at startup and after
+// a reset.
+type MarketSpec struct {
+	Market TradingPair
+	// Seed is a CSV file of resting orders placed when the market is created.
+	// Markets without one start empty.
+	Seed  string
+	Rules MarketRules
+	// Fees replaces the fee schedule of the market when set.
+	Fees *FeeSchedule
+}
+
+// DefaultMarkets are the markets opened when no others are configured,
+// seeded from the data directory of the working directory.
+func DefaultMarkets() []MarketSpec {
+	return []MarketSpec{
+		{Market: NewTradingPair("ETH", "USD"), Seed: "data/eth_usd_order_book.csv"},
+		{Market: NewTradingPair("ETH", "GBP"), Seed: "data/eth_gbp_order_book.csv"},
+		{Market: NewTradingPair("BTC", "USD"), Seed: "data/btc_usd_order_book.csv"},
+		{Market: NewTradingPair("BTC", "GBP"), Seed: "data/btc_gbp_order_book.csv"},
+	}
+}
+
+// SetMarkets declares the markets that OpenMarkets opens, and the rules new
+// orders on them are checked against. Like risk checks, rules only apply to
+// new orders and are not journaled.
+func (platform *TradingPlatform) SetMarkets(specs []MarketSpec) error {
+	rules := make(map[TradingPair]MarketRules, len(specs))
+	for _, spec := range specs {
+		if err := spec.Rules.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", spec.Market.ToString(), err)
+		}
+		rules[spec.Market] = spec.Rules
+	}
+
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	platform.markets = specs
+	platform.rules = rules
+	return nil
+}
+
+// OpenMarkets creates every declared market that does not exist yet, seeding
+// it from its CSV file, and sets the declared fee schedules. Markets that
+// already exist, e.g. after recovering from the journal, keep their orders.
+func (platform *TradingPlatform) OpenMarkets() error {
+	platform.mu.RLock()
+	specs := platform.markets
+	platform.mu.RUnlock()
+
+	for _, spec := range specs {
+		if !platform.hasMarket(spec.Market) {
+			if err := platform.openMarket(spec); err != nil {
+				return err
+			}
+		}
+
+		if spec.Fees != nil {
+			if err := platform.SetFeeSchedule(spec.Market, spec.Fees); err != nil {
+				return fmt.Errorf("%s: %w", spec.Market.ToString(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasMarket reports whether a market exists. Unlike GetOrderBook it takes the
+// platform lock, so it is safe while the platform is trading.
+func (platform *TradingPlatform) hasMarket(pair TradingPair) bool {
+	platform.mu.RLock()
+	defer platform.mu.RUnlock()
+
+	_, ok := platform.Orderbooks[pair]
+	return ok
+}
+
+func (platform *TradingPlatform) openMarket(spec MarketSpec) error {
+	if spec.Seed != "" {
+		return platform.importFile(spec.Market, spec.Seed)
+	}
+
+	_, err := platform.AddNewMarket(spec.Market)
+	return err
+}
+
+// checkRules checks a new order against the rules of its market. Invalid
+// orders are left for placeX to reject.
+func (platform *TradingPlatform) checkRules(pair TradingPair, orderType OrderType, price float64, order *Order) error {
+	rules, ok := platform.rules[pair]
+	if !ok || order.validate(orderType, price) != nil {
+		return nil
+	}
+
+	if err := rules.check(orderType, price, order.Size); err != nil {
+		platform.publishRejected(pair, order, err)
+		return err
+	}
+
+	return nil
+}
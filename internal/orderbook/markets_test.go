@@ -0,0 +1,100 @@
+package orderbook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketRules(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	require.NoError(t, tradingPlatform.SetMarkets([]MarketSpec{
+		{Market: pair, Rules: MarketRules{TickSize: 0.5, LotSize: 0.01, MinSize: 0.1, MaxSize: 10}},
+	}))
+	require.NoError(t, tradingPlatform.OpenMarkets(), "opening markets should not return an error")
+
+	assert.NoError(t, tradingPlatform.PlaceLimitOrder(pair, 100.5, NewOrder(Ask, 1.25)), "order following the rules should be accepted")
+
+	tests := map[string]struct {
+		price float64
+		size  float64
+	}{
+		"price off the tick": {100.3, 1},
+		"size off the lot":   {100, 1.005},
+		"size below the min": {100, 0.05},
+		"size above the max": {100, 10.5},
+	}
+	for name, tt := range tests {
+		err := tradingPlatform.PlaceLimitOrder(pair, tt.price, NewOrder(Ask, tt.size))
+		assert.IsType(t, &InvalidOrderError{}, err, name+" should be rejected")
+	}
+
+	_, err := tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 0.05))
+	assert.IsType(t, &InvalidOrderError{}, err, "market order below the min size should be rejected")
+	_, err = tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 0.25))
+	assert.NoError(t, err, "market orders should not be checked against the tick size")
+
+	err = tradingPlatform.SetMarkets([]MarketSpec{{Market: pair, Rules: MarketRules{MinSize: 2, MaxSize: 1}}})
+	assert.Error(t, err, "min size above max size should be refused")
+}
+
+func TestOpenMarkets(t *testing.T) {
+	dir := t.TempDir()
+	seed := filepath.Join(dir, "btc_usd.csv")
+	csv := "Ask Price,Ask Amount,Bid Price,Bid Amount\n101,1,99,2\n102,3,98,4\n"
+	require.NoError(t, os.WriteFile(seed, []byte(csv), 0o600))
+
+	btcusd := TradingPair{"BTC", "USD"}
+	ethusd := TradingPair{"ETH", "USD"}
+	tradingPlatform := NewTradingPlatform()
+	require.NoError(t, tradingPlatform.SetMarkets([]MarketSpec{
+		{Market: btcusd, Seed: seed},
+		{Market: ethusd, Fees: &FeeSchedule{TakerRate: 0.001}},
+	}))
+	require.NoError(t, tradingPlatform.OpenMarkets(), "opening markets should not return an error")
+
+	btc, err := tradingPlatform.GetOrderBook(btcusd)
+	require.NoError(t, err, "seeded market should be created")
+	assert.Equal(t, 4, len(btc.orders), "seed orders should rest on the book")
+
+	eth, err := tradingPlatform.GetOrderBook(ethusd)
+	require.NoError(t, err, "market without a seed should be created")
+	assert.Equal(t, 0.001, eth.Fees.TakerRate, "fee schedule should be set")
+
+	require.NoError(t, tradingPlatform.OpenMarkets(), "opening markets again should not return an error")
+	assert.Equal(t, 4, len(btc.orders), "existing markets should not be seeded again")
+
+	require.NoError(t, tradingPlatform.Reset(), "reset should not return an error")
+	btc, _ = tradingPlatform.GetOrderBook(btcusd)
+	assert.Equal(t, 4, len(btc.orders), "reset should reseed the declared markets")
+}
+
+func TestOpenMarketsWhileTrading(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	require.NoError(t, tradingPlatform.SetMarkets([]MarketSpec{{Market: TradingPair{"BTC", "USD"}}}))
+
+	// Run with -race: OpenMarkets must not read the markets while they change.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			tradingPlatform.AddNewMarket(TradingPair{fmt.Sprint("T", i), "USD"})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, tradingPlatform.OpenMarkets())
+	}
+	<-done
+}
+
+func TestOpenMarketsMissingSeed(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	tradingPlatform.SetMarkets([]MarketSpec{{Market: TradingPair{"BTC", "USD"}, Seed: filepath.Join(t.TempDir(), "missing.csv")}})
+
+	assert.Error(t, tradingPlatform.OpenMarkets(), "missing seed file should be an error rather than a panic")
+}
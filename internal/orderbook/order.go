@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/richo225/octgopus/internal/accounting"
 )
 
 type Side string
@@ -29,11 +31,44 @@ func (side *Side) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// SelfTradePrevention decides what happens when an order would match a
+// resting order placed by the same signer. The mode of the incoming order
+// applies; the mode of the resting order is ignored.
+type SelfTradePrevention string
+
+const (
+	// STPNone lets the orders trade with each other.
+	STPNone SelfTradePrevention = "none"
+	// STPCancelNewest cancels the rest of the incoming order.
+	STPCancelNewest SelfTradePrevention = "cancel_newest"
+	// STPCancelOldest cancels the resting order and keeps matching.
+	STPCancelOldest SelfTradePrevention = "cancel_oldest"
+	// STPCancelBoth cancels the resting order and the rest of the incoming one.
+	STPCancelBoth SelfTradePrevention = "cancel_both"
+	// STPDecrementAndCancel reduces both orders by the smaller of their sizes,
+	// cancelling the smaller one, or both when they are equal.
+	STPDecrementAndCancel SelfTradePrevention = "decrement_and_cancel"
+)
+
+func (mode SelfTradePrevention) valid() bool {
+	switch mode {
+	case STPNone, STPCancelNewest, STPCancelOldest, STPCancelBoth, STPDecrementAndCancel:
+		return true
+	}
+
+	return false
+}
+
 type Order struct {
+	ID        uint64  `json:"id"`
 	Side      Side    `json:"side"`
 	Price     float64 `json:"price"`
 	Size      float64 `json:"size"`
 	Timestamp int64   `json:"timestamp"`
+	// Signer is the account that placed the order, if it was authenticated.
+	Signer string `json:"signer,omitempty"`
+	// SelfTradePrevention is filled in with the platform default when empty.
+	SelfTradePrevention SelfTradePrevention `json:"self_trade_prevention,omitempty"`
 }
 
 func NewOrder(side Side, size float64) *Order {
@@ -43,3 +78,34 @@ func NewOrder(side Side, size float64) *Order {
 		Timestamp: time.Now().UnixNano(),
 	}
 }
+
+// validate checks the side and size of the order, and its price when it is
+// placed as a limit order. Amounts follow the same rules as accounting.
+func (order *Order) validate(orderType OrderType, price float64) error {
+	if order.Side != Bid && order.Side != Ask {
+		return &InvalidOrderError{"side", errors.New("must be bid or ask")}
+	}
+	if err := accounting.ValidateAmount(order.Size); err != nil {
+		return &InvalidOrderError{"size", err}
+	}
+	if orderType == LimitOrder {
+		if err := accounting.ValidateAmount(price); err != nil {
+			return &InvalidOrderError{"price", err}
+		}
+	}
+	if order.SelfTradePrevention != "" && !order.SelfTradePrevention.valid() {
+		return &InvalidOrderError{"self_trade_prevention", errors.New("unknown mode")}
+	}
+
+	return nil
+}
+
+// selfTrades reports whether matching order against a resting order would
+// trade a signer with itself under a mode that prevents it.
+func (order *Order) selfTrades(resting *Order) bool {
+	if order.Signer == "" || order.Signer != resting.Signer {
+		return false
+	}
+
+	return order.SelfTradePrevention != "" && order.SelfTradePrevention != STPNone
+}
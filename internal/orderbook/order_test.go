@@ -1,6 +1,7 @@
 package orderbook
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,3 +13,23 @@ func TestNewOrder(t *testing.T) {
 	assert.Equal(t, Bid, order.Side, "order side should be Bid")
 	assert.Equal(t, float64(5), order.Size, "order size should be 5")
 }
+
+func TestOrderValidate(t *testing.T) {
+	assert.NoError(t, NewOrder(Bid, 5).validate(LimitOrder, 250), "valid limit order should pass")
+	assert.NoError(t, NewOrder(Ask, 5).validate(MarketOrder, 0), "market order should not need a price")
+
+	invalid := []struct {
+		order     *Order
+		orderType OrderType
+		price     float64
+	}{
+		{NewOrder("sideways", 5), LimitOrder, 250},
+		{NewOrder(Bid, -5), LimitOrder, 250},
+		{NewOrder(Bid, math.NaN()), MarketOrder, 0},
+		{NewOrder(Bid, 5), LimitOrder, math.Inf(1)},
+		{NewOrder(Bid, 5), LimitOrder, 0},
+	}
+	for _, tc := range invalid {
+		assert.IsType(t, &InvalidOrderError{}, tc.order.validate(tc.orderType, tc.price), "%+v at %v should be invalid", *tc.order, tc.price)
+	}
+}
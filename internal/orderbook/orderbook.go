@@ -1,16 +1,31 @@
 package orderbook
 
 import (
+	"math"
 	"sort"
 	"sync"
+	"time"
 )
 
 type Orderbook struct {
-	Market    *TradingPair       `json:"market"`
-	Asks      []*Limit           `json:"asks"`
-	Bids      []*Limit           `json:"bids"`
-	askLimits map[float64]*Limit `json:"-"`
-	bidLimits map[float64]*Limit `json:"-"`
+	Market *TradingPair `json:"market"`
+	Asks   []*Limit     `json:"asks"`
+	Bids   []*Limit     `json:"bids"`
+	State  TradingState `json:"state"`
+	// ResumeAt is when a circuit breaker halt ends, in unix nanoseconds.
+	ResumeAt       int64   `json:"resume_at,omitempty"`
+	LastTradePrice float64 `json:"last_trade_price"`
+	// Indicative is the uncross the auction would make if it ended now.
+	Indicative *Uncross     `json:"indicative,omitempty"`
+	Fees       *FeeSchedule `json:"fees,omitempty"`
+	// volumes is the quote volume each signer has traded, for fee tiers.
+	volumes      map[string]float64 `json:"-"`
+	askLimits    map[float64]*Limit `json:"-"`
+	bidLimits    map[float64]*Limit `json:"-"`
+	orders       map[uint64]*Order  `json:"-"`
+	recentTrades []pricePoint       `json:"-"`
+	// trades are the latest trades of the market, oldest first.
+	trades []TradeRecord `json:"-"`
 
 	mu sync.RWMutex
 }
@@ -19,8 +34,32 @@ func newOrderBook() *Orderbook {
 	return &Orderbook{
 		Asks:      []*Limit{},
 		Bids:      []*Limit{},
+		State:     StateOpen,
 		askLimits: make(map[float64]*Limit),
 		bidLimits: make(map[float64]*Limit),
+		orders:    make(map[uint64]*Order),
+		volumes:   make(map[string]float64),
+	}
+}
+
+// maxTradeHistory is the number of trades each orderbook keeps for exports.
+const maxTradeHistory = 10000
+
+// TradeRecord is a trade in the history of a market.
+type TradeRecord struct {
+	Trade
+	ExecutedAt int64 `json:"executed_at"`
+}
+
+// addTrades adds matches to the trade history, dropping the oldest trades
+// beyond maxTradeHistory.
+func (book *Orderbook) addTrades(matches []Match, takerSide Side, at time.Time) {
+	for _, match := range matches {
+		book.trades = append(book.trades, TradeRecord{newTrade(match, takerSide), at.UnixNano()})
+	}
+
+	if over := len(book.trades) - maxTradeHistory; over > 0 {
+		book.trades = append([]TradeRecord{}, book.trades[over:]...)
 	}
 }
 
@@ -74,10 +113,26 @@ func (book *Orderbook) totalAskVolume() float64 {
 	return total
 }
 
+func (book *Orderbook) levelVolume(side Side, price float64) float64 {
+	var limit *Limit
+	if side == Bid {
+		limit = book.bidLimits[price]
+	} else {
+		limit = book.askLimits[price]
+	}
+	if limit == nil {
+		return 0
+	}
+
+	return limit.TotalVolume
+}
+
 func (book *Orderbook) placeLimitOrder(price float64, order *Order) *Order {
 	book.mu.Lock()
 	defer book.mu.Unlock()
 
+	book.orders[order.ID] = order
+
 	if order.Side == Bid {
 		limit, ok := book.bidLimits[price]
 		if ok {
@@ -103,55 +158,108 @@ func (book *Orderbook) placeLimitOrder(price float64, order *Order) *Order {
 	return order
 }
 
-func (book *Orderbook) placeMarketOrder(order *Order) ([]Match, error) {
+// placeMarketOrder fills order against the best prices on the other side of
+// the book. Resting orders of the same signer count towards the available
+// volume, so an order can be left unfilled when self-trade prevention
+// cancels them.
+func (book *Orderbook) placeMarketOrder(order *Order) ([]Match, []SelfTrade, error) {
 	book.mu.Lock()
 	defer book.mu.Unlock()
 
 	matches := []Match{}
+	selfTrades := []SelfTrade{}
 
+	var (
+		side   Side
+		limits []*Limit
+		volume float64
+	)
 	if order.Side == Bid {
-		if book.totalAskVolume() < order.Size {
-			return nil, &InsufficientVolumeError{book.totalAskVolume(), order.Size}
-		}
-
-		for _, limit := range book.GetAsks() {
-			limitMatches := limit.matchOrder(order)
-			matches = append(matches, limitMatches...)
+		side, volume = Ask, book.totalAskVolume()
+		limits = append(limits, book.GetAsks()...)
+	} else {
+		side, volume = Bid, book.totalBidVolume()
+		limits = append(limits, book.GetBids()...)
+	}
 
-			if len(limit.Orders) == 0 {
-				book.removeLimit(Ask, limit)
-			}
+	if volume < order.Size {
+		return nil, nil, &InsufficientVolumeError{volume, order.Size}
+	}
 
-			if order.Size == 0 {
-				break
-			}
+	// limits is a copy, as emptied limits are removed from the book below.
+	for _, limit := range limits {
+		limitMatches, limitSelfTrades := limit.matchOrder(order)
+		for _, selfTrade := range limitSelfTrades {
+			selfTrade.AfterMatches += len(matches)
+			selfTrades = append(selfTrades, selfTrade)
+		}
+		matches = append(matches, limitMatches...)
+		book.forgetFilled(limitMatches)
+		book.forgetSelfTrades(limitSelfTrades)
 
+		if len(limit.Orders) == 0 {
+			book.removeLimit(side, limit)
 		}
-	} else {
-		if book.totalBidVolume() < order.Size {
-			return nil, &InsufficientVolumeError{book.totalBidVolume(), order.Size}
+
+		if order.Size == 0 {
+			break
 		}
+	}
 
-		for _, limit := range book.GetBids() {
-			limitMatches := limit.matchOrder(order)
-			matches = append(matches, limitMatches...)
+	if len(matches) > 0 {
+		book.LastTradePrice = matches[len(matches)-1].Price
+	}
 
-			if len(limit.Orders) == 0 {
-				book.removeLimit(Bid, limit)
-			}
+	return matches, selfTrades, nil
+}
 
-			if order.Size == 0 {
-				break
-			}
+// fillEstimate walks the other side of the book to find the worst price and
+// total cost of filling size, without changing it. ok is false when there is
+// not enough volume.
+func (book *Orderbook) fillEstimate(side Side, size float64) (worst float64, cost float64, ok bool) {
+	limits := book.GetAsks()
+	if side == Ask {
+		limits = book.GetBids()
+	}
+
+	for _, limit := range limits {
+		filled := math.Min(limit.TotalVolume, size)
+		worst = limit.Price
+		cost += filled * limit.Price
+		size -= filled
+
+		if size == 0 {
+			return worst, cost, true
+		}
+	}
+
+	return worst, cost, false
+}
+
+func (book *Orderbook) forgetFilled(matches []Match) {
+	for _, match := range matches {
+		if match.Ask.Size == 0 {
+			delete(book.orders, match.Ask.ID)
+		}
+		if match.Bid.Size == 0 {
+			delete(book.orders, match.Bid.ID)
 		}
 	}
+}
 
-	return matches, nil
+func (book *Orderbook) forgetSelfTrades(selfTrades []SelfTrade) {
+	for _, selfTrade := range selfTrades {
+		if selfTrade.Maker.Size == 0 {
+			delete(book.orders, selfTrade.Maker.ID)
+		}
+	}
 }
 
 func (book *Orderbook) cancelOrder(order *Order) {
 	var limit *Limit
 
+	delete(book.orders, order.ID)
+
 	price := order.Price
 	side := order.Side
 	if side == Bid {
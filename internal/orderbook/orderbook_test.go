@@ -105,8 +105,10 @@ func TestOrderBookPlaceMarketBuyOrder(t *testing.T) {
 		buyOrder,
 		3,
 		250,
+		0,
+		0,
 	}}
-	actualMatches, _ := orderbook.placeMarketOrder(buyOrder)
+	actualMatches, _, _ := orderbook.placeMarketOrder(buyOrder)
 	assert.Equal(t, expectedMatches, actualMatches, "placeMarketOrder should return correct matches")
 
 	assert.Equal(t, float64(5), orderbook.totalAskVolume(), "total ask volume should be 5")
@@ -127,8 +129,10 @@ func TestOrderBookPlaceMarketBuyOrderMultiMatch(t *testing.T) {
 		buyOrder,
 		3,
 		250,
+		0,
+		0,
 	}}
-	actualMatches, _ := orderbook.placeMarketOrder(buyOrder)
+	actualMatches, _, _ := orderbook.placeMarketOrder(buyOrder)
 	assert.Equal(t, expectedMatches, actualMatches, "placeMarketOrder should return correct matches")
 
 	assert.Equal(t, float64(0), buyOrder.Size, "buy order size should be 0")
@@ -151,14 +155,18 @@ func TestOrderBookPlaceMarketBuyOrderMultiPriceLimitMatch(t *testing.T) {
 			buyOrder,
 			2,
 			240,
+			0,
+			0,
 		},
 		{
 			sellOrder1,
 			buyOrder,
 			1,
 			250,
+			0,
+			0,
 		}}
-	actualMatches, _ := orderbook.placeMarketOrder(buyOrder)
+	actualMatches, _, _ := orderbook.placeMarketOrder(buyOrder)
 	assert.Equal(t, expectedMatches, actualMatches, "placeMarketOrder should return correct matches")
 
 	assert.Equal(t, float64(0), buyOrder.Size, "buy order size should be 0")
@@ -178,7 +186,7 @@ func TestOrderBookPlaceMarketBuyOrderInsufficientVolume(t *testing.T) {
 
 	orderbook.placeLimitOrder(250, sellOrder)
 
-	_, err := orderbook.placeMarketOrder(buyOrder)
+	_, _, err := orderbook.placeMarketOrder(buyOrder)
 	assert.Equal(t, &InsufficientVolumeError{2, 3}, err, "placeMarketOrder should return InsufficientVolumeError")
 	assert.Equal(t, float64(3), buyOrder.Size, "buy order size should be 3")
 }
@@ -194,8 +202,10 @@ func TestOrderBookPlaceMarketSellOrder(t *testing.T) {
 		buyOrder,
 		3,
 		250,
+		0,
+		0,
 	}}
-	actualMatches, _ := orderbook.placeMarketOrder(sellOrder)
+	actualMatches, _, _ := orderbook.placeMarketOrder(sellOrder)
 	assert.Equal(t, expectedMatches, actualMatches, "placeMarketOrder should return correct matches")
 
 	assert.Equal(t, float64(5), buyOrder.Size, "buy order size should be 5")
@@ -215,8 +225,10 @@ func TestOrderBookPlaceMarketSellOrderMultiMatch(t *testing.T) {
 		buyOrder1,
 		3,
 		250,
+		0,
+		0,
 	}}
-	actualMatches, _ := orderbook.placeMarketOrder(sellOrder)
+	actualMatches, _, _ := orderbook.placeMarketOrder(sellOrder)
 	assert.Equal(t, expectedMatches, actualMatches, "placeMarketOrder should return correct matches")
 
 	assert.Equal(t, float64(0), sellOrder.Size, "sell order size should be 0")
@@ -239,14 +251,18 @@ func TestOrderBookPlaceMarketSellOrderMultiPriceLimitMatch(t *testing.T) {
 			buyOrder2,
 			2,
 			250,
+			0,
+			0,
 		},
 		{
 			sellOrder,
 			buyOrder1,
 			1,
 			240,
+			0,
+			0,
 		}}
-	actualMatches, _ := orderbook.placeMarketOrder(sellOrder)
+	actualMatches, _, _ := orderbook.placeMarketOrder(sellOrder)
 	assert.Equal(t, expectedMatches, actualMatches, "placeMarketOrder should return correct matches")
 
 	assert.Equal(t, float64(0), sellOrder.Size, "sell order size should be 0")
@@ -266,7 +282,7 @@ func TestOrderBookPlaceMarketSellOrderInsufficientVolume(t *testing.T) {
 
 	orderbook.placeLimitOrder(250, buyOrder)
 
-	_, err := orderbook.placeMarketOrder(sellOrder)
+	_, _, err := orderbook.placeMarketOrder(sellOrder)
 	assert.Equal(t, &InsufficientVolumeError{2, 3}, err, "placeMarketOrder should return InsufficientVolumeError")
 	assert.Equal(t, float64(3), sellOrder.Size, "sell order size should be 3")
 }
@@ -0,0 +1,187 @@
+package orderbook
+
+import "math"
+
+// RiskCheck inspects an order before it reaches the orderbook. Returning an
+// error rejects the order; errors should implement HTTPCode so the API can
+// report them.
+type RiskCheck interface {
+	Check(req *RiskRequest) error
+}
+
+// RiskCheckFunc adapts a function to a RiskCheck.
+type RiskCheckFunc func(req *RiskRequest) error
+
+func (f RiskCheckFunc) Check(req *RiskRequest) error {
+	return f(req)
+}
+
+// RiskRequest describes an order for the risk checks. Checks run with the
+// platform locked, so they can read its state but must not call into it.
+type RiskRequest struct {
+	Market    TradingPair
+	Order     *Order
+	OrderType OrderType
+	// Price is the limit price, or the worst price a market order would fill
+	// at.
+	Price float64
+	// Notional is the quote value of the order, the cost of filling it for a
+	// market order.
+	Notional float64
+	Book     *Orderbook
+
+	platform *TradingPlatform
+}
+
+// OpenOrders counts the orders the signer has resting on every market.
+func (req *RiskRequest) OpenOrders() int {
+	count := 0
+	req.eachOpenOrder(func(*Orderbook, *Order) {
+		count++
+	})
+
+	return count
+}
+
+// Position is the signer's net position in an asset from their trades.
+func (req *RiskRequest) Position(asset string) float64 {
+	return req.platform.positions[req.Order.Signer][asset]
+}
+
+// ProjectedPosition is the signer's position in an asset if this order and
+// every resting order of theirs moving the position the same way were filled.
+func (req *RiskRequest) ProjectedPosition(asset string) float64 {
+	delta := positionDelta(req.Market, req.Order.Side, req.Order.Size, req.Price, asset)
+	projected := req.Position(asset) + delta
+
+	req.eachOpenOrder(func(book *Orderbook, order *Order) {
+		resting := positionDelta(*book.Market, order.Side, order.Size, order.Price, asset)
+		if resting*delta > 0 {
+			projected += resting
+		}
+	})
+
+	return projected
+}
+
+func (req *RiskRequest) eachOpenOrder(fn func(*Orderbook, *Order)) {
+	for _, book := range req.platform.Orderbooks {
+		for _, order := range book.orders {
+			if order.Signer == req.Order.Signer {
+				fn(book, order)
+			}
+		}
+	}
+}
+
+// positionDelta is the change in a position in asset from filling size at
+// price on a market.
+func positionDelta(market TradingPair, side Side, size float64, price float64, asset string) float64 {
+	delta := 0.0
+	switch asset {
+	case market.Base:
+		delta = size
+	case market.Quote:
+		delta = -size * price
+	}
+	if side == Ask {
+		delta = -delta
+	}
+
+	return delta
+}
+
+// MaxOrderNotional rejects orders worth more than limit in the quote asset.
+func MaxOrderNotional(limit float64) RiskCheck {
+	return RiskCheckFunc(func(req *RiskRequest) error {
+		if req.Notional > limit {
+			return &OrderNotionalExceededError{req.Notional, limit}
+		}
+
+		return nil
+	})
+}
+
+// MaxOpenOrders rejects limit orders from signers who already have limit
+// orders resting across every market.
+func MaxOpenOrders(limit int) RiskCheck {
+	return RiskCheckFunc(func(req *RiskRequest) error {
+		if req.Order.Signer == "" || req.OrderType != LimitOrder {
+			return nil
+		}
+		if open := req.OpenOrders(); open >= limit {
+			return &OpenOrdersExceededError{req.Order.Signer, limit}
+		}
+
+		return nil
+	})
+}
+
+// MaxPosition rejects orders that could take a signer's position in an asset
+// beyond its limit, long or short. Assets without a limit are unrestricted.
+func MaxPosition(limits map[string]float64) RiskCheck {
+	return RiskCheckFunc(func(req *RiskRequest) error {
+		if req.Order.Signer == "" {
+			return nil
+		}
+
+		for _, asset := range []string{req.Market.Base, req.Market.Quote} {
+			limit, ok := limits[asset]
+			if !ok {
+				continue
+			}
+			if projected := req.ProjectedPosition(asset); math.Abs(projected) > limit {
+				return &PositionLimitExceededError{req.Order.Signer, asset, projected, limit}
+			}
+		}
+
+		return nil
+	})
+}
+
+// PriceReference is the price a PriceBand is measured from.
+type PriceReference string
+
+const (
+	// ReferenceLastTrade uses the price of the last trade on the market.
+	ReferenceLastTrade PriceReference = "last_trade"
+	// ReferenceTopOfBook uses the mid price of the best bid and ask, or the
+	// best price when only one side has orders.
+	ReferenceTopOfBook PriceReference = "top_of_book"
+)
+
+// PriceBand rejects orders priced more than percent away from the reference
+// price. Orders on a market without a reference price are not checked.
+func PriceBand(percent float64, reference PriceReference) RiskCheck {
+	return RiskCheckFunc(func(req *RiskRequest) error {
+		ref := referencePrice(req.Book, reference)
+		if ref == 0 {
+			return nil
+		}
+
+		low, high := ref*(1-percent/100), ref*(1+percent/100)
+		if req.Price < low || req.Price > high {
+			return &PriceOutsideBandError{req.Price, low, high}
+		}
+
+		return nil
+	})
+}
+
+func referencePrice(book *Orderbook, reference PriceReference) float64 {
+	if reference == ReferenceLastTrade {
+		return book.LastTradePrice
+	}
+
+	bestAsk, bestBid := book.bestAsk(), book.bestBid()
+	switch {
+	case bestAsk != nil && bestBid != nil:
+		return (bestAsk.Price + bestBid.Price) / 2
+	case bestAsk != nil:
+		return bestAsk.Price
+	case bestBid != nil:
+		return bestBid.Price
+	}
+
+	return 0
+}
@@ -0,0 +1,119 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signedOrder(side Side, size float64, signer string) *Order {
+	order := NewOrder(side, size)
+	order.Signer = signer
+	return order
+}
+
+func TestRiskMaxOrderNotional(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+	tradingPlatform.AddRiskCheck(MaxOrderNotional(1000))
+
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(pair, 100, NewOrder(Ask, 10)), "order worth the limit should be accepted")
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(pair, 150, NewOrder(Ask, 5)), "order worth less than the limit should be accepted")
+
+	err := tradingPlatform.PlaceLimitOrder(pair, 100, NewOrder(Bid, 11))
+	assert.IsType(t, &OrderNotionalExceededError{}, err, "limit order worth more than the limit should be rejected")
+
+	// Filling 12 takes all 10 at 100 and 2 at 150.
+	_, err = tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 12))
+	assert.IsType(t, &OrderNotionalExceededError{}, err, "market order costing more than the limit should be rejected")
+
+	matches, err := tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 10))
+	assert.Nil(t, err, "market order costing the limit should be accepted")
+	assert.Equal(t, 1, len(matches), "market order should match")
+}
+
+func TestRiskMaxOpenOrders(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	btcusd := TradingPair{"BTC", "USD"}
+	ethusd := TradingPair{"ETH", "USD"}
+	tradingPlatform.AddNewMarket(btcusd)
+	tradingPlatform.AddNewMarket(ethusd)
+	tradingPlatform.AddRiskCheck(MaxOpenOrders(2))
+
+	first := signedOrder(Bid, 1, "alice")
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(btcusd, 100, first), "first order should be accepted")
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(ethusd, 10, signedOrder(Bid, 1, "alice")), "second order should be accepted")
+
+	err := tradingPlatform.PlaceLimitOrder(btcusd, 100, signedOrder(Bid, 1, "alice"))
+	assert.IsType(t, &OpenOrdersExceededError{}, err, "third order should be rejected across markets")
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(btcusd, 100, signedOrder(Bid, 1, "bob")), "other signers should not be limited")
+
+	tradingPlatform.CancelOrder(btcusd, first.ID)
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(btcusd, 100, signedOrder(Bid, 1, "alice")), "cancelling should free up an order")
+}
+
+func TestRiskMaxPosition(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+	tradingPlatform.AddRiskCheck(MaxPosition(map[string]float64{"BTC": 5}))
+
+	tradingPlatform.PlaceLimitOrder(pair, 100, NewOrder(Ask, 20))
+
+	_, err := tradingPlatform.PlaceMarketOrder(pair, signedOrder(Bid, 3, "alice"))
+	assert.Nil(t, err, "order within the limit should be accepted")
+	assert.Equal(t, float64(3), tradingPlatform.Position("alice", "BTC"), "alice should be long 3 BTC")
+	assert.Equal(t, float64(-300), tradingPlatform.Position("alice", "USD"), "alice should be short 300 USD")
+
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(pair, 90, signedOrder(Bid, 1, "alice")), "resting bid within the limit should be accepted")
+
+	_, err = tradingPlatform.PlaceMarketOrder(pair, signedOrder(Bid, 2, "alice"))
+	assert.IsType(t, &PositionLimitExceededError{}, err, "order that could take alice past 5 BTC with their resting bid should be rejected")
+
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(pair, 150, signedOrder(Ask, 8, "alice")), "selling down to short 5 should be accepted")
+	err = tradingPlatform.PlaceLimitOrder(pair, 150, signedOrder(Ask, 1, "alice"))
+	assert.IsType(t, &PositionLimitExceededError{}, err, "selling past short 5 should be rejected")
+}
+
+func TestRiskPriceBand(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+	tradingPlatform.AddRiskCheck(PriceBand(10, ReferenceTopOfBook))
+
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(pair, 100, NewOrder(Ask, 5)), "first order should have no reference")
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(pair, 90, NewOrder(Bid, 5)), "bid within 10% of the best ask should be accepted")
+
+	// The mid price is now 95.
+	err := tradingPlatform.PlaceLimitOrder(pair, 105, NewOrder(Ask, 5))
+	assert.IsType(t, &PriceOutsideBandError{}, err, "ask more than 10% above the mid should be rejected")
+	assert.Equal(t, 422, err.(*PriceOutsideBandError).HTTPCode(), "rejection should be unprocessable")
+
+	tradingPlatform.AddRiskCheck(PriceBand(1, ReferenceLastTrade))
+	assert.Nil(t, tradingPlatform.PlaceLimitOrder(pair, 100, NewOrder(Ask, 5)), "last trade band should not apply before a trade")
+
+	tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 5))
+	assert.Equal(t, float64(100), tradingPlatform.Orderbooks[pair].LastTradePrice, "last trade price should be recorded")
+
+	err = tradingPlatform.PlaceLimitOrder(pair, 98, NewOrder(Ask, 5))
+	assert.IsType(t, &PriceOutsideBandError{}, err, "ask more than 1% below the last trade should be rejected")
+}
+
+func TestRiskRejectionEvent(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+	tradingPlatform.AddRiskCheck(MaxOrderNotional(10))
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	order := NewOrder(Bid, 1)
+	err := tradingPlatform.PlaceLimitOrder(pair, 100, order)
+
+	events := receiveEvents(t, sub, 1)
+	rejected := events[0].(*OrderRejected)
+	assert.Equal(t, order.ID, rejected.Order.ID, "rejected event should carry the order")
+	assert.Equal(t, err.Error(), rejected.Reason, "rejected event should carry the risk error")
+}
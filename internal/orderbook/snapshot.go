@@ -0,0 +1,417 @@
+package orderbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kr/pretty"
+	"github.com/richo225/octgopus/internal/accounting"
+)
+
+// SnapshotVersion 2 added the ledger. Version 1 snapshots are still restored,
+// with each balance becoming an opening ledger entry.
+const SnapshotVersion = 2
+
+// Snapshot is the complete state of a TradingPlatform after the journaled
+// command numbered Sequence.
+type Snapshot struct {
+	Version     int                `json:"version"`
+	Sequence    uint64             `json:"sequence"`
+	LastOrderID uint64             `json:"last_order_id"`
+	Markets     []MarketSnapshot   `json:"markets"`
+	Accounts    map[string]float64 `json:"accounts"`
+	Ledger      *LedgerSnapshot    `json:"ledger,omitempty"`
+	// Positions is missing from snapshots taken before positions were
+	// tracked, which restore with every position at zero.
+	Positions map[string]map[string]float64 `json:"positions,omitempty"`
+}
+
+type LedgerSnapshot struct {
+	Entries  []accounting.Entry `json:"entries"`
+	LastTxID uint64             `json:"last_tx_id"`
+}
+
+type MarketSnapshot struct {
+	Market TradingPair     `json:"market"`
+	Asks   []LimitSnapshot `json:"asks"`
+	Bids   []LimitSnapshot `json:"bids"`
+	State  TradingState    `json:"state,omitempty"`
+	// Halted is only set in snapshots taken before trading states.
+	Halted   bool  `json:"halted,omitempty"`
+	ResumeAt int64 `json:"resume_at,omitempty"`
+	// LastTradePrice is the reference for price bands.
+	LastTradePrice float64            `json:"last_trade_price,omitempty"`
+	Fees           *FeeSchedule       `json:"fees,omitempty"`
+	Volumes        map[string]float64 `json:"volumes,omitempty"`
+	Trades         []TradeRecord      `json:"trades,omitempty"`
+}
+
+type LimitSnapshot struct {
+	Price  float64 `json:"price"`
+	Orders []Order `json:"orders"`
+}
+
+// currentSequence is the sequence of the last command applied.
+func (platform *TradingPlatform) currentSequence() uint64 {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	return platform.sequence
+}
+
+// Snapshot captures the current state of every orderbook and account.
+func (platform *TradingPlatform) Snapshot() *Snapshot {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	snapshot := &Snapshot{
+		Version:     SnapshotVersion,
+		Sequence:    platform.sequence,
+		LastOrderID: platform.lastOrderID,
+		Markets:     []MarketSnapshot{},
+		Accounts:    make(map[string]float64),
+	}
+
+	for _, orderbook := range platform.Orderbooks {
+		snapshot.Markets = append(snapshot.Markets, MarketSnapshot{
+			Market:         *orderbook.Market,
+			Asks:           snapshotLimits(orderbook.GetAsks()),
+			Bids:           snapshotLimits(orderbook.GetBids()),
+			State:          orderbook.State,
+			ResumeAt:       orderbook.ResumeAt,
+			LastTradePrice: orderbook.LastTradePrice,
+			Fees:           orderbook.Fees,
+			Volumes:        copyVolumes(orderbook.volumes),
+			Trades:         append([]TradeRecord(nil), orderbook.trades...),
+		})
+	}
+	sort.Slice(snapshot.Markets, func(i, j int) bool {
+		return snapshot.Markets[i].Market.ToString() < snapshot.Markets[j].Market.ToString()
+	})
+
+	for signer, balance := range platform.Accounts.Accounts {
+		snapshot.Accounts[signer] = balance
+	}
+
+	snapshot.Positions = make(map[string]map[string]float64)
+	for signer, positions := range platform.positions {
+		snapshot.Positions[signer] = make(map[string]float64)
+		for asset, position := range positions {
+			snapshot.Positions[signer][asset] = position
+		}
+	}
+
+	ledger := platform.Accounts.Ledger
+	snapshot.Ledger = &LedgerSnapshot{Entries: []accounting.Entry{}, LastTxID: ledger.LastTxID}
+	for _, entry := range ledger.Entries {
+		snapshot.Ledger.Entries = append(snapshot.Ledger.Entries, *entry)
+	}
+
+	return snapshot
+}
+
+func snapshotLimits(limits []*Limit) []LimitSnapshot {
+	snapshots := []LimitSnapshot{}
+	for _, limit := range limits {
+		orders := []Order{}
+		for _, order := range limit.Orders {
+			orders = append(orders, *order)
+		}
+		snapshots = append(snapshots, LimitSnapshot{Price: limit.Price, Orders: orders})
+	}
+
+	return snapshots
+}
+
+// Restore replaces the state of the platform with a snapshot. Recover can then
+// replay the journal from the command after the snapshot.
+func (platform *TradingPlatform) Restore(snapshot *Snapshot) error {
+	if snapshot.Version != SnapshotVersion && snapshot.Version != 1 {
+		return &SnapshotVersionError{snapshot.Version}
+	}
+
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	platform.Orderbooks = make(map[TradingPair]*Orderbook)
+	for _, market := range snapshot.Markets {
+		orderbook := platform.addNewMarket(market.Market)
+		restoreLimits(orderbook, market.Asks)
+		restoreLimits(orderbook, market.Bids)
+		orderbook.ResumeAt = market.ResumeAt
+		switch {
+		case market.State != "":
+			orderbook.State = market.State
+		case market.Halted:
+			orderbook.State = StateHalted
+		}
+		if orderbook.State == StateAuction {
+			orderbook.Indicative = orderbook.indicativeUncross()
+		}
+		orderbook.LastTradePrice = market.LastTradePrice
+		orderbook.Fees = market.Fees
+		orderbook.volumes = copyVolumes(market.Volumes)
+		orderbook.trades = append([]TradeRecord(nil), market.Trades...)
+	}
+
+	platform.positions = make(map[string]map[string]float64)
+	for signer, positions := range snapshot.Positions {
+		platform.positions[signer] = make(map[string]float64)
+		for asset, position := range positions {
+			platform.positions[signer][asset] = position
+		}
+	}
+
+	platform.Accounts.Reset()
+	if snapshot.Ledger != nil {
+		entries := []*accounting.Entry{}
+		for i := range snapshot.Ledger.Entries {
+			entry := snapshot.Ledger.Entries[i]
+			entries = append(entries, &entry)
+		}
+		platform.Accounts.Ledger = accounting.RestoreLedger(entries, snapshot.Ledger.LastTxID)
+	}
+	for signer, balance := range snapshot.Accounts {
+		platform.Accounts.Accounts[signer] = 0
+		if snapshot.Ledger == nil && balance != 0 {
+			platform.Accounts.DepositAt(signer, balance, time.Unix(0, 0))
+		} else {
+			platform.Accounts.Accounts[signer] = balance
+		}
+	}
+
+	platform.sequence = snapshot.Sequence
+	platform.lastOrderID = snapshot.LastOrderID
+
+	return nil
+}
+
+func copyVolumes(volumes map[string]float64) map[string]float64 {
+	copied := make(map[string]float64, len(volumes))
+	for signer, volume := range volumes {
+		copied[signer] = volume
+	}
+
+	return copied
+}
+
+func restoreLimits(orderbook *Orderbook, limits []LimitSnapshot) {
+	for _, limit := range limits {
+		for _, order := range limit.Orders {
+			order := order
+			orderbook.placeLimitOrder(limit.Price, &order)
+		}
+	}
+}
+
+// SnapshotStore keeps snapshots as JSON files named after their sequence.
+type SnapshotStore struct {
+	Dir string
+	// Keep is the number of most recent snapshots to retain, defaulting to 2.
+	Keep int
+}
+
+const snapshotExt = ".snapshot.json"
+
+func (store *SnapshotStore) path(sequence uint64) string {
+	return filepath.Join(store.Dir, fmt.Sprintf("%020d%s", sequence, snapshotExt))
+}
+
+// Save writes the snapshot atomically and removes older snapshots beyond Keep.
+func (store *SnapshotStore) Save(snapshot *Snapshot) error {
+	if err := os.MkdirAll(store.Dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(store.Dir, "snapshot-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), store.path(snapshot.Sequence)); err != nil {
+		return err
+	}
+
+	return store.prune()
+}
+
+func (store *SnapshotStore) list() ([]string, error) {
+	entries, err := os.ReadDir(store.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), snapshotExt) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (store *SnapshotStore) prune() error {
+	keep := store.Keep
+	if keep <= 0 {
+		keep = 2
+	}
+
+	names, err := store.list()
+	if err != nil {
+		return err
+	}
+
+	for len(names) > keep {
+		if err := os.Remove(filepath.Join(store.Dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+
+	return nil
+}
+
+// Latest loads the most recent snapshot, or returns nil if there are none.
+func (store *SnapshotStore) Latest() (*Snapshot, error) {
+	names, err := store.list()
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(store.Dir, names[len(names)-1]))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// Compactor discards journaled commands already covered by a snapshot, e.g. a
+// *wal.Log.
+type Compactor interface {
+	Compact(upTo uint64) error
+}
+
+// Snapshotter periodically saves a snapshot of the platform and compacts the
+// journal up to it.
+type Snapshotter struct {
+	platform  *TradingPlatform
+	store     *SnapshotStore
+	compactor Compactor
+	interval  time.Duration
+	stop      chan struct{}
+	stopped   chan struct{}
+
+	// saved is the sequence of the newest snapshot in the store, read from it
+	// once when seeded and kept up to date as snapshots are saved.
+	seeded   bool
+	saved    uint64
+	hasSaved bool
+
+	mu sync.Mutex
+}
+
+func NewSnapshotter(platform *TradingPlatform, store *SnapshotStore, compactor Compactor, interval time.Duration) *Snapshotter {
+	return &Snapshotter{
+		platform:  platform,
+		store:     store,
+		compactor: compactor,
+		interval:  interval,
+	}
+}
+
+// SnapshotNow saves a snapshot unless nothing has changed since the last one,
+// then compacts the journal.
+func (s *Snapshotter) SnapshotNow() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.seeded {
+		latest, err := s.store.Latest()
+		if err != nil {
+			return err
+		}
+		if latest != nil {
+			s.saved, s.hasSaved = latest.Sequence, true
+		}
+		s.seeded = true
+	}
+	if s.hasSaved && s.saved == s.platform.currentSequence() {
+		return nil
+	}
+
+	snapshot := s.platform.Snapshot()
+	if err := s.store.Save(snapshot); err != nil {
+		return err
+	}
+	s.saved, s.hasSaved = snapshot.Sequence, true
+
+	return s.compactor.Compact(snapshot.Sequence)
+}
+
+func (s *Snapshotter) Start() {
+	s.stop = make(chan struct{})
+	s.stopped = make(chan struct{})
+
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.SnapshotNow(); err != nil {
+					pretty.Log("Snapshot failed", err.Error())
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Snapshotter) Stop() {
+	if s.stop == nil {
+		return
+	}
+
+	close(s.stop)
+	<-s.stopped
+	s.stop = nil
+}
@@ -0,0 +1,212 @@
+package orderbook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/richo225/octgopus/internal/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotScript() []func(p *TradingPlatform) {
+	btcusd := TradingPair{"BTC", "USD"}
+	ethusd := TradingPair{"ETH", "USD"}
+	order := func(side Side, size float64, ts int64) *Order {
+		return &Order{Side: side, Size: size, Timestamp: ts}
+	}
+
+	return []func(p *TradingPlatform){
+		func(p *TradingPlatform) { p.AddNewMarket(btcusd) },
+		func(p *TradingPlatform) { p.AddNewMarket(ethusd) },
+		func(p *TradingPlatform) { p.PlaceLimitOrder(btcusd, 250, order(Ask, 8, 1)) },
+		func(p *TradingPlatform) { p.PlaceLimitOrder(btcusd, 250, order(Ask, 4, 2)) },
+		func(p *TradingPlatform) { p.PlaceLimitOrder(btcusd, 240, order(Ask, 2, 3)) },
+		func(p *TradingPlatform) { p.PlaceLimitOrder(btcusd, 200, order(Bid, 5, 4)) },
+		func(p *TradingPlatform) { p.PlaceLimitOrder(ethusd, 20, order(Bid, 50, 5)) },
+		func(p *TradingPlatform) { p.CreateAccount("alice") },
+		func(p *TradingPlatform) { p.CreateAccount("bob") },
+		func(p *TradingPlatform) { p.Deposit("alice", 100) },
+		func(p *TradingPlatform) { p.PlaceMarketOrder(btcusd, order(Bid, 3, 6)) },
+		func(p *TradingPlatform) { p.CancelOrder(btcusd, 4) },
+		func(p *TradingPlatform) { p.Send("alice", "bob", 40) },
+		func(p *TradingPlatform) { p.PlaceMarketOrder(ethusd, order(Ask, 20, 7)) },
+		func(p *TradingPlatform) { p.Withdraw("bob", 15) },
+		func(p *TradingPlatform) { p.HaltMarket(ethusd) },
+		func(p *TradingPlatform) { p.PlaceLimitOrder(btcusd, 235, order(Ask, 1, 8)) },
+	}
+}
+
+func openSnapshotTestJournal(t *testing.T, dir string) *wal.Log {
+	t.Helper()
+
+	log, err := wal.Open(wal.Options{Dir: filepath.Join(dir, "wal"), SegmentSize: 256})
+	require.NoError(t, err, "journal should open")
+	t.Cleanup(func() { log.Close() })
+
+	return log
+}
+
+func recoverFromSnapshot(t *testing.T, dir string) *TradingPlatform {
+	t.Helper()
+
+	log := openSnapshotTestJournal(t, dir)
+	store := &SnapshotStore{Dir: filepath.Join(dir, "snapshots")}
+
+	platform := NewTradingPlatform()
+	snapshot, err := store.Latest()
+	require.NoError(t, err, "latest snapshot should load")
+	if snapshot != nil {
+		require.NoError(t, platform.Restore(snapshot), "snapshot should restore")
+	}
+	require.NoError(t, platform.Recover(log), "journal tail should replay")
+	platform.UseJournal(log)
+
+	return platform
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	platform := NewTradingPlatform()
+	for _, step := range snapshotScript() {
+		step(platform)
+	}
+
+	restored := NewTradingPlatform()
+	require.NoError(t, restored.Restore(platform.Snapshot()))
+
+	assert.Equal(t, platform.Snapshot(), restored.Snapshot(), "restored platform should match the snapshot")
+
+	orderbook, _ := restored.GetOrderBook(TradingPair{"BTC", "USD"})
+	assert.Equal(t, 3, len(orderbook.orders), "restored book should index resting orders")
+}
+
+func TestSnapshotRestoresVersion1Balances(t *testing.T) {
+	platform := NewTradingPlatform()
+	require.NoError(t, platform.Restore(&Snapshot{Version: 1, Accounts: map[string]float64{"alice": 60, "bob": 0}}))
+
+	balance, _ := platform.Accounts.BalanceOf("alice")
+	assert.Equal(t, float64(60), balance, "alice should keep the balance")
+	_, err := platform.Accounts.BalanceOf("bob")
+	assert.Nil(t, err, "empty accounts should be kept")
+
+	entries, _ := platform.Transactions("alice", 0, 10)
+	assert.Equal(t, 1, len(entries), "balance should become an opening ledger entry")
+	assert.Nil(t, platform.Accounts.Reconcile(), "restored ledger should reconcile")
+}
+
+func TestSnapshotUnsupportedVersion(t *testing.T) {
+	err := NewTradingPlatform().Restore(&Snapshot{Version: SnapshotVersion + 1})
+	assert.IsType(t, &SnapshotVersionError{}, err, "unknown snapshot version should be refused")
+}
+
+func TestSnapshotStoreKeepsLatest(t *testing.T) {
+	store := &SnapshotStore{Dir: t.TempDir(), Keep: 2}
+
+	for seq := uint64(1); seq <= 4; seq++ {
+		require.NoError(t, store.Save(&Snapshot{Version: SnapshotVersion, Sequence: seq}))
+	}
+
+	names, _ := store.list()
+	assert.Equal(t, 2, len(names), "store should prune older snapshots")
+
+	latest, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), latest.Sequence, "latest should be the highest sequence")
+}
+
+// TestSnapshotKillAndRecover stops the platform after every command, having
+// taken a snapshot after every possible earlier command, and checks that the
+// snapshot plus the journal tail rebuilds exactly the same state.
+func TestSnapshotKillAndRecover(t *testing.T) {
+	script := snapshotScript()
+
+	for kill := 1; kill <= len(script); kill++ {
+		for snapAt := -1; snapAt < kill; snapAt++ {
+			t.Run(fmt.Sprintf("kill %d snapshot %d", kill, snapAt), func(t *testing.T) {
+				dir := t.TempDir()
+				log := openSnapshotTestJournal(t, dir)
+				store := &SnapshotStore{Dir: filepath.Join(dir, "snapshots")}
+
+				platform := NewTradingPlatform()
+				platform.UseJournal(log)
+				snapshotter := NewSnapshotter(platform, store, log, 0)
+
+				for i, step := range script[:kill] {
+					step(platform)
+					if i == snapAt {
+						require.NoError(t, snapshotter.SnapshotNow(), "snapshot should be saved")
+					}
+				}
+
+				recovered := recoverFromSnapshot(t, dir)
+
+				assert.Equal(t, platform.Snapshot(), recovered.Snapshot(), "recovered state should match the state at the crash")
+			})
+		}
+	}
+}
+
+func TestSnapshotCompactsJournal(t *testing.T) {
+	dir := t.TempDir()
+	log := openSnapshotTestJournal(t, dir)
+	store := &SnapshotStore{Dir: filepath.Join(dir, "snapshots")}
+
+	platform := NewTradingPlatform()
+	platform.UseJournal(log)
+	for _, step := range snapshotScript() {
+		step(platform)
+	}
+
+	snapshotter := NewSnapshotter(platform, store, log, 0)
+	require.NoError(t, snapshotter.SnapshotNow())
+
+	assert.Equal(t, log.LastSequence()+1, log.FirstSequence(), "journal should be compacted up to the snapshot")
+
+	platform.PlaceLimitOrder(TradingPair{"BTC", "USD"}, 230, &Order{Side: Ask, Size: 1, Timestamp: 9})
+
+	recovered := recoverFromSnapshot(t, dir)
+	assert.Equal(t, platform.Snapshot(), recovered.Snapshot(), "recovered state should include commands after the snapshot")
+}
+
+func TestSnapshotMissingForCompactedJournal(t *testing.T) {
+	dir := t.TempDir()
+	log := openSnapshotTestJournal(t, dir)
+
+	platform := NewTradingPlatform()
+	platform.UseJournal(log)
+	for _, step := range snapshotScript() {
+		step(platform)
+	}
+	require.NoError(t, log.Compact(log.LastSequence()))
+
+	err := NewTradingPlatform().Recover(log)
+	assert.IsType(t, &JournalGapError{}, err, "recovering a compacted journal without its snapshot should fail")
+}
+
+func TestSnapshotSkipsUnchangedPlatform(t *testing.T) {
+	dir := t.TempDir()
+	log := openSnapshotTestJournal(t, dir)
+	store := &SnapshotStore{Dir: filepath.Join(dir, "snapshots")}
+
+	platform := NewTradingPlatform()
+	platform.UseJournal(log)
+	for _, step := range snapshotScript() {
+		step(platform)
+	}
+	require.NoError(t, NewSnapshotter(platform, store, log, 0).SnapshotNow())
+
+	// A restarted snapshotter is seeded from the store once, and afterwards
+	// only compares sequences: the unreadable file would fail a second read.
+	snapshotter := NewSnapshotter(platform, store, log, 0)
+	require.NoError(t, snapshotter.SnapshotNow(), "unchanged platform should not be saved again")
+	require.NoError(t, os.WriteFile(store.path(log.LastSequence()+1), []byte("not json"), 0o644))
+	assert.NoError(t, snapshotter.SnapshotNow(), "unchanged platform should not read the store again")
+
+	platform.PlaceLimitOrder(TradingPair{"BTC", "USD"}, 230, &Order{Side: Ask, Size: 1, Timestamp: 9})
+	require.NoError(t, snapshotter.SnapshotNow(), "changed platform should be saved")
+	latest, err := (&SnapshotStore{Dir: store.Dir}).Latest()
+	require.NoError(t, err)
+	assert.Equal(t, log.LastSequence(), latest.Sequence, "snapshot should cover the new command")
+}
@@ -0,0 +1,150 @@
+package orderbook
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kr/pretty"
+)
+
+// TradingState controls which orders a market accepts.
+type TradingState string
+
+const (
+	// StateOpen markets match orders continuously.
+	StateOpen TradingState = "open"
+	// StateHalted markets refuse new orders until resumed, but still allow
+	// cancels. Circuit breakers halt markets.
+	StateHalted TradingState = "halted"
+	// StateCancelOnly markets only allow resting orders to be cancelled, e.g.
+	// while winding a market down.
+	StateCancelOnly TradingState = "cancel_only"
+	// StateAuction markets accept limit orders without matching them, and
+	// refuse market orders.
+	StateAuction TradingState = "auction"
+)
+
+func (state TradingState) valid() bool {
+	switch state {
+	case StateOpen, StateHalted, StateCancelOnly, StateAuction:
+		return true
+	}
+
+	return false
+}
+
+// CircuitBreaker halts a market when a trade moves its price more than
+// Percent away from any trade in the preceding Window. The market reopens
+// after HaltFor, or stays halted until resumed when HaltFor is zero.
+type CircuitBreaker struct {
+	Percent float64
+	Window  time.Duration
+	HaltFor time.Duration
+}
+
+type pricePoint struct {
+	price float64
+	at    time.Time
+}
+
+// SetCircuitBreaker applies a circuit breaker to every market. A nil breaker
+// turns automatic halts off.
+func (platform *TradingPlatform) SetCircuitBreaker(breaker *CircuitBreaker) {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	platform.circuitBreaker = breaker
+}
+
+// SetMarketState moves a market to a new trading state.
+func (platform *TradingPlatform) SetMarketState(pair TradingPair, state TradingState, reason string) error {
+	_, err := platform.submit(Command{Type: SetMarketStateCommand, Market: &pair, State: state, Reason: reason})
+	return err
+}
+
+func (platform *TradingPlatform) setState(pair TradingPair, state TradingState, reason string, resumeAt int64, at time.Time) error {
+	if !state.valid() {
+		return &InvalidTradingStateError{state}
+	}
+
+	orderbook, err := platform.GetOrderBook(pair)
+	if err != nil {
+		return err
+	}
+
+	// Leaving an auction for continuous trading uncrosses the book.
+	events := []Event{}
+	if orderbook.State == StateAuction && state == StateOpen {
+		events = append(events, platform.uncrossEvents(orderbook, at)...)
+	}
+
+	orderbook.State = state
+	orderbook.ResumeAt = resumeAt
+	orderbook.recentTrades = nil
+	events = append(events, &MarketStatusChanged{
+		EventMeta: platform.eventMeta(pair),
+		State:     state,
+		Reason:    reason,
+		ResumeAt:  resumeAt,
+	})
+	if indicative := platform.indicativeEvent(orderbook); indicative != nil {
+		events = append(events, indicative)
+	}
+	platform.events.publish(events...)
+
+	return nil
+}
+
+// checkCircuitBreaker halts the market when the trades of an order moved the
+// price too far. The halt is journaled as its own command, so replay does not
+// depend on the breaker configuration.
+func (platform *TradingPlatform) checkCircuitBreaker(pair TradingPair, matches []Match) {
+	breaker := platform.circuitBreaker
+	orderbook := platform.Orderbooks[pair]
+	if breaker == nil || len(matches) == 0 || orderbook == nil || orderbook.State != StateOpen {
+		return
+	}
+
+	now := platform.clock.Now()
+	move := orderbook.recordTrades(now, matches, breaker.Window)
+	if move <= breaker.Percent {
+		return
+	}
+
+	var resumeAt int64
+	if breaker.HaltFor > 0 {
+		resumeAt = now.Add(breaker.HaltFor).UnixNano()
+	}
+
+	reason := fmt.Sprintf("circuit breaker: price moved %.2f%% within %s", move, breaker.Window)
+	_, err := platform.commit(Command{Type: SetMarketStateCommand, Market: &pair, State: StateHalted, Reason: reason, ResumeAt: resumeAt})
+	if err != nil {
+		pretty.Log("Failed to halt market", pair.ToString(), err.Error())
+	}
+}
+
+// recordTrades remembers the prices traded in the last window and returns
+// the largest move, in percent, of the new prices from any of them.
+func (book *Orderbook) recordTrades(now time.Time, matches []Match, window time.Duration) float64 {
+	cutoff := now.Add(-window)
+	recent := book.recentTrades[:0]
+	for _, point := range book.recentTrades {
+		if !point.at.Before(cutoff) {
+			recent = append(recent, point)
+		}
+	}
+
+	move := 0.0
+	for _, match := range matches {
+		for _, point := range recent {
+			if m := 100 * math.Abs(match.Price-point.price) / point.price; m > move {
+				move = m
+			}
+		}
+		recent = append(recent, pricePoint{match.Price, now})
+	}
+	book.recentTrades = recent
+
+	return move
+}
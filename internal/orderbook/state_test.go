@@ -0,0 +1,96 @@
+package orderbook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestTradingPlatformMarketStates(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	orderbook, _ := tradingPlatform.AddNewMarket(pair)
+	resting := NewOrder(Ask, 5)
+	tradingPlatform.PlaceLimitOrder(pair, 250, resting)
+
+	assert.Equal(t, StateOpen, orderbook.State, "new market should be open")
+
+	assert.NoError(t, tradingPlatform.SetMarketState(pair, StateAuction, "opening"), "setting a state should not return an error")
+	assert.NoError(t, tradingPlatform.PlaceLimitOrder(pair, 260, NewOrder(Ask, 1)), "auction should accept limit orders")
+	_, err := tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1))
+	assert.IsType(t, &MarketInAuctionError{}, err, "auction should refuse market orders")
+
+	tradingPlatform.SetMarketState(pair, StateCancelOnly, "delisting")
+	err = tradingPlatform.PlaceLimitOrder(pair, 250, NewOrder(Ask, 1))
+	assert.IsType(t, &MarketCancelOnlyError{}, err, "cancel only market should refuse limit orders")
+	_, err = tradingPlatform.CancelOrder(pair, resting.ID)
+	assert.NoError(t, err, "cancel only market should allow cancels")
+
+	err = tradingPlatform.SetMarketState(pair, "closed", "")
+	assert.IsType(t, &InvalidTradingStateError{}, err, "unknown state should be refused")
+	assert.Equal(t, StateCancelOnly, orderbook.State, "unknown state should not change the market")
+}
+
+func TestTradingPlatformCircuitBreaker(t *testing.T) {
+	clock := &manualClock{time.Unix(1700000000, 0)}
+	dir := t.TempDir()
+	pair := TradingPair{"BTC", "USD"}
+
+	tradingPlatform := recoverPlatform(t, dir)
+	tradingPlatform.SetClock(clock)
+	tradingPlatform.SetCircuitBreaker(&CircuitBreaker{Percent: 10, Window: time.Minute, HaltFor: 5 * time.Minute})
+	orderbook, _ := tradingPlatform.AddNewMarket(pair)
+
+	tradingPlatform.PlaceLimitOrder(pair, 100, NewOrder(Ask, 1))
+	tradingPlatform.PlaceLimitOrder(pair, 120, NewOrder(Ask, 1))
+	tradingPlatform.PlaceLimitOrder(pair, 140, NewOrder(Ask, 1))
+
+	_, err := tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1))
+	require.NoError(t, err, "first trade should be accepted")
+
+	// The trade at 100 is older than the window when 120 trades.
+	clock.advance(2 * time.Minute)
+	_, err = tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1))
+	require.NoError(t, err, "trade after the window should be accepted")
+	assert.Equal(t, StateOpen, orderbook.State, "move outside the window should not halt the market")
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	clock.advance(30 * time.Second)
+	_, err = tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1))
+	require.NoError(t, err, "trade that trips the breaker should still execute")
+	assert.Equal(t, StateHalted, orderbook.State, "17% move within the window should halt the market")
+	assert.Equal(t, clock.now.Add(5*time.Minute).UnixNano(), orderbook.ResumeAt, "halt should end after HaltFor")
+
+	events := receiveEvents(t, sub, 6)
+	status := events[5].(*MarketStatusChanged)
+	assert.Equal(t, StateHalted, status.State, "halt should be published")
+	assert.Contains(t, status.Reason, "circuit breaker", "halt should give the reason")
+
+	err = tradingPlatform.PlaceLimitOrder(pair, 130, NewOrder(Ask, 1))
+	assert.IsType(t, &MarketHaltedError{}, err, "halted market should refuse orders")
+
+	clock.advance(5 * time.Minute)
+	assert.NoError(t, tradingPlatform.PlaceLimitOrder(pair, 130, NewOrder(Ask, 1)), "market should reopen after the halt")
+	assert.Equal(t, StateOpen, orderbook.State, "market should be open")
+
+	recovered := recoverPlatform(t, dir)
+	recoveredBook, _ := recovered.GetOrderBook(pair)
+	assert.Equal(t, StateOpen, recoveredBook.State, "replay should reproduce the halt and resume without a breaker")
+	assert.Equal(t, orderbook.GetAsks()[0].TotalVolume, recoveredBook.GetAsks()[0].TotalVolume, "replay should rebuild the book")
+}
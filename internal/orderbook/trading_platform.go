@@ -1,14 +1,12 @@
 package orderbook
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"errors"
-	"os"
-	"strconv"
+	"sort"
 	"sync"
+	"time"
 
-	"github.com/kr/pretty"
 	"github.com/richo225/octgopus/internal/accounting"
 )
 
@@ -55,6 +53,26 @@ type TradingPlatform struct {
 	Accounts   *accounting.Accounts
 	Orderbooks map[TradingPair]*Orderbook `json:"orderbooks"`
 
+	events  *EventBus
+	clock   Clock
+	journal Journal
+	// journalErr is set once an append fails, after which the platform
+	// refuses commands, as the journal no longer holds everything applied.
+	journalErr  error
+	sequence    uint64
+	lastOrderID uint64
+	// selfTradePrevention is given to orders placed without a mode.
+	selfTradePrevention SelfTradePrevention
+	riskChecks          []RiskCheck
+	circuitBreaker      *CircuitBreaker
+	auctions            AuctionSchedule
+	// positions holds the net position of each signer in each asset.
+	positions map[string]map[string]float64
+	// markets are opened at startup and after a reset, and rules holds the
+	// order rules of each of them.
+	markets []MarketSpec
+	rules   map[TradingPair]MarketRules
+
 	mu sync.RWMutex
 }
 
@@ -62,13 +80,184 @@ func NewTradingPlatform() *TradingPlatform {
 	return &TradingPlatform{
 		Accounts:   accounting.NewAccounts(),
 		Orderbooks: make(map[TradingPair]*Orderbook),
+		events:     NewEventBus(),
+		clock:      systemClock{},
+		positions:  make(map[string]map[string]float64),
+	}
+}
+
+// SetClock replaces the clock used to timestamp events, e.g. with a
+// FixedClock when replaying.
+func (platform *TradingPlatform) SetClock(clock Clock) {
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	platform.clock = clock
+}
+
+// SetSelfTradePrevention sets the mode given to orders placed without one.
+func (platform *TradingPlatform) SetSelfTradePrevention(mode SelfTradePrevention) error {
+	if !mode.valid() {
+		return &InvalidOrderError{"self_trade_prevention", errors.New("unknown mode")}
 	}
+
+	platform.mu.Lock()
+	defer platform.mu.Unlock()
+
+	platform.selfTradePrevention = mode
+	return nil
 }
 
-func (platform *TradingPlatform) AddNewMarket(pair TradingPair) *Orderbook {
+// AddRiskCheck appends a check to the risk checks every new order has to pass
+// before it reaches the orderbook. Checks run in the order they were added.
+func (platform *TradingPlatform) AddRiskCheck(check RiskCheck) {
 	platform.mu.Lock()
 	defer platform.mu.Unlock()
 
+	platform.riskChecks = append(platform.riskChecks, check)
+}
+
+// Position is the net amount of an asset a signer has bought, negative when
+// they have sold more than they bought.
+func (platform *TradingPlatform) Position(signer string, asset string) float64 {
+	platform.mu.RLock()
+	defer platform.mu.RUnlock()
+
+	return platform.positions[signer][asset]
+}
+
+// Subscribe returns a subscription to every lifecycle event the platform
+// publishes, starting with the next event.
+func (platform *TradingPlatform) Subscribe() *Subscription {
+	return platform.events.Subscribe()
+}
+
+// SubscribeLimit subscribes with a bounded queue, see EventBus.SubscribeLimit.
+func (platform *TradingPlatform) SubscribeLimit(limit int) *Subscription {
+	return platform.events.SubscribeLimit(limit)
+}
+
+func (platform *TradingPlatform) AddNewMarket(pair TradingPair) (*Orderbook, error) {
+	result, err := platform.submit(Command{Type: AddMarketCommand, Market: &pair})
+	return result.orderbook, err
+}
+
+// HaltMarket stops a market accepting new orders. Resting orders can still be
+// cancelled.
+func (platform *TradingPlatform) HaltMarket(pair TradingPair) error {
+	return platform.SetMarketState(pair, StateHalted, "")
+}
+
+// ResumeMarket reopens a market for continuous trading.
+func (platform *TradingPlatform) ResumeMarket(pair TradingPair) error {
+	return platform.SetMarketState(pair, StateOpen, "")
+}
+
+// RemoveMarket cancels every order resting on a market and removes it,
+// returning the cancelled orders.
+func (platform *TradingPlatform) RemoveMarket(pair TradingPair) ([]Order, error) {
+	result, err := platform.submit(Command{Type: RemoveMarketCommand, Market: &pair})
+	return result.orders, err
+}
+
+// PlaceOrder places a market or limit order, returning a copy of the order and
+// its matches as they were when it was placed. Unlike the order passed in,
+// they are safe to read while the platform keeps trading.
+func (platform *TradingPlatform) PlaceOrder(pair TradingPair, orderType OrderType, price float64, order *Order) (Order, []Match, error) {
+	result, err := platform.submit(Command{Type: PlaceOrderCommand, Market: &pair, OrderType: orderType, Price: price, Order: order})
+	if err != nil {
+		return Order{}, nil, err
+	}
+
+	return result.placed, result.matches, nil
+}
+
+func (platform *TradingPlatform) PlaceMarketOrder(pair TradingPair, order *Order) ([]Match, error) {
+	result, err := platform.submit(Command{Type: PlaceOrderCommand, Market: &pair, OrderType: MarketOrder, Order: order})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.matches, nil
+}
+
+func (platform *TradingPlatform) PlaceLimitOrder(pair TradingPair, price float64, order *Order) error {
+	_, err := platform.submit(Command{Type: PlaceOrderCommand, Market: &pair, OrderType: LimitOrder, Price: price, Order: order})
+	return err
+}
+
+func (platform *TradingPlatform) CancelOrder(pair TradingPair, id uint64) (*Order, error) {
+	result, err := platform.submit(Command{Type: CancelOrderCommand, Market: &pair, OrderID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.order, nil
+}
+
+func (platform *TradingPlatform) CreateAccount(signer string) error {
+	_, err := platform.submit(Command{Type: CreateAccountCommand, Signer: signer})
+	return err
+}
+
+func (platform *TradingPlatform) Deposit(signer string, amount float64) (*accounting.Tx, error) {
+	result, err := platform.submit(Command{Type: DepositCommand, Signer: signer, Amount: amount})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.txs[0], nil
+}
+
+func (platform *TradingPlatform) Withdraw(signer string, amount float64) (*accounting.Tx, error) {
+	result, err := platform.submit(Command{Type: WithdrawCommand, Signer: signer, Amount: amount})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.txs[0], nil
+}
+
+func (platform *TradingPlatform) Send(sender string, recipient string, amount float64) ([]*accounting.Tx, error) {
+	return platform.SendWithOptions(sender, recipient, amount, accounting.SendOptions{})
+}
+
+func (platform *TradingPlatform) SendWithOptions(sender string, recipient string, amount float64, opts accounting.SendOptions) ([]*accounting.Tx, error) {
+	result, err := platform.submit(Command{
+		Type:            SendCommand,
+		Signer:          sender,
+		Recipient:       recipient,
+		Amount:          amount,
+		CreateRecipient: opts.CreateRecipient,
+		IdempotencyKey:  opts.IdempotencyKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.txs, nil
+}
+
+// AdjustBalance corrects the balance of signer by amount, which is negative to
+// reduce it, recording reason in the ledger.
+func (platform *TradingPlatform) AdjustBalance(signer string, amount float64, reason string) (*accounting.Tx, error) {
+	result, err := platform.submit(Command{Type: AdjustBalanceCommand, Signer: signer, Amount: amount, Reason: reason})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.txs[0], nil
+}
+
+// Transactions pages through the ledger entries of signer, newest first.
+func (platform *TradingPlatform) Transactions(signer string, before uint64, limit int) ([]*accounting.Entry, error) {
+	platform.mu.RLock()
+	defer platform.mu.RUnlock()
+
+	return platform.Accounts.Transactions(signer, before, limit)
+}
+
+func (platform *TradingPlatform) addNewMarket(pair TradingPair) *Orderbook {
 	ob := newOrderBook()
 	ob.Market = &pair
 	platform.Orderbooks[pair] = ob
@@ -76,36 +265,334 @@ func (platform *TradingPlatform) AddNewMarket(pair TradingPair) *Orderbook {
 	return ob
 }
 
-func (platform *TradingPlatform) PlaceMarketOrder(pair TradingPair, order *Order) ([]Match, error) {
-	platform.mu.RLock()
-	orderbook, err := platform.GetOrderBook(pair)
-	platform.mu.RUnlock()
+func (platform *TradingPlatform) placeMarketOrder(pair TradingPair, order *Order, at time.Time) ([]Match, error) {
+	if err := order.validate(MarketOrder, 0); err != nil {
+		platform.publishRejected(pair, order, err)
+		return nil, err
+	}
 
+	orderbook, err := platform.openOrderBook(pair, MarketOrder)
 	if err != nil {
+		platform.publishRejected(pair, order, err)
 		return nil, err
 	}
 
-	matches, err := orderbook.placeMarketOrder(order)
+	accepted := *order
+	matches, selfTrades, err := orderbook.placeMarketOrder(order)
 	if err != nil {
+		platform.publishRejected(pair, order, err)
 		return nil, err
 	}
+	platform.trackPositions(pair, matches)
+	platform.chargeFees(orderbook, matches, order.Side, at)
+	orderbook.addTrades(matches, order.Side, at)
+
+	events := []Event{&OrderAccepted{EventMeta: platform.eventMeta(pair), Order: accepted}}
+	events = append(events, platform.matchEvents(orderbook, order, accepted.Size, matches, selfTrades)...)
+	platform.events.publish(events...)
 
 	return matches, nil
 }
 
-func (platform *TradingPlatform) PlaceLimitOrder(pair TradingPair, price float64, order *Order) error {
-	platform.mu.RLock()
-	orderbook, err := platform.GetOrderBook(pair)
-	platform.mu.RUnlock()
+// checkRisk runs the risk checks on a new order. Orders that are invalid or
+// for a market that is not open are left for placeX to reject.
+func (platform *TradingPlatform) checkRisk(pair TradingPair, orderType OrderType, price float64, order *Order) error {
+	if len(platform.riskChecks) == 0 || order.validate(orderType, price) != nil {
+		return nil
+	}
+	orderbook, err := platform.openOrderBook(pair, orderType)
+	if err != nil {
+		return nil
+	}
+
+	orderbook.mu.Lock()
+	defer orderbook.mu.Unlock()
+
+	req := &RiskRequest{
+		Market:    pair,
+		Order:     order,
+		OrderType: orderType,
+		Price:     price,
+		Notional:  order.Size * price,
+		Book:      orderbook,
+		platform:  platform,
+	}
+	if orderType == MarketOrder {
+		worst, cost, ok := orderbook.fillEstimate(order.Side, order.Size)
+		if !ok {
+			return nil
+		}
+		req.Price, req.Notional = worst, cost
+	}
+
+	for _, check := range platform.riskChecks {
+		if err := check.Check(req); err != nil {
+			platform.publishRejected(pair, order, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trackPositions moves the positions of the signers on both sides of each
+// match. Orders without a signer have no position.
+func (platform *TradingPlatform) trackPositions(pair TradingPair, matches []Match) {
+	for _, match := range matches {
+		for _, order := range []*Order{match.Ask, match.Bid} {
+			if order.Signer == "" {
+				continue
+			}
+
+			positions, ok := platform.positions[order.Signer]
+			if !ok {
+				positions = make(map[string]float64)
+				platform.positions[order.Signer] = positions
+			}
+			positions[pair.Base] += positionDelta(pair, order.Side, match.SizeFilled, match.Price, pair.Base)
+			positions[pair.Quote] += positionDelta(pair, order.Side, match.SizeFilled, match.Price, pair.Quote)
+		}
+	}
+}
+
+func (platform *TradingPlatform) placeLimitOrder(pair TradingPair, price float64, order *Order) error {
+	if err := order.validate(LimitOrder, price); err != nil {
+		platform.publishRejected(pair, order, err)
+		return err
+	}
 
+	orderbook, err := platform.openOrderBook(pair, LimitOrder)
 	if err != nil {
+		platform.publishRejected(pair, order, err)
 		return err
 	}
 
 	orderbook.placeLimitOrder(price, order)
+
+	events := []Event{
+		&OrderAccepted{EventMeta: platform.eventMeta(pair), Order: *order},
+		platform.levelChanged(orderbook, order.Side, price),
+	}
+	if indicative := platform.indicativeEvent(orderbook); indicative != nil {
+		events = append(events, indicative)
+	}
+	platform.events.publish(events...)
+
 	return nil
 }
 
+func (platform *TradingPlatform) cancelOrder(pair TradingPair, id uint64) (*Order, error) {
+	orderbook, err := platform.GetOrderBook(pair)
+	if err != nil {
+		return nil, err
+	}
+
+	orderbook.mu.Lock()
+	order, ok := orderbook.orders[id]
+	if !ok {
+		orderbook.mu.Unlock()
+		return nil, &OrderNotFoundError{id}
+	}
+	price := order.Price
+	orderbook.cancelOrder(order)
+	orderbook.mu.Unlock()
+
+	cancelled := *order
+	cancelled.Price = price
+
+	events := []Event{
+		&OrderCancelled{EventMeta: platform.eventMeta(pair), Order: cancelled},
+		platform.levelChanged(orderbook, order.Side, price),
+	}
+	if indicative := platform.indicativeEvent(orderbook); indicative != nil {
+		events = append(events, indicative)
+	}
+	platform.events.publish(events...)
+
+	return order, nil
+}
+
+func (platform *TradingPlatform) assignOrderID(order *Order) {
+	if order.ID == 0 {
+		platform.lastOrderID++
+		order.ID = platform.lastOrderID
+	} else if order.ID > platform.lastOrderID {
+		platform.lastOrderID = order.ID
+	}
+}
+
+func (platform *TradingPlatform) eventMeta(pair TradingPair) EventMeta {
+	return EventMeta{
+		Market:    pair,
+		Timestamp: platform.clock.Now().UnixNano(),
+	}
+}
+
+// openOrderBook returns the orderbook of a market that is accepting orders of
+// orderType in its current trading state.
+func (platform *TradingPlatform) openOrderBook(pair TradingPair, orderType OrderType) (*Orderbook, error) {
+	orderbook, err := platform.GetOrderBook(pair)
+	if err != nil {
+		return nil, err
+	}
+
+	switch orderbook.State {
+	case StateHalted:
+		return nil, &MarketHaltedError{pair}
+	case StateCancelOnly:
+		return nil, &MarketCancelOnlyError{pair}
+	case StateAuction:
+		if orderType == MarketOrder {
+			return nil, &MarketInAuctionError{pair}
+		}
+	}
+
+	return orderbook, nil
+}
+
+func (platform *TradingPlatform) removeMarket(pair TradingPair) ([]Order, error) {
+	orderbook, err := platform.GetOrderBook(pair)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled := []Order{}
+	events := []Event{}
+	for _, limits := range [][]*Limit{orderbook.GetAsks(), orderbook.GetBids()} {
+		for _, limit := range limits {
+			for _, order := range limit.Orders {
+				order := *order
+				cancelled = append(cancelled, order)
+				events = append(events, &OrderCancelled{EventMeta: platform.eventMeta(pair), Order: order})
+			}
+		}
+	}
+	events = append(events, &MarketRemoved{EventMeta: platform.eventMeta(pair)})
+
+	delete(platform.Orderbooks, pair)
+	platform.events.publish(events...)
+
+	return cancelled, nil
+}
+
+func (platform *TradingPlatform) publishRejected(pair TradingPair, order *Order, err error) {
+	platform.events.publish(&OrderRejected{
+		EventMeta: platform.eventMeta(pair),
+		Order:     *order,
+		Reason:    err.Error(),
+	})
+}
+
+func (platform *TradingPlatform) levelChanged(orderbook *Orderbook, side Side, price float64) *BookLevelChanged {
+	return &BookLevelChanged{
+		EventMeta:   platform.eventMeta(*orderbook.Market),
+		Side:        side,
+		Price:       price,
+		TotalVolume: orderbook.levelVolume(side, price),
+	}
+}
+
+// matchEvents describes the matches and prevented self trades of a taker in
+// the order they happened, followed by the levels they touched.
+func (platform *TradingPlatform) matchEvents(orderbook *Orderbook, taker *Order, size float64, matches []Match, selfTrades []SelfTrade) []Event {
+	pair := *orderbook.Market
+	events := []Event{}
+	levels := []float64{}
+	touched := make(map[float64]bool)
+	touch := func(price float64) {
+		if !touched[price] {
+			touched[price] = true
+			levels = append(levels, price)
+		}
+	}
+
+	makerSide := Ask
+	if taker.Side == Ask {
+		makerSide = Bid
+	}
+
+	remaining := size
+	takerCancelled := false
+	next := 0
+	prevent := func(upTo int) {
+		for ; next < len(selfTrades) && selfTrades[next].AfterMatches <= upTo; next++ {
+			selfTrade := selfTrades[next]
+			remaining -= selfTrade.TakerCancelled
+			takerCancelled = takerCancelled || selfTrade.TakerCancelled > 0
+			events = append(events, platform.selfTradeEvents(pair, selfTrade, remaining)...)
+			touch(selfTrade.Price)
+		}
+	}
+
+	for i, match := range matches {
+		prevent(i)
+
+		maker := match.Ask
+		if taker.Side == Ask {
+			maker = match.Bid
+		}
+		remaining -= match.SizeFilled
+
+		events = append(events,
+			&TradeExecuted{EventMeta: platform.eventMeta(pair), Trade: newTrade(match, taker.Side)},
+			&OrderFilled{
+				EventMeta:  platform.eventMeta(pair),
+				OrderID:    maker.ID,
+				Side:       maker.Side,
+				Price:      match.Price,
+				SizeFilled: match.SizeFilled,
+				Remaining:  maker.Size,
+			},
+			&OrderFilled{
+				EventMeta:  platform.eventMeta(pair),
+				OrderID:    taker.ID,
+				Side:       taker.Side,
+				Price:      match.Price,
+				SizeFilled: match.SizeFilled,
+				Remaining:  remaining,
+			},
+		)
+		touch(match.Price)
+	}
+	prevent(len(matches))
+
+	// A market order never rests, so whatever self-trade prevention left of
+	// it is cancelled.
+	if takerCancelled || taker.Size > 0 {
+		events = append(events, &OrderCancelled{EventMeta: platform.eventMeta(pair), Order: *taker})
+	}
+
+	for _, price := range levels {
+		events = append(events, platform.levelChanged(orderbook, makerSide, price))
+	}
+
+	return events
+}
+
+func (platform *TradingPlatform) selfTradeEvents(pair TradingPair, selfTrade SelfTrade, takerRemaining float64) []Event {
+	events := []Event{&SelfTradePrevented{
+		EventMeta:      platform.eventMeta(pair),
+		Signer:         selfTrade.Taker.Signer,
+		Mode:           selfTrade.Mode,
+		Price:          selfTrade.Price,
+		MakerOrderID:   selfTrade.Maker.ID,
+		MakerCancelled: selfTrade.MakerCancelled,
+		MakerRemaining: selfTrade.Maker.Size,
+		TakerOrderID:   selfTrade.Taker.ID,
+		TakerCancelled: selfTrade.TakerCancelled,
+		TakerRemaining: takerRemaining,
+	}}
+
+	if selfTrade.MakerCancelled > 0 && selfTrade.Maker.Size == 0 {
+		cancelled := *selfTrade.Maker
+		cancelled.Price = selfTrade.Price
+		cancelled.Size = selfTrade.MakerCancelled
+		events = append(events, &OrderCancelled{EventMeta: platform.eventMeta(pair), Order: cancelled})
+	}
+
+	return events
+}
+
 func (platform *TradingPlatform) GetOrderBook(pair TradingPair) (*Orderbook, error) {
 	orderbook, ok := platform.Orderbooks[pair]
 	if !ok {
@@ -115,63 +602,85 @@ func (platform *TradingPlatform) GetOrderBook(pair TradingPair) (*Orderbook, err
 	return orderbook, nil
 }
 
-func (platform *TradingPlatform) Reset() {
-	platform.Orderbooks = make(map[TradingPair]*Orderbook)
-	platform.Accounts = accounting.NewAccounts()
-
-	platform.SeedData()
+// MarketSummary is the state and top of book of a market. Prices are zero
+// when there is no bid, ask or trade.
+type MarketSummary struct {
+	Market         TradingPair  `json:"market"`
+	State          TradingState `json:"state"`
+	BestBid        float64      `json:"best_bid"`
+	BestAsk        float64      `json:"best_ask"`
+	LastTradePrice float64      `json:"last_trade_price"`
 }
 
-func (platform *TradingPlatform) SeedData() {
-	pretty.Log("Seeding data...")
-
-	ethusd := NewTradingPair("ETH", "USD")
-	ethgbp := NewTradingPair("ETH", "GBP")
-	btcusd := NewTradingPair("BTC", "USD")
-	btcgbp := NewTradingPair("BTC", "GBP")
+// Markets summarises every market, sorted by name.
+func (platform *TradingPlatform) Markets() []MarketSummary {
+	platform.mu.RLock()
+	defer platform.mu.RUnlock()
 
-	writeFromFile(platform, "data/eth_usd_order_book.csv", ethusd)
-	writeFromFile(platform, "data/eth_gbp_order_book.csv", ethgbp)
-	writeFromFile(platform, "data/btc_usd_order_book.csv", btcusd)
-	writeFromFile(platform, "data/btc_gbp_order_book.csv", btcgbp)
+	markets := []MarketSummary{}
+	for pair, orderbook := range platform.Orderbooks {
+		markets = append(markets, summarise(pair, orderbook))
+	}
+	sort.Slice(markets, func(i, j int) bool {
+		return markets[i].Market.ToString() < markets[j].Market.ToString()
+	})
 
-	pretty.Log("Seeding data complete!")
+	return markets
 }
 
-func writeFromFile(platform *TradingPlatform, filepath string, pair TradingPair) {
-	pretty.Log("Seeding data for " + pair.ToString() + "...")
+// Market summarises one market.
+func (platform *TradingPlatform) Market(pair TradingPair) (MarketSummary, error) {
+	platform.mu.RLock()
+	defer platform.mu.RUnlock()
 
-	f, err := os.Open(filepath)
+	orderbook, err := platform.GetOrderBook(pair)
 	if err != nil {
-		panic(err)
+		return MarketSummary{}, err
 	}
-	defer f.Close()
 
-	r := csv.NewReader(f)
-	_, err = r.Read()
-	if err != nil {
-		panic(err)
+	return summarise(pair, orderbook), nil
+}
+
+func summarise(pair TradingPair, orderbook *Orderbook) MarketSummary {
+	orderbook.mu.Lock()
+	defer orderbook.mu.Unlock()
+
+	summary := MarketSummary{Market: pair, State: orderbook.State, LastTradePrice: orderbook.LastTradePrice}
+	if bid := orderbook.bestBid(); bid != nil {
+		summary.BestBid = bid.Price
+	}
+	if ask := orderbook.bestAsk(); ask != nil {
+		summary.BestAsk = ask.Price
 	}
 
-	platform.AddNewMarket(pair)
+	return summary
+}
 
-	for {
-		record, err := r.Read()
-		if err != nil {
-			break
-		}
+// GetOrder returns a copy of a resting order.
+func (platform *TradingPlatform) GetOrder(pair TradingPair, id uint64) (Order, error) {
+	platform.mu.RLock()
+	defer platform.mu.RUnlock()
 
-		askPrice, _ := strconv.ParseFloat(record[0], 64)
-		askAmount, _ := strconv.ParseFloat(record[1], 64)
-		bidPrice, _ := strconv.ParseFloat(record[2], 64)
-		bidAmount, _ := strconv.ParseFloat(record[3], 64)
+	orderbook, err := platform.GetOrderBook(pair)
+	if err != nil {
+		return Order{}, err
+	}
 
-		askOrder := NewOrder(Ask, askAmount)
-		platform.PlaceLimitOrder(pair, askPrice, askOrder)
+	orderbook.mu.Lock()
+	defer orderbook.mu.Unlock()
 
-		bidOrder := NewOrder(Bid, bidAmount)
-		platform.PlaceLimitOrder(pair, bidPrice, bidOrder)
+	order, ok := orderbook.orders[id]
+	if !ok {
+		return Order{}, &OrderNotFoundError{id}
+	}
+
+	return *order, nil
+}
+
+func (platform *TradingPlatform) Reset() error {
+	if _, err := platform.submit(Command{Type: ResetCommand}); err != nil {
+		return err
 	}
 
-	pretty.Log("Seeding data for " + pair.ToString() + " complete!")
+	return platform.OpenMarkets()
 }
@@ -78,3 +78,124 @@ func TestTrdingPlatformPlaceLimitOrder(t *testing.T) {
 	assert.Equal(t, sellOrder, orderbook.askLimits[120].Orders[0], "order book should have the correct sell order in askLimits")
 	assert.Equal(t, sellOrder, orderbook.Asks[0].Orders[0], "order book should have the correct buy order in bids")
 }
+
+func TestTradingPlatformCancelOrder(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+
+	tradingPlatform.AddNewMarket(pair)
+
+	order1 := NewOrder(Bid, 5)
+	order2 := NewOrder(Bid, 8)
+
+	tradingPlatform.PlaceLimitOrder(pair, 250, order1)
+	tradingPlatform.PlaceLimitOrder(pair, 250, order2)
+
+	assert.NotEqual(t, order1.ID, order2.ID, "orders should be assigned unique ids")
+
+	cancelled, err := tradingPlatform.CancelOrder(pair, order1.ID)
+	assert.NoError(t, err, "cancelOrder should not return an error")
+	assert.Equal(t, order1, cancelled, "cancelOrder should return the cancelled order")
+
+	orderbook, _ := tradingPlatform.GetOrderBook(pair)
+	assert.Equal(t, float64(8), orderbook.totalBidVolume(), "order book should have the correct total bid volume")
+
+	_, err = tradingPlatform.CancelOrder(pair, order1.ID)
+	assert.Equal(t, &OrderNotFoundError{order1.ID}, err, "cancelling twice should return OrderNotFoundError")
+}
+
+func TestTradingPlatformRejectsInvalidOrders(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+
+	err := tradingPlatform.PlaceLimitOrder(pair, -250, NewOrder(Bid, 5))
+	assert.IsType(t, &InvalidOrderError{}, err, "negative price should be refused")
+
+	_, err = tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, -5))
+	assert.IsType(t, &InvalidOrderError{}, err, "negative size should be refused")
+
+	orderbook, _ := tradingPlatform.GetOrderBook(pair)
+	assert.Empty(t, orderbook.Bids, "refused orders should not rest on the book")
+}
+
+func TestTradingPlatformAddExistingMarket(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+	tradingPlatform.PlaceLimitOrder(pair, 250, NewOrder(Ask, 5))
+
+	_, err := tradingPlatform.AddNewMarket(pair)
+	assert.IsType(t, &MarketAlreadyExistsError{}, err, "adding an existing market should be refused")
+
+	orderbook, _ := tradingPlatform.GetOrderBook(pair)
+	assert.Equal(t, float64(5), orderbook.totalAskVolume(), "existing book should be kept")
+}
+
+func TestTradingPlatformHaltMarket(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+	resting := NewOrder(Ask, 5)
+	tradingPlatform.PlaceLimitOrder(pair, 250, resting)
+
+	assert.NoError(t, tradingPlatform.HaltMarket(pair), "halting a market should not return an error")
+
+	err := tradingPlatform.PlaceLimitOrder(pair, 250, NewOrder(Ask, 5))
+	assert.IsType(t, &MarketHaltedError{}, err, "halted market should refuse limit orders")
+	_, err = tradingPlatform.PlaceMarketOrder(pair, NewOrder(Bid, 1))
+	assert.IsType(t, &MarketHaltedError{}, err, "halted market should refuse market orders")
+	_, err = tradingPlatform.CancelOrder(pair, resting.ID)
+	assert.NoError(t, err, "halted market should still allow cancels")
+
+	tradingPlatform.ResumeMarket(pair)
+	err = tradingPlatform.PlaceLimitOrder(pair, 250, NewOrder(Ask, 5))
+	assert.NoError(t, err, "resumed market should accept orders")
+}
+
+func TestTradingPlatformRemoveMarket(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	pair := TradingPair{"BTC", "USD"}
+	tradingPlatform.AddNewMarket(pair)
+	tradingPlatform.PlaceLimitOrder(pair, 250, NewOrder(Ask, 5))
+	tradingPlatform.PlaceLimitOrder(pair, 200, NewOrder(Bid, 3))
+
+	sub := tradingPlatform.Subscribe()
+	defer sub.Unsubscribe()
+
+	cancelled, err := tradingPlatform.RemoveMarket(pair)
+	assert.NoError(t, err, "removing a market should not return an error")
+	assert.Equal(t, 2, len(cancelled), "resting orders should be cancelled")
+
+	_, err = tradingPlatform.GetOrderBook(pair)
+	assert.IsType(t, &OrderbookNotFoundError{}, err, "removed market should be gone")
+
+	events := receiveEvents(t, sub, 3)
+	assert.IsType(t, &OrderCancelled{}, events[0], "cancels should be published first")
+	assert.IsType(t, &MarketRemoved{}, events[2], "removal should be published last")
+}
+
+func TestTradingPlatformMarkets(t *testing.T) {
+	tradingPlatform := NewTradingPlatform()
+	btcusd := TradingPair{"BTC", "USD"}
+	ethusd := TradingPair{"ETH", "USD"}
+	tradingPlatform.AddNewMarket(ethusd)
+	tradingPlatform.AddNewMarket(btcusd)
+	tradingPlatform.PlaceLimitOrder(btcusd, 101, NewOrder(Ask, 2))
+	tradingPlatform.PlaceLimitOrder(btcusd, 102, NewOrder(Ask, 1))
+	tradingPlatform.PlaceLimitOrder(btcusd, 99, NewOrder(Bid, 1))
+	tradingPlatform.PlaceMarketOrder(btcusd, NewOrder(Bid, 1))
+	tradingPlatform.HaltMarket(ethusd)
+
+	assert.Equal(t, []MarketSummary{
+		{Market: btcusd, State: StateOpen, BestBid: 99, BestAsk: 101, LastTradePrice: 101},
+		{Market: ethusd, State: StateHalted},
+	}, tradingPlatform.Markets(), "markets should be summarised in name order")
+
+	summary, err := tradingPlatform.Market(ethusd)
+	assert.NoError(t, err)
+	assert.Equal(t, MarketSummary{Market: ethusd, State: StateHalted}, summary, "one market should be summarised")
+
+	_, err = tradingPlatform.Market(TradingPair{"LTC", "USD"})
+	assert.IsType(t, &OrderbookNotFoundError{}, err, "a missing market should not be found")
+}
@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type RateLimitedError struct {
+	retryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return "RateLimited: retry after " + strconv.Itoa(e.RetryAfterSeconds()) + "s"
+}
+
+func (e *RateLimitedError) Reason() string {
+	return "RateLimited"
+}
+
+func (e *RateLimitedError) HTTPCode() int {
+	return http.StatusTooManyRequests
+}
+
+// RetryAfterSeconds is the wait rounded up to whole seconds, as used by the
+// Retry-After header.
+func (e *RateLimitedError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.retryAfter.Seconds()))
+}
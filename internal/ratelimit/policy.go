@@ -0,0 +1,42 @@
+package ratelimit
+
+// Policy limits a class of requests both per signer and per IP address, each
+// with its own buckets.
+type Policy struct {
+	PerSigner Limit
+	PerIP     Limit
+}
+
+type PolicyLimiter struct {
+	signers *Limiter
+	ips     *Limiter
+}
+
+// NewPolicyLimiter limits requests by policy, using clock or the system clock
+// if it is nil.
+func NewPolicyLimiter(policy Policy, clock Clock) *PolicyLimiter {
+	return &PolicyLimiter{
+		signers: NewLimiterWithClock(policy.PerSigner, clock),
+		ips:     NewLimiterWithClock(policy.PerIP, clock),
+	}
+}
+
+// Allow takes a token for the request from the signer's bucket, unless the
+// request is anonymous, and from the IP's bucket. A refused request takes no
+// token from either.
+func (p *PolicyLimiter) Allow(signer string, ip string) error {
+	if signer != "" {
+		if ok, wait := p.signers.Allow(signer); !ok {
+			return &RateLimitedError{wait}
+		}
+	}
+
+	if ok, wait := p.ips.Allow(ip); !ok {
+		if signer != "" {
+			p.signers.refund(signer)
+		}
+		return &RateLimitedError{wait}
+	}
+
+	return nil
+}
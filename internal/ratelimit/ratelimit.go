@@ -0,0 +1,137 @@
+// Package ratelimit implements token bucket rate limiting keyed by client,
+// e.g. by signer or IP address.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Limit allows Rate requests per second on average, with bursts of up to
+// Burst requests. The zero Limit allows everything.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter keeps a token bucket per key. Buckets start full and are forgotten
+// once they have refilled, so idle keys cost nothing.
+type Limiter struct {
+	limit   Limit
+	clock   Clock
+	buckets map[string]*bucket
+	swept   time.Time
+
+	mu sync.Mutex
+}
+
+func NewLimiter(limit Limit) *Limiter {
+	return NewLimiterWithClock(limit, systemClock{})
+}
+
+// NewLimiterWithClock is NewLimiter with a clock, e.g. a fake one in tests. A
+// nil clock is the system clock.
+func NewLimiterWithClock(limit Limit, clock Clock) *Limiter {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return &Limiter{
+		limit:   limit,
+		clock:   clock,
+		buckets: make(map[string]*bucket),
+		swept:   clock.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of key. When the bucket is empty it
+// returns false and how long until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.limit.Unlimited() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) / l.limit.Rate
+		return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// refund puts back a token taken by Allow, e.g. when another limiter refused
+// the request after all.
+func (l *Limiter) refund(key string) {
+	if l.limit.Unlimited() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = math.Min(float64(l.limit.Burst), b.tokens+1)
+	}
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	return math.Min(float64(l.limit.Burst), b.tokens+elapsed*l.limit.Rate)
+}
+
+// sweep forgets full buckets, at most once per refill period.
+func (l *Limiter) sweep(now time.Time) {
+	period := time.Duration(float64(l.limit.Burst) / l.limit.Rate * float64(time.Second))
+	if now.Sub(l.swept) < period {
+		return
+	}
+
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func TestLimiterBurstThenRefill(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLimiterWithClock(Limit{Rate: 2, Burst: 3}, clock)
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.Allow("alice")
+		assert.True(t, ok, "request %d should be within the burst", i+1)
+	}
+
+	ok, wait := limiter.Allow("alice")
+	assert.False(t, ok, "request beyond the burst should be limited")
+	assert.Equal(t, 500*time.Millisecond, wait, "next token should arrive after 1/rate seconds")
+
+	clock.Advance(500 * time.Millisecond)
+	ok, _ = limiter.Allow("alice")
+	assert.True(t, ok, "a token should have refilled")
+
+	ok, _ = limiter.Allow("bob")
+	assert.True(t, ok, "other keys should have their own bucket")
+}
+
+func TestLimiterRefillIsCapped(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLimiterWithClock(Limit{Rate: 10, Burst: 2}, clock)
+
+	limiter.Allow("alice")
+	clock.Advance(time.Hour)
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if ok, _ := limiter.Allow("alice"); ok {
+			allowed++
+		}
+	}
+	assert.Equal(t, 2, allowed, "idle time should not build up more than a burst")
+}
+
+func TestLimiterForgetsIdleBuckets(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLimiterWithClock(Limit{Rate: 1, Burst: 1}, clock)
+
+	limiter.Allow("alice")
+	limiter.Allow("bob")
+	clock.Advance(2 * time.Second)
+	limiter.Allow("carol")
+
+	assert.Equal(t, 1, len(limiter.buckets), "refilled buckets should be forgotten")
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	limiter := NewLimiterWithClock(Limit{}, newFakeClock())
+
+	for i := 0; i < 1000; i++ {
+		ok, _ := limiter.Allow("alice")
+		require.True(t, ok, "zero limit should allow everything")
+	}
+}
+
+func TestPolicyLimiter(t *testing.T) {
+	clock := newFakeClock()
+	policy := NewPolicyLimiter(Policy{PerSigner: Limit{Rate: 1, Burst: 1}, PerIP: Limit{Rate: 1, Burst: 3}}, clock)
+
+	assert.NoError(t, policy.Allow("alice", "10.0.0.1"), "first request should be allowed")
+
+	err := policy.Allow("alice", "10.0.0.2")
+	assert.IsType(t, &RateLimitedError{}, err, "signer should be limited across IPs")
+	assert.Equal(t, 1, err.(*RateLimitedError).RetryAfterSeconds(), "retry after should be rounded up to seconds")
+
+	assert.NoError(t, policy.Allow("bob", "10.0.0.1"), "another signer on the same IP should be allowed")
+	assert.NoError(t, policy.Allow("", "10.0.0.1"), "anonymous requests should only use the IP bucket")
+	assert.IsType(t, &RateLimitedError{}, policy.Allow("", "10.0.0.1"), "IP should be limited across signers")
+}
+
+func TestPolicyLimiterRefusedByIP(t *testing.T) {
+	clock := newFakeClock()
+	policy := NewPolicyLimiter(Policy{PerSigner: Limit{Rate: 1, Burst: 2}, PerIP: Limit{Rate: 1, Burst: 1}}, clock)
+
+	assert.NoError(t, policy.Allow("alice", "10.0.0.1"), "first request should be allowed")
+	for i := 0; i < 3; i++ {
+		assert.IsType(t, &RateLimitedError{}, policy.Allow("alice", "10.0.0.1"), "IP should be limited")
+	}
+
+	assert.NoError(t, policy.Allow("alice", "10.0.0.2"), "requests refused by the IP limit should not use the signer's tokens")
+	assert.IsType(t, &RateLimitedError{}, policy.Allow("alice", "10.0.0.3"), "signer should still be limited")
+
+	clock.Advance(time.Second)
+	assert.NoError(t, policy.Allow("alice", "10.0.0.1"), "signer and IP should refill")
+}
+
+func TestParseLimit(t *testing.T) {
+	cases := map[string]Limit{
+		"":         {},
+		"10/s":     {Rate: 10, Burst: 10},
+		"600/m:50": {Rate: 10, Burst: 50},
+		"5/100ms":  {Rate: 50, Burst: 5},
+		"1/2s:4":   {Rate: 0.5, Burst: 4},
+	}
+	for s, expected := range cases {
+		limit, err := ParseLimit(s)
+		assert.NoError(t, err, "%q should parse", s)
+		assert.Equal(t, expected, limit, "%q should parse to %+v", s, expected)
+	}
+
+	for _, s := range []string{"10", "x/s", "0/s", "10/", "10/s:0", "10/fortnight"} {
+		_, err := ParseLimit(s)
+		assert.Error(t, err, "%q should not parse", s)
+	}
+}
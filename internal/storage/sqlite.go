@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/richo225/octgopus/internal/accounting"
+	"github.com/richo225/octgopus/internal/orderbook"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS accounts (
+	signer     TEXT PRIMARY KEY,
+	balance    REAL NOT NULL,
+	updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	entry_id   INTEGER NOT NULL,
+	tx_id      INTEGER NOT NULL,
+	account    TEXT NOT NULL,
+	action     TEXT NOT NULL,
+	debit      REAL NOT NULL,
+	credit     REAL NOT NULL,
+	balance    REAL NOT NULL,
+	reference  TEXT NOT NULL DEFAULT '',
+	memo       TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ledger_entries_account ON ledger_entries (account, id);
+
+CREATE TABLE IF NOT EXISTS orders (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	order_id   INTEGER NOT NULL,
+	run        TEXT NOT NULL DEFAULT '',
+	base       TEXT NOT NULL,
+	quote      TEXT NOT NULL,
+	side       TEXT NOT NULL,
+	price      REAL NOT NULL,
+	size       REAL NOT NULL,
+	signer     TEXT NOT NULL DEFAULT '',
+	remaining  REAL NOT NULL,
+	status     TEXT NOT NULL,
+	reason     TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS orders_market ON orders (base, quote, id);
+CREATE INDEX IF NOT EXISTS orders_order_id ON orders (order_id, id);
+
+CREATE TABLE IF NOT EXISTS trades (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	base         TEXT NOT NULL,
+	quote        TEXT NOT NULL,
+	price        REAL NOT NULL,
+	size         REAL NOT NULL,
+	ask_order_id INTEGER NOT NULL,
+	bid_order_id INTEGER NOT NULL,
+	ask_order    INTEGER,
+	bid_order    INTEGER,
+	taker_side   TEXT NOT NULL,
+	ask_fee      REAL NOT NULL DEFAULT 0,
+	bid_fee      REAL NOT NULL DEFAULT 0,
+	executed_at  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS trades_market ON trades (base, quote, id);
+`
+
+// SQLiteStore persists accounts, ledger entries, orders and trades to a local
+// SQLite database. It implements both accounting.Repository and
+// orderbook.Repository.
+//
+// Order ids restart when the platform runs without a journal, so each order
+// row has its own id, and is stamped with the run that recorded it. Updates
+// and trades refer to the latest row of an order id, which is the order that
+// is live.
+type SQLiteStore struct {
+	// DB is exposed for ad hoc reporting queries.
+	DB  *sql.DB
+	run string
+}
+
+var (
+	_ accounting.Repository = (*SQLiteStore)(nil)
+	_ orderbook.Repository  = (*SQLiteStore)(nil)
+)
+
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer; sharing one connection avoids lock errors.
+	db.SetMaxOpenConns(1)
+
+	if err := migrateOrders(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Databases created before fees were added lack the fee columns, and
+	// those created before orders had their own row id lack the links to them.
+	columns := []struct{ name, definition string }{
+		{"ask_fee", "REAL NOT NULL DEFAULT 0"},
+		{"bid_fee", "REAL NOT NULL DEFAULT 0"},
+		{"ask_order", "INTEGER"},
+		{"bid_order", "INTEGER"},
+	}
+	for _, column := range columns {
+		if err := addColumn(db, "trades", column.name, column.definition); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &SQLiteStore{DB: db, run: formatTime(time.Now())}, nil
+}
+
+// migrateOrders rebuilds an orders table keyed by order id, as created before
+// orders had their own row id, keeping its rows.
+func migrateOrders(db *sql.DB) error {
+	var columns, orderIDs int
+	err := db.QueryRow(`SELECT COUNT(*), COUNT(CASE WHEN name = 'order_id' THEN 1 END) FROM pragma_table_info('orders')`).Scan(&columns, &orderIDs)
+	if err != nil || columns == 0 || orderIDs > 0 {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range []string{
+		`DROP INDEX IF EXISTS orders_market`,
+		`ALTER TABLE orders RENAME TO orders_by_order_id`,
+		schema,
+		`INSERT INTO orders (order_id, base, quote, side, price, size, signer, remaining, status, reason, created_at, updated_at)
+		SELECT id, base, quote, side, price, size, signer, remaining, status, reason, created_at, updated_at
+		FROM orders_by_order_id ORDER BY id`,
+		`DROP TABLE orders_by_order_id`,
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// addColumn adds column to table unless it already has it.
+func addColumn(db *sql.DB, table string, column string, definition string) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.DB.Close()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func (s *SQLiteStore) SaveAccount(signer string, balance float64) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO accounts (signer, balance, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (signer) DO UPDATE SET balance = excluded.balance, updated_at = excluded.updated_at`,
+		signer, balance, formatTime(time.Now()))
+
+	return err
+}
+
+// SaveEntries writes the entries of a ledger transaction atomically. Entry ids
+// restart when the platform is reset, so rows keep their own id.
+func (s *SQLiteStore) SaveEntries(entries []*accounting.Entry) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, err := tx.Exec(`
+			INSERT INTO ledger_entries (entry_id, tx_id, account, action, debit, credit, balance, reference, memo, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			entry.ID, entry.TxID, entry.Account, string(entry.Action), entry.Debit, entry.Credit, entry.Balance,
+			entry.Reference, entry.Memo, formatTime(time.Unix(0, entry.Timestamp)))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) SaveOrder(record *orderbook.OrderRecord) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO orders (order_id, run, base, quote, side, price, size, signer, remaining, status, reason, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.ID, s.run, record.Market.Base, record.Market.Quote, string(record.Side), record.Price, record.Size,
+		record.Signer, record.Remaining, string(record.Status), record.Reason, formatTime(record.CreatedAt), formatTime(record.UpdatedAt))
+
+	return err
+}
+
+func (s *SQLiteStore) UpdateOrder(id uint64, remaining float64, status orderbook.OrderStatus, updatedAt time.Time) error {
+	_, err := s.DB.Exec(`
+		UPDATE orders SET remaining = ?, status = ?, updated_at = ?
+		WHERE id = (SELECT MAX(id) FROM orders WHERE order_id = ?)`,
+		remaining, string(status), formatTime(updatedAt), id)
+
+	return err
+}
+
+func (s *SQLiteStore) ReduceOrder(id uint64, remaining float64, updatedAt time.Time) error {
+	_, err := s.DB.Exec(`
+		UPDATE orders SET remaining = ?, updated_at = ?
+		WHERE id = (SELECT MAX(id) FROM orders WHERE order_id = ?)`,
+		remaining, formatTime(updatedAt), id)
+
+	return err
+}
+
+func (s *SQLiteStore) SaveTrade(trade *orderbook.TradeExecuted) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO trades (base, quote, price, size, ask_order_id, bid_order_id, ask_order, bid_order, taker_side, ask_fee, bid_fee, executed_at)
+		VALUES (?, ?, ?, ?, ?, ?,
+			(SELECT MAX(id) FROM orders WHERE order_id = ?), (SELECT MAX(id) FROM orders WHERE order_id = ?),
+			?, ?, ?, ?)`,
+		trade.Market.Base, trade.Market.Quote, trade.Trade.Price, trade.Trade.Size,
+		trade.Trade.AskOrderID, trade.Trade.BidOrderID, trade.Trade.AskOrderID, trade.Trade.BidOrderID,
+		string(trade.Trade.TakerSide),
+		trade.Trade.AskFee, trade.Trade.BidFee, formatTime(time.Unix(0, trade.Timestamp)))
+
+	return err
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+
+	store, err := OpenSQLite(filepath.Join(t.TempDir(), "octgopus.db"))
+	require.NoError(t, err, "store should open")
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestSQLiteStoreAccounts(t *testing.T) {
+	store := openTestStore(t)
+	platform := orderbook.NewTradingPlatform()
+	platform.Accounts.UseRepository(store)
+
+	platform.CreateAccount("alice")
+	platform.CreateAccount("bob")
+	platform.Deposit("alice", 100)
+	platform.Send("alice", "bob", 30)
+
+	var balance float64
+	require.NoError(t, store.DB.QueryRow(`SELECT balance FROM accounts WHERE signer = 'alice'`).Scan(&balance))
+	assert.Equal(t, float64(70), balance, "alice should have a balance of 70")
+
+	require.NoError(t, store.DB.QueryRow(`SELECT balance FROM accounts WHERE signer = 'bob'`).Scan(&balance))
+	assert.Equal(t, float64(30), balance, "bob should have a balance of 30")
+
+	rows, err := store.DB.Query(`SELECT action, credit - debit, balance FROM ledger_entries WHERE account = 'alice' ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	type entry struct {
+		action  string
+		amount  float64
+		balance float64
+	}
+	entries := []entry{}
+	for rows.Next() {
+		e := entry{}
+		rows.Scan(&e.action, &e.amount, &e.balance)
+		entries = append(entries, e)
+	}
+	assert.Equal(t, []entry{{"deposit", 100, 100}, {"transfer", -30, 70}}, entries, "ledger should have alice's history")
+}
+
+func TestSQLiteStoreOrdersAndTrades(t *testing.T) {
+	store := openTestStore(t)
+	platform := orderbook.NewTradingPlatform()
+	pair := orderbook.NewTradingPair("BTC", "USD")
+	platform.AddNewMarket(pair)
+
+	recorder := orderbook.NewHistoryRecorder(platform, store)
+	recorder.Start()
+
+	sellOrder := orderbook.NewOrder(orderbook.Ask, 8)
+	buyOrder := orderbook.NewOrder(orderbook.Bid, 3)
+	platform.PlaceLimitOrder(pair, 250, sellOrder)
+	platform.PlaceMarketOrder(pair, buyOrder)
+
+	recorder.Stop()
+
+	var status string
+	var remaining float64
+	require.NoError(t, store.DB.QueryRow(`SELECT status, remaining FROM orders WHERE order_id = ?`, sellOrder.ID).Scan(&status, &remaining))
+	assert.Equal(t, "partially_filled", status, "maker should be partially filled")
+	assert.Equal(t, float64(5), remaining, "maker should have 5 remaining")
+
+	require.NoError(t, store.DB.QueryRow(`SELECT status FROM orders WHERE order_id = ?`, buyOrder.ID).Scan(&status))
+	assert.Equal(t, "filled", status, "taker should be filled")
+
+	var price, size float64
+	require.NoError(t, store.DB.QueryRow(`SELECT price, size FROM trades WHERE base = 'BTC' AND quote = 'USD'`).Scan(&price, &size))
+	assert.Equal(t, float64(250), price, "trade should be at 250")
+	assert.Equal(t, float64(3), size, "trade should be for 3")
+}
+
+func TestSQLiteStoreRestartedOrderIDs(t *testing.T) {
+	store := openTestStore(t)
+	pair := orderbook.NewTradingPair("BTC", "USD")
+
+	// Without a journal a restarted platform hands out the same order ids.
+	for run := 0; run < 2; run++ {
+		platform := orderbook.NewTradingPlatform()
+		platform.AddNewMarket(pair)
+		recorder := orderbook.NewHistoryRecorder(platform, store)
+		recorder.Start()
+
+		sellOrder := orderbook.NewOrder(orderbook.Ask, 8)
+		platform.PlaceLimitOrder(pair, 250, sellOrder)
+		if run == 1 {
+			platform.PlaceMarketOrder(pair, orderbook.NewOrder(orderbook.Bid, 3))
+		}
+
+		recorder.Stop()
+		require.Equal(t, uint64(1), sellOrder.ID)
+	}
+
+	rows, err := store.DB.Query(`SELECT id, status, remaining FROM orders WHERE order_id = 1 ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	type order struct {
+		id        int64
+		status    string
+		remaining float64
+	}
+	orders := []order{}
+	for rows.Next() {
+		o := order{}
+		rows.Scan(&o.id, &o.status, &o.remaining)
+		orders = append(orders, o)
+	}
+	require.Len(t, orders, 2, "an order id reused after a restart should not overwrite the earlier order")
+	assert.Equal(t, "open", orders[0].status, "earlier order should keep its history")
+	assert.Equal(t, float64(8), orders[0].remaining)
+	assert.Equal(t, "partially_filled", orders[1].status, "fill should update the live order")
+
+	var askOrder int64
+	require.NoError(t, store.DB.QueryRow(`SELECT ask_order FROM trades`).Scan(&askOrder))
+	assert.Equal(t, orders[1].id, askOrder, "trade should link to the live order's row")
+}
+
+func TestSQLiteStoreMigratesOrders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "octgopus.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE orders (
+			id INTEGER PRIMARY KEY, base TEXT NOT NULL, quote TEXT NOT NULL, side TEXT NOT NULL,
+			price REAL NOT NULL, size REAL NOT NULL, signer TEXT NOT NULL DEFAULT '', remaining REAL NOT NULL,
+			status TEXT NOT NULL, reason TEXT NOT NULL DEFAULT '', created_at TEXT NOT NULL, updated_at TEXT NOT NULL
+		);
+		CREATE INDEX orders_market ON orders (base, quote, id);
+		INSERT INTO orders VALUES (7, 'BTC', 'USD', 'ask', 250, 8, '', 8, 'open', '', '', '');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	store, err := OpenSQLite(path)
+	require.NoError(t, err, "store should open a database from before orders had their own id")
+	defer store.Close()
+
+	var orderID int64
+	var status string
+	require.NoError(t, store.DB.QueryRow(`SELECT order_id, status FROM orders`).Scan(&orderID, &status))
+	assert.Equal(t, int64(7), orderID, "existing orders should keep their order id")
+	assert.Equal(t, "open", status)
+}
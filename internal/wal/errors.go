@@ -0,0 +1,35 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrClosed         = errors.New("wal: log is closed")
+	ErrRecordTooLarge = errors.New("wal: record too large")
+)
+
+type CorruptLogError struct {
+	Path   string
+	Offset int64
+	Reason string
+}
+
+func (e *CorruptLogError) Error() string {
+	return fmt.Sprintf("CorruptLog : %s at offset %d in %s", e.Reason, e.Offset, e.Path)
+}
+
+// FailedLogError is returned by every append after one failed in a way that
+// left the log in an unknown state.
+type FailedLogError struct {
+	err error
+}
+
+func (e *FailedLogError) Error() string {
+	return "LogFailed : " + e.err.Error()
+}
+
+func (e *FailedLogError) Unwrap() error {
+	return e.err
+}
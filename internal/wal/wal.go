@@ -0,0 +1,540 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs after every append, so an acknowledged command is never lost.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs in the background every Options.SyncInterval.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever SyncPolicy = "never"
+)
+
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch SyncPolicy(s) {
+	case SyncAlways, SyncInterval, SyncNever:
+		return SyncPolicy(s), nil
+	case "":
+		return SyncAlways, nil
+	default:
+		return "", fmt.Errorf("invalid sync policy %q", s)
+	}
+}
+
+const (
+	segmentExt = ".wal"
+
+	// Each record is framed as payload length, crc32 of sequence and payload,
+	// then the sequence number, followed by the payload itself.
+	headerSize = 16
+
+	maxRecordSize = 16 << 20
+
+	defaultSegmentSize  = 64 << 20
+	defaultSyncInterval = 100 * time.Millisecond
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type Options struct {
+	Dir          string
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	// SegmentSize is the size in bytes after which a new segment file is started.
+	SegmentSize int64
+	// ReadOnly opens the log for Replay only. A torn tail is skipped rather
+	// than truncated and nothing on disk is modified.
+	ReadOnly bool
+}
+
+type segment struct {
+	path     string
+	firstSeq uint64
+	lastSeq  uint64
+	size     int64
+}
+
+// Log is an append-only, segmented write-ahead log of opaque records, each
+// identified by a sequence number starting at 1.
+type Log struct {
+	opts     Options
+	segments []*segment
+	file     *os.File
+	lastSeq  uint64
+	tornTail int64
+	dirty    bool
+	// failed is set when a partial record could not be cut off, after which
+	// appends are refused.
+	failed  error
+	stop    chan struct{}
+	stopped chan struct{}
+
+	mu sync.Mutex
+}
+
+func Open(opts Options) (*Log, error) {
+	if opts.Sync == "" {
+		opts.Sync = SyncAlways
+	}
+	if opts.SyncInterval == 0 {
+		opts.SyncInterval = defaultSyncInterval
+	}
+	if opts.SegmentSize == 0 {
+		opts.SegmentSize = defaultSegmentSize
+	}
+
+	log := &Log{opts: opts}
+
+	if opts.ReadOnly {
+		if err := log.load(); err != nil {
+			return nil, err
+		}
+		return log, nil
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	if err := log.load(); err != nil {
+		return nil, err
+	}
+
+	if err := log.openTail(); err != nil {
+		return nil, err
+	}
+
+	if opts.Sync == SyncInterval {
+		log.stop = make(chan struct{})
+		log.stopped = make(chan struct{})
+		go log.syncLoop()
+	}
+
+	return log, nil
+}
+
+// load scans every segment on disk, verifying each record and that sequence
+// numbers continue from one segment to the next. A damaged or incomplete
+// record at the very end of the last segment is treated as a torn write and
+// cut off; damage anywhere else is reported as corruption.
+func (log *Log) load() error {
+	entries, err := os.ReadDir(log.opts.Dir)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), segmentExt) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		var first uint64
+		if _, err := fmt.Sscanf(strings.TrimSuffix(name, segmentExt), "%d", &first); err != nil {
+			return &CorruptLogError{Path: name, Reason: "unrecognised segment name"}
+		}
+
+		seg := &segment{path: filepath.Join(log.opts.Dir, name), firstSeq: first}
+		last := i == len(names)-1
+
+		if i > 0 && first != log.lastSeq+1 {
+			return &CorruptLogError{Path: seg.path, Reason: fmt.Sprintf("segment starts at sequence %d, expecting %d", first, log.lastSeq+1)}
+		}
+
+		if err := log.scan(seg, last); err != nil {
+			return err
+		}
+
+		log.segments = append(log.segments, seg)
+	}
+
+	return nil
+}
+
+func (log *Log) scan(seg *segment, last bool) error {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	expected := seg.firstSeq
+	err = readRecords(f, func(seq uint64, payload []byte, size int64) error {
+		if seq != expected {
+			return &CorruptLogError{Path: seg.path, Offset: offset, Reason: fmt.Sprintf("sequence %d, expecting %d", seq, expected)}
+		}
+		expected++
+		offset += size
+		return nil
+	})
+
+	if bad, ok := err.(*badRecordError); ok {
+		// Only the last record of the log can have been torn by a crash while
+		// it was being written; a bad record followed by more data is damage.
+		if !last || bad.end < info.Size() {
+			return &CorruptLogError{Path: seg.path, Offset: offset, Reason: bad.reason}
+		}
+
+		log.tornTail = info.Size() - offset
+		if !log.opts.ReadOnly {
+			if err := os.Truncate(seg.path, offset); err != nil {
+				return err
+			}
+		}
+	} else if err != nil {
+		return err
+	}
+
+	seg.size = offset
+	seg.lastSeq = expected - 1
+	log.lastSeq = seg.lastSeq
+
+	return nil
+}
+
+// badRecordError is an incomplete or damaged record, ending at end if its
+// length can be trusted, or past the end of the data if it cannot.
+type badRecordError struct {
+	end    int64
+	reason string
+}
+
+func (e *badRecordError) Error() string {
+	return e.reason
+}
+
+// readRecords calls fn for each intact record, returning a *badRecordError on
+// the first incomplete or damaged one.
+func readRecords(r io.Reader, fn func(seq uint64, payload []byte, size int64) error) error {
+	br := bufio.NewReader(r)
+	header := make([]byte, headerSize)
+	var offset int64
+
+	for {
+		if n, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return &badRecordError{offset + int64(n) + 1, "incomplete record header"}
+		}
+
+		length := binary.BigEndian.Uint32(header[0:4])
+		sum := binary.BigEndian.Uint32(header[4:8])
+		seq := binary.BigEndian.Uint64(header[8:16])
+		end := offset + int64(headerSize+length)
+
+		if length > maxRecordSize {
+			return &badRecordError{end, "record length too large"}
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return &badRecordError{end, "incomplete record"}
+		}
+
+		if checksum(header[8:16], payload) != sum {
+			return &badRecordError{end, "checksum mismatch"}
+		}
+
+		if err := fn(seq, payload, int64(headerSize+length)); err != nil {
+			return err
+		}
+		offset = end
+	}
+}
+
+func checksum(seq []byte, payload []byte) uint32 {
+	crc := crc32.Update(0, crcTable, seq)
+	return crc32.Update(crc, crcTable, payload)
+}
+
+func (log *Log) openTail() error {
+	if len(log.segments) == 0 {
+		return log.roll()
+	}
+
+	tail := log.segments[len(log.segments)-1]
+	f, err := os.OpenFile(tail.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	log.file = f
+
+	return nil
+}
+
+// roll closes the current segment and starts a new one beginning at the next
+// sequence number.
+func (log *Log) roll() error {
+	if log.file != nil {
+		if err := log.file.Sync(); err != nil {
+			return err
+		}
+		if err := log.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	first := log.lastSeq + 1
+	path := filepath.Join(log.opts.Dir, fmt.Sprintf("%020d%s", first, segmentExt))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	log.file = f
+	log.segments = append(log.segments, &segment{path: path, firstSeq: first, lastSeq: first - 1})
+
+	return syncDir(log.opts.Dir)
+}
+
+// Append writes payload as the next record and returns its sequence number.
+func (log *Log) Append(payload []byte) (uint64, error) {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil {
+		return 0, ErrClosed
+	}
+	if log.failed != nil {
+		return 0, &FailedLogError{log.failed}
+	}
+	if len(payload) > maxRecordSize {
+		return 0, ErrRecordTooLarge
+	}
+
+	tail := log.segments[len(log.segments)-1]
+	if tail.size > 0 && tail.size+int64(headerSize+len(payload)) > log.opts.SegmentSize {
+		if err := log.roll(); err != nil {
+			return 0, err
+		}
+		tail = log.segments[len(log.segments)-1]
+	}
+
+	seq := log.lastSeq + 1
+	record := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint64(record[8:16], seq)
+	binary.BigEndian.PutUint32(record[4:8], checksum(record[8:16], payload))
+	copy(record[headerSize:], payload)
+
+	if _, err := log.file.Write(record); err != nil {
+		return 0, log.undo(tail, err)
+	}
+
+	if log.opts.Sync == SyncAlways {
+		if err := log.file.Sync(); err != nil {
+			return 0, log.undo(tail, err)
+		}
+	} else {
+		log.dirty = true
+	}
+
+	log.lastSeq = seq
+	tail.lastSeq = seq
+	tail.size += int64(len(record))
+
+	return seq, nil
+}
+
+// undo cuts a record that failed to be written or synced off the tail, so that
+// the next record follows the last acknowledged one. If that fails too, the
+// log refuses further appends rather than write after the partial record.
+func (log *Log) undo(tail *segment, err error) error {
+	if terr := log.file.Truncate(tail.size); terr != nil {
+		log.failed = err
+		return &FailedLogError{err}
+	}
+
+	return err
+}
+
+// Replay calls fn for every record with a sequence number of at least from,
+// in order.
+func (log *Log) Replay(from uint64, fn func(seq uint64, payload []byte) error) error {
+	log.mu.Lock()
+	segments := make([]segment, len(log.segments))
+	for i, seg := range log.segments {
+		segments[i] = *seg
+	}
+	log.mu.Unlock()
+
+	for _, seg := range segments {
+		if seg.lastSeq < from {
+			continue
+		}
+
+		f, err := os.Open(seg.path)
+		if err != nil {
+			return err
+		}
+
+		err = readRecords(io.LimitReader(f, seg.size), func(seq uint64, payload []byte, _ int64) error {
+			if seq < from {
+				return nil
+			}
+			return fn(seq, payload)
+		})
+		f.Close()
+
+		if _, ok := err.(*badRecordError); ok {
+			return &CorruptLogError{Path: seg.path, Reason: "damaged record during replay"}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Compact removes every segment made up entirely of records with a sequence
+// number of at most upTo, e.g. once they are covered by a snapshot. The
+// segment being written to is rolled over first if it is fully covered.
+func (log *Log) Compact(upTo uint64) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil {
+		return ErrClosed
+	}
+
+	tail := log.segments[len(log.segments)-1]
+	if tail.size > 0 && tail.lastSeq <= upTo {
+		if err := log.roll(); err != nil {
+			return err
+		}
+	}
+
+	removed := 0
+	for _, seg := range log.segments[:len(log.segments)-1] {
+		if seg.lastSeq > upTo {
+			break
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return err
+		}
+		removed++
+	}
+	log.segments = log.segments[removed:]
+
+	return syncDir(log.opts.Dir)
+}
+
+// FirstSequence is the sequence number of the oldest record still held by the
+// log, which is greater than 1 once it has been compacted.
+func (log *Log) FirstSequence() uint64 {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if len(log.segments) == 0 {
+		return log.lastSeq + 1
+	}
+
+	return log.segments[0].firstSeq
+}
+
+// LastSequence is the sequence number of the most recently appended record,
+// or 0 for an empty log.
+func (log *Log) LastSequence() uint64 {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	return log.lastSeq
+}
+
+// TornTail reports how many bytes of an incomplete trailing record were
+// discarded when the log was opened.
+func (log *Log) TornTail() int64 {
+	return log.tornTail
+}
+
+func (log *Log) Sync() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	return log.sync()
+}
+
+func (log *Log) sync() error {
+	if log.file == nil || !log.dirty {
+		return nil
+	}
+
+	log.dirty = false
+	return log.file.Sync()
+}
+
+func (log *Log) syncLoop() {
+	defer close(log.stopped)
+
+	ticker := time.NewTicker(log.opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			log.Sync()
+		case <-log.stop:
+			return
+		}
+	}
+}
+
+func (log *Log) Close() error {
+	if log.stop != nil {
+		close(log.stop)
+		<-log.stopped
+		log.stop = nil
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.file == nil {
+		return nil
+	}
+
+	log.dirty = true
+	err := log.sync()
+	if cerr := log.file.Close(); err == nil {
+		err = cerr
+	}
+	log.file = nil
+
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,281 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func replayAll(t *testing.T, log *Log, from uint64) ([]uint64, []string) {
+	t.Helper()
+
+	seqs := []uint64{}
+	payloads := []string{}
+	err := log.Replay(from, func(seq uint64, payload []byte) error {
+		seqs = append(seqs, seq)
+		payloads = append(payloads, string(payload))
+		return nil
+	})
+	require.NoError(t, err, "replay should not return an error")
+
+	return seqs, payloads
+}
+
+func TestLogAppendAndReplay(t *testing.T) {
+	log, err := Open(Options{Dir: t.TempDir()})
+	require.NoError(t, err)
+	defer log.Close()
+
+	for _, payload := range []string{"one", "two", "three"} {
+		_, err := log.Append([]byte(payload))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, uint64(3), log.LastSequence(), "log should have 3 records")
+
+	seqs, payloads := replayAll(t, log, 1)
+	assert.Equal(t, []uint64{1, 2, 3}, seqs, "replay should return every sequence number")
+	assert.Equal(t, []string{"one", "two", "three"}, payloads, "replay should return payloads in order")
+
+	seqs, _ = replayAll(t, log, 3)
+	assert.Equal(t, []uint64{3}, seqs, "replay should start from the requested sequence")
+}
+
+func TestLogReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir, Sync: SyncNever})
+	require.NoError(t, err)
+	log.Append([]byte("one"))
+	log.Append([]byte("two"))
+	require.NoError(t, log.Close())
+
+	log, err = Open(Options{Dir: dir})
+	require.NoError(t, err)
+	defer log.Close()
+
+	seq, err := log.Append([]byte("three"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), seq, "appends should continue after the last record")
+
+	_, payloads := replayAll(t, log, 1)
+	assert.Equal(t, []string{"one", "two", "three"}, payloads, "reopened log should keep earlier records")
+	assert.Equal(t, int64(0), log.TornTail(), "cleanly closed log should have no torn tail")
+}
+
+func TestLogSegments(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir, SegmentSize: 64})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		log.Append([]byte("0123456789abcdef"))
+	}
+	require.NoError(t, log.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	assert.Greater(t, len(files), 1, "log should roll over into several segments")
+
+	log, err = Open(Options{Dir: dir, SegmentSize: 64})
+	require.NoError(t, err)
+	defer log.Close()
+
+	seqs, _ := replayAll(t, log, 4)
+	assert.Equal(t, []uint64{4, 5, 6, 7, 8, 9, 10}, seqs, "replay should span segments")
+}
+
+func TestLogTornTail(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir})
+	require.NoError(t, err)
+	log.Append([]byte("one"))
+	log.Append([]byte("two"))
+	require.NoError(t, log.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	info, _ := os.Stat(files[0])
+	require.NoError(t, os.Truncate(files[0], info.Size()-2))
+
+	log, err = Open(Options{Dir: dir})
+	require.NoError(t, err)
+	defer log.Close()
+
+	assert.Equal(t, int64(headerSize+1), log.TornTail(), "partial record should be reported as a torn tail")
+	assert.Equal(t, uint64(1), log.LastSequence(), "partial record should be discarded")
+
+	seq, _ := log.Append([]byte("again"))
+	assert.Equal(t, uint64(2), seq, "next append should reuse the discarded sequence number")
+
+	_, payloads := replayAll(t, log, 1)
+	assert.Equal(t, []string{"one", "again"}, payloads, "log should be readable after a torn tail")
+}
+
+func TestLogCorruptTail(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir})
+	require.NoError(t, err)
+	log.Append([]byte("one"))
+	log.Append([]byte("two"))
+	require.NoError(t, log.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	data, _ := os.ReadFile(files[0])
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(files[0], data, 0o644))
+
+	log, err = Open(Options{Dir: dir})
+	require.NoError(t, err)
+	defer log.Close()
+
+	assert.Equal(t, uint64(1), log.LastSequence(), "record failing its checksum should be discarded")
+}
+
+func TestLogCorruptSegment(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir, SegmentSize: 32})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		log.Append([]byte("0123456789abcdef"))
+	}
+	require.NoError(t, log.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	data, _ := os.ReadFile(files[0])
+	data[headerSize] ^= 0xff
+	require.NoError(t, os.WriteFile(files[0], data, 0o644))
+
+	_, err = Open(Options{Dir: dir, SegmentSize: 32})
+	assert.IsType(t, &CorruptLogError{}, err, "damage before the last segment should be reported")
+}
+
+func TestLogCorruptBeforeTail(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir})
+	require.NoError(t, err)
+	log.Append([]byte("one"))
+	log.Append([]byte("two"))
+	require.NoError(t, log.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	data, _ := os.ReadFile(files[0])
+	data[headerSize] ^= 0xff
+	require.NoError(t, os.WriteFile(files[0], data, 0o644))
+
+	_, err = Open(Options{Dir: dir})
+	assert.IsType(t, &CorruptLogError{}, err, "damaged record followed by others should be reported, not truncated")
+
+	after, _ := os.ReadFile(files[0])
+	assert.Equal(t, data, after, "corrupt log should be left as it is")
+}
+
+func TestLogSegmentGap(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir, SegmentSize: 32})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		log.Append([]byte("0123456789abcdef"))
+	}
+	require.NoError(t, log.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.Greater(t, len(files), 2)
+	require.NoError(t, os.Remove(files[1]))
+
+	_, err = Open(Options{Dir: dir, SegmentSize: 32})
+	assert.IsType(t, &CorruptLogError{}, err, "missing segment should be reported")
+}
+
+func TestLogFailedAppend(t *testing.T) {
+	log, err := Open(Options{Dir: t.TempDir()})
+	require.NoError(t, err)
+	log.Append([]byte("one"))
+
+	// Closing the file underneath the log fails both the write and cutting
+	// it off again.
+	log.file.Close()
+	_, err = log.Append([]byte("two"))
+	assert.Error(t, err, "failed write should return an error")
+
+	_, err = log.Append([]byte("three"))
+	assert.IsType(t, &FailedLogError{}, err, "log should refuse appends once a partial record may be left behind")
+	assert.Equal(t, uint64(1), log.LastSequence(), "failed appends should not advance the sequence")
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	policy, err := ParseSyncPolicy("interval")
+	assert.NoError(t, err)
+	assert.Equal(t, SyncInterval, policy, "interval should parse")
+
+	policy, err = ParseSyncPolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, SyncAlways, policy, "empty policy should default to always")
+
+	_, err = ParseSyncPolicy("sometimes")
+	assert.Error(t, err, "unknown policy should return an error")
+}
+
+func TestLogCompact(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir, SegmentSize: 64})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		log.Append([]byte("0123456789abcdef"))
+	}
+
+	require.NoError(t, log.Compact(5))
+	assert.LessOrEqual(t, log.FirstSequence(), uint64(6), "records after the compaction point should be kept")
+	assert.Greater(t, log.FirstSequence(), uint64(1), "segments before the compaction point should be removed")
+
+	require.NoError(t, log.Compact(10))
+	assert.Equal(t, uint64(11), log.FirstSequence(), "fully compacted log should start after the last record")
+
+	seq, _ := log.Append([]byte("next"))
+	assert.Equal(t, uint64(11), seq, "appends should continue after compaction")
+	require.NoError(t, log.Close())
+
+	log, err = Open(Options{Dir: dir, SegmentSize: 64})
+	require.NoError(t, err)
+	defer log.Close()
+
+	assert.Equal(t, uint64(11), log.LastSequence(), "reopened log should keep its sequence after compaction")
+	seqs, _ := replayAll(t, log, 1)
+	assert.Equal(t, []uint64{11}, seqs, "compacted records should not be replayed")
+}
+
+func TestLogReadOnly(t *testing.T) {
+	dir := t.TempDir()
+
+	log, err := Open(Options{Dir: dir})
+	require.NoError(t, err)
+	log.Append([]byte("one"))
+	log.Append([]byte("two"))
+	require.NoError(t, log.Close())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	info, _ := os.Stat(files[0])
+	require.NoError(t, os.Truncate(files[0], info.Size()-2))
+
+	log, err = Open(Options{Dir: dir, ReadOnly: true})
+	require.NoError(t, err)
+	defer log.Close()
+
+	_, payloads := replayAll(t, log, 1)
+	assert.Equal(t, []string{"one"}, payloads, "read only log should skip the torn tail")
+
+	_, err = log.Append([]byte("three"))
+	assert.Equal(t, ErrClosed, err, "read only log should refuse appends")
+
+	after, _ := os.Stat(files[0])
+	assert.Equal(t, info.Size()-2, after.Size(), "read only log should not truncate the torn tail")
+}
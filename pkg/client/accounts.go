@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// idempotencyKeyHeader carries the key that makes a retried send return the
+// original transfer.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+func accountPath(signer string) string {
+	return "/v1/accounts/" + url.PathEscape(signer)
+}
+
+type accountBalance struct {
+	Signer  string  `json:"signer"`
+	Balance float64 `json:"balance"`
+}
+
+type createAccountBody struct {
+	Signer string `json:"signer"`
+}
+
+func (c *Client) CreateAccount(ctx context.Context, signer string) error {
+	return c.doJSON(ctx, newRequest(http.MethodPost, "/v1/accounts", nil), createAccountBody{signer}, nil)
+}
+
+func (c *Client) Balance(ctx context.Context, signer string) (float64, error) {
+	account := accountBalance{}
+	if _, err := c.do(ctx, newRequest(http.MethodGet, accountPath(signer), nil), &account); err != nil {
+		return 0, err
+	}
+
+	return account.Balance, nil
+}
+
+type amountBody struct {
+	Amount float64 `json:"amount"`
+}
+
+func (c *Client) Deposit(ctx context.Context, signer string, amount float64) (*Tx, error) {
+	return c.accountAction(ctx, signer, "deposits", amount)
+}
+
+func (c *Client) Withdraw(ctx context.Context, signer string, amount float64) (*Tx, error) {
+	return c.accountAction(ctx, signer, "withdrawals", amount)
+}
+
+func (c *Client) accountAction(ctx context.Context, signer string, action string, amount float64) (*Tx, error) {
+	tx := &Tx{}
+	if err := c.doJSON(ctx, newRequest(http.MethodPost, accountPath(signer)+"/"+action, nil), amountBody{amount}, tx); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+type SendRequest struct {
+	Signer    string
+	Recipient string
+	Amount    float64
+	// CreateRecipient opens an account for a recipient that does not have one.
+	CreateRecipient bool
+	// IdempotencyKey makes a repeated send return the original transfer. Sends
+	// are only retried when it is set.
+	IdempotencyKey string
+}
+
+type sendBody struct {
+	Recipient       string  `json:"recipient"`
+	Amount          float64 `json:"amount"`
+	CreateRecipient bool    `json:"create_recipient"`
+}
+
+// Send transfers between accounts. It returns the withdraw from the sender
+// and the deposit to the recipient, which share a transaction id.
+func (c *Client) Send(ctx context.Context, send SendRequest) ([]Tx, error) {
+	r := newRequest(http.MethodPost, accountPath(send.Signer)+"/transfers", nil)
+	if send.IdempotencyKey != "" {
+		r.header.Set(idempotencyKeyHeader, send.IdempotencyKey)
+	}
+
+	txs := []Tx{}
+	err := c.doJSON(ctx, r, sendBody{send.Recipient, send.Amount, send.CreateRecipient}, &txs)
+
+	return txs, err
+}
+
+// Transactions returns a page of a signer's ledger entries, newest first,
+// starting before the entry id before, or from the newest when it is zero. A
+// zero limit uses the API default.
+func (c *Client) Transactions(ctx context.Context, signer string, before uint64, limit int) (*TransactionsPage, error) {
+	query := url.Values{}
+	if before > 0 {
+		query.Set("cursor", strconv.FormatUint(before, 10))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	page := &TransactionsPage{}
+	meta, err := c.do(ctx, newRequest(http.MethodGet, accountPath(signer)+"/transactions", query), &page.Entries)
+	if err != nil {
+		return nil, err
+	}
+	if meta != nil && meta.NextCursor != "" {
+		if page.Next, err = strconv.ParseUint(meta.NextCursor, 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected cursor %q", meta.NextCursor)
+		}
+	}
+
+	return page, nil
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// The admin endpoints require a key with the admin role.
+
+// Reset removes every market and account and opens the configured markets
+// again.
+func (c *Client) Reset(ctx context.Context) error {
+	_, err := c.do(ctx, newRequest(http.MethodPost, "/v1/admin/reset", nil), nil)
+	return err
+}
+
+func (c *Client) CreateMarket(ctx context.Context, market Market) (*Orderbook, error) {
+	book := &Orderbook{}
+	if err := c.doJSON(ctx, newRequest(http.MethodPost, "/v1/markets", nil), market, book); err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// RemoveMarket closes a market and returns the orders it cancelled.
+func (c *Client) RemoveMarket(ctx context.Context, market Market) ([]Order, error) {
+	cancelled := []Order{}
+	_, err := c.do(ctx, newRequest(http.MethodDelete, marketPath(market), nil), &cancelled)
+
+	return cancelled, err
+}
+
+func (c *Client) HaltMarket(ctx context.Context, market Market) error {
+	return c.SetMarketState(ctx, market, StateHalted, "")
+}
+
+func (c *Client) ResumeMarket(ctx context.Context, market Market) error {
+	return c.SetMarketState(ctx, market, StateOpen, "")
+}
+
+type marketStateBody struct {
+	State  TradingState `json:"state"`
+	Reason string       `json:"reason"`
+}
+
+func (c *Client) SetMarketState(ctx context.Context, market Market, state TradingState, reason string) error {
+	return c.doJSON(ctx, newRequest(http.MethodPut, marketPath(market)+"/state", nil), marketStateBody{state, reason}, nil)
+}
+
+// SetFees replaces the fee schedule of a market. A nil schedule removes its
+// fees.
+func (c *Client) SetFees(ctx context.Context, market Market, schedule *FeeSchedule) (*FeeSchedule, error) {
+	r := newRequest(http.MethodPut, marketPath(market)+"/fees", nil)
+	if schedule == nil {
+		r.method = http.MethodDelete
+		_, err := c.do(ctx, r, nil)
+		return nil, err
+	}
+
+	set := &FeeSchedule{}
+	if err := c.doJSON(ctx, r, schedule, set); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// Accounts returns the balance of every account.
+func (c *Client) Accounts(ctx context.Context) (map[string]float64, error) {
+	accounts := make(map[string]float64)
+	query := url.Values{"limit": {strconv.Itoa(maxPageLimit)}}
+	for {
+		page := []accountBalance{}
+		meta, err := c.do(ctx, newRequest(http.MethodGet, "/v1/admin/accounts", query), &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range page {
+			accounts[account.Signer] = account.Balance
+		}
+
+		if meta == nil || meta.NextCursor == "" {
+			return accounts, nil
+		}
+		query.Set("cursor", meta.NextCursor)
+	}
+}
+
+type adjustBody struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+}
+
+// AdjustBalance corrects a balance, recording reason in the ledger. A negative
+// amount reduces it.
+func (c *Client) AdjustBalance(ctx context.Context, signer string, amount float64, reason string) (*Tx, error) {
+	tx := &Tx{}
+	r := newRequest(http.MethodPost, "/v1/admin/accounts/"+url.PathEscape(signer)+"/adjustments", nil)
+	if err := c.doJSON(ctx, r, adjustBody{amount, reason}, tx); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,295 @@
+// Package client is a Go client for the octgopus trading API.
+//
+//	c := client.New("http://localhost:8080", client.Options{KeyID: "dev-alice", Secret: secret})
+//	book, err := c.Orderbook(ctx, client.NewMarket("BTC", "USD"))
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+//
+// Failed requests return an *APIError. Reads, and sends with an idempotency
+// key, are retried when the server is unavailable; any request is retried
+// when it was rate limited, as it was not processed.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richo225/octgopus/internal/auth"
+)
+
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// maxRetryBackoff caps the exponential backoff, but not a wait the
+	// server asked for with Retry-After.
+	maxRetryBackoff = 5 * time.Second
+	// maxPageLimit is the most the API returns in a page.
+	maxPageLimit = 500
+)
+
+type Options struct {
+	// KeyID and Secret sign requests. Requests are sent unsigned without
+	// them.
+	KeyID  string
+	Secret string
+	// HTTPClient defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+	// MaxRetries defaults to DefaultMaxRetries. A negative value disables
+	// retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubling after each.
+	// It defaults to DefaultRetryBackoff.
+	RetryBackoff time.Duration
+}
+
+type Client struct {
+	baseURL string
+	key     *auth.Key
+	http    *http.Client
+	retries int
+	backoff time.Duration
+}
+
+// New returns a client for the API at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts Options) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    opts.HTTPClient,
+		retries: opts.MaxRetries,
+		backoff: opts.RetryBackoff,
+	}
+	if opts.KeyID != "" {
+		c.key = &auth.Key{ID: opts.KeyID, Secret: opts.Secret}
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	if c.retries == 0 {
+		c.retries = DefaultMaxRetries
+	}
+	if c.backoff == 0 {
+		c.backoff = DefaultRetryBackoff
+	}
+
+	return c
+}
+
+// request is a call to the API. The body is kept as bytes so that it can be
+// signed and sent again on each attempt.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        []byte
+	contentType string
+	header      http.Header
+}
+
+func newRequest(method string, path string, query url.Values) *request {
+	return &request{method: method, path: path, query: query, header: http.Header{}}
+}
+
+func (r *request) withJSON(v interface{}) (*request, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	r.body, r.contentType = body, "application/json"
+
+	return r, nil
+}
+
+// idempotent reports whether the request can be sent again after a failure
+// that may have happened after the server acted on it.
+func (r *request) idempotent() bool {
+	return r.method == http.MethodGet || r.method == http.MethodHead || r.header.Get(idempotencyKeyHeader) != ""
+}
+
+// send makes the request, retrying it when allowed, and returns the response
+// of a successful attempt. The caller closes its body.
+func (c *Client) send(ctx context.Context, r *request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, r)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		wait, retry := c.shouldRetry(r, err, attempt)
+		if !retry {
+			return nil, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, r *request) (*http.Response, error) {
+	u := c.baseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+
+	// Each attempt is signed with a new nonce, as the server refuses a nonce
+	// it has seen.
+	if c.key != nil {
+		if err := auth.SignRequest(req, c.key, time.Now(), nonce()); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+
+	return resp, nil
+}
+
+// shouldRetry returns how long to wait before retrying a failed attempt.
+func (c *Client) shouldRetry(r *request, err error, attempt int) (time.Duration, bool) {
+	if attempt >= c.retries {
+		return 0, false
+	}
+
+	backoff := c.backoff << attempt
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		// The request may have reached the server before the connection
+		// failed.
+		return backoff, r.idempotent()
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests:
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter, true
+		}
+		return backoff, true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return backoff, r.idempotent()
+	}
+
+	return 0, false
+}
+
+// envelope is the body of a successful JSON response.
+type envelope struct {
+	Data interface{} `json:"data"`
+	Meta *pageMeta   `json:"meta"`
+}
+
+type pageMeta struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// do makes the request and decodes the data of its response into out. It
+// returns the meta of a page.
+func (c *Client) do(ctx context.Context, r *request, out interface{}) (*pageMeta, error) {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return nil, err
+	}
+
+	body := &envelope{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return nil, err
+	}
+
+	return body.Meta, nil
+}
+
+// doJSON sends v as the JSON body of the request.
+func (c *Client) doJSON(ctx context.Context, r *request, v interface{}, out interface{}) error {
+	r, err := r.withJSON(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(ctx, r, out)
+	return err
+}
+
+func decodeError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	apiErr := &APIError{}
+	failed := struct {
+		Error *struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+	if err := json.Unmarshal(body, &failed); err == nil && failed.Error != nil && failed.Error.Message != "" {
+		apiErr.Message, apiErr.reason = failed.Error.Message, failed.Error.Reason
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	apiErr.StatusCode = resp.StatusCode
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return apiErr
+}
+
+func nonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// marketPath is the path of a market, as BASE-QUOTE.
+func marketPath(market Market) string {
+	return "/v1/markets/" + url.PathEscape(market.Base+"-"+market.Quote)
+}
@@ -0,0 +1,292 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/richo225/octgopus/internal/api"
+	"github.com/richo225/octgopus/internal/auth"
+	"github.com/richo225/octgopus/internal/orderbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var btcUSD = NewMarket("BTC", "USD")
+
+// testAPI serves the real API with keys for alice, bob and an admin, and
+// BTC/USD configured to open empty.
+func testAPI(t *testing.T) (alice *Client, bob *Client, admin *Client) {
+	platform := orderbook.NewTradingPlatform()
+	require.NoError(t, platform.SetMarkets([]orderbook.MarketSpec{{Market: orderbook.NewTradingPair("BTC", "USD")}}))
+	require.NoError(t, platform.OpenMarkets())
+
+	keys := auth.NewKeyStore(
+		&auth.Key{ID: "alice-key", Secret: "alice-secret", Signer: "alice"},
+		&auth.Key{ID: "bob-key", Secret: "bob-secret", Signer: "bob"},
+		&auth.Key{ID: "admin-key", Secret: "admin-secret", Signer: "admin", Roles: []string{auth.AdminRole}},
+	)
+	server := httptest.NewServer(api.NewServer(platform, api.Config{Verifier: auth.NewVerifier(keys), AuditLog: io.Discard}))
+	t.Cleanup(server.Close)
+
+	alice = New(server.URL, Options{KeyID: "alice-key", Secret: "alice-secret"})
+	bob = New(server.URL, Options{KeyID: "bob-key", Secret: "bob-secret"})
+	admin = New(server.URL, Options{KeyID: "admin-key", Secret: "admin-secret"})
+
+	return alice, bob, admin
+}
+
+func TestClientTrading(t *testing.T) {
+	alice, bob, _ := testAPI(t)
+	ctx := context.Background()
+
+	require.NoError(t, alice.Health(ctx), "API should be up")
+
+	for _, c := range []struct {
+		client *Client
+		signer string
+	}{{alice, "alice"}, {bob, "bob"}} {
+		require.NoError(t, c.client.CreateAccount(ctx, c.signer), "account should be created")
+		tx, err := c.client.Deposit(ctx, c.signer, 1000)
+		require.NoError(t, err, "deposit should not return an error")
+		assert.Equal(t, Deposit, tx.Action)
+	}
+
+	ask, err := alice.PlaceLimitOrder(ctx, OrderRequest{Market: btcUSD, Side: Ask, Price: 100, Size: 2})
+	require.NoError(t, err, "limit order should be placed")
+	assert.NotZero(t, ask.ID, "order should be given an id")
+	assert.Equal(t, "alice", ask.Signer, "order should be placed for the key's signer")
+
+	matches, err := bob.PlaceMarketOrder(ctx, OrderRequest{Market: btcUSD, Side: Bid, Price: 100, Size: 0.5})
+	require.NoError(t, err, "market order should be placed")
+	require.Len(t, matches, 1, "market order should match the ask")
+	assert.Equal(t, 100.0, matches[0].Price)
+	assert.Equal(t, 0.5, matches[0].SizeFilled)
+	assert.Equal(t, ask.ID, matches[0].Ask.ID, "match should fill the resting ask")
+
+	book, err := bob.Orderbook(ctx, btcUSD)
+	require.NoError(t, err, "book should be returned")
+	assert.Equal(t, btcUSD, book.Market)
+	assert.Equal(t, StateOpen, book.State)
+	assert.Equal(t, 100.0, book.LastTradePrice)
+	require.Len(t, book.Asks, 1)
+	assert.Equal(t, 1.5, book.Asks[0].TotalVolume, "ask should be partly filled")
+
+	_, err = bob.Order(ctx, btcUSD, ask.ID)
+	assert.ErrorIs(t, err, ErrForbidden, "another signer's order should be forbidden")
+	resting, err := alice.Order(ctx, btcUSD, ask.ID)
+	require.NoError(t, err, "order should be returned")
+	assert.Equal(t, 1.5, resting.Size)
+
+	summary, err := bob.Market(ctx, btcUSD)
+	require.NoError(t, err, "market should be summarised")
+	assert.Equal(t, StateOpen, summary.State)
+
+	markets, err := bob.Markets(ctx)
+	require.NoError(t, err, "markets should be returned")
+	assert.Equal(t, []MarketSummary{{Market: btcUSD, State: StateOpen, BestAsk: 100, LastTradePrice: 100}}, markets)
+
+	cancelled, err := alice.CancelOrder(ctx, btcUSD, ask.ID)
+	require.NoError(t, err, "order should be cancelled")
+	assert.Equal(t, 1.5, cancelled.Size, "cancelled order should have its remaining size")
+
+	var csv bytes.Buffer
+	require.NoError(t, bob.Export(ctx, &csv, btcUSD, ExportTrades, ExportCSV), "trades should be exported")
+	assert.Equal(t, 2, strings.Count(csv.String(), "\n"), "export should hold a header and the trade")
+}
+
+func TestClientAccounts(t *testing.T) {
+	alice, bob, _ := testAPI(t)
+	ctx := context.Background()
+
+	require.NoError(t, alice.CreateAccount(ctx, "alice"))
+	_, err := alice.Deposit(ctx, "alice", 100)
+	require.NoError(t, err)
+	_, err = alice.Withdraw(ctx, "alice", 10)
+	require.NoError(t, err)
+
+	send := SendRequest{Signer: "alice", Recipient: "bob", Amount: 30, CreateRecipient: true, IdempotencyKey: "send-1"}
+	first, err := alice.Send(ctx, send)
+	require.NoError(t, err, "send should not return an error")
+	again, err := alice.Send(ctx, send)
+	require.NoError(t, err, "repeated send should not return an error")
+	require.Len(t, first, 2, "send should return both legs")
+	assert.Equal(t, Tx{ID: first[0].ID, Action: Withdraw, Signer: "alice", Amount: 30, Timestamp: first[0].Timestamp}, first[0])
+	assert.Equal(t, "bob", first[1].Signer)
+	assert.Equal(t, first, again, "repeated send should return the original transfer")
+
+	balance, err := alice.Balance(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, balance, "balance should reflect the deposit, withdrawal and one send")
+	balance, err = bob.Balance(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, balance, "recipient account should be created by the send")
+
+	page, err := alice.Transactions(ctx, "alice", 0, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2, "page should be limited")
+	assert.Equal(t, Transfer, page.Entries[0].Action, "newest entry should come first")
+	assert.NotZero(t, page.Next, "a full page should have a next page")
+
+	page, err = alice.Transactions(ctx, "alice", page.Next, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1, "next page should hold the remaining entry")
+	assert.Zero(t, page.Next, "last page should not have a next page")
+}
+
+func TestClientAdmin(t *testing.T) {
+	alice, _, admin := testAPI(t)
+	ctx := context.Background()
+	ethUSD := NewMarket("ETH", "USD")
+
+	book, err := admin.CreateMarket(ctx, ethUSD)
+	require.NoError(t, err, "market should be created")
+	assert.Equal(t, ethUSD, book.Market)
+
+	result, err := admin.ImportOrderbook(ctx, ethUSD, strings.NewReader("Side,Price,Size\nask,101,1\nbid,99,2\n"), false)
+	require.NoError(t, err, "orders should be imported")
+	assert.Equal(t, ImportResult{Market: ethUSD, Layout: "orders", Orders: 2}, *result)
+
+	require.NoError(t, admin.HaltMarket(ctx, ethUSD), "market should be halted")
+	_, err = alice.PlaceLimitOrder(ctx, OrderRequest{Market: ethUSD, Side: Bid, Price: 98, Size: 1})
+	assert.ErrorIs(t, err, ErrConflict, "orders should be refused while halted")
+	require.NoError(t, admin.ResumeMarket(ctx, ethUSD), "market should be resumed")
+	require.NoError(t, admin.SetMarketState(ctx, ethUSD, StateCancelOnly, "maintenance"), "state should be set")
+
+	fees, err := admin.SetFees(ctx, ethUSD, &FeeSchedule{MakerRate: -0.0001, TakerRate: 0.002})
+	require.NoError(t, err, "fees should be set")
+	assert.Equal(t, 0.002, fees.TakerRate)
+	fees, err = admin.SetFees(ctx, ethUSD, nil)
+	require.NoError(t, err, "fees should be cleared")
+	assert.Nil(t, fees)
+
+	require.NoError(t, alice.CreateAccount(ctx, "alice"))
+	tx, err := admin.AdjustBalance(ctx, "alice", 25, "goodwill")
+	require.NoError(t, err, "balance should be adjusted")
+	assert.Equal(t, Adjustment, tx.Action)
+	accounts, err := admin.Accounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, accounts["alice"])
+
+	cancelled, err := admin.RemoveMarket(ctx, ethUSD)
+	require.NoError(t, err, "market should be removed")
+	assert.Len(t, cancelled, 2, "resting orders should be cancelled")
+
+	require.NoError(t, admin.Reset(ctx), "platform should be reset")
+	markets, err := admin.Markets(ctx)
+	require.NoError(t, err)
+	require.Len(t, markets, 1, "reset should open the configured markets")
+	assert.Equal(t, btcUSD, markets[0].Market)
+}
+
+func TestClientErrors(t *testing.T) {
+	alice, _, _ := testAPI(t)
+	ctx := context.Background()
+
+	_, err := alice.Orderbook(ctx, NewMarket("ETH", "USD"))
+	require.ErrorIs(t, err, ErrNotFound, "missing market should be not found")
+	apiErr := &APIError{}
+	require.True(t, errors.As(err, &apiErr), "error should be an APIError")
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "MarketNotfound : ETH/USD", apiErr.Message)
+	assert.Equal(t, "MarketNotfound", apiErr.Reason())
+
+	_, err = alice.Balance(ctx, "alice")
+	require.True(t, errors.As(err, &apiErr), "error should be an APIError")
+	assert.Equal(t, "AccountNotFound", apiErr.Reason(), "accounting errors should give their own reason")
+
+	_, err = alice.Deposit(ctx, "bob", 10)
+	assert.ErrorIs(t, err, ErrForbidden, "acting for another signer should be forbidden")
+	require.True(t, errors.As(err, &apiErr), "error should be an APIError")
+	assert.Equal(t, "Forbidden", apiErr.Reason())
+
+	_, err = alice.CreateMarket(ctx, NewMarket("ETH", "USD"))
+	assert.ErrorIs(t, err, ErrForbidden, "admin routes should need the admin role")
+
+	_, err = alice.PlaceLimitOrder(ctx, OrderRequest{Market: btcUSD, Side: "sideways", Price: 1, Size: 1})
+	assert.ErrorIs(t, err, ErrUnprocessable, "invalid orders should fail validation")
+
+	stranger := New(alice.baseURL, Options{KeyID: "alice-key", Secret: "wrong"})
+	_, err = stranger.Balance(ctx, "alice")
+	assert.ErrorIs(t, err, ErrUnauthorized, "a bad signature should be unauthorized")
+}
+
+func TestAPIErrorReason(t *testing.T) {
+	assert.Equal(t, "MarketNotfound", (&APIError{Message: "MarketNotfound : ETH/USD"}).Reason())
+	assert.Equal(t, "RateLimited", (&APIError{Message: "RateLimited: retry after 1s"}).Reason(), "either separator should give the reason")
+	assert.Equal(t, "", (&APIError{Message: "file is required"}).Reason(), "messages without a name should have no reason")
+	assert.Equal(t, "Unavailable", (&APIError{Message: "try again", reason: "Unavailable"}).Reason(), "the server's reason should be used")
+}
+
+// flakyServer answers with status until it has failed failures times.
+func flakyServer(t *testing.T, failures int32, status int, body string) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"code":0,"reason":"Unavailable","message":"try again"}}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestClientRetries(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{RetryBackoff: time.Millisecond}
+
+	server, calls := flakyServer(t, 2, http.StatusServiceUnavailable, `{"data":{"signer":"alice","balance":12.5}}`)
+	balance, err := New(server.URL, opts).Balance(ctx, "alice")
+	require.NoError(t, err, "reads should be retried")
+	assert.Equal(t, 12.5, balance)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls), "read should be sent until it succeeds")
+
+	server, calls = flakyServer(t, 1, http.StatusServiceUnavailable, `{"data":{"id":1}}`)
+	_, err = New(server.URL, opts).Deposit(ctx, "alice", 10)
+	assert.ErrorIs(t, err, ErrServer, "deposits should not be retried")
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls), "deposit should be sent once")
+
+	server, calls = flakyServer(t, 1, http.StatusServiceUnavailable, `{"data":[{"id":1},{"id":1}]}`)
+	_, err = New(server.URL, opts).Send(ctx, SendRequest{Signer: "alice", Recipient: "bob", Amount: 1, IdempotencyKey: "k"})
+	require.NoError(t, err, "sends with an idempotency key should be retried")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+	server, calls = flakyServer(t, 1, http.StatusTooManyRequests, `{"data":{"id":1}}`)
+	_, err = New(server.URL, opts).Deposit(ctx, "alice", 10)
+	require.NoError(t, err, "rate limited requests should be retried")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+
+	server, calls = flakyServer(t, 1, http.StatusServiceUnavailable, `{"data":{}}`)
+	_, err = New(server.URL, Options{MaxRetries: -1}).Balance(ctx, "alice")
+	assert.ErrorIs(t, err, ErrServer, "retries should be disabled by a negative MaxRetries")
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	server, calls = flakyServer(t, 10, http.StatusServiceUnavailable, `{"data":{}}`)
+	_, err = New(server.URL, opts).Balance(ctx, "alice")
+	assert.ErrorIs(t, err, ErrServer, "retries should give up")
+	assert.Equal(t, int32(1+DefaultMaxRetries), atomic.LoadInt32(calls))
+}
+
+func TestClientContext(t *testing.T) {
+	server, calls := flakyServer(t, 100, http.StatusServiceUnavailable, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := New(server.URL, Options{RetryBackoff: time.Second}).Markets(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "waiting to retry should stop with the context")
+	assert.Less(t, time.Since(start), time.Second, "client should not wait out the backoff")
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors an APIError matches with errors.Is, by its status code.
+var (
+	ErrBadRequest    = errors.New("bad request")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrUnprocessable = errors.New("unprocessable")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrServer        = errors.New("server error")
+)
+
+// APIError is the error body of a failed request.
+type APIError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is how long the server asked the client to wait, from the
+	// Retry-After header of a rate limited request.
+	RetryAfter time.Duration
+	reason     string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d: %s", e.StatusCode, e.Message)
+}
+
+// Reason is the reason the server gave the error, e.g. MarketNotfound or
+// AccountUnderFunded. Errors without one, from servers older than /v1, fall
+// back to the name their message starts with.
+func (e *APIError) Reason() string {
+	if e.reason != "" {
+		return e.reason
+	}
+
+	name, _, ok := strings.Cut(e.Message, ":")
+	name = strings.TrimSpace(name)
+	if !ok || strings.Contains(name, " ") {
+		return ""
+	}
+
+	return name
+}
+
+func (e *APIError) Is(target error) bool {
+	switch {
+	case e.StatusCode >= http.StatusInternalServerError:
+		return target == ErrServer
+	case e.StatusCode == http.StatusBadRequest:
+		return target == ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return target == ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return target == ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return target == ErrConflict
+	case e.StatusCode == http.StatusUnprocessableEntity:
+		return target == ErrUnprocessable
+	case e.StatusCode == http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	}
+
+	return false
+}
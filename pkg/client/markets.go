@@ -0,0 +1,97 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Health checks that the API is up.
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.do(ctx, newRequest(http.MethodGet, "/v1/health", nil), nil)
+	return err
+}
+
+// Markets summarises every market, sorted by name.
+func (c *Client) Markets(ctx context.Context) ([]MarketSummary, error) {
+	markets := []MarketSummary{}
+	query := url.Values{"limit": {strconv.Itoa(maxPageLimit)}}
+	for {
+		page := []MarketSummary{}
+		meta, err := c.do(ctx, newRequest(http.MethodGet, "/v1/markets", query), &page)
+		if err != nil {
+			return nil, err
+		}
+		markets = append(markets, page...)
+
+		if meta == nil || meta.NextCursor == "" {
+			return markets, nil
+		}
+		query.Set("cursor", meta.NextCursor)
+	}
+}
+
+// Market summarises one market.
+func (c *Client) Market(ctx context.Context, market Market) (*MarketSummary, error) {
+	summary := &MarketSummary{}
+	if _, err := c.do(ctx, newRequest(http.MethodGet, marketPath(market), nil), summary); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (c *Client) Orderbook(ctx context.Context, market Market) (*Orderbook, error) {
+	book := &Orderbook{}
+	if _, err := c.do(ctx, newRequest(http.MethodGet, marketPath(market)+"/book", nil), book); err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// ImportOrderbook places the orders of a CSV file on a market, creating the
+// market first when createMarket is set. It requires an admin key.
+func (c *Client) ImportOrderbook(ctx context.Context, market Market, csv io.Reader, createMarket bool) (*ImportResult, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("create_market", strconv.FormatBool(createMarket))
+	part, err := form.CreateFormFile("file", "orderbook.csv")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, csv); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	r := newRequest(http.MethodPost, marketPath(market)+"/import", nil)
+	r.body, r.contentType = body.Bytes(), form.FormDataContentType()
+
+	result := &ImportResult{}
+	if _, err := c.do(ctx, r, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Export writes a market's book, orders or trades to w as CSV or JSON.
+func (c *Client) Export(ctx context.Context, w io.Writer, market Market, view ExportView, format ExportFormat) error {
+	query := url.Values{"view": {string(view)}, "format": {string(format)}}
+
+	resp, err := c.send(ctx, newRequest(http.MethodGet, marketPath(market)+"/export", query))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
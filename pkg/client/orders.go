@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// OrderRequest is an order to place. The API requires a price for market
+// orders too, although they fill at the prices of the resting orders.
+type OrderRequest struct {
+	Market Market
+	Side   Side
+	Price  float64
+	Size   float64
+	// SelfTradePrevention defaults to the platform mode when empty.
+	SelfTradePrevention SelfTradePrevention
+}
+
+type placeOrderBody struct {
+	Side                Side                `json:"side"`
+	Type                OrderType           `json:"type"`
+	Price               float64             `json:"price"`
+	Size                float64             `json:"size"`
+	SelfTradePrevention SelfTradePrevention `json:"self_trade_prevention,omitempty"`
+}
+
+func (o OrderRequest) body(orderType OrderType) placeOrderBody {
+	return placeOrderBody{o.Side, orderType, o.Price, o.Size, o.SelfTradePrevention}
+}
+
+// orderResult is a placed order and the matches it made.
+type orderResult struct {
+	Order   Order   `json:"order"`
+	Matches []Match `json:"matches"`
+}
+
+func (c *Client) placeOrder(ctx context.Context, order OrderRequest, orderType OrderType) (*orderResult, error) {
+	result := &orderResult{}
+	if err := c.doJSON(ctx, newRequest(http.MethodPost, marketPath(order.Market)+"/orders", nil), order.body(orderType), result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// PlaceLimitOrder places an order that rests on the book until it is filled
+// or cancelled, and returns it with its id.
+func (c *Client) PlaceLimitOrder(ctx context.Context, order OrderRequest) (*Order, error) {
+	result, err := c.placeOrder(ctx, order, LimitOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.Order, nil
+}
+
+// PlaceMarketOrder fills an order against the resting orders and returns the
+// matches.
+func (c *Client) PlaceMarketOrder(ctx context.Context, order OrderRequest) ([]Match, error) {
+	result, err := c.placeOrder(ctx, order, MarketOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Matches, nil
+}
+
+func orderPath(market Market, id uint64) string {
+	return marketPath(market) + "/orders/" + strconv.FormatUint(id, 10)
+}
+
+// Order returns a resting order.
+func (c *Client) Order(ctx context.Context, market Market, id uint64) (*Order, error) {
+	order := &Order{}
+	if _, err := c.do(ctx, newRequest(http.MethodGet, orderPath(market, id), nil), order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// CancelOrder removes a resting order from the book and returns it.
+func (c *Client) CancelOrder(ctx context.Context, market Market, id uint64) (*Order, error) {
+	cancelled := &Order{}
+	if _, err := c.do(ctx, newRequest(http.MethodDelete, orderPath(market, id), nil), cancelled); err != nil {
+		return nil, err
+	}
+
+	return cancelled, nil
+}
@@ -0,0 +1,181 @@
+package client
+
+// Market is a trading pair such as BTC/USD.
+type Market struct {
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+}
+
+func NewMarket(base string, quote string) Market {
+	return Market{Base: base, Quote: quote}
+}
+
+func (m Market) String() string {
+	return m.Base + "/" + m.Quote
+}
+
+type Side string
+
+const (
+	Bid Side = "bid"
+	Ask Side = "ask"
+)
+
+type OrderType string
+
+const (
+	LimitOrder  OrderType = "limit"
+	MarketOrder OrderType = "market"
+)
+
+// SelfTradePrevention decides what happens when an order would match another
+// order of the same signer.
+type SelfTradePrevention string
+
+const (
+	STPNone               SelfTradePrevention = "none"
+	STPCancelNewest       SelfTradePrevention = "cancel_newest"
+	STPCancelOldest       SelfTradePrevention = "cancel_oldest"
+	STPCancelBoth         SelfTradePrevention = "cancel_both"
+	STPDecrementAndCancel SelfTradePrevention = "decrement_and_cancel"
+)
+
+type TradingState string
+
+const (
+	StateOpen       TradingState = "open"
+	StateHalted     TradingState = "halted"
+	StateCancelOnly TradingState = "cancel_only"
+	StateAuction    TradingState = "auction"
+)
+
+type Order struct {
+	ID                  uint64              `json:"id"`
+	Side                Side                `json:"side"`
+	Price               float64             `json:"price"`
+	Size                float64             `json:"size"`
+	Timestamp           int64               `json:"timestamp"`
+	Signer              string              `json:"signer,omitempty"`
+	SelfTradePrevention SelfTradePrevention `json:"self_trade_prevention,omitempty"`
+}
+
+// Limit is a price level of a book and the orders resting at it.
+type Limit struct {
+	Price       float64  `json:"price"`
+	TotalVolume float64  `json:"total_volume"`
+	Orders      []*Order `json:"orders"`
+}
+
+// Uncross is the price and volume an auction would trade at.
+type Uncross struct {
+	Price     float64 `json:"price"`
+	Volume    float64 `json:"volume"`
+	Imbalance float64 `json:"imbalance"`
+}
+
+type FeeTier struct {
+	MinVolume float64 `json:"min_volume"`
+	MakerRate float64 `json:"maker_rate"`
+	TakerRate float64 `json:"taker_rate"`
+}
+
+// FeeSchedule sets the fees of a market as a fraction of the quote value of
+// each trade. A negative maker rate is a rebate.
+type FeeSchedule struct {
+	MakerRate float64   `json:"maker_rate"`
+	TakerRate float64   `json:"taker_rate"`
+	Tiers     []FeeTier `json:"tiers,omitempty"`
+}
+
+type Orderbook struct {
+	Market Market       `json:"market"`
+	Asks   []*Limit     `json:"asks"`
+	Bids   []*Limit     `json:"bids"`
+	State  TradingState `json:"state"`
+	// ResumeAt is when a circuit breaker halt ends, in unix nanoseconds.
+	ResumeAt       int64        `json:"resume_at,omitempty"`
+	LastTradePrice float64      `json:"last_trade_price"`
+	Indicative     *Uncross     `json:"indicative,omitempty"`
+	Fees           *FeeSchedule `json:"fees,omitempty"`
+}
+
+type MarketSummary struct {
+	Market         Market       `json:"market"`
+	State          TradingState `json:"state"`
+	BestBid        float64      `json:"best_bid"`
+	BestAsk        float64      `json:"best_ask"`
+	LastTradePrice float64      `json:"last_trade_price"`
+}
+
+// Match is a fill between a resting order and an incoming market order.
+type Match struct {
+	Ask        *Order  `json:"ask"`
+	Bid        *Order  `json:"bid"`
+	SizeFilled float64 `json:"size_filled"`
+	Price      float64 `json:"price"`
+	AskFee     float64 `json:"ask_fee"`
+	BidFee     float64 `json:"bid_fee"`
+}
+
+// ImportLayout is the kind of CSV file an import was read as.
+type ImportLayout string
+
+type ImportResult struct {
+	Market Market       `json:"market"`
+	Layout ImportLayout `json:"layout"`
+	Orders int          `json:"orders"`
+}
+
+type ExportView string
+
+const (
+	ExportBook   ExportView = "book"
+	ExportOrders ExportView = "orders"
+	ExportTrades ExportView = "trades"
+)
+
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportJSON ExportFormat = "json"
+)
+
+type TxAction string
+
+const (
+	Deposit    TxAction = "deposit"
+	Withdraw   TxAction = "withdraw"
+	Transfer   TxAction = "transfer"
+	Adjustment TxAction = "adjustment"
+	Fee        TxAction = "fee"
+)
+
+type Tx struct {
+	ID        uint64   `json:"id"`
+	Action    TxAction `json:"action"`
+	Signer    string   `json:"signer"`
+	Amount    float64  `json:"amount"`
+	Timestamp int64    `json:"timestamp"`
+}
+
+// Entry is one side of a ledger transaction.
+type Entry struct {
+	ID        uint64   `json:"id"`
+	TxID      uint64   `json:"tx_id"`
+	Action    TxAction `json:"action"`
+	Account   string   `json:"account"`
+	Debit     float64  `json:"debit"`
+	Credit    float64  `json:"credit"`
+	Balance   float64  `json:"balance"`
+	Timestamp int64    `json:"timestamp"`
+	Reference string   `json:"reference,omitempty"`
+	Memo      string   `json:"memo,omitempty"`
+}
+
+// TransactionsPage is a page of ledger entries, newest first. Next is passed
+// as Before to fetch the following page, and is zero on the last page.
+type TransactionsPage struct {
+	Entries []*Entry `json:"entries"`
+	Next    uint64   `json:"next,omitempty"`
+}
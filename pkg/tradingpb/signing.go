@@ -0,0 +1,64 @@
+package tradingpb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/richo225/octgopus/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Metadata keys of the request signature. gRPC metadata keys are lowercase.
+const (
+	KeyMetadata       = "x-api-key"
+	TimestampMetadata = "x-api-timestamp"
+	NonceMetadata     = "x-api-nonce"
+	SignatureMetadata = "x-api-signature"
+)
+
+// SignedBody is the part of a request covered by its signature: the
+// deterministic protobuf encoding of the request message.
+func SignedBody(req interface{}) ([]byte, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil, nil
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
+
+// UnarySigner signs every unary call with an API key, e.g.
+//
+//	conn, err := grpc.Dial(addr, grpc.WithUnaryInterceptor(tradingpb.UnarySigner(keyID, secret)), ...)
+func UnarySigner(keyID string, secret string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		body, err := SignedBody(req)
+		if err != nil {
+			return err
+		}
+
+		timestamp := time.Now().Unix()
+		nonce := newNonce()
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			KeyMetadata, keyID,
+			TimestampMetadata, strconv.FormatInt(timestamp, 10),
+			NonceMetadata, nonce,
+			SignatureMetadata, auth.Sign(secret, http.MethodPost, method, timestamp, nonce, body),
+		)
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func newNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}